@@ -29,7 +29,7 @@ var appCopyCmd = &cobra.Command{
 		input := &qbclient.CopyAppInput{Properties: &qbclient.CopyAppInputProperties{}}
 		qbcli.GetOptions(ctx, logger, input, appCopyCfg)
 
-		output, err := qb.CopyApp(input)
+		output, err := qb.CopyAppWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
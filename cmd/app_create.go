@@ -24,7 +24,7 @@ var appCreateCmd = &cobra.Command{
 		input := &qbclient.CreateAppInput{}
 		qbcli.GetOptions(ctx, logger, input, appCreateCfg)
 
-		output, err := qb.CreateApp(input)
+		output, err := qb.CreateAppWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
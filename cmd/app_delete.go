@@ -29,7 +29,7 @@ var appDeleteCmd = &cobra.Command{
 		input := &qbclient.DeleteAppInput{}
 		qbcli.GetOptions(ctx, logger, input, appDeleteCfg)
 
-		output, err := qb.DeleteApp(input)
+		output, err := qb.DeleteAppWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
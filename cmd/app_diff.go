@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var appDiffCfg *viper.Viper
+
+var appDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the tables, fields, relationships, reports, and variables in two apps",
+	Long: `Compare the tables, fields, relationships, reports, and variables in two apps
+
+The differences are rendered as JSON by default, or as human readable text that
+is colored in terminals if --format text is passed.`,
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		if err = globalCfg.Validate(); err == nil {
+			globalCfg.SetDefaultAppID(appDiffCfg)
+			qbcli.SetOptionFromArg(appDiffCfg, args, 0, qbclient.OptionAppID)
+			qbcli.SetOptionFromArg(appDiffCfg, args, 1, "other-app-id")
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
+
+		opts := &qbcli.AppDiffOptions{}
+		qbcli.GetOptions(ctx, logger, opts, appDiffCfg)
+
+		oqb, err := qbcli.NewOtherClient(qb, globalCfg, opts.OtherProfile)
+		qbcli.HandleError(ctx, logger, "error configuring client", err)
+
+		diff, err := qbcli.DiffApps(ctx, qb, oqb, opts.AppID, opts.OtherAppID)
+		if globalCfg.Format() != qbcli.AppDiffFormatText {
+			qbcli.Render(ctx, logger, cmd, globalCfg, diff, err)
+			return
+		}
+
+		qbcli.HandleError(ctx, logger, "error comparing apps", err)
+		if !globalCfg.Quiet() {
+			err = qbcli.WriteAppDiff(os.Stdout, diff, qbcli.IsTerminal(os.Stdout))
+			qbcli.HandleError(ctx, logger, "error writing diff", err)
+		}
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	appDiffCfg, flags = cliutil.AddCommand(appCmd, appDiffCmd, qbclient.EnvPrefix)
+	flags.SetOptions(&qbcli.AppDiffOptions{})
+}
@@ -29,7 +29,7 @@ var appEventsCmd = &cobra.Command{
 		input := &qbclient.ListAppEventsInput{}
 		qbcli.GetOptions(ctx, logger, input, appEventsCfg)
 
-		output, err := qb.ListAppEvents(input)
+		output, err := qb.ListAppEventsWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
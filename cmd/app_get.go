@@ -29,7 +29,7 @@ var appGetCmd = &cobra.Command{
 		input := &qbclient.GetAppInput{}
 		qbcli.GetOptions(ctx, logger, input, appGetCfg)
 
-		output, err := qb.GetApp(input)
+		output, err := qb.GetAppWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
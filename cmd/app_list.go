@@ -24,7 +24,7 @@ var appListCmd = &cobra.Command{
 		input := &qbclient.ListAppsInput{}
 		qbcli.GetOptions(ctx, logger, input, appListCfg)
 
-		output, err := qb.ListApps(input)
+		output, err := qb.ListAppsWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
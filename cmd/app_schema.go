@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var appSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "App schema commands",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	appCmd.AddCommand(appSchemaCmd)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var appSchemaApplyCfg *viper.Viper
+
+var appSchemaApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Change an app to match a desired schema",
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		if err = globalCfg.Validate(); err == nil {
+			globalCfg.SetDefaultAppID(appSchemaApplyCfg)
+			qbcli.SetOptionFromArg(appSchemaApplyCfg, args, 0, qbclient.OptionAppID)
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
+
+		opts := &qbcli.SchemaPlanOptions{}
+		qbcli.GetOptions(ctx, logger, opts, appSchemaApplyCfg)
+
+		plan, err := qbcli.SchemaApply(ctx, qb, opts)
+		if globalCfg.Format() == qbcli.SchemaFormatJSON {
+			qbcli.Render(ctx, logger, cmd, globalCfg, plan, err)
+			return
+		}
+
+		if plan != nil && !globalCfg.Quiet() {
+			qbcli.HandleError(ctx, logger, "error writing plan", qbcli.WriteSchemaPlan(os.Stdout, plan))
+		}
+		qbcli.HandleError(ctx, logger, "error applying app schema changes", err)
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	appSchemaApplyCfg, flags = cliutil.AddCommand(appSchemaCmd, appSchemaApplyCmd, qbclient.EnvPrefix)
+	flags.SetOptions(&qbcli.SchemaPlanOptions{})
+}
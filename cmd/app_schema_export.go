@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var appSchemaExportCfg *viper.Viper
+
+var appSchemaExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the tables, fields, relationships, reports, and variables in an app",
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		if err = globalCfg.Validate(); err == nil {
+			globalCfg.SetDefaultAppID(appSchemaExportCfg)
+			qbcli.SetOptionFromArg(appSchemaExportCfg, args, 0, qbclient.OptionAppID)
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
+
+		opts := &qbcli.SchemaExportOptions{}
+		qbcli.GetOptions(ctx, logger, opts, appSchemaExportCfg)
+
+		err := qbcli.SchemaExport(ctx, qb, opts)
+		qbcli.HandleError(ctx, logger, "error exporting app schema", err)
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	appSchemaExportCfg, flags = cliutil.AddCommand(appSchemaCmd, appSchemaExportCmd, qbclient.EnvPrefix)
+	flags.SetOptions(&qbcli.SchemaExportOptions{})
+}
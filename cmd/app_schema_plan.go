@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var appSchemaPlanCfg *viper.Viper
+
+var appSchemaPlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the changes that make an app match a desired schema",
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		if err = globalCfg.Validate(); err == nil {
+			globalCfg.SetDefaultAppID(appSchemaPlanCfg)
+			qbcli.SetOptionFromArg(appSchemaPlanCfg, args, 0, qbclient.OptionAppID)
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
+
+		opts := &qbcli.SchemaPlanOptions{}
+		qbcli.GetOptions(ctx, logger, opts, appSchemaPlanCfg)
+
+		plan, err := qbcli.SchemaPlanFromFile(ctx, qb, opts)
+		if globalCfg.Format() == qbcli.SchemaFormatJSON {
+			qbcli.Render(ctx, logger, cmd, globalCfg, plan, err)
+			return
+		}
+
+		if plan != nil && !globalCfg.Quiet() {
+			qbcli.HandleError(ctx, logger, "error writing plan", qbcli.WriteSchemaPlan(os.Stdout, plan))
+		}
+		qbcli.HandleError(ctx, logger, "error planning app schema changes", err)
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	appSchemaPlanCfg, flags = cliutil.AddCommand(appSchemaCmd, appSchemaPlanCmd, qbclient.EnvPrefix)
+	flags.SetOptions(&qbcli.SchemaPlanOptions{})
+}
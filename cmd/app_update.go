@@ -29,7 +29,7 @@ var appUpdateCmd = &cobra.Command{
 		input := &qbclient.UpdateAppInput{}
 		qbcli.GetOptions(ctx, logger, input, appUpdateCfg)
 
-		output, err := qb.UpdateApp(input)
+		output, err := qb.UpdateAppWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
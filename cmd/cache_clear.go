@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cacheClearCfg *viper.Viper
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove cached table schemas for the realm",
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		if err = globalCfg.Validate(); err == nil {
+			qbcli.SetOptionFromArg(cacheClearCfg, args, 0, qbclient.OptionTableID)
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, _ := qbcli.NewLogger(cmd, globalCfg)
+		cache := qbcli.NewSchemaCache(globalCfg)
+
+		var err error
+		if tableID := cacheClearCfg.GetString(qbclient.OptionTableID); tableID != "" {
+			err = cache.Delete(tableID)
+		} else {
+			err = cache.Clear()
+		}
+
+		qbcli.HandleError(ctx, logger, "error clearing schema cache", err)
+		logger.Notice(ctx, "schema cache cleared")
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	cacheClearCfg, flags = cliutil.AddCommand(cacheCmd, cacheClearCmd, qbclient.EnvPrefix)
+	flags.String(qbclient.OptionTableID, "", "", "unique identifier (dbid) of the table, defaults to every table")
+}
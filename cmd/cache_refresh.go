@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cacheRefreshCfg *viper.Viper
+
+var cacheRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Retrieve and cache a table's schema",
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		if err = globalCfg.Validate(); err == nil {
+			globalCfg.SetDefaultTableID(cacheRefreshCfg)
+			qbcli.SetOptionFromArg(cacheRefreshCfg, args, 0, qbclient.OptionTableID)
+			if cacheRefreshCfg.GetString(qbclient.OptionTableID) == "" {
+				err = fmt.Errorf("option %q: %w", qbclient.OptionTableID, errors.New("value required"))
+			}
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
+
+		tableID := cacheRefreshCfg.GetString(qbclient.OptionTableID)
+		err := qbcli.RefreshTableSchema(ctx, qb, tableID)
+		qbcli.HandleError(ctx, logger, "error refreshing schema cache", err)
+		logger.Notice(ctx, "schema cache refreshed")
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	cacheRefreshCfg, flags = cliutil.AddCommand(cacheCmd, cacheRefreshCmd, qbclient.EnvPrefix)
+	flags.String(qbclient.OptionTableID, "", "", qbcli.OptionTableIDDescription)
+}
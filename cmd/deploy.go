@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var deployCfg *viper.Viper
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy field properties, formulas, pages, and variables in the quickbase.yml file",
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		if err = globalCfg.SetProfileFromEnvironment(deployCfg.GetString("file"), deployCfg.GetString("env")); err != nil {
+			return
+		}
+		if err = globalCfg.Validate(); err == nil {
+			globalCfg.SetDefaultAppID(deployCfg)
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
+
+		input := &qbcli.DeployInput{}
+		qbcli.GetOptions(ctx, logger, input, deployCfg)
+
+		output, err := qbcli.Deploy(ctx, qb, input)
+		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
+
+		// Exit with an error so that failed deployments fail CI pipelines.
+		if len(output.Errors) > 0 {
+			qbcli.HandleError(ctx, logger, "error deploying", fmt.Errorf("%s %s: %s", output.Errors[0].Resource, output.Errors[0].Name, output.Errors[0].Error))
+		}
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	deployCfg, flags = cliutil.AddCommand(rootCmd, deployCmd, qbclient.EnvPrefix)
+	flags.SetOptions(&qbcli.DeployInput{})
+}
@@ -33,7 +33,10 @@ var fieldCreateCmd = &cobra.Command{
 			input.Properties.Formula = input.Properties.FormulaFile
 		}
 
-		output, err := qb.CreateField(input)
+		output, err := qb.CreateFieldWithContext(ctx, input)
+		if err == nil {
+			logger.ErrorIfError(ctx, "error invalidating schema cache", qbcli.InvalidateTableSchema(input.TableID))
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -30,7 +30,10 @@ var fieldCreateLookupCmd = &cobra.Command{
 		input := &qbclient.UpdateRelationshipInput{}
 		qbcli.GetOptions(ctx, logger, input, fieldCreateLookupCfg)
 
-		output, err := qb.UpdateRelationship(input)
+		output, err := qb.UpdateRelationshipWithContext(ctx, input)
+		if err == nil {
+			logger.ErrorIfError(ctx, "error invalidating schema cache", qbcli.InvalidateTableSchema(input.ChildTableID))
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
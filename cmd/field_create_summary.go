@@ -33,7 +33,10 @@ var fieldCreateSummaryCmd = &cobra.Command{
 		qbcli.GetOptions(ctx, logger, sf, fieldCreateSummaryCfg)
 		input.SummaryFields = []*qbclient.RelationshipSummaryField{sf}
 
-		output, err := qb.UpdateRelationship(input)
+		output, err := qb.UpdateRelationshipWithContext(ctx, input)
+		if err == nil {
+			logger.ErrorIfError(ctx, "error invalidating schema cache", qbcli.InvalidateTableSchema(input.ChildTableID, output.ParentTableID))
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -16,6 +16,7 @@ var fieldDeleteCmd = &cobra.Command{
 
 	Args: func(cmd *cobra.Command, args []string) (err error) {
 		if err = globalCfg.Validate(); err == nil {
+			globalCfg.SetDefaultAppID(fieldDeleteCfg)
 			globalCfg.SetDefaultTableID(fieldDeleteCfg)
 			qbcli.SetOptionFromArg(fieldDeleteCfg, args, 0, qbclient.OptionTableID)
 			qbcli.SetOptionFromArg(fieldDeleteCfg, args, 1, qbclient.OptionFieldID)
@@ -29,7 +30,15 @@ var fieldDeleteCmd = &cobra.Command{
 		input := &qbclient.DeleteFieldsInput{}
 		qbcli.GetOptions(ctx, logger, input, fieldDeleteCfg)
 
-		output, err := qb.DeleteFields(input)
+		// Warn about formulas, lookups, and summaries that reference the
+		// fields, since they break once the fields are gone.
+		appID := fieldDeleteCfg.GetString(qbclient.OptionAppID)
+		qbcli.LogFieldDependents(ctx, logger, qb, appID, input.TableID, input.FieldIDs)
+
+		output, err := qb.DeleteFieldsWithContext(ctx, input)
+		if err == nil {
+			logger.ErrorIfError(ctx, "error invalidating schema cache", qbcli.InvalidateTableSchema(input.TableID))
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -38,4 +47,5 @@ func init() {
 	var flags *cliutil.Flagger
 	fieldDeleteCfg, flags = cliutil.AddCommand(fieldCmd, fieldDeleteCmd, qbclient.EnvPrefix)
 	flags.SetOptions(&qbclient.DeleteFieldsInput{})
+	flags.String(qbclient.OptionAppID, "", "", "app scanned for fields that depend on the deleted fields, defaults to only the table")
 }
@@ -29,7 +29,7 @@ var fieldGetCmd = &cobra.Command{
 		input := &qbclient.GetFieldInput{}
 		qbcli.GetOptions(ctx, logger, input, fieldGetCfg)
 
-		output, err := qb.GetField(input)
+		output, err := qb.GetFieldWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -28,7 +28,7 @@ var fieldListCmd = &cobra.Command{
 		input := &qbclient.ListFieldsInput{}
 		qbcli.GetOptions(ctx, logger, input, fieldListCfg)
 
-		output, err := qb.ListFields(input)
+		output, err := qb.ListFieldsWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
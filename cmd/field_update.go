@@ -34,7 +34,10 @@ var fieldUpdateCmd = &cobra.Command{
 			input.Properties.Formula = input.Properties.FormulaFile
 		}
 
-		output, err := qb.UpdateField(input)
+		output, err := qb.UpdateFieldWithContext(ctx, input)
+		if err == nil {
+			logger.ErrorIfError(ctx, "error invalidating schema cache", qbcli.InvalidateTableSchema(input.TableID))
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -37,7 +37,7 @@ var fileCreateCmd = &cobra.Command{
 		qbcli.GetOptions(ctx, logger, file, fileCreateCfg)
 		input.Fields = []*qbclient.CreateFileInputField{file}
 
-		output, err := qb.CreateFile(input)
+		output, err := qb.CreateFileWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
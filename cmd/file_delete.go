@@ -31,7 +31,7 @@ var fileDeleteCmd = &cobra.Command{
 		input := &qbclient.DeleteFileInput{}
 		qbcli.GetOptions(ctx, logger, input, fileDeleteCfg)
 
-		output, err := qb.DeleteFile(input)
+		output, err := qb.DeleteFileWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -15,7 +15,9 @@ var formulaDeployCmd = &cobra.Command{
 	Short: "Deploy formulas to an app",
 
 	Args: func(cmd *cobra.Command, args []string) (err error) {
-		err = globalCfg.Validate()
+		if err = globalCfg.SetProfileFromEnvironment(formulaDeployCfg.GetString("file"), formulaDeployCfg.GetString("env")); err == nil {
+			err = globalCfg.Validate()
+		}
 		return
 	},
 
@@ -25,7 +27,7 @@ var formulaDeployCmd = &cobra.Command{
 		input := &qbcli.DeployFormulaInput{}
 		qbcli.GetOptions(ctx, logger, input, formulaDeployCfg)
 
-		output, err := qbcli.DeployFormula(qb, input)
+		output, err := qbcli.DeployFormulaWithContext(ctx, qb, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var formulaDepsCfg *viper.Viper
+
+var formulaDepsCmd = &cobra.Command{
+	Use:   "deps",
+	Short: "Show the fields that formulas, lookups, and summaries depend on",
+	Long: `Show the fields that formulas, lookups, and summaries depend on
+
+The graph is rendered as JSON by default, or in the Graphviz DOT language if
+--format dot is passed, e.g., quickbase-cli formula deps --format dot | dot -Tsvg`,
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		if err = globalCfg.Validate(); err == nil {
+			globalCfg.SetDefaultAppID(formulaDepsCfg)
+			globalCfg.SetDefaultTableID(formulaDepsCfg)
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
+
+		input := &qbcli.FormulaDepsInput{}
+		qbcli.GetOptions(ctx, logger, input, formulaDepsCfg)
+
+		output, err := qbcli.FormulaDependencies(ctx, qb, input)
+		if globalCfg.Format() != qbcli.FormulaDepsFormatDOT {
+			qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
+			return
+		}
+
+		qbcli.HandleError(ctx, logger, "error building dependency graph", err)
+		qbcli.HandleError(ctx, logger, "error writing dependency graph", qbcli.WriteFieldDepsDOT(os.Stdout, output))
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	formulaDepsCfg, flags = cliutil.AddCommand(formulaCmd, formulaDepsCmd, qbclient.EnvPrefix)
+	flags.SetOptions(&qbcli.FormulaDepsInput{})
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/cpliakas/cliutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var formulaLintCfg *viper.Viper
+
+var formulaLintCmd = &cobra.Command{
+	Use:   "lint [FILE]...",
+	Short: "Check formulas for errors without running them",
+
+	Args: func(cmd *cobra.Command, args []string) (err error) {
+		// Fields are read from the realm's schema cache unless an app schema
+		// export is passed, so the configuration is only needed then.
+		if formulaLintCfg.GetString("schema") == "" {
+			if err = globalCfg.SetProfileFromEnvironment(formulaLintCfg.GetString("file"), formulaLintCfg.GetString("env")); err == nil {
+				err = globalCfg.Validate()
+			}
+		}
+		if err == nil {
+			globalCfg.SetDefaultTableID(formulaLintCfg)
+		}
+		return
+	},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, logger, _ := qbcli.NewLogger(cmd, globalCfg)
+
+		input := &qbcli.LintFormulaInput{Files: args}
+		qbcli.GetOptions(ctx, logger, input, formulaLintCfg)
+
+		var cache *qbcli.SchemaCache
+		if input.Schema == "" {
+			cache = qbcli.NewSchemaCache(globalCfg)
+		}
+
+		output, err := qbcli.LintFormula(input, cache)
+		if globalCfg.Format() == qbcli.SchemaFormatJSON {
+			qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
+		} else if output != nil && !globalCfg.Quiet() {
+			qbcli.HandleError(ctx, logger, "error writing diagnostics", qbcli.WriteFormulaLint(os.Stdout, output))
+		}
+		qbcli.HandleError(ctx, logger, "error linting formulas", err)
+
+		if output.Errors > 0 {
+			err = qbcli.LintFailedError("%v errors found", output.Errors)
+			qbcli.HandleError(ctx, logger, "lint failed", err)
+		}
+	},
+}
+
+func init() {
+	var flags *cliutil.Flagger
+	formulaLintCfg, flags = cliutil.AddCommand(formulaCmd, formulaLintCmd, qbclient.EnvPrefix)
+	flags.SetOptions(&qbcli.LintFormulaInput{})
+}
@@ -32,7 +32,7 @@ var formulaRunCmd = &cobra.Command{
 		input := &qbclient.RunFormulaInput{}
 		qbcli.GetOptions(ctx, logger, input, formulaRunCfg)
 
-		output, err := qb.RunFormula(input)
+		output, err := qb.RunFormulaWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/QuickBase/quickbase-cli/qbcli"
 	"github.com/QuickBase/quickbase-cli/qbclient"
 	"github.com/cpliakas/cliutil"
@@ -15,17 +17,35 @@ var formulaTestCmd = &cobra.Command{
 	Short: "Test formulas",
 
 	Args: func(cmd *cobra.Command, args []string) (err error) {
-		err = globalCfg.Validate()
+		// Offline tests don't connect to a realm, so no configuration is
+		// required.
+		if formulaTestCfg.GetBool("offline") {
+			return
+		}
+		if err = globalCfg.SetProfileFromEnvironment(formulaTestCfg.GetString("file"), formulaTestCfg.GetString("env")); err == nil {
+			err = globalCfg.Validate()
+		}
 		return
 	},
 
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
+		var qb *qbclient.Client
+		ctx, logger, _ := qbcli.NewLogger(cmd, globalCfg)
+		if !formulaTestCfg.GetBool("offline") {
+			ctx, logger, qb = qbcli.NewClient(cmd, globalCfg)
+		}
 
 		input := &qbcli.TestFormulaInput{}
 		qbcli.GetOptions(ctx, logger, input, formulaTestCfg)
 
-		output, err := qbcli.TestFormula(qb, input)
+		output, err := qbcli.TestFormulaWithContext(ctx, qb, input)
+		if err == nil && input.JUnitFile != "" {
+			file, ferr := os.Create(input.JUnitFile)
+			qbcli.HandleError(ctx, logger, "error creating junit file", ferr)
+			err = qbcli.WriteFormulaTestReport(file, output)
+			file.Close()
+			qbcli.HandleError(ctx, logger, "error writing junit file", err)
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 
 		if len(output.Failed) > 0 {
@@ -27,7 +27,7 @@ var pageCreateCmd = &cobra.Command{
 		input := &qbclient.CreatePageInput{Body: &qbclient.CreatePageInputBody{}}
 		qbcli.GetOptions(ctx, logger, input, pageCreateCfg)
 
-		output, err := qb.CreatePage(input)
+		output, err := qb.CreatePageWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -28,7 +28,7 @@ var pageGetCmd = &cobra.Command{
 		input := &qbclient.GetPageInput{}
 		qbcli.GetOptions(ctx, logger, input, pageGetCfg)
 
-		output, err := qb.GetPage(input)
+		output, err := qb.GetPageWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -27,7 +27,7 @@ var pageUpdateCmd = &cobra.Command{
 		input := &qbclient.UpdatePageInput{Body: &qbclient.UpdatePageInputBody{}}
 		qbcli.GetOptions(ctx, logger, input, pageUpdateCfg)
 
-		output, err := qb.UpdatePage(input)
+		output, err := qb.UpdatePageWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -29,7 +29,7 @@ var recordsDeleteCmd = &cobra.Command{
 		input := &qbclient.DeleteRecordsInput{}
 		qbcli.GetOptions(ctx, logger, input, recordsDeleteCfg)
 
-		output, err := qb.DeleteRecords(input)
+		output, err := qb.DeleteRecordsWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
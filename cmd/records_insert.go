@@ -26,13 +26,13 @@ var recordsInsertCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx, logger, qb := qbcli.NewClient(cmd, globalCfg)
 
-		err := qbcli.CacheTableSchema(qb, recordsInsertCfg.GetString("to"))
+		_, err := qbcli.GetTableSchemaWithContext(ctx, qb, recordsInsertCfg.GetString("to"))
 		qbcli.HandleError(ctx, logger, "error setting field type map", err)
 
 		input := &qbclient.InsertRecordsInput{}
 		qbcli.GetOptions(ctx, logger, input, recordsInsertCfg)
 
-		output, err := qb.InsertRecords(input)
+		output, err := qb.InsertRecordsWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -29,7 +29,13 @@ var recordsQueryCmd = &cobra.Command{
 		input := &qbclient.QueryRecordsInput{Options: &qbclient.QueryRecordsInputOptions{}}
 		qbcli.GetOptions(ctx, logger, input, recordsQueryCfg)
 
-		output, err := qb.QueryRecords(input)
+		// Stream every record if the --all option is passed.
+		if recordsQueryCfg.GetBool("all") {
+			qbcli.RenderRecords(ctx, logger, cmd, globalCfg, qb.QueryRecordsAllWithContext(ctx, input))
+			return
+		}
+
+		output, err := qb.QueryRecordsWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -38,4 +44,5 @@ func init() {
 	var flags *cliutil.Flagger
 	recordsQueryCfg, flags = cliutil.AddCommand(recordsCmd, recordsQueryCmd, qbclient.EnvPrefix)
 	flags.SetOptions(&qbclient.QueryRecordsInput{Options: &qbclient.QueryRecordsInputOptions{}})
+	flags.Bool("all", "", false, "page through the results and return every record as JSON Lines")
 }
@@ -29,7 +29,10 @@ var relationshipCreateCmd = &cobra.Command{
 		input := &qbclient.CreateRelationshipInput{ForeignKeyField: &qbclient.CreateRelationshipInputForeignKeyField{}}
 		qbcli.GetOptions(ctx, logger, input, relationshipCreateCfg)
 
-		output, err := qb.CreateRelationship(input)
+		output, err := qb.CreateRelationshipWithContext(ctx, input)
+		if err == nil {
+			logger.ErrorIfError(ctx, "error invalidating schema cache", qbcli.InvalidateTableSchema(input.ChildTableID, input.ParentTableID))
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -29,7 +29,10 @@ var relationshipDeleteCmd = &cobra.Command{
 		input := &qbclient.DeleteRelationshipInput{}
 		qbcli.GetOptions(ctx, logger, input, relationshipDeleteCfg)
 
-		output, err := qb.DeleteRelationship(input)
+		output, err := qb.DeleteRelationshipWithContext(ctx, input)
+		if err == nil {
+			logger.ErrorIfError(ctx, "error invalidating schema cache", qbcli.InvalidateTableSchema(input.ChildTableID))
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -28,7 +28,7 @@ var relationshipListCmd = &cobra.Command{
 		input := &qbclient.ListRelationshipsInput{}
 		qbcli.GetOptions(ctx, logger, input, relationshipListCfg)
 
-		output, err := qb.ListRelationships(input)
+		output, err := qb.ListRelationshipsWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
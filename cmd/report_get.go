@@ -29,7 +29,7 @@ var reportGetCmd = &cobra.Command{
 		input := &qbclient.GetReportInput{}
 		qbcli.GetOptions(ctx, logger, input, reportGetCfg)
 
-		output, err := qb.GetReport(input)
+		output, err := qb.GetReportWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
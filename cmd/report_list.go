@@ -28,7 +28,7 @@ var reportListCmd = &cobra.Command{
 		input := &qbclient.ListReportsInput{}
 		qbcli.GetOptions(ctx, logger, input, reportListCfg)
 
-		output, err := qb.ListReports(input)
+		output, err := qb.ListReportsWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
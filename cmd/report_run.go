@@ -29,7 +29,13 @@ var reportRunCmd = &cobra.Command{
 		input := &qbclient.RunReportInput{}
 		qbcli.GetOptions(ctx, logger, input, reportRunCfg)
 
-		output, err := qb.RunReport(input)
+		// Stream every record if the --all option is passed.
+		if reportRunCfg.GetBool("all") {
+			qbcli.RenderRecords(ctx, logger, cmd, globalCfg, qb.RunReportAllWithContext(ctx, input))
+			return
+		}
+
+		output, err := qb.RunReportWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -38,4 +44,5 @@ func init() {
 	var flags *cliutil.Flagger
 	reportRunCfg, flags = cliutil.AddCommand(reportCmd, reportRunCmd, qbclient.EnvPrefix)
 	flags.SetOptions(&qbclient.RunReportInput{})
+	flags.Bool("all", "", false, "page through the results and return every record as JSON Lines")
 }
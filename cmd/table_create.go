@@ -28,7 +28,7 @@ var tableCreateCmd = &cobra.Command{
 		input := &qbclient.CreateTableInput{}
 		qbcli.GetOptions(ctx, logger, input, tableCreateCfg)
 
-		output, err := qb.CreateTable(input)
+		output, err := qb.CreateTableWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -28,7 +28,10 @@ var tableDeleteCmd = &cobra.Command{
 		input := &qbclient.DeleteTableInput{}
 		qbcli.GetOptions(ctx, logger, input, tableDeleteCfg)
 
-		output, err := qb.DeleteTable(input)
+		output, err := qb.DeleteTableWithContext(ctx, input)
+		if err == nil {
+			logger.ErrorIfError(ctx, "error invalidating schema cache", qbcli.InvalidateTableSchema(input.TableID))
+		}
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -27,8 +27,15 @@ var tableExportCmd = &cobra.Command{
 
 		opts := &qbcli.ExportOptions{}
 		qbcli.GetOptions(ctx, logger, opts, tableExportCfg)
+		qbcli.InstrumentClient(ctx, qb, opts.MetricsFile, opts.OTLPEndpoint)
 
-		err := qbcli.Export(qb, opts)
+		// Store the incremental export state in the config dir by default.
+		if opts.StateFile == "" {
+			opts.StateFile = qbclient.Filepath(globalCfg.ConfigDir(), "exports", opts.TableID+".json")
+		}
+
+		err := qbcli.ExportWithContext(ctx, qb, opts)
+		qbcli.FlushMetrics(ctx, logger, qb, opts.MetricsFile, opts.OTLPEndpoint)
 		qbcli.HandleError(ctx, logger, "error exporting records", err)
 	},
 }
@@ -29,7 +29,7 @@ var tableGetCmd = &cobra.Command{
 		input := &qbclient.GetTableInput{}
 		qbcli.GetOptions(ctx, logger, input, tableGetCfg)
 
-		output, err := qb.GetTable(input)
+		output, err := qb.GetTableWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -27,8 +27,10 @@ var tableImportCmd = &cobra.Command{
 
 		opts := &qbcli.ImportOptions{}
 		qbcli.GetOptions(ctx, logger, opts, tableImportCfg)
+		qbcli.InstrumentClient(ctx, qb, opts.MetricsFile, opts.OTLPEndpoint)
 
-		output, err := qbcli.Import(qb, opts)
+		output, err := qbcli.ImportWithContext(ctx, qb, opts)
+		qbcli.FlushMetrics(ctx, logger, qb, opts.MetricsFile, opts.OTLPEndpoint)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
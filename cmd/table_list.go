@@ -28,7 +28,7 @@ var tableListCmd = &cobra.Command{
 		input := &qbclient.ListTablesInput{}
 		qbcli.GetOptions(ctx, logger, input, tableListCfg)
 
-		output, err := qb.ListTables(input)
+		output, err := qb.ListTablesWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -29,7 +29,7 @@ var tableUpdateCmd = &cobra.Command{
 		input := &qbclient.UpdateTableInput{}
 		qbcli.GetOptions(ctx, logger, input, tableUpdateCfg)
 
-		output, err := qb.UpdateTable(input)
+		output, err := qb.UpdateTableWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
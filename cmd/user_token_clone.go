@@ -24,7 +24,7 @@ var userTokenCloneCmd = &cobra.Command{
 		input := &qbclient.CloneUserTokenInput{}
 		qbcli.GetOptions(ctx, logger, input, userTokenCloneCfg)
 
-		output, err := qb.CloneUserToken(input)
+		output, err := qb.CloneUserTokenWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -27,7 +27,7 @@ var userTokenDeactivateCmd = &cobra.Command{
 		input := &qbclient.DeactivateUserTokenInput{}
 		qbcli.GetOptions(ctx, logger, input, userTokenDeactivateCfg)
 
-		output, err := qb.DeactivateUserToken(input)
+		output, err := qb.DeactivateUserTokenWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -27,7 +27,7 @@ var userTokenDeleteCmd = &cobra.Command{
 		input := &qbclient.DeleteUserTokenInput{}
 		qbcli.GetOptions(ctx, logger, input, userTokenDeleteCfg)
 
-		output, err := qb.DeleteUserToken(input)
+		output, err := qb.DeleteUserTokenWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -29,7 +29,7 @@ var variableGetCmd = &cobra.Command{
 		input := &qbclient.GetVariableInput{}
 		qbcli.GetOptions(ctx, logger, input, variableGetCfg)
 
-		output, err := qb.GetVariable(input)
+		output, err := qb.GetVariableWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
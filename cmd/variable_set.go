@@ -29,7 +29,7 @@ var variableSetCmd = &cobra.Command{
 		input := &qbclient.SetVariableInput{}
 		qbcli.GetOptions(ctx, logger, input, variableSetCfg)
 
-		output, err := qb.SetVariable(input)
+		output, err := qb.SetVariableWithContext(ctx, input)
 		qbcli.Render(ctx, logger, cmd, globalCfg, output, err)
 	},
 }
@@ -16,5 +16,6 @@ require (
 	github.com/rs/xid v1.3.0
 	github.com/spf13/cobra v1.1.3
 	github.com/spf13/viper v1.7.1
+	github.com/xuri/excelize/v2 v2.4.1
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
@@ -0,0 +1,392 @@
+package qbcli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/viper"
+)
+
+// AppDiff* constants contain how an entity differs between two apps.
+const (
+	AppDiffAdded   = "added"
+	AppDiffRemoved = "removed"
+	AppDiffChanged = "changed"
+)
+
+// SchemaResourceReport is a report in a schema diff.
+const SchemaResourceReport = "report"
+
+// AppDiffFormatText is the format that renders the differences as human
+// readable text instead of JSON.
+const AppDiffFormatText = "text"
+
+// AppDiffOptions are the options read through the command line.
+type AppDiffOptions struct {
+	AppID        string `validate:"required" cliutil:"option=app-id"`
+	OtherAppID   string `validate:"required" cliutil:"option=other-app-id usage='unique identifier of the app compared against (required)'"`
+	OtherProfile string `cliutil:"option=other-profile usage='configuration profile of the app compared against, defaults to --profile'"`
+}
+
+// AppDiff contains the differences between two apps. Old values are from the
+// app and new values are from the other app.
+type AppDiff struct {
+	AppID       string          `json:"appId"`
+	OtherAppID  string          `json:"otherAppId"`
+	Differences []*AppDiffEntry `json:"differences"`
+}
+
+// AppDiffEntry is a table, field, relationship, report, or variable that
+// only exists in one of the apps or that differs between them.
+type AppDiffEntry struct {
+	Status   string                `json:"status"`
+	Resource string                `json:"resource"`
+	Table    string                `json:"table,omitempty"`
+	Name     string                `json:"name"`
+	Diff     []*SchemaPropertyDiff `json:"diff,omitempty"`
+}
+
+// NewOtherClient returns a client for the profile that the other app is in.
+// The client for the current profile is returned if profile is empty or the
+// same as the current one.
+func NewOtherClient(qb *qbclient.Client, cfg GlobalConfig, profile string) (*qbclient.Client, error) {
+	if profile == "" || profile == cfg.Profile() {
+		return qb, nil
+	}
+
+	other := viper.New()
+	other.Set(qbclient.OptionProfile, profile)
+	other.Set(qbclient.OptionConfigDir, cfg.ConfigDir())
+	if err := qbclient.ReadInConfig(other); err != nil {
+		return nil, fmt.Errorf("error reading configuration for profile %q: %w", profile, err)
+	}
+	if other.GetString(qbclient.OptionRealmHostname) == "" && cfg.ReplayFile() == "" {
+		return nil, fmt.Errorf("profile %q: %w", profile, errors.New("realm hostname required"))
+	}
+
+	// Log, dump, record, and replay requests to the other app the same way.
+	oqb := qbclient.New(qbclient.NewConfig(other))
+	oqb.Middleware = append(oqb.Middleware, qb.Middleware...)
+	oqb.Plugins = append(oqb.Plugins, qb.Plugins...)
+	oqb.DryRun, oqb.DryRunWriter = qb.DryRun, qb.DryRunWriter
+	oqb.UseCassette(qb)
+
+	return oqb, nil
+}
+
+// DiffApps exports the schemas of two apps and compares them.
+func DiffApps(ctx context.Context, qb, oqb *qbclient.Client, appID, otherAppID string) (*AppDiff, error) {
+	a, err := ExportAppSchema(ctx, qb, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ExportAppSchema(ctx, oqb, otherAppID)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffAppSchemas(a, b), nil
+}
+
+// DiffAppSchemas compares two app schemas. IDs diverge between copies of an
+// app, so tables are matched by alias then name, fields by label,
+// relationships by parent table and foreign key label, reports by name, and
+// field IDs in table and report properties are compared by label.
+func DiffAppSchemas(a, b *AppSchema) *AppDiff {
+	d := &AppDiff{AppID: a.AppID, OtherAppID: b.AppID, Differences: []*AppDiffEntry{}}
+
+	matched := make(map[*SchemaTable]bool, len(b.Tables))
+	for _, at := range a.Tables {
+		bt := matchDiffTable(b.Tables, at)
+		if bt == nil {
+			d.add(AppDiffRemoved, SchemaResourceTable, "", schemaTableKey(at), nil)
+			continue
+		}
+		matched[bt] = true
+		d.diffTables(at, bt)
+	}
+	for _, bt := range b.Tables {
+		if !matched[bt] {
+			d.add(AppDiffAdded, SchemaResourceTable, "", schemaTableKey(bt), nil)
+		}
+	}
+
+	d.diffVariables(a.Variables, b.Variables)
+
+	return d
+}
+
+func (d *AppDiff) add(status, resource, table, name string, diff []*SchemaPropertyDiff) {
+	if status == AppDiffChanged && len(diff) == 0 {
+		return
+	}
+	d.Differences = append(d.Differences, &AppDiffEntry{
+		Status:   status,
+		Resource: resource,
+		Table:    table,
+		Name:     name,
+		Diff:     diff,
+	})
+}
+
+func matchDiffTable(tables []*SchemaTable, t *SchemaTable) *SchemaTable {
+	if t.Alias != "" {
+		for _, c := range tables {
+			if strings.EqualFold(c.Alias, t.Alias) {
+				return c
+			}
+		}
+	}
+	for _, c := range tables {
+		if c.Name == t.Name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (d *AppDiff) diffTables(a, b *SchemaTable) {
+	key := schemaTableKey(a)
+	alabels, blabels := schemaFieldLabels(a), schemaFieldLabels(b)
+
+	diff := compareSchemaProperties(a, b, false, "TableID", "KeyFieldID", "DefaultSortFieldID")
+	diff = append(diff, compareLabels("key_field", alabels[a.KeyFieldID], blabels[b.KeyFieldID])...)
+	diff = append(diff, compareLabels("default_sort_field", alabels[a.DefaultSortFieldID], blabels[b.DefaultSortFieldID])...)
+	d.add(AppDiffChanged, SchemaResourceTable, "", key, diff)
+
+	// Fields
+	matched := make(map[*SchemaField]bool, len(b.Fields))
+	for _, af := range a.Fields {
+		var bf *SchemaField
+		for _, f := range b.Fields {
+			if f.Label == af.Label {
+				bf = f
+				break
+			}
+		}
+		if bf == nil {
+			d.add(AppDiffRemoved, SchemaResourceField, key, af.Label, nil)
+			continue
+		}
+		matched[bf] = true
+
+		// The parent table and related field properties contain IDs.
+		diff := compareSchemaProperties(af, bf, false, "FieldID", "ParentTable", "RelatedField")
+		diff = append(diff, compareLabels("related_field", alabels[af.RelatedField], blabels[bf.RelatedField])...)
+		d.add(AppDiffChanged, SchemaResourceField, key, af.Label, diff)
+	}
+	for _, bf := range b.Fields {
+		if !matched[bf] {
+			d.add(AppDiffAdded, SchemaResourceField, key, bf.Label, nil)
+		}
+	}
+
+	// Relationships
+	matchedRels := make(map[*SchemaRelationship]bool, len(b.Relationships))
+	for _, ar := range a.Relationships {
+		var br *SchemaRelationship
+		for _, r := range b.Relationships {
+			if !matchedRels[r] && r.ParentTable == ar.ParentTable && relationshipKeyLabel(r) == relationshipKeyLabel(ar) {
+				br = r
+				break
+			}
+		}
+		if br == nil {
+			d.add(AppDiffRemoved, SchemaResourceRelationship, key, relationshipName(ar), nil)
+			continue
+		}
+		matchedRels[br] = true
+
+		var diff []*SchemaPropertyDiff
+		diff = append(diff, compareLabels("lookup_fields", relationshipFieldLabels(ar.LookupFields), relationshipFieldLabels(br.LookupFields))...)
+		diff = append(diff, compareLabels("summary_fields", relationshipFieldLabels(ar.SummaryFields), relationshipFieldLabels(br.SummaryFields))...)
+		d.add(AppDiffChanged, SchemaResourceRelationship, key, relationshipName(ar), diff)
+	}
+	for _, br := range b.Relationships {
+		if !matchedRels[br] {
+			d.add(AppDiffAdded, SchemaResourceRelationship, key, relationshipName(br), nil)
+		}
+	}
+
+	// Reports
+	matchedReports := make(map[*SchemaReport]bool, len(b.Reports))
+	for _, ar := range a.Reports {
+		var br *SchemaReport
+		for _, r := range b.Reports {
+			if !matchedReports[r] && r.Name == ar.Name {
+				br = r
+				break
+			}
+		}
+		if br == nil {
+			d.add(AppDiffRemoved, SchemaResourceReport, key, ar.Name, nil)
+			continue
+		}
+		matchedReports[br] = true
+
+		diff := compareSchemaProperties(ar, br, false, "ReportID")
+		diff = append(diff, compareLabels("fields", reportFieldLabels(ar.Fields, alabels), reportFieldLabels(br.Fields, blabels))...)
+		diff = append(diff, compareLabels("sort_by", reportOrderLabels(ar.SortBy, alabels), reportOrderLabels(br.SortBy, blabels))...)
+		diff = append(diff, compareLabels("group_by", reportOrderLabels(ar.GroupBy, alabels), reportOrderLabels(br.GroupBy, blabels))...)
+		d.add(AppDiffChanged, SchemaResourceReport, key, ar.Name, diff)
+	}
+	for _, br := range b.Reports {
+		if !matchedReports[br] {
+			d.add(AppDiffAdded, SchemaResourceReport, key, br.Name, nil)
+		}
+	}
+}
+
+func (d *AppDiff) diffVariables(a, b map[string]string) {
+	names := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		av, aok := a[name]
+		bv, bok := b[name]
+		switch {
+		case !bok:
+			d.add(AppDiffRemoved, SchemaResourceVariable, "", name, nil)
+		case !aok:
+			d.add(AppDiffAdded, SchemaResourceVariable, "", name, nil)
+		case av != bv:
+			d.add(AppDiffChanged, SchemaResourceVariable, "", name, []*SchemaPropertyDiff{{Property: "value", Old: av, New: bv}})
+		}
+	}
+}
+
+// compareLabels returns a diff if the labels differ.
+func compareLabels(property, a, b string) []*SchemaPropertyDiff {
+	if a == b {
+		return nil
+	}
+	return []*SchemaPropertyDiff{{Property: property, Old: a, New: b}}
+}
+
+func schemaFieldLabels(t *SchemaTable) map[int]string {
+	labels := make(map[int]string, len(t.Fields))
+	for _, f := range t.Fields {
+		if f.FieldID != 0 {
+			labels[f.FieldID] = f.Label
+		}
+	}
+	return labels
+}
+
+func relationshipKeyLabel(r *SchemaRelationship) string {
+	if r.ForeignKeyField == nil {
+		return ""
+	}
+	return r.ForeignKeyField.Label
+}
+
+// relationshipName identifies a relationship by its parent table and, since
+// a table can have multiple relationships to the same parent, its foreign key.
+func relationshipName(r *SchemaRelationship) string {
+	if label := relationshipKeyLabel(r); label != "" {
+		return r.ParentTable + " (" + label + ")"
+	}
+	return r.ParentTable
+}
+
+func relationshipFieldLabels(fields []*SchemaRelationshipField) string {
+	labels := make([]string, len(fields))
+	for idx, f := range fields {
+		labels[idx] = f.Label
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ", ")
+}
+
+func reportFieldLabels(fids []int, labels map[int]string) string {
+	out := make([]string, len(fids))
+	for idx, fid := range fids {
+		out[idx] = fieldLabelOrID(fid, labels)
+	}
+	return strings.Join(out, ", ")
+}
+
+func reportOrderLabels(order []*SchemaReportOrder, labels map[int]string) string {
+	out := make([]string, len(order))
+	for idx, o := range order {
+		out[idx] = strings.TrimSpace(fieldLabelOrID(o.FieldID, labels) + " " + o.Order + " " + o.Grouping)
+	}
+	return strings.Join(out, ", ")
+}
+
+func fieldLabelOrID(fid int, labels map[int]string) string {
+	if label, ok := labels[fid]; ok {
+		return label
+	}
+	return fmt.Sprintf("field %d", fid)
+}
+
+// WriteAppDiff writes a human readable description of the differences to w,
+// optionally colored for terminals.
+func WriteAppDiff(w io.Writer, diff *AppDiff, color bool) error {
+	var buf bytes.Buffer
+
+	colors := map[string]text.Color{
+		AppDiffAdded:   text.FgGreen,
+		AppDiffRemoved: text.FgRed,
+		AppDiffChanged: text.FgYellow,
+	}
+	symbols := map[string]string{
+		AppDiffAdded:   "+",
+		AppDiffRemoved: "-",
+		AppDiffChanged: "~",
+	}
+
+	fmt.Fprintf(&buf, "Comparing app %s with app %s\n\n", diff.AppID, diff.OtherAppID)
+
+	for _, e := range diff.Differences {
+		line := fmt.Sprintf("%s %s %s %q", symbols[e.Status], e.Status, e.Resource, e.Name)
+		if e.Table != "" {
+			line += fmt.Sprintf(" in table %q", e.Table)
+		}
+		if color {
+			line = colors[e.Status].Sprint(line)
+		}
+		buf.WriteString(line + "\n")
+		writeSchemaPropertyDiffs(&buf, e.Diff)
+	}
+
+	switch n := len(diff.Differences); n {
+	case 0:
+		buf.WriteString("No differences.\n")
+	case 1:
+		buf.WriteString("\n1 difference.\n")
+	default:
+		fmt.Fprintf(&buf, "\n%d differences.\n", n)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// IsTerminal returns true if f is a terminal and colors are not disabled via
+// the NO_COLOR environment variable.
+func IsTerminal(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
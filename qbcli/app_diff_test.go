@@ -0,0 +1,86 @@
+package qbcli_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+func TestDiffAppSchemas(t *testing.T) {
+	ts := newSchemaServer(t)
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	a, err := qbcli.ExportAppSchema(context.Background(), qb, "bqgruir3g")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, _ := qbcli.ExportAppSchema(context.Background(), qb, "bqgruir3g")
+
+	// Copies of an app have different IDs, which are not differences.
+	b.AppID = "bqgruir4h"
+	for _, table := range b.Tables {
+		table.TableID += "x"
+		table.KeyFieldID += 100
+		for _, f := range table.Fields {
+			f.FieldID += 100
+		}
+		for _, r := range table.Reports {
+			r.ReportID += "0"
+			for idx := range r.Fields {
+				r.Fields[idx] += 100
+			}
+		}
+	}
+
+	tasks := b.Tables[1]
+	tasks.Fields[1].Required = false
+	tasks.Fields[3].Formula = "[Due] - Today() + 1"
+	tasks.Fields = append(tasks.Fields, &qbcli.SchemaField{FieldID: 109, Label: "Due", Type: qbclient.FieldDate})
+	tasks.Reports[1].Fields = tasks.Reports[1].Fields[:2]
+	b.Variables["b"] = "3"
+	delete(b.Variables, "a")
+
+	diff := qbcli.DiffAppSchemas(a, b)
+
+	entries := []string{}
+	for _, e := range diff.Differences {
+		props := []string{}
+		for _, d := range e.Diff {
+			props = append(props, fmt.Sprintf("%s=%v>%v", d.Property, d.Old, d.New))
+		}
+		entries = append(entries, fmt.Sprintf("%s %s %s/%s %s", e.Status, e.Resource, e.Table, e.Name, strings.Join(props, ";")))
+	}
+
+	expected := []string{
+		"changed field _DBID_TASKS/Name required=true>false",
+		"changed field _DBID_TASKS/Days Left formula=[Due] - Today()>[Due] - Today() + 1",
+		"added field _DBID_TASKS/Due ",
+		"changed report _DBID_TASKS/Overdue fields=Days Left, Name, Record ID#>Days Left, Name",
+		"removed variable /a ",
+		"changed variable /b value=2>3",
+	}
+	if strings.Join(entries, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got differences:\n%s\nexpected:\n%s", strings.Join(entries, "\n"), strings.Join(expected, "\n"))
+	}
+
+	var buf bytes.Buffer
+	qbcli.WriteAppDiff(&buf, diff, false)
+	for _, line := range []string{
+		`~ changed field "Name" in table "_DBID_TASKS"`,
+		`    required: true => false`,
+		`6 differences.`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected diff to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
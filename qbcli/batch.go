@@ -1,99 +1,171 @@
 package qbcli
 
 import (
-	"encoding/csv"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
 )
 
 // ExportOptions are the options read through the command line.
 type ExportOptions struct {
-	TableID   string `validate:"required" cliutil:"option=table-id"`
-	Filepath  string `cliutil:"option=file usage='file the data is exported to'"`
-	BatchSize int    `cliutil:"option=batch-size default=10000"`
-	Delay     int    `cliutil:"option=delay"`
-
-	// Fields    []int  `cliutil:"option=fields"`
+	TableID     string `validate:"required" cliutil:"option=table-id"`
+	Filepath    string `cliutil:"option=file usage='file the data is exported to'"`
+	BatchSize   int    `cliutil:"option=batch-size default=10000"`
+	Delay       int    `cliutil:"option=delay"`
+	Incremental bool   `cliutil:"option=incremental usage='only export records modified since the last incremental export'"`
+	Resume      bool   `cliutil:"option=resume usage='resume an interrupted incremental export, appending to the file'"`
+	StateFile   string `cliutil:"option=state-file usage='file the incremental export state is stored in'"`
+	Format      string `cliutil:"option=data-format usage='format of the data (csv or jsonl or xlsx) detected from the file extension by default'"`
+	Sheet       string `cliutil:"option=sheet usage='name of the sheet the data is written to in xlsx workbooks'"`
+
+	Select []int                               `cliutil:"option=select"`
+	Where  string                              `cliutil:"option=where func=query"`
+	SortBy []*qbclient.QueryRecordsInputSortBy `cliutil:"option=sort-by func=sort"`
+
+	MetricsFile  string `cliutil:"option=metrics-file usage='file a summary of the API requests is written to'"`
+	OTLPEndpoint string `cliutil:"option=otlp-endpoint usage='OpenTelemetry collector the API request metrics and traces are exported to, e.g., http://localhost:4318'"`
 }
 
 // Export exports data from a Quickbase table into an io.Writer.
+//
+// If opts.Incremental is set, only the records modified since the last
+// successful incremental export are written. The progress is stored in
+// opts.StateFile after each batch, and setting opts.Resume continues an
+// interrupted export after the last record ID written. The export must be
+// resumed with the opts.Select and opts.Where it was started with.
 func Export(qb *qbclient.Client, opts *ExportOptions) error {
+	return ExportWithContext(context.Background(), qb, opts)
+}
+
+// ExportWithContext is Export using the passed context.
+func ExportWithContext(ctx context.Context, qb *qbclient.Client, opts *ExportOptions) error {
+
+	if opts.Resume && !opts.Incremental {
+		return qberrors.Client(nil).Safef(qberrors.InvalidInput, "only incremental exports can be resumed")
+	}
+
+	// Read the state of the previous incremental export.
+	var state *exportState
+	if opts.Incremental {
+		if opts.StateFile == "" {
+			return errors.New("state file required for incremental exports")
+		}
+
+		var err error
+		if state, err = readExportState(opts.StateFile, opts.TableID); err != nil {
+			return err
+		}
+	}
+	resume := opts.Resume && state != nil && state.Pending != nil
+
+	format, err := dataFormat(opts.Format, opts.Filepath)
+	if err != nil {
+		return err
+	}
+	if resume && format == DataFormatXLSX {
+		return errors.New("xlsx exports cannot be resumed")
+	}
+	if opts.Incremental && len(opts.SortBy) > 0 {
+		return errors.New("incremental exports are sorted by record ID and cannot be sorted by other fields")
+	}
 
 	var file io.Writer
+	writeHeader := true
 	if opts.Filepath != "" {
-		var err error
-		file, err = os.OpenFile(opts.Filepath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+		if resume {
+			flag = os.O_RDWR | os.O_CREATE | os.O_APPEND
+		}
+
+		f, err := os.OpenFile(opts.Filepath, flag, 0644)
 		if err != nil {
 			return fmt.Errorf("error opening file: %w", err)
 		}
+		defer f.Close()
+		file = f
+
+		// Don't repeat the header when appending to a resumed export.
+		if resume {
+			if info, err := f.Stat(); err == nil && info.Size() > 0 {
+				writeHeader = false
+			}
+		}
 	} else {
 		file = os.Stdout
+		writeHeader = !resume
 	}
 
 	// Get the table's fields.
-	fields, err := GetTableSchema(qb, opts.TableID)
+	fields, err := GetTableSchemaWithContext(ctx, qb, opts.TableID)
 	if err != nil {
 		return fmt.Errorf("error getting table metadata: %w", err)
 	}
 
-	// Build a list of every fid.
-	fids := []int{}
-	for _, field := range fields {
-		fids = append(fids, field.FieldID)
+	fids, err := exportFields(fields, opts.Select)
+	if err != nil {
+		return err
+	}
+	if resume {
+		if err = state.Pending.checkQuery(fids, opts.Where); err != nil {
+			return err
+		}
 	}
-	sort.Ints(fids)
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	var writer exportWriter
+	switch format {
+	case DataFormatJSONL:
+		writer = newJSONLExportWriter(file, fields, fids)
+	case DataFormatXLSX:
+		if writer, err = newXLSXExportWriter(file, opts.Sheet, fields, fids); err != nil {
+			return err
+		}
+	default:
+		writer = newCSVExportWriter(file, fields, fids)
+	}
 
 	// Write the header.
-	header := make([]string, len(fids))
-	for idx, fid := range fids {
-		header[idx] = fields[fid].Label
+	if writeHeader {
+		if err = writer.WriteHeader(); err != nil {
+			return err
+		}
+	}
+
+	if opts.Incremental {
+		return exportIncremental(ctx, qb, opts, state, resume, writer, fids)
 	}
-	writer.Write(header)
 
 	// Batch read records.
 	size := opts.BatchSize
 	skip := 0
 	for {
 
-		// Query records, sorted by record ID.
+		// Query records, sorted by record ID unless otherwise specified.
 		qri := &qbclient.QueryRecordsInput{
 			Select: fids,
 			From:   opts.TableID,
-			SortBy: []*qbclient.QueryRecordsInputSortBy{
-				{FieldID: 3, Order: qbclient.SortByASC},
-			},
+			Where:  opts.Where,
+			SortBy: exportSortBy(opts.SortBy),
 			Options: &qbclient.QueryRecordsInputOptions{
 				Top:  size,
 				Skip: skip,
 			},
 		}
-		qro, err := qb.QueryRecords(qri)
+		qro, err := qb.QueryRecordsWithContext(ctx, qri)
 		if err != nil {
 			return fmt.Errorf("error querying records: %w", err)
 		}
 
 		// Write the row data.
-		for _, record := range qro.Data {
-			row := make([]string, len(fids))
-			for idx, fid := range fids {
-				row[idx] = record[fid].Value.String()
-			}
-			writer.Write(row)
-		}
-
-		// Flush the buffer.
-		writer.Flush()
-		if err = writer.Error(); err != nil {
+		if err = writer.WriteRecords(qro.Data); err != nil {
 			return err
 		}
 
@@ -104,12 +176,175 @@ func Export(qb *qbclient.Client, opts *ExportOptions) error {
 		}
 
 		// Delay before the next API call.
-		if opts.Delay > 0 {
-			time.Sleep(time.Duration(opts.Delay) * time.Millisecond)
+		if err = delay(ctx, opts.Delay); err != nil {
+			return err
 		}
 	}
 
-	return nil
+	return writer.Close()
+}
+
+// exportFields returns the fids of the exported fields in column order, which
+// are the selected fids or every fid in the table in ascending order.
+func exportFields(fields FieldMap, selected []int) ([]int, error) {
+	if len(selected) == 0 {
+		fids := []int{}
+		for _, field := range fields {
+			fids = append(fids, field.FieldID)
+		}
+		sort.Ints(fids)
+		return fids, nil
+	}
+
+	seen := make(map[int]bool, len(selected))
+	for _, fid := range selected {
+		if _, ok := fields[fid]; !ok {
+			return nil, fmt.Errorf("field %v not in table", fid)
+		}
+		if seen[fid] {
+			return nil, fmt.Errorf("field %v selected more than once", fid)
+		}
+		seen[fid] = true
+	}
+
+	return selected, nil
+}
+
+// exportSortBy returns the sort order of exported records. The record ID is
+// always the last sort field so that pages are stable.
+func exportSortBy(sortBy []*qbclient.QueryRecordsInputSortBy) []*qbclient.QueryRecordsInputSortBy {
+	for _, s := range sortBy {
+		if s.FieldID == 3 {
+			return sortBy
+		}
+	}
+	return append(sortBy, &qbclient.QueryRecordsInputSortBy{FieldID: 3, Order: qbclient.SortByASC})
+}
+
+// exportIncremental exports the records modified since the watermark in the
+// state, paging through them by record ID so that records modified during
+// the export are neither skipped nor repeated.
+func exportIncremental(ctx context.Context, qb *qbclient.Client, opts *ExportOptions, state *exportState, resume bool, writer exportWriter, fids []int) error {
+
+	// Start a new export, which covers the records modified since the
+	// watermark up to the most recently modified record. Records modified
+	// after the export starts are picked up by the next run.
+	if !resume {
+		to, ok, err := exportUpperBound(ctx, qb, opts.TableID, state.Watermark)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err = writer.Close(); err != nil {
+				return err
+			}
+			state.Pending = nil
+			return writeExportState(opts.StateFile, state)
+		}
+
+		state.Pending = &exportPending{From: state.Watermark, To: to, Select: fids, Where: opts.Where}
+		if err = writeExportState(opts.StateFile, state); err != nil {
+			return err
+		}
+	}
+
+	// The record ID is needed to track progress, even if it isn't exported.
+	qfids := append([]int{3}, fids...)
+	for _, fid := range fids {
+		if fid == 3 {
+			qfids = fids
+			break
+		}
+	}
+
+	for {
+		where := state.Pending.where()
+		if opts.Where != "" {
+			where = "(" + opts.Where + ")AND" + where
+		}
+
+		qri := &qbclient.QueryRecordsInput{
+			Select: qfids,
+			From:   opts.TableID,
+			Where:  where,
+			SortBy: []*qbclient.QueryRecordsInputSortBy{
+				{FieldID: 3, Order: qbclient.SortByASC},
+			},
+			Options: &qbclient.QueryRecordsInputOptions{
+				Top: opts.BatchSize,
+			},
+		}
+		qro, err := qb.QueryRecordsWithContext(ctx, qri)
+		if err != nil {
+			return fmt.Errorf("error querying records: %w", err)
+		}
+		if len(qro.Data) == 0 {
+			break
+		}
+
+		// Write the row data, then record our progress.
+		if err = writer.WriteRecords(qro.Data); err != nil {
+			return err
+		}
+
+		last := qro.Data[len(qro.Data)-1]
+		if rid, ok := last[3]; ok && rid.Value != nil {
+			state.Pending.LastRecordID = int(rid.Value.Float64)
+		}
+		if err = writeExportState(opts.StateFile, state); err != nil {
+			return err
+		}
+
+		// Break if this page contained every remaining record.
+		if qro.Metadata == nil || len(qro.Data) >= qro.Metadata.TotalRecords {
+			break
+		}
+
+		// Delay before the next API call.
+		if err = delay(ctx, opts.Delay); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	// Advance the watermark now that the export is complete.
+	state.Watermark = state.Pending.To
+	state.Pending = nil
+	return writeExportState(opts.StateFile, state)
+}
+
+// exportUpperBound returns the Date Modified of the most recently modified
+// record changed after from. It returns false if no records were modified.
+func exportUpperBound(ctx context.Context, qb *qbclient.Client, tableID string, from time.Time) (time.Time, bool, error) {
+	qri := &qbclient.QueryRecordsInput{
+		Select: []int{2},
+		From:   tableID,
+		SortBy: []*qbclient.QueryRecordsInputSortBy{
+			{FieldID: 2, Order: qbclient.SortByDESC},
+		},
+		Options: &qbclient.QueryRecordsInputOptions{Top: 1},
+	}
+	if !from.IsZero() {
+		qri.Where = fmt.Sprintf("{2.AF.%d}", epochMilliseconds(from))
+	}
+
+	qro, err := qb.QueryRecordsWithContext(ctx, qri)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("error querying date modified: %w", err)
+	}
+	if len(qro.Data) == 0 {
+		return time.Time{}, false, nil
+	}
+
+	dm, ok := qro.Data[0][2]
+	if !ok || dm.Value == nil {
+		return time.Time{}, false, errors.New("date modified not returned")
+	}
+
+	return dm.Value.Time, true, nil
 }
 
 // ImportOptions are the options read through the command line.
@@ -117,16 +352,53 @@ type ImportOptions struct {
 	TableID      string            `validate:"required" cliutil:"option=table-id"`
 	Filepath     string            `cliutil:"option=file usage='file the data is imported from'"`
 	BatchSize    int               `cliutil:"option=batch-size default=10000"`
+	Concurrency  int               `cliutil:"option=concurrency default=1 usage='number of batches uploaded in parallel'"`
 	Map          map[string]string `cliutil:"option=map"`
 	Delay        int               `cliutil:"option=delay"`
 	Timeout      int               `cliutil:"option=timeout default=5 usage='timeout in seconds waiting for data to be read from stdin'"`
 	MergeFieldID int               `cliutil:"option=merge-field-id"`
+	Checkpoint   string            `cliutil:"option=checkpoint usage='file the import progress is recorded in so that it can be resumed'"`
+	Resume       bool              `cliutil:"option=resume usage='skip the records committed by a previous import recorded in the checkpoint file'"`
+	Format       string            `cliutil:"option=data-format usage='format of the data (csv or jsonl or xlsx) detected from the file extension by default'"`
+	Sheet        string            `cliutil:"option=sheet usage='name of the sheet the data is read from in xlsx workbooks, defaults to the first sheet'"`
+	MetricsFile  string            `cliutil:"option=metrics-file usage='file a summary of the API requests is written to'"`
+	OTLPEndpoint string            `cliutil:"option=otlp-endpoint usage='OpenTelemetry collector the API request metrics and traces are exported to, e.g., http://localhost:4318'"`
 
 	// Fields    []int  `cliutil:"option=fields"`
 }
 
+// importBatch is a batch of records read from the CSV data.
+type importBatch struct {
+	seq     int
+	lines   []int // Line numbers of the records, the CSV header is line 1.
+	records []map[int]*qbclient.InsertRecordsInputData
+}
+
+// importResult is the result of inserting an importBatch.
+type importResult struct {
+	batch  *importBatch
+	output *qbclient.InsertRecordsOutput
+	err    error
+}
+
 // Import imports data from an io.Reader into a Quickbase table.
+//
+// Batches are uploaded by opts.Concurrency workers in parallel, but the
+// results are merged in the order the batches were read so the returned
+// metadata is deterministic. The keys in LineErrors are the lines that the
+// records start on, counted from the end of the CSV header, so the first
+// record is line 1.
+//
+// If opts.Checkpoint is set, each committed batch is recorded in the
+// checkpoint file. If opts.Resume is also set, the records committed by a
+// previous import are skipped and included in the returned metadata. The
+// checkpoint file is removed once the import completes successfully.
 func Import(qb *qbclient.Client, opts *ImportOptions) (*qbclient.InsertRecordsOutputMetadata, error) {
+	return ImportWithContext(context.Background(), qb, opts)
+}
+
+// ImportWithContext is Import using the passed context.
+func ImportWithContext(ctx context.Context, qb *qbclient.Client, opts *ImportOptions) (*qbclient.InsertRecordsOutputMetadata, error) {
 	metadata := &qbclient.InsertRecordsOutputMetadata{
 		CreatedRecordIDs:              []int{},
 		LineErrors:                    map[string][]string{},
@@ -137,11 +409,12 @@ func Import(qb *qbclient.Client, opts *ImportOptions) (*qbclient.InsertRecordsOu
 
 	var file io.Reader
 	if opts.Filepath != "" {
-		var err error
-		file, err = os.Open(opts.Filepath)
+		f, err := os.Open(opts.Filepath)
 		if err != nil {
 			return metadata, fmt.Errorf("error opening file: %w", err)
 		}
+		defer f.Close()
+		file = f
 	} else {
 		file = os.Stdin
 		if err := waitStdin(opts.Timeout); err != nil {
@@ -150,146 +423,235 @@ func Import(qb *qbclient.Client, opts *ImportOptions) (*qbclient.InsertRecordsOu
 	}
 
 	// Get the table's fields.
-	fields, err := GetTableSchema(qb, opts.TableID)
+	fields, err := GetTableSchemaWithContext(ctx, qb, opts.TableID)
 	if err != nil {
 		return metadata, fmt.Errorf("error getting table metadata: %w", err)
 	}
 
-	// Build a map of field label to fid.
-	lmap := make(map[string]int, len(fields))
-	for _, field := range fields {
-		lmap[field.Label] = field.FieldID
+	// Open the checkpoint file, restoring the progress of the previous import
+	// if we are resuming.
+	cp, committed, err := openImportCheckpoint(opts, metadata, qb.DryRun)
+	if err != nil {
+		return metadata, err
 	}
 
-	reader := csv.NewReader(file)
-	fmap := []int{}
-
-	// Batch write records.
-	line := 0
-	eof := false
-	records := []map[int]*qbclient.InsertRecordsInputData{}
+	// Cancel the readers and workers if a batch fails.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Read the CSV data into batches.
+	var readErr error
+	batches := make(chan *importBatch)
+	go func() {
+		defer close(batches)
+		readErr = readImportBatches(ctx, file, fields, opts, committed, batches)
+	}()
+
+	// Upload the batches in parallel.
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
 
-	for {
+	var wg sync.WaitGroup
+	results := make(chan *importResult)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+
+				// Drain the remaining batches if the import failed.
+				if ctx.Err() != nil {
+					continue
+				}
 
-		// Read each record from the CSV data.
-		// TODO better error handling.
-		row, err := reader.Read()
-		if err == io.EOF {
-			eof = true
-		} else if err != nil {
-			return metadata, fmt.Errorf("error reading line %v: %w", line, err)
+				input := &qbclient.InsertRecordsInput{
+					To:           opts.TableID,
+					Data:         batch.records,
+					MergeFieldID: opts.MergeFieldID,
+				}
+				output, err := qb.InsertRecordsWithContext(ctx, input)
+				results <- &importResult{batch: batch, output: output, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Merge the results in the order the batches were read, holding on to
+	// results that finish early until the preceding batches are merged.
+	pending := map[int]*importResult{}
+	next := 0
+	for result := range results {
+		if err != nil {
+			continue
+		}
+		if result.err != nil {
+			err = fmt.Errorf("error inserting records: %w", result.err)
+			cancel()
+			continue
 		}
 
-		// If first line, map the header to field IDs.
-		// Otherwise, build the data records.
-		if !eof {
-			if line == 0 {
-				for _, label := range row {
-
-					// Check the field label map first.
-					if destLabel, ok := opts.Map[label]; ok {
-						label = destLabel
-					}
-
-					// Now get the field ID.
-					fid, ok := lmap[label]
-					if !ok {
-						return metadata, fmt.Errorf("%s field not in destination table", label)
-					}
+		pending[result.batch.seq] = result
+		for r, ok := pending[next]; ok; r, ok = pending[next] {
+			delete(pending, next)
+			if err = commitImportResult(metadata, cp, r); err != nil {
+				cancel()
+				break
+			}
+			next++
+		}
+	}
 
-					// Append the fid from the field map.
-					fmap = append(fmap, fid)
-				}
-			} else {
+	if err == nil {
+		err = readErr
+	}
+	if cerr := cp.close(err == nil); err == nil {
+		err = cerr
+	}
 
-				record := make(map[int]*qbclient.InsertRecordsInputData)
-				for idx, data := range row {
+	return metadata, err
+}
 
-					// TODO defensive coding ...
-					fid := fmap[idx]
+// readImportBatches reads records from the data and sends them to batches in
+// groups of opts.BatchSize, skipping the records on or before the committed
+// line.
+func readImportBatches(ctx context.Context, file io.Reader, fields FieldMap, opts *ImportOptions, committed int, batches chan<- *importBatch) error {
+	format, err := dataFormat(opts.Format, opts.Filepath)
+	if err != nil {
+		return err
+	}
 
-					// We cannot insert record metadata.
-					if fid != 3 && fid <= 5 {
-						continue
-					}
+	var reader importReader
+	mapper := newImportFieldMapper(fields, opts)
+	switch format {
+	case DataFormatJSONL:
+		reader = newJSONLImportReader(file, mapper)
+	case DataFormatXLSX:
+		if reader, err = newXLSXImportReader(file, opts.Sheet, mapper); err != nil {
+			return err
+		}
+	default:
+		if reader, err = newCSVImportReader(file, mapper); err != nil {
+			return err
+		}
+	}
 
-					// Skip the record ID if it isn't the merge field.
-					if fid == 3 && opts.MergeFieldID != 3 {
-						continue
-					}
+	size := opts.BatchSize
+	if size < 1 {
+		size = 1
+	}
 
-					// TODO defensive coding ...
-					ftype := fields[fid].Type
+	batch := &importBatch{}
+	send := func() error {
+		if batch.seq > 0 {
+			if err := delay(ctx, opts.Delay); err != nil {
+				return err
+			}
+		}
 
-					// Create a *qbclient.Value from the string value and field type.
-					val, err := qbclient.NewValueFromString(data, ftype)
-					if err != nil {
-						return metadata, fmt.Errorf("value invalid for field %v: %w", fid, err)
-					}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case batches <- batch:
+		}
 
-					// Add the value to the record .
-					record[fid] = &qbclient.InsertRecordsInputData{Value: val}
-				}
+		batch = &importBatch{seq: batch.seq + 1}
+		return nil
+	}
 
-				records = append(records, record)
-			}
+	// Build the data records, sending each batch when it is full.
+	for {
+		record, line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
 		}
 
-		// Write the batch if we hit the batch size of the end of the data set.
-		if len(records) >= opts.BatchSize || (len(records) > 0 && eof) {
-			input := &qbclient.InsertRecordsInput{
-				To:           opts.TableID,
-				Data:         records,
-				MergeFieldID: opts.MergeFieldID,
-			}
+		// Skip the records committed by a previous import.
+		if line <= committed {
+			continue
+		}
 
-			iro, err := qb.InsertRecords(input)
-			if err != nil {
-				return metadata, fmt.Errorf("error inserting records: %w", err)
+		batch.lines = append(batch.lines, line)
+		batch.records = append(batch.records, record)
+		if len(batch.records) >= size {
+			if err := send(); err != nil {
+				return err
 			}
+		}
+	}
 
-			// Empty the records for the next batch.
-			rlen := len(records)
-			records = []map[int]*qbclient.InsertRecordsInputData{}
+	// Send the last, partial batch.
+	if len(batch.records) > 0 {
+		return send()
+	}
+	return nil
+}
 
-			metadata.CreatedRecordIDs = append(metadata.CreatedRecordIDs, iro.Metadata.CreatedRecordIDs...)
-			metadata.TotalNumberOfRecordsProcessed += iro.Metadata.TotalNumberOfRecordsProcessed
-			metadata.UnchangedRecordIDs = append(metadata.UnchangedRecordIDs, iro.Metadata.UnchangedRecordIDs...)
-			metadata.UpdatedRecordIDs = append(metadata.UpdatedRecordIDs, iro.Metadata.UpdatedRecordIDs...)
+// commitImportResult merges the result of a batch into the metadata and
+// records it in the checkpoint file.
+func commitImportResult(metadata *qbclient.InsertRecordsOutputMetadata, cp *importCheckpoint, result *importResult) error {
+	m, err := importResultMetadata(result)
+	if err != nil {
+		return err
+	}
 
-			// Merging the line errors isn't so simple.
-			for k, v := range iro.Metadata.LineErrors {
-				n, err := strconv.Atoi(k)
-				if err != nil {
-					return metadata, fmt.Errorf("%s: expecting lineErrors key to be an integer", k)
-				}
+	mergeImportMetadata(metadata, m)
+	return cp.commit(result.batch.lines[len(result.batch.lines)-1], m)
+}
 
-				// This works no matter the batch size, but I have no idea why
-				// we have to subtract 1 from n when eof is true. If this is
-				// buggy, blame cpliakas@quickbase.com.
-				n = line - rlen + n
-				if eof {
-					n--
-				}
+// importResultMetadata returns the metadata for a batch, mapping the line
+// errors to the line numbers of the records in the data.
+func importResultMetadata(result *importResult) (*qbclient.InsertRecordsOutputMetadata, error) {
+	m := &qbclient.InsertRecordsOutputMetadata{LineErrors: map[string][]string{}}
+	if result.output.Metadata == nil {
+		return m, nil
+	}
 
-				metadata.LineErrors[strconv.Itoa(n)] = v
-			}
+	*m = *result.output.Metadata
+	m.LineErrors = make(map[string][]string, len(result.output.Metadata.LineErrors))
 
-			// Delay before the next API call.
-			if opts.Delay > 0 && !eof {
-				time.Sleep(time.Duration(opts.Delay) * time.Millisecond)
-			}
+	// The keys are the 1-based positions of the records in the batch.
+	for k, v := range result.output.Metadata.LineErrors {
+		n, err := strconv.Atoi(k)
+		if err != nil || n < 1 || n > len(result.batch.lines) {
+			return nil, fmt.Errorf("%s: expecting lineErrors key to be a record in the batch", k)
 		}
+		m.LineErrors[strconv.Itoa(result.batch.lines[n-1])] = v
+	}
 
-		// Break if we are at the end of the file.
-		if eof {
-			break
-		}
+	return m, nil
+}
 
-		line++
+// mergeImportMetadata merges the metadata in src into dst.
+func mergeImportMetadata(dst, src *qbclient.InsertRecordsOutputMetadata) {
+	dst.CreatedRecordIDs = append(dst.CreatedRecordIDs, src.CreatedRecordIDs...)
+	dst.TotalNumberOfRecordsProcessed += src.TotalNumberOfRecordsProcessed
+	dst.UnchangedRecordIDs = append(dst.UnchangedRecordIDs, src.UnchangedRecordIDs...)
+	dst.UpdatedRecordIDs = append(dst.UpdatedRecordIDs, src.UpdatedRecordIDs...)
+	for k, v := range src.LineErrors {
+		dst.LineErrors[k] = v
 	}
+}
 
-	return metadata, nil
+// delay pauses for ms milliseconds between batches. It returns early with the
+// context's error if ctx is canceled while waiting.
+func delay(ctx context.Context, ms int) error {
+	if ms <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		return nil
+	}
 }
 
 // TODO move this to cliutil.
@@ -0,0 +1,737 @@
+package qbcli_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qbtest"
+	"github.com/spf13/viper"
+	"github.com/xuri/excelize/v2"
+)
+
+// newImportServer returns a fake API that creates a record with the ID in
+// field 7, or reports a line error if field 6 is "bad". Requests are delayed
+// at random so that concurrent batches complete out of order. Inserts fail if
+// fail is not nil and returns true.
+func newImportServer(t *testing.T, fail func() bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fields":
+			fmt.Fprint(w, `[{"id":3,"label":"Record ID#","fieldType":"recordid"},`)
+			fmt.Fprint(w, `{"id":6,"label":"Name","fieldType":"text"},{"id":7,"label":"Number","fieldType":"numeric"}]`)
+
+		case "/records":
+			if fail != nil && fail() {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message":"Bad Request","description":"insert failed"}`)
+				return
+			}
+
+			var input struct {
+				Data []map[string]struct {
+					Value interface{} `json:"value"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				t.Errorf("error decoding request: %s", err)
+			}
+
+			time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+
+			metadata := &qbclient.InsertRecordsOutputMetadata{
+				CreatedRecordIDs:   []int{},
+				LineErrors:         map[string][]string{},
+				UnchangedRecordIDs: []int{},
+				UpdatedRecordIDs:   []int{},
+			}
+			for idx, record := range input.Data {
+				if record["6"].Value == "bad" {
+					metadata.LineErrors[strconv.Itoa(idx+1)] = []string{"Incompatible value"}
+					continue
+				}
+				metadata.CreatedRecordIDs = append(metadata.CreatedRecordIDs, int(record["7"].Value.(float64)))
+				metadata.TotalNumberOfRecordsProcessed++
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"metadata": metadata})
+
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+	}))
+}
+
+// writeImportFile writes a CSV file with the passed number of rows, returning
+// the filename and the expected created record IDs and line errors. Every
+// third row is invalid. Lines are counted from the end of the header, so row
+// n is line n.
+func writeImportFile(t *testing.T, rows int) (string, []int, map[string][]string) {
+	var b strings.Builder
+	b.WriteString("Name,Number\n")
+	wantCreated := []int{}
+	wantErrors := map[string][]string{}
+	for n := 1; n <= rows; n++ {
+		name := fmt.Sprintf("Row %d", n)
+		if n%3 == 0 {
+			name = "bad"
+			wantErrors[strconv.Itoa(n)] = []string{"Incompatible value"}
+		} else {
+			wantCreated = append(wantCreated, n)
+		}
+		fmt.Fprintf(&b, "%s,%d\n", name, n)
+	}
+
+	f, err := ioutil.TempFile("", "import*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(b.String())
+	f.Close()
+
+	return f.Name(), wantCreated, wantErrors
+}
+
+func TestImportLineErrors(t *testing.T) {
+	const rows = 10
+
+	ts := newImportServer(t, nil)
+	defer ts.Close()
+
+	filename, wantCreated, wantErrors := writeImportFile(t, rows)
+	defer os.Remove(filename)
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	for _, concurrency := range []int{1, 4} {
+		for size := 1; size <= rows+1; size++ {
+			t.Run(fmt.Sprintf("concurrency %d batch size %d", concurrency, size), func(t *testing.T) {
+				opts := &qbcli.ImportOptions{
+					TableID:     "bqgruir7z",
+					Filepath:    filename,
+					BatchSize:   size,
+					Concurrency: concurrency,
+				}
+
+				metadata, err := qbcli.ImportWithContext(context.Background(), qb, opts)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				if !reflect.DeepEqual(metadata.LineErrors, wantErrors) {
+					t.Errorf("got line errors %v, expected %v", metadata.LineErrors, wantErrors)
+				}
+				if !reflect.DeepEqual(metadata.CreatedRecordIDs, wantCreated) {
+					t.Errorf("got created IDs %v, expected %v", metadata.CreatedRecordIDs, wantCreated)
+				}
+				if metadata.TotalNumberOfRecordsProcessed != len(wantCreated) {
+					t.Errorf("got %v processed, expected %v", metadata.TotalNumberOfRecordsProcessed, len(wantCreated))
+				}
+			})
+		}
+	}
+}
+
+func TestImportLineNumbers(t *testing.T) {
+	ts := newImportServer(t, nil)
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	tests := []struct {
+		pattern    string
+		data       string
+		wantErrors map[string][]string
+	}{
+		// CSV lines are counted from the end of the header. Quoted values span
+		// several lines, and the last line has no line break.
+		{
+			"import*.csv",
+			"Name,Number\n\"Row\none\",1\nbad,2\n\n\"Row\r\nthree\",3\r\nbad,4",
+			map[string][]string{"3": {"Incompatible value"}, "7": {"Incompatible value"}},
+		},
+
+		// JSON Lines are counted from the start of the data.
+		{
+			"import*.jsonl",
+			"{\"Name\":\"Row one\",\"Number\":1}\n{\"Name\":\"bad\",\"Number\":2}\n\n{\"Name\":\"Row three\",\"Number\":3}\n{\"Name\":\"bad\",\"Number\":4}",
+			map[string][]string{"2": {"Incompatible value"}, "5": {"Incompatible value"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			f, err := ioutil.TempFile("", tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			f.WriteString(tt.data)
+			f.Close()
+
+			opts := &qbcli.ImportOptions{TableID: "bqgruir7z", Filepath: f.Name(), BatchSize: 2, Concurrency: 1}
+			metadata, err := qbcli.Import(qb, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(metadata.LineErrors, tt.wantErrors) {
+				t.Errorf("got line errors %v, expected %v", metadata.LineErrors, tt.wantErrors)
+			}
+			if !reflect.DeepEqual(metadata.CreatedRecordIDs, []int{1, 3}) {
+				t.Errorf("got created IDs %v, expected [1 3]", metadata.CreatedRecordIDs)
+			}
+		})
+	}
+}
+
+func TestImportResume(t *testing.T) {
+	const rows = 10
+
+	// Fail the last of the 5 batches in the first import.
+	var mu sync.Mutex
+	inserts, failing := 0, true
+	ts := newImportServer(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		inserts++
+		return failing && inserts == 5
+	})
+	defer ts.Close()
+
+	filename, wantCreated, wantErrors := writeImportFile(t, rows)
+	defer os.Remove(filename)
+	cpfile := filename + ".checkpoint"
+	defer os.Remove(cpfile)
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	opts := &qbcli.ImportOptions{
+		TableID:     "bqgruir7z",
+		Filepath:    filename,
+		BatchSize:   2,
+		Concurrency: 1,
+	}
+
+	// Checkpoints are opt-in, so resuming requires a checkpoint file.
+	opts.Resume = true
+	if _, err := qbcli.ImportWithContext(context.Background(), qb, opts); err == nil || !strings.Contains(err.Error(), "checkpoint") {
+		t.Fatalf("got error %v, expected the checkpoint option to be required", err)
+	}
+	opts.Resume, opts.Checkpoint = false, cpfile
+
+	if _, err := qbcli.ImportWithContext(context.Background(), qb, opts); err == nil {
+		t.Fatal("got nil, expected error")
+	}
+	if !qbclient.FileExists(cpfile) {
+		t.Fatal("expected checkpoint file to exist")
+	}
+
+	// Dry runs leave the checkpoint file alone.
+	checkpoint, _ := ioutil.ReadFile(cpfile)
+	qb.DryRun, qb.DryRunWriter = true, ioutil.Discard
+	if _, err := qbcli.ImportWithContext(context.Background(), qb, opts); err != nil {
+		t.Fatalf("unexpected error in dry run: %s", err)
+	}
+	qb.DryRun = false
+	if b, _ := ioutil.ReadFile(cpfile); string(b) != string(checkpoint) {
+		t.Errorf("got checkpoint %q after dry run, expected %q", b, checkpoint)
+	}
+
+	// Resume the import, which should only send the failed batch.
+	mu.Lock()
+	failing, inserts = false, 0
+	mu.Unlock()
+	opts.Resume = true
+
+	metadata, err := qbcli.ImportWithContext(context.Background(), qb, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if inserts != 1 {
+		t.Errorf("got %v inserts, expected 1", inserts)
+	}
+	if !reflect.DeepEqual(metadata.LineErrors, wantErrors) {
+		t.Errorf("got line errors %v, expected %v", metadata.LineErrors, wantErrors)
+	}
+	if !reflect.DeepEqual(metadata.CreatedRecordIDs, wantCreated) {
+		t.Errorf("got created IDs %v, expected %v", metadata.CreatedRecordIDs, wantCreated)
+	}
+	if qbclient.FileExists(cpfile) {
+		t.Error("expected checkpoint file to be removed")
+	}
+}
+
+// exportTable is a fake table whose records are keyed by record ID, with
+// Date Modified as the value.
+type exportTable struct {
+	sync.Mutex
+	records map[int]time.Time
+	queries int
+	failAt  int
+}
+
+var (
+	reAfter    = regexp.MustCompile(`\{2\.AF\.(\d+)\}`)
+	reOnBefore = regexp.MustCompile(`\{2\.OBF\.(\d+)\}`)
+	reGreater  = regexp.MustCompile(`\{3\.GT\.(\d+)\}`)
+)
+
+// whereInt returns the integer captured by re in where, or def.
+func whereInt(re *regexp.Regexp, where string, def int64) int64 {
+	if m := re.FindStringSubmatch(where); m != nil {
+		n, _ := strconv.ParseInt(m[1], 10, 64)
+		return n
+	}
+	return def
+}
+
+// newExportServer returns a fake API that evaluates the queries sent by
+// incremental exports against the table. Queries fail from the failAt query
+// onwards if it is set.
+func newExportServer(t *testing.T, table *exportTable) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fields":
+			fmt.Fprint(w, `[{"id":2,"label":"Date Modified","fieldType":"timestamp"},`)
+			fmt.Fprint(w, `{"id":3,"label":"Record ID#","fieldType":"recordid"}]`)
+
+		case "/records/query":
+			table.Lock()
+			defer table.Unlock()
+
+			if table.queries++; table.failAt > 0 && table.queries >= table.failAt {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message":"Bad Request","description":"query failed"}`)
+				return
+			}
+
+			var input qbclient.QueryRecordsInput
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				t.Errorf("error decoding request: %s", err)
+			}
+
+			after := whereInt(reAfter, input.Where, -1)
+			onBefore := whereInt(reOnBefore, input.Where, math.MaxInt64)
+			greater := whereInt(reGreater, input.Where, 0)
+
+			rids := []int{}
+			for rid, dm := range table.records {
+				ms := dm.UnixNano() / int64(time.Millisecond)
+				if ms > after && ms <= onBefore && int64(rid) > greater {
+					rids = append(rids, rid)
+				}
+			}
+
+			sort.Ints(rids)
+			if input.SortBy[0].FieldID == 2 {
+				sort.Slice(rids, func(i, j int) bool { return table.records[rids[i]].After(table.records[rids[j]]) })
+			}
+
+			total := len(rids)
+			if top := input.Options.Top; top > 0 && len(rids) > top {
+				rids = rids[:top]
+			}
+
+			data := []string{}
+			for _, rid := range rids {
+				dm := table.records[rid].Format(qbclient.FormatDateTime)
+				data = append(data, fmt.Sprintf(`{"2":{"value":%q},"3":{"value":%d}}`, dm, rid))
+			}
+
+			fmt.Fprintf(w, `{"data":[%s],"fields":[{"id":2,"type":"timestamp"},{"id":3,"type":"recordid"}],`, strings.Join(data, ","))
+			fmt.Fprintf(w, `"metadata":{"totalRecords":%d,"numRecords":%d,"numFields":2,"skip":0}}`, total, len(rids))
+
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+	}))
+}
+
+// exportedRecordIDs returns the record IDs in the exported CSV file, and
+// whether the header is written exactly once.
+func exportedRecordIDs(t *testing.T, filename string) ([]int, bool) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rids := []int{}
+	headers := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		cols := strings.Split(line, ",")
+		if cols[1] == "Record ID#" {
+			headers++
+			continue
+		}
+		rid, _ := strconv.Atoi(cols[1])
+		rids = append(rids, rid)
+	}
+
+	return rids, headers == 1
+}
+
+func TestExportIncremental(t *testing.T) {
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	table := &exportTable{records: map[int]time.Time{}}
+	for rid := 1; rid <= 5; rid++ {
+		table.records[rid] = base.Add(time.Duration(rid) * time.Second)
+	}
+
+	ts := newExportServer(t, table)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "qbcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	opts := &qbcli.ExportOptions{
+		TableID:     "bqgruir8a",
+		Filepath:    qbclient.Filepath(dir, "export.csv"),
+		BatchSize:   2,
+		Incremental: true,
+		StateFile:   qbclient.Filepath(dir, "state.json"),
+	}
+
+	tests := []struct {
+		name    string
+		change  func()
+		resume  bool
+		want    []int
+		wantErr bool
+	}{
+		{"first run exports every record", func() {}, false, []int{1, 2, 3, 4, 5}, false},
+		{"nothing modified", func() {}, false, []int{}, false},
+		{"modified and new records", func() {
+			table.records[2] = base.Add(10 * time.Second)
+			table.records[6] = base.Add(11 * time.Second)
+		}, false, []int{2, 6}, false},
+		{"interrupted after the first page", func() {
+			for rid := 7; rid <= 11; rid++ {
+				table.records[rid] = base.Add(time.Duration(rid+10) * time.Second)
+			}
+			table.queries, table.failAt = 0, 3
+		}, false, []int{7, 8}, false},
+		{"resumed with a different query", func() {
+			table.failAt = 0
+			opts.Where = "{6.EX.'Row 9'}"
+		}, true, []int{7, 8}, true},
+		{"resumed after the last record ID", func() {
+			opts.Where = ""
+		}, true, []int{7, 8, 9, 10, 11}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table.Lock()
+			tt.change()
+			table.Unlock()
+
+			opts.Resume = tt.resume
+			err := qbcli.ExportWithContext(context.Background(), qb, opts)
+			if wantErr := tt.wantErr || table.failAt > 0; wantErr && err == nil {
+				t.Fatal("got nil, expected error")
+			} else if !wantErr && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			rids, header := exportedRecordIDs(t, opts.Filepath)
+			if !reflect.DeepEqual(rids, tt.want) {
+				t.Errorf("got records %v, expected %v", rids, tt.want)
+			}
+			if !header {
+				t.Error("expected the header to be written once")
+			}
+		})
+	}
+
+	// Only incremental exports record the progress needed to resume them.
+	opts.Incremental = false
+	if err := qbcli.ExportWithContext(context.Background(), qb, opts); err == nil {
+		t.Error("got nil, expected error resuming an export that isn't incremental")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := qbtest.NewServer()
+	defer s.Close()
+	qb := s.Client()
+
+	app, err := qb.CreateApp(&qbclient.CreateAppInput{Name: "Round Trip"})
+	if err != nil {
+		t.Fatalf("unexpected error creating app: %s", err)
+	}
+
+	type field struct {
+		label, ftype, value string
+	}
+
+	text := field{"Text", "text-multi-line", `"Line one\nLine, \"two\""`}
+	tests := []struct {
+		filename string
+		sheet    string
+		fields   []field
+	}{
+		{"export.csv", "", []field{
+			text,
+			{"Number", "numeric", `1.5`},
+			{"Checkbox", "checkbox", `true`},
+			{"Date", "date", `"2021-03-04"`},
+		}},
+		{"export.jsonl", "", []field{
+			text,
+			{"List", "multitext", `["One","Two, Three"]`},
+			{"Duration", "duration", `5400000`},
+			{"Checkbox", "checkbox", `true`},
+			{"User", "user", `{"id":"123.abcd","email":"jdoe@example.com","name":"Jane Doe"}`},
+			{"Number", "numeric", `1.5`},
+			{"Timestamp", "timestamp", `"2021-03-04T05:06:07Z"`},
+		}},
+		{"export.xlsx", "Data", []field{
+			text,
+			{"Number", "numeric", `1234567.891`},
+			{"Checkbox", "checkbox", `true`},
+			{"Date", "date", `"2021-03-04"`},
+			{"Timestamp", "timestamp", `"2021-03-04T05:06:07Z"`},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+
+			// Create a table with the fields and return its ID and the IDs of
+			// the fields.
+			newTable := func(name string) (string, []int) {
+				table, err := qb.CreateTable(&qbclient.CreateTableInput{AppID: app.AppID, Name: name})
+				if err != nil {
+					t.Fatalf("unexpected error creating table: %s", err)
+				}
+				fids := []int{}
+				for _, f := range tt.fields {
+					out, err := qb.CreateField(&qbclient.CreateFieldInput{TableID: table.TableID, Field: qbclient.Field{Label: f.label, Type: f.ftype}})
+					if err != nil {
+						t.Fatalf("unexpected error creating field: %s", err)
+					}
+					fids = append(fids, out.FieldID)
+				}
+				qbcli.InvalidateTableSchema(table.TableID)
+				return table.TableID, fids
+			}
+
+			src, fids := newTable("Source")
+			dst, _ := newTable("Destination")
+
+			record := make(map[int]*qbclient.InsertRecordsInputData)
+			for idx, f := range tt.fields {
+				val, err := qbclient.NewValueFromJSON([]byte(f.value), f.ftype)
+				if err != nil {
+					t.Fatalf("value not valid for %s: %s", f.label, err)
+				}
+				record[fids[idx]] = &qbclient.InsertRecordsInputData{Value: val}
+			}
+			if _, err := qb.InsertRecords(&qbclient.InsertRecordsInput{To: src, Data: []map[int]*qbclient.InsertRecordsInputData{record}}); err != nil {
+				t.Fatalf("unexpected error inserting record: %s", err)
+			}
+
+			dir, err := ioutil.TempDir("", "qbcli")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			filename := qbclient.Filepath(dir, tt.filename)
+
+			eopts := &qbcli.ExportOptions{TableID: src, Filepath: filename, BatchSize: 10, Sheet: tt.sheet, Select: fids}
+			if err := qbcli.ExportWithContext(context.Background(), qb, eopts); err != nil {
+				t.Fatalf("unexpected error exporting: %s", err)
+			}
+
+			iopts := &qbcli.ImportOptions{TableID: dst, Filepath: filename, BatchSize: 10, Sheet: tt.sheet}
+			if tt.sheet != "" {
+				iopts.Sheet = "Missing"
+				if _, err := qbcli.ImportWithContext(context.Background(), qb, iopts); err == nil {
+					t.Fatal("got nil, expected error importing a missing sheet")
+				}
+				iopts.Sheet = tt.sheet
+			}
+			if _, err := qbcli.ImportWithContext(context.Background(), qb, iopts); err != nil {
+				t.Fatalf("unexpected error importing: %s", err)
+			}
+
+			// Every value should be imported exactly as it was exported.
+			query := func(tableID string) string {
+				out, err := qb.QueryRecords(&qbclient.QueryRecordsInput{Select: fids, From: tableID})
+				if err != nil {
+					t.Fatalf("unexpected error querying records: %s", err)
+				}
+				b, _ := json.Marshal(out.Data)
+				return string(b)
+			}
+			if have, want := query(dst), query(src); have != want {
+				t.Errorf("got %s, expected %s", have, want)
+			}
+		})
+	}
+}
+
+func TestImportXLSXNumberFormats(t *testing.T) {
+	s := qbtest.NewServer()
+	defer s.Close()
+	qb := s.Client()
+
+	app, err := qb.CreateApp(&qbclient.CreateAppInput{Name: "Import"})
+	if err != nil {
+		t.Fatalf("unexpected error creating app: %s", err)
+	}
+	table, err := qb.CreateTable(&qbclient.CreateTableInput{AppID: app.AppID, Name: "Rows"})
+	if err != nil {
+		t.Fatalf("unexpected error creating table: %s", err)
+	}
+
+	// The fields are 6, 7, and 8, after the built-in fields.
+	for _, f := range []qbclient.Field{{Label: "Amount", Type: "numeric"}, {Label: "Due", Type: "date"}, {Label: "Updated", Type: "timestamp"}} {
+		if _, err := qb.CreateField(&qbclient.CreateFieldInput{TableID: table.TableID, Field: f}); err != nil {
+			t.Fatalf("unexpected error creating field: %s", err)
+		}
+	}
+	qbcli.InvalidateTableSchema(table.TableID)
+
+	// Write a workbook whose number formats display less than is stored, i.e.,
+	// "1,234,567.89", "03-04-21", and "3/4/21 5:06".
+	due := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	f := excelize.NewFile()
+	f.SetSheetRow("Sheet1", "A1", &[]interface{}{"Amount", "Due", "Updated"})
+	f.SetSheetRow("Sheet1", "A2", &[]interface{}{1234567.891, due, updated})
+	for axis, numFmt := range map[string]int{"A2": 4, "B2": 14, "C2": 22} {
+		style, err := f.NewStyle(&excelize.Style{NumFmt: numFmt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.SetCellStyle("Sheet1", axis, axis, style)
+	}
+
+	dir, err := ioutil.TempDir("", "qbcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := qbclient.Filepath(dir, "import.xlsx")
+	if err := f.SaveAs(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &qbcli.ImportOptions{TableID: table.TableID, Filepath: filename, BatchSize: 10}
+	if _, err := qbcli.ImportWithContext(context.Background(), qb, opts); err != nil {
+		t.Fatalf("unexpected error importing: %s", err)
+	}
+
+	out, err := qb.QueryRecords(&qbclient.QueryRecordsInput{Select: []int{6, 7, 8}, From: table.TableID})
+	if err != nil {
+		t.Fatalf("unexpected error querying records: %s", err)
+	}
+	if len(out.Data) != 1 {
+		t.Fatalf("got %v records, expected 1", len(out.Data))
+	}
+
+	record := out.Data[0]
+	if have, want := record[6].Value.Float64, 1234567.891; have != want {
+		t.Errorf("got amount %v, expected %v", have, want)
+	}
+	if have, want := record[7].Value.Time, due; !have.Equal(want) {
+		t.Errorf("got due date %v, expected %v", have, want)
+	}
+	if have, want := record[8].Value.Time, updated; !have.Equal(want) {
+		t.Errorf("got updated time %v, expected %v", have, want)
+	}
+}
+
+func TestExportSelect(t *testing.T) {
+	s := qbtest.NewServer()
+	defer s.Close()
+	qb := s.Client()
+
+	app, err := qb.CreateApp(&qbclient.CreateAppInput{Name: "Export"})
+	if err != nil {
+		t.Fatalf("unexpected error creating app: %s", err)
+	}
+	table, err := qb.CreateTable(&qbclient.CreateTableInput{AppID: app.AppID, Name: "Rows"})
+	if err != nil {
+		t.Fatalf("unexpected error creating table: %s", err)
+	}
+
+	// The fields are 6, 7, and 8, after the built-in fields.
+	for _, f := range []qbclient.Field{{Label: "Name", Type: "text"}, {Label: "Status", Type: "text"}, {Label: "Number", Type: "numeric"}} {
+		if _, err := qb.CreateField(&qbclient.CreateFieldInput{TableID: table.TableID, Field: f}); err != nil {
+			t.Fatalf("unexpected error creating field: %s", err)
+		}
+	}
+
+	// Table IDs are reused across fake servers, so drop any cached schema.
+	qbcli.InvalidateTableSchema(table.TableID)
+
+	data := []map[int]*qbclient.InsertRecordsInputData{}
+	for n, status := range []string{"Open", "Open", "Closed"} {
+		data = append(data, map[int]*qbclient.InsertRecordsInputData{
+			6: {Value: qbclient.NewTextValue(fmt.Sprintf("Row %d", n+1))},
+			7: {Value: qbclient.NewTextValue(status)},
+			8: {Value: qbclient.NewNumericValue(float64(n + 1))},
+		})
+	}
+	if _, err := qb.InsertRecords(&qbclient.InsertRecordsInput{To: table.TableID, Data: data}); err != nil {
+		t.Fatalf("unexpected error inserting records: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "qbcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	opts := &qbcli.ExportOptions{
+		TableID:   table.TableID,
+		Filepath:  qbclient.Filepath(dir, "export.csv"),
+		BatchSize: 10,
+		Select:    []int{8, 6},
+		Where:     qbcli.ParseQuery("7=Open"),
+		SortBy:    []*qbclient.QueryRecordsInputSortBy{{FieldID: 8, Order: qbclient.SortByDESC}},
+	}
+	if err := qbcli.ExportWithContext(context.Background(), qb, opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, _ := ioutil.ReadFile(opts.Filepath)
+	if have, want := string(b), "Number,Name\n2,Row 2\n1,Row 1\n"; have != want {
+		t.Errorf("got %q, expected %q", have, want)
+	}
+
+	opts.Select = []int{6, 9}
+	if err := qbcli.ExportWithContext(context.Background(), qb, opts); err == nil {
+		t.Error("got nil, expected error selecting a field not in the table")
+	}
+}
@@ -0,0 +1,152 @@
+package qbcli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// importCheckpoint is a journal of the batches committed by Import. Each
+// committed batch is appended to the file as a JSON object on its own line,
+// so a crash can at worst truncate the last entry.
+type importCheckpoint struct {
+	filename string
+	tableID  string
+	file     *os.File
+}
+
+// importCheckpointEntry models an entry in the checkpoint file.
+type importCheckpointEntry struct {
+	TableID  string                                `json:"tableId"`
+	Line     int                                   `json:"line"`
+	Metadata *qbclient.InsertRecordsOutputMetadata `json:"metadata"`
+}
+
+// openImportCheckpoint opens the checkpoint file for the import. If resuming,
+// the metadata of the committed batches is merged into metadata, and the last
+// committed CSV line is returned. Otherwise any previous checkpoint is
+// discarded. Checkpoints are opt-in, so a nil checkpoint is returned if no
+// checkpoint file was configured.
+//
+// In dry-run mode the checkpoint file is only read when resuming, and a nil
+// checkpoint is returned, so the synthetic batches aren't recorded and the
+// progress of a previous import isn't lost.
+func openImportCheckpoint(opts *ImportOptions, metadata *qbclient.InsertRecordsOutputMetadata, dryRun bool) (cp *importCheckpoint, line int, err error) {
+	filename := opts.Checkpoint
+	if filename == "" {
+		if opts.Resume {
+			err = errors.New("the checkpoint option is required to resume an import")
+		}
+		return
+	}
+
+	if dryRun {
+		if opts.Resume {
+			line, err = loadImportCheckpoint(filename, opts.TableID, metadata)
+		}
+		return
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if opts.Resume {
+		if line, err = loadImportCheckpoint(filename, opts.TableID, metadata); err != nil {
+			return
+		}
+	} else {
+		flag |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(filename, flag, 0644)
+	if err != nil {
+		err = fmt.Errorf("error opening checkpoint file: %w", err)
+		return
+	}
+
+	cp = &importCheckpoint{filename: filename, tableID: opts.TableID, file: f}
+	return
+}
+
+// loadImportCheckpoint reads the checkpoint file, merging the metadata of the
+// committed batches into metadata and returning the last committed CSV line.
+// A missing file means there is nothing to resume.
+func loadImportCheckpoint(filename, tableID string, metadata *qbclient.InsertRecordsOutputMetadata) (line int, err error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("error opening checkpoint file: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	for {
+		var entry importCheckpointEntry
+
+		// Stop at the first entry we can't decode, which is either the end of
+		// the file or an entry truncated by a crash.
+		if derr := dec.Decode(&entry); derr != nil {
+			if derr != io.EOF && derr != io.ErrUnexpectedEOF {
+				if _, ok := derr.(*json.SyntaxError); !ok {
+					return 0, fmt.Errorf("error reading checkpoint file: %w", derr)
+				}
+			}
+			return line, nil
+		}
+
+		if entry.TableID != tableID {
+			return 0, fmt.Errorf("checkpoint file is for table %s, not %s", entry.TableID, tableID)
+		}
+		if entry.Metadata != nil {
+			if entry.Metadata.LineErrors == nil {
+				entry.Metadata.LineErrors = map[string][]string{}
+			}
+			mergeImportMetadata(metadata, entry.Metadata)
+		}
+		line = entry.Line
+	}
+}
+
+// commit records that the records up to and including the CSV line were
+// committed, along with the batch's metadata.
+func (cp *importCheckpoint) commit(line int, m *qbclient.InsertRecordsOutputMetadata) error {
+	if cp == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(importCheckpointEntry{TableID: cp.tableID, Line: line, Metadata: m})
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoint: %w", err)
+	}
+
+	if _, err = cp.file.Write(append(b, '\n')); err == nil {
+		err = cp.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("error writing checkpoint file: %w", err)
+	}
+
+	return nil
+}
+
+// close closes the checkpoint file, removing it if the import completed so
+// a later import doesn't skip any records.
+func (cp *importCheckpoint) close(completed bool) error {
+	if cp == nil {
+		return nil
+	}
+
+	if err := cp.file.Close(); err != nil {
+		return fmt.Errorf("error closing checkpoint file: %w", err)
+	}
+	if completed {
+		if err := os.Remove(cp.filename); err != nil {
+			return fmt.Errorf("error removing checkpoint file: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
 	"github.com/cpliakas/cliutil"
 	"github.com/rs/xid"
 	"github.com/spf13/cobra"
@@ -15,6 +18,7 @@ import (
 // NewLogger returns a new *cliutil.LeveledLogger.
 func NewLogger(cmd *cobra.Command, cfg GlobalConfig) (ctx context.Context, logger *cliutil.LeveledLogger, transid xid.ID) {
 	ctx, logger, transid = cliutil.NewLoggerWithContext(context.Background(), cfg.LogLevel())
+	ctx = context.WithValue(ctx, transIDKey{}, transid.String())
 	logger.SetOutput(os.Stderr)
 
 	// Open the log file and set the logger to write to it.
@@ -27,58 +31,153 @@ func NewLogger(cmd *cobra.Command, cfg GlobalConfig) (ctx context.Context, logge
 	return
 }
 
-// NewClient returns a new *qbclient.Client.
+// transIDKey is the context key of the transaction ID set by NewLogger.
+type transIDKey struct{}
+
+// transactionID returns the transaction ID set in ctx by NewLogger.
+func transactionID(ctx context.Context) string {
+	transid, _ := ctx.Value(transIDKey{}).(string)
+	return transid
+}
+
+// NewClient returns a new *qbclient.Client. The returned context is canceled
+// when the process receives an interrupt signal, which aborts in-flight calls
+// made with it.
 func NewClient(cmd *cobra.Command, cfg GlobalConfig) (ctx context.Context, logger *cliutil.LeveledLogger, qb *qbclient.Client) {
 	var transid xid.ID
 	ctx, logger, transid = NewLogger(cmd, cfg)
+	ctx = ContextWithInterrupt(ctx)
 
-	// Instantiate the Quick Base API client with the logger plugin.
+	// Instantiate the Quick Base API client with the logger middleware.
 	qb = qbclient.New(cfg)
-	qb.AddPlugin(NewLoggerPlugin(ctx, logger))
+	qb.AddMiddleware(NewLoggerPlugin(ctx, logger))
+	qb.DryRun = cfg.DryRun()
+
+	// Share table schemas between invocations.
+	SetSchemaCache(NewSchemaCache(cfg))
 
 	// Dump raw requests and responses to the dump directory.
 	if dumpDir := cfg.DumpDirectory(); dumpDir != "" {
-		qb.AddPlugin(NewDumpPlugin(ctx, logger, transid.String(), dumpDir))
+		qb.AddMiddleware(NewDumpPlugin(ctx, logger, transid.String(), dumpDir))
+	}
+
+	// Record interactions to, or replay them from, a cassette file.
+	if recordFile := cfg.RecordFile(); recordFile != "" {
+		HandleError(ctx, logger, "error creating cassette file", qb.Record(recordFile))
+	} else if replayFile := cfg.ReplayFile(); replayFile != "" {
+		HandleError(ctx, logger, "error reading cassette file", qb.Replay(replayFile))
 	}
 
 	return
 }
 
+// ContextWithInterrupt returns a copy of ctx that is canceled when the process
+// receives an interrupt or termination signal. Subsequent signals are handled
+// normally, so pressing Ctrl+C twice still kills the process.
+func ContextWithInterrupt(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-ch:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(ch)
+	}()
+
+	return ctx
+}
+
 // FieldMap is a map of field IDs to field definitions.
 type FieldMap map[int]*qbclient.ListFieldsOutputField
 
+func newFieldMap(fields []*qbclient.ListFieldsOutputField) FieldMap {
+	m := make(FieldMap, len(fields))
+	for _, field := range fields {
+		m[field.FieldID] = field
+	}
+	return m
+}
+
 var _fmap map[string]FieldMap
 
-// CacheTableSchema caches schema information for a table.
+var _schemaCache *SchemaCache
+
+// SetSchemaCache sets the disk cache used by GetTableSchema. Passing nil only
+// caches schemas in memory.
+func SetSchemaCache(c *SchemaCache) { _schemaCache = c }
+
+// CacheTableSchema retrieves schema information for a table and caches it in
+// memory and on disk.
 func CacheTableSchema(qb *qbclient.Client, tableID string) error {
-	output, err := qb.ListFieldsByTableID(tableID)
+	return CacheTableSchemaWithContext(context.Background(), qb, tableID)
+}
+
+// CacheTableSchemaWithContext is CacheTableSchema using the passed context.
+func CacheTableSchemaWithContext(ctx context.Context, qb *qbclient.Client, tableID string) error {
+	fields, err := fetchTableSchema(ctx, qb, tableID)
 	if err != nil {
 		return err
 	}
 
-	m := make(FieldMap, len(output.Fields))
-	for _, field := range output.Fields {
-		m[field.FieldID] = field
-	}
+	// The disk cache is an optimization, so failing to write it isn't fatal.
+	_schemaCache.Set(tableID, fields)
 
-	_fmap[tableID] = m
 	return nil
 }
 
+// RefreshTableSchema is CacheTableSchema, except that it returns an error if
+// the disk cache is disabled or the schema can't be written to it.
+func RefreshTableSchema(ctx context.Context, qb *qbclient.Client, tableID string) error {
+	if !_schemaCache.Enabled() {
+		return qberrors.Client(nil).Safef(qberrors.InvalidInput, "the schema cache is disabled, set schema_cache_ttl to enable it")
+	}
+	fields, err := fetchTableSchema(ctx, qb, tableID)
+	if err != nil {
+		return err
+	}
+	return _schemaCache.Set(tableID, fields)
+}
+
+// fetchTableSchema retrieves schema information for a table and caches it in
+// memory.
+func fetchTableSchema(ctx context.Context, qb *qbclient.Client, tableID string) ([]*qbclient.ListFieldsOutputField, error) {
+	output, err := qb.ListFieldsByTableIDWithContext(ctx, tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	_fmap[tableID] = newFieldMap(output.Fields)
+	return output.Fields, nil
+}
+
 // GetTableSchema returns schema information for a table. If the schema is not
-// in the in-memory cache, it retrieves the data and caches it.
+// in the in-memory or disk cache, it retrieves the data and caches it.
 func GetTableSchema(qb *qbclient.Client, tableID string) (FieldMap, error) {
-	var err error
-	_, ok := _fmap[tableID]
-	if !ok {
-		err = CacheTableSchema(qb, tableID)
+	return GetTableSchemaWithContext(context.Background(), qb, tableID)
+}
+
+// GetTableSchemaWithContext is GetTableSchema using the passed context.
+func GetTableSchemaWithContext(ctx context.Context, qb *qbclient.Client, tableID string) (FieldMap, error) {
+	if m, ok := _fmap[tableID]; ok {
+		return m, nil
 	}
+
+	if m, ok := _schemaCache.Get(tableID); ok {
+		_fmap[tableID] = m
+		return m, nil
+	}
+
+	err := CacheTableSchemaWithContext(ctx, qb, tableID)
 	return _fmap[tableID], err
 }
 
-// GetCachedTableSchema returns schema information for a table.
-//
-// TODO Caching beyond in-memory caching?
+// GetCachedTableSchema returns schema information for a table from the
+// in-memory cache, which is populated by GetTableSchema.
 func GetCachedTableSchema(tableID string) (FieldMap, error) {
 	m, ok := _fmap[tableID]
 	if !ok {
@@ -88,6 +187,21 @@ func GetCachedTableSchema(tableID string) (FieldMap, error) {
 	return m, nil
 }
 
+// InvalidateTableSchema removes the tables' schemas from the in-memory and
+// disk caches. It is called after the CLI changes the tables' fields.
+func InvalidateTableSchema(tableIDs ...string) error {
+	for _, tableID := range tableIDs {
+		if tableID == "" {
+			continue
+		}
+		delete(_fmap, tableID)
+		if err := _schemaCache.Delete(tableID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func init() {
 	_fmap = make(map[string]FieldMap)
 }
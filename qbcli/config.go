@@ -3,6 +3,7 @@ package qbcli
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/QuickBase/quickbase-cli/qbclient"
 	"github.com/cpliakas/cliutil"
@@ -12,12 +13,15 @@ import (
 
 // Option* constants contain CLI options.
 const (
+	OptionDryRun         = "dry-run"
 	OptionDumpDirectory  = "dump-dir"
 	OptionFormat         = "format"
 	OptionJMESPathFilter = "filter"
 	OptionLogFile        = "log-file"
 	OptionLogLevel       = "log-level"
 	OptionQuiet          = "quiet"
+	OptionRecord         = "record"
+	OptionReplay         = "replay"
 )
 
 // Option*Description constants contain common option descriptions.
@@ -33,6 +37,7 @@ const (
 func NewGlobalConfig(cmd *cobra.Command, cfg *viper.Viper) GlobalConfig {
 	flags := cliutil.NewFlagger(cmd, cfg)
 
+	flags.PersistentBool(OptionDryRun, "", false, "print requests that change data instead of sending them")
 	flags.PersistentString(OptionDumpDirectory, "d", "", "directory for files that request/response are dumped to for debugging")
 	flags.PersistentString(OptionFormat, "", "", "display data in an alternate format, e.g., table")
 	flags.PersistentString(OptionJMESPathFilter, "F", "", "JMESPath filter applied to output")
@@ -40,6 +45,8 @@ func NewGlobalConfig(cmd *cobra.Command, cfg *viper.Viper) GlobalConfig {
 	flags.PersistentString(OptionLogLevel, "l", cliutil.LogNotice, "minimum log level")
 	flags.PersistentString(qbclient.OptionProfile, "p", "default", "configuration profile")
 	flags.PersistentBool(OptionQuiet, "q", false, OptionQuietDescription)
+	flags.PersistentString(OptionRecord, "", "", "cassette file requests and responses are recorded to")
+	flags.PersistentString(OptionReplay, "", "", "cassette file responses are replayed from instead of sending requests")
 	flags.PersistentString(qbclient.OptionRealmHostname, "r", "", "realm hostname, e.g., example.quickbase.com")
 	flags.PersistentString(qbclient.OptionUserToken, "u", "", "user token used to authenticate API requests")
 
@@ -63,6 +70,10 @@ func (c GlobalConfig) DefaultFieldID() int { return c.cfg.GetInt(qbclient.Option
 // DefaultTableID returns the default table ID.
 func (c GlobalConfig) DefaultTableID() string { return c.cfg.GetString(qbclient.OptionTableID) }
 
+// DryRun returns whether to print requests that change data instead of
+// sending them.
+func (c GlobalConfig) DryRun() bool { return c.cfg.GetBool(OptionDryRun) }
+
 // DumpDirectory returns the configured dump file directory.
 func (c GlobalConfig) DumpDirectory() string { return c.cfg.GetString(OptionDumpDirectory) }
 
@@ -84,9 +95,42 @@ func (c GlobalConfig) Profile() string { return c.cfg.GetString(qbclient.OptionP
 // Quiet returns whehter to suppress output written to stdout.
 func (c GlobalConfig) Quiet() bool { return c.cfg.GetBool(OptionQuiet) }
 
+// RateLimit returns the maximum number of requests per second.
+func (c GlobalConfig) RateLimit() float64 { return c.cfg.GetFloat64(qbclient.OptionRateLimit) }
+
+// RateLimitBurst returns the maximum number of requests sent in a burst.
+func (c GlobalConfig) RateLimitBurst() int { return c.cfg.GetInt(qbclient.OptionRateLimitBurst) }
+
+// RateLimitShared returns whether the rate limit is shared across processes.
+func (c GlobalConfig) RateLimitShared() bool { return c.cfg.GetBool(qbclient.OptionRateLimitShared) }
+
 // RealmHostname returns the configured realm hostname.
 func (c GlobalConfig) RealmHostname() string { return c.cfg.GetString(qbclient.OptionRealmHostname) }
 
+// RecordFile returns the cassette file interactions are recorded to.
+func (c GlobalConfig) RecordFile() string { return c.cfg.GetString(OptionRecord) }
+
+// ReplayFile returns the cassette file interactions are replayed from.
+func (c GlobalConfig) ReplayFile() string { return c.cfg.GetString(OptionReplay) }
+
+// RetryJitter returns whether to randomize the backoff between retries.
+func (c GlobalConfig) RetryJitter() bool { return qbclient.NewConfig(c.cfg).RetryJitter() }
+
+// RetryMax returns the maximum number of retries.
+func (c GlobalConfig) RetryMax() int { return qbclient.NewConfig(c.cfg).RetryMax() }
+
+// RetryWaitMax returns the maximum time to wait between retries.
+func (c GlobalConfig) RetryWaitMax() time.Duration { return qbclient.NewConfig(c.cfg).RetryWaitMax() }
+
+// RetryWaitMin returns the minimum time to wait between retries.
+func (c GlobalConfig) RetryWaitMin() time.Duration { return qbclient.NewConfig(c.cfg).RetryWaitMin() }
+
+// SchemaCacheTTL returns how long table schemas are cached on disk. Zero, the
+// default, disables the disk cache.
+func (c GlobalConfig) SchemaCacheTTL() time.Duration {
+	return c.cfg.GetDuration(qbclient.OptionSchemaCacheTTL)
+}
+
 // UserToken returns the configured log level.
 func (c GlobalConfig) UserToken() string { return c.cfg.GetString(qbclient.OptionUserToken) }
 
@@ -104,13 +148,35 @@ func (c *GlobalConfig) Validate() error {
 		return err
 	}
 
-	if c.RealmHostname() == "" {
+	if c.RecordFile() != "" && c.ReplayFile() != "" {
+		return fmt.Errorf("options %q and %q: %w", OptionRecord, OptionReplay, errors.New("mutually exclusive"))
+	}
+
+	// Replayed cassettes don't need a realm, so bug reports can be reproduced
+	// by anyone.
+	if c.RealmHostname() == "" && c.ReplayFile() == "" {
 		return fmt.Errorf("option %q: %w", qbclient.OptionRealmHostname, errors.New("value required"))
 	}
 
 	return nil
 }
 
+// SetProfileFromEnvironment sets the profile to the one configured for the
+// environment in a quickbase.yml file. A profile passed explicitly takes
+// precedence. This must be called before Validate reads the configuration.
+func (c GlobalConfig) SetProfileFromEnvironment(file, env string) error {
+	if env == "" || c.cfg.IsSet(qbclient.OptionProfile) {
+		return nil
+	}
+
+	profile, err := QuickbaseFileProfile(file, env)
+	if err == nil && profile != "" {
+		c.cfg.Set(qbclient.OptionProfile, profile)
+	}
+
+	return err
+}
+
 // SetDefaultAppID sets the default app in the command's configuration.
 func (c GlobalConfig) SetDefaultAppID(cfg *viper.Viper) {
 	if appID := c.DefaultAppID(); appID != "" {
@@ -0,0 +1,265 @@
+package qbcli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
+)
+
+// Deploy* constants contain the resources deployed from a quickbase.yml file.
+const (
+	DeployResourceField    = "field"
+	DeployResourceFormula  = "formula"
+	DeployResourcePage     = "page"
+	DeployResourceVariable = "variable"
+)
+
+// DefaultPageType is the type of page created when not set, i.e., an XSL
+// stylesheet or HTML page.
+const DefaultPageType = 1
+
+type DeployInput struct {
+	File  string `cliutil:"option=file default=quickbase.yml"`
+	Env   string `cliutil:"option=env usage='environment in the quickbase file whose variables replace placeholders'"`
+	AppID string `cliutil:"option=app-id"`
+}
+
+type DeployOutput struct {
+	Deployed []*DeployResult `json:"deployed"`
+	Errors   []*DeployResult `json:"errors"`
+}
+
+// DeployResult identifies a deployed resource. Fields and formulas are named
+// by their table and field IDs, e.g., bqgruir7z.6.
+type DeployResult struct {
+	Resource string `json:"resource"`
+	Name     string `json:"name"`
+	Error    string `json:"error,omitempty"`
+}
+
+// deployStep is a change that is ready to be sent to Quickbase.
+type deployStep struct {
+	resource string
+	name     string
+	deploy   func(ctx context.Context) error
+}
+
+// Deploy deploys the field properties, formulas, pages, and variables in the
+// deploy section of a quickbase.yml file.
+//
+// Every file is read and every field is retrieved before anything is
+// deployed, so a mistake in the quickbase.yml file doesn't leave the app
+// partially deployed. Deployment stops at the first error returned by the
+// API, which is reported with the resources deployed before it.
+func Deploy(ctx context.Context, qb *qbclient.Client, in *DeployInput) (out *DeployOutput, err error) {
+	var file *QuickbaseFile
+	if file, err = ParseQuickbaseFileEnv(in.File, in.Env); err != nil {
+		return
+	}
+
+	var steps []*deployStep
+	if steps, err = prepareDeploy(ctx, qb, file, in.AppID); err != nil {
+		return
+	}
+
+	out = &DeployOutput{Deployed: []*DeployResult{}, Errors: []*DeployResult{}}
+	for _, step := range steps {
+		result := &DeployResult{Resource: step.resource, Name: step.name}
+		if derr := step.deploy(ctx); derr != nil {
+			result.Error = derr.Error()
+			out.Errors = append(out.Errors, result)
+			break
+		}
+		out.Deployed = append(out.Deployed, result)
+	}
+
+	return
+}
+
+func prepareDeploy(ctx context.Context, qb *qbclient.Client, file *QuickbaseFile, appID string) ([]*deployStep, error) {
+	d := file.Deploy
+	if d == nil {
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "quickbase file has no deploy section")
+	}
+
+	if d.AppID != "" {
+		appID = d.AppID
+	}
+	if appID == "" && (len(d.Pages) > 0 || len(d.Variables) > 0) {
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "app_id required to deploy pages and variables")
+	}
+
+	var steps []*deployStep
+
+	// Field properties are deployed first so that formulas can be deployed to
+	// fields that are relabeled in the same deployment.
+	for _, f := range d.Fields {
+		in, err := newDeployFieldInput(ctx, qb, f.TableID, f.FieldID)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", deployFieldName(f.TableID, f.FieldID), err)
+		}
+		f.apply(in)
+		steps = append(steps, in.step(qb, DeployResourceField))
+	}
+
+	for _, f := range d.Formulas {
+		b, err := file.ReadFile(f.File, f.Substitute)
+		if err != nil {
+			return nil, err
+		}
+
+		in, err := newDeployFieldInput(ctx, qb, f.TableID, f.FieldID)
+		if err != nil {
+			return nil, fmt.Errorf("formula %s: %w", deployFieldName(f.TableID, f.FieldID), err)
+		}
+		in.Properties.Formula = string(b)
+		steps = append(steps, in.step(qb, DeployResourceFormula))
+	}
+
+	for _, p := range d.Pages {
+		b, err := file.ReadFile(p.File, p.Substitute)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, newDeployPageStep(qb, appID, p, string(b)))
+	}
+
+	names := make([]string, 0, len(d.Variables))
+	for name := range d.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		in := &qbclient.SetVariableInput{AppID: appID, Name: name, Value: d.Variables[name]}
+		steps = append(steps, &deployStep{
+			resource: DeployResourceVariable,
+			name:     name,
+			deploy: func(ctx context.Context) error {
+				_, err := qb.SetVariableWithContext(ctx, in)
+				return err
+			},
+		})
+	}
+
+	return steps, nil
+}
+
+func deployFieldName(tableID string, fieldID int) string {
+	return tableID + "." + strconv.Itoa(fieldID)
+}
+
+// deployFieldInput is an update to a field, initialized from the field's
+// current state.
+type deployFieldInput struct {
+	*qbclient.UpdateFieldInput
+}
+
+// newDeployFieldInput retrieves a field and returns an update that only
+// changes it as directed. Searchable and add to reports are always sent, so
+// they are copied from the field so that they are not reset.
+func newDeployFieldInput(ctx context.Context, qb *qbclient.Client, tableID string, fieldID int) (*deployFieldInput, error) {
+	current, err := qb.GetFieldWithContext(ctx, &qbclient.GetFieldInput{TableID: tableID, FieldID: fieldID})
+	if err != nil {
+		return nil, fmt.Errorf("error getting field: %w", err)
+	}
+
+	in := &qbclient.UpdateFieldInput{
+		TableID:    tableID,
+		FieldID:    fieldID,
+		Properties: &qbclient.UpdateFieldInputProperties{},
+	}
+	in.Searchable = current.Searchable
+	in.AddToNewReports = current.AddToNewReports
+
+	return &deployFieldInput{UpdateFieldInput: in}, nil
+}
+
+func (in *deployFieldInput) step(qb *qbclient.Client, resource string) *deployStep {
+	return &deployStep{
+		resource: resource,
+		name:     deployFieldName(in.TableID, in.FieldID),
+		deploy: func(ctx context.Context) error {
+			_, err := qb.UpdateFieldWithContext(ctx, in.UpdateFieldInput)
+			if err == nil {
+				// A stale schema is refreshed when the cache expires.
+				InvalidateTableSchema(in.TableID)
+			}
+			return err
+		},
+	}
+}
+
+// apply sets the properties on the update. Properties that are false or empty
+// are omitted from updates unless they are cleared, so the properties set to
+// false or empty values are added to in.Clear.
+func (f *QuickbaseFileDeployField) apply(in *deployFieldInput) {
+	in.Label = f.Label
+
+	clear := func(name string, zero bool) {
+		if zero {
+			in.Clear = append(in.Clear, name)
+		}
+	}
+
+	if f.HelpText != nil {
+		in.FieldHelpText = *f.HelpText
+		clear("fieldHelp", *f.HelpText == "")
+	}
+	if f.Required != nil {
+		in.Required = *f.Required
+		clear("required", !*f.Required)
+	}
+	if f.Unique != nil {
+		in.Unique = *f.Unique
+		clear("unique", !*f.Unique)
+	}
+	if f.DefaultValue != nil {
+		in.Properties.DefaultValue = *f.DefaultValue
+		clear("defaultValue", *f.DefaultValue == "")
+	}
+	if f.Choices != nil {
+		in.Properties.Choices = f.Choices
+		clear("choices", len(f.Choices) == 0)
+	}
+	if f.AllowNewChoices != nil {
+		in.Properties.AllowNewChoices = *f.AllowNewChoices
+		clear("allowNewChoices", !*f.AllowNewChoices)
+	}
+	if f.SortAsGiven != nil {
+		in.Properties.SortChoicesAsGiven = *f.SortAsGiven
+		clear("sortAsGiven", !*f.SortAsGiven)
+	}
+}
+
+func newDeployPageStep(qb *qbclient.Client, appID string, p *QuickbaseFileDeployPage, body string) *deployStep {
+	step := &deployStep{resource: DeployResourcePage, name: p.Name}
+
+	if p.PageID != 0 {
+		if step.name == "" {
+			step.name = strconv.Itoa(p.PageID)
+		}
+		in := &qbclient.UpdatePageInput{AppID: appID, PageID: p.PageID, Body: &qbclient.UpdatePageInputBody{Data: body}}
+		step.deploy = func(ctx context.Context) error {
+			_, err := qb.UpdatePageWithContext(ctx, in)
+			return err
+		}
+		return step
+	}
+
+	ptype := p.Type
+	if ptype == 0 {
+		ptype = DefaultPageType
+	}
+	in := &qbclient.CreatePageInput{AppID: appID, Name: p.Name, Type: ptype, Body: &qbclient.CreatePageInputBody{Data: body}}
+	step.deploy = func(ctx context.Context) error {
+		_, err := qb.CreatePageWithContext(ctx, in)
+		return err
+	}
+
+	return step
+}
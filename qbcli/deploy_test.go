@@ -0,0 +1,158 @@
+package qbcli_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+// rewriteTransport sends every request to a test server, including XML API
+// requests that are sent to the realm hostname.
+type rewriteTransport struct{ u *url.URL }
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = t.u.Scheme, t.u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func writeDeployFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "qbcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(qbclient.Filepath(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDeploy(t *testing.T) {
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if action := r.Header.Get("QUICKBASE-ACTION"); action != "" {
+			requests = append(requests, action+" "+r.URL.Path)
+			fmt.Fprintf(w, `<qdbapi><action>%s</action><errcode>0</errcode><pageID>12</pageID></qdbapi>`, action)
+			return
+		}
+
+		requests = append(requests, r.Method+" "+r.URL.String()+" "+strings.TrimSpace(string(body)))
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"id":6,"label":"Status","fieldType":"text-multiple-choice","findEnabled":false,"appearsByDefault":true,"properties":{"choices":["Open"]}}`)
+		} else {
+			fmt.Fprint(w, `{"id":6}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+	qb.ReamlHostname = "example.quickbase.com"
+	qb.HTTPClient = &http.Client{Transport: rewriteTransport{u}}
+
+	dir := writeDeployFiles(t, map[string]string{
+		"quickbase.yml": `
+deploy:
+  app_id: bqgruir3g
+  fields:
+    - table_id: bqgruir7z
+      field_id: 6
+      required: true
+      choices: [Open, Closed]
+  formulas:
+    - file: days.formula
+      table_id: bqgruir7z
+      field_id: 8
+  pages:
+    - file: index.html
+      name: index.html
+  variables:
+    env: prod
+`,
+		"days.formula": "[Due] - Today()",
+		"index.html":   "<h1>Hello</h1>",
+	})
+	defer os.RemoveAll(dir)
+
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	out, err := qbcli.Deploy(context.Background(), qb, &qbcli.DeployInput{File: "quickbase.yml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(out.Errors) > 0 || len(out.Deployed) != 4 {
+		t.Fatalf("got %d deployed and errors %v, expected 4 deployed", len(out.Deployed), out.Errors)
+	}
+
+	// Fields are retrieved before anything is deployed, and searchable and
+	// add to reports are sent as they are so that they are not reset.
+	expected := []string{
+		`GET /fields/6?tableId=bqgruir7z `,
+		`GET /fields/8?tableId=bqgruir7z `,
+		`POST /fields/6?tableId=bqgruir7z {"required":true,"findEnabled":false,"appearsByDefault":true,"properties":{"choices":["Open","Closed"]}}`,
+		`POST /fields/8?tableId=bqgruir7z {"findEnabled":false,"appearsByDefault":true,"properties":{"formula":"[Due] - Today()"}}`,
+		`API_AddReplaceDBPage /db/bqgruir3g`,
+		`API_SetDBvar /db/bqgruir3g`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got requests:\n%s\nexpected:\n%s", strings.Join(requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestDeployUnsetProperty(t *testing.T) {
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.String()+" "+strings.TrimSpace(string(body)))
+		fmt.Fprint(w, `{"id":6,"label":"Name","fieldType":"text","required":true,"fieldHelp":"Who","properties":{"choices":["A"]}}`)
+	}))
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	dir := writeDeployFiles(t, map[string]string{
+		"quickbase.yml": `
+deploy:
+  fields:
+    - table_id: bqgruir7z
+      field_id: 6
+      required: false
+      help_text: ""
+      choices: []
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	out, err := qbcli.Deploy(context.Background(), qb, &qbcli.DeployInput{File: qbclient.Filepath(dir, "quickbase.yml")})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(out.Errors) > 0 || len(out.Deployed) != 1 {
+		t.Fatalf("got %d deployed and errors %v, expected 1 deployed", len(out.Deployed), out.Errors)
+	}
+
+	// Properties set to false or empty values are sent so they are cleared.
+	expected := []string{
+		`GET /fields/6?tableId=bqgruir7z `,
+		`POST /fields/6?tableId=bqgruir7z {"appearsByDefault":false,"fieldHelp":"","findEnabled":false,"properties":{"choices":[]},"required":false}`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got requests:\n%s\nexpected:\n%s", strings.Join(requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
@@ -11,12 +11,17 @@ import (
 
 var (
 	TestsFailed = qberrors.ErrSafe{Message: "tests failed", StatusCode: http.StatusBadRequest}
+	LintFailed  = qberrors.ErrSafe{Message: "lint failed", StatusCode: http.StatusBadRequest}
 )
 
 func TestsFailedError(format string, a ...interface{}) error {
 	return qberrors.Client(nil).Safef(TestsFailed, format, a...)
 }
 
+func LintFailedError(format string, a ...interface{}) error {
+	return qberrors.Client(nil).Safef(LintFailed, format, a...)
+}
+
 // HandleError handles an error by logging it and returning a non-zero status.
 // We reserve Fatal errors for internal problems.
 func HandleError(ctx context.Context, logger *cliutil.LeveledLogger, message string, err error) {
@@ -0,0 +1,117 @@
+package qbcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
+)
+
+// exportState is the state of incremental exports for a table, which is
+// persisted between runs.
+type exportState struct {
+	TableID string `json:"tableId"`
+
+	// Watermark is the Date Modified of the most recently modified record
+	// exported by the last successful run. It is zero before the first run.
+	Watermark time.Time `json:"watermark"`
+
+	// Pending is the export in progress, which is nil after a successful run.
+	Pending *exportPending `json:"pending,omitempty"`
+}
+
+// exportPending is an incremental export in progress. It exports records
+// where From < Date Modified <= To, in record ID order, so that an export can
+// be resumed after the last record ID written. Select and Where are the query
+// options the export was started with, which must not change when it is
+// resumed so that the records appended match the ones already written.
+// Incremental exports can't be sorted, so the sort order doesn't vary.
+type exportPending struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	LastRecordID int       `json:"lastRecordId"`
+	Select       []int     `json:"select"`
+	Where        string    `json:"where,omitempty"`
+}
+
+// checkQuery returns an error if the query options of a resumed export differ
+// from the ones the export was started with.
+func (p *exportPending) checkQuery(fids []int, where string) error {
+	if fmt.Sprint(p.Select) != fmt.Sprint(fids) || p.Where != where {
+		return qberrors.Client(nil).Safef(qberrors.InvalidInput,
+			"the export being resumed was started with select %v and where %q", p.Select, p.Where)
+	}
+	return nil
+}
+
+// where returns the query that selects the records remaining in the export.
+func (p *exportPending) where() string {
+	q := fmt.Sprintf("{2.OBF.%d}", epochMilliseconds(p.To))
+	if !p.From.IsZero() {
+		q = fmt.Sprintf("{2.AF.%d}AND%s", epochMilliseconds(p.From), q)
+	}
+	if p.LastRecordID > 0 {
+		q += fmt.Sprintf("AND{3.GT.%d}", p.LastRecordID)
+	}
+	return q
+}
+
+// epochMilliseconds returns t as milliseconds since the Unix epoch, which is
+// how Quickbase queries compare date/time values.
+func epochMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// readExportState reads the incremental export state for a table. A missing
+// file means no incremental export has been run.
+func readExportState(filename, tableID string) (*exportState, error) {
+	state := &exportState{TableID: tableID}
+	if !qbclient.FileExists(filename) {
+		return state, nil
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading export state: %w", err)
+	}
+	if err = json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("error parsing export state: %w", err)
+	}
+
+	if state.TableID != tableID {
+		return nil, fmt.Errorf("export state file is for table %s, not %s", state.TableID, tableID)
+	}
+
+	return state, nil
+}
+
+// writeExportState writes the incremental export state, replacing the file
+// atomically so an interruption can't corrupt it.
+func writeExportState(filename string, state *exportState) error {
+	dir := filepath.Dir(filename)
+	if !qbclient.DirExists(dir) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error creating export state directory: %w", err)
+		}
+	}
+
+	b, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return fmt.Errorf("error encoding export state: %w", err)
+	}
+
+	tmp := filename + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("error writing export state: %w", err)
+	}
+	if err = os.Rename(tmp, filename); err != nil {
+		return fmt.Errorf("error writing export state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,394 @@
+package qbcli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// DataFormat* constants contain the formats of imported and exported data.
+const (
+	DataFormatCSV   = "csv"
+	DataFormatJSONL = "jsonl"
+	DataFormatXLSX  = "xlsx"
+)
+
+// dataFormat returns the format of the data, which is detected from the file
+// extension if not explicitly set. It defaults to CSV.
+func dataFormat(format, filename string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".jsonl", ".ndjson":
+			return DataFormatJSONL, nil
+		case ".xlsx":
+			return DataFormatXLSX, nil
+		default:
+			return DataFormatCSV, nil
+		}
+	}
+
+	switch f := strings.ToLower(format); f {
+	case DataFormatCSV, DataFormatJSONL, DataFormatXLSX:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%s: data format not valid", format)
+	}
+}
+
+// importReader reads the records being imported.
+type importReader interface {
+
+	// Read returns the next record and the line it was read from. It returns
+	// io.EOF when there are no more records.
+	Read() (record map[int]*qbclient.InsertRecordsInputData, line int, err error)
+}
+
+// importFieldMapper maps the labels in the imported data to fields in the
+// destination table.
+type importFieldMapper struct {
+	fields FieldMap
+	lmap   map[string]int
+	opts   *ImportOptions
+}
+
+func newImportFieldMapper(fields FieldMap, opts *ImportOptions) *importFieldMapper {
+	lmap := make(map[string]int, len(fields))
+	for _, field := range fields {
+		lmap[field.Label] = field.FieldID
+	}
+	return &importFieldMapper{fields: fields, lmap: lmap, opts: opts}
+}
+
+// fieldID returns the fid of the field the label maps to.
+func (m *importFieldMapper) fieldID(label string) (int, error) {
+
+	// Check the field label map first.
+	if destLabel, ok := m.opts.Map[label]; ok {
+		label = destLabel
+	}
+
+	// Now get the field ID.
+	fid, ok := m.lmap[label]
+	if !ok {
+		return 0, fmt.Errorf("%s field not in destination table", label)
+	}
+
+	return fid, nil
+}
+
+// skip returns true if the field can't be written.
+func (m *importFieldMapper) skip(fid int) bool {
+
+	// We cannot insert record metadata.
+	if fid != 3 && fid <= 5 {
+		return true
+	}
+
+	// Skip the record ID if it isn't the merge field.
+	return fid == 3 && m.opts.MergeFieldID != 3
+}
+
+// csvImportReader reads records from CSV data. The first line is the header,
+// which contains the field labels. Records are numbered by the line they start
+// on, which accounts for quoted values that span several lines. Lines are
+// counted from the end of the header, so the first record is line 1.
+type csvImportReader struct {
+	reader *csv.Reader
+	lines  *lineReader
+	mapper *importFieldMapper
+	fmap   []int
+	header int
+}
+
+func newCSVImportReader(r io.Reader, mapper *importFieldMapper) (*csvImportReader, error) {
+	lines := &lineReader{r: bufio.NewReader(r)}
+	ir := &csvImportReader{reader: csv.NewReader(lines), lines: lines, mapper: mapper}
+
+	// Map the header to field IDs.
+	header, _, err := ir.read()
+	if err == io.EOF {
+		return ir, nil
+	} else if err != nil {
+		return nil, err
+	}
+	ir.header = ir.lines.n
+
+	for _, label := range header {
+		fid, err := mapper.fieldID(label)
+		if err != nil {
+			return nil, err
+		}
+		ir.fmap = append(ir.fmap, fid)
+	}
+
+	return ir, nil
+}
+
+// read reads the next row and returns the line it starts on.
+func (ir *csvImportReader) read() ([]string, int, error) {
+	row, err := ir.reader.Read()
+	if err == io.EOF {
+		return nil, ir.lines.n + 1 - ir.header, err
+	} else if err != nil {
+		// Parse errors contain the line in the file, so they aren't prefixed
+		// with the line counted from the header.
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			return nil, perr.StartLine - ir.header, fmt.Errorf("error reading data: %w", err)
+		}
+		line := ir.lines.n + 1 - ir.header
+		return nil, line, fmt.Errorf("error reading line %v: %w", line, err)
+	}
+
+	// The row ends on the last line read, so count back the line breaks in
+	// its values to get the line it starts on.
+	line := ir.lines.n
+	if !ir.lines.eol {
+		line++
+	}
+	for _, data := range row {
+		line -= strings.Count(data, "\n")
+	}
+
+	return row, line - ir.header, nil
+}
+
+// Read implements importReader.Read.
+func (ir *csvImportReader) Read() (map[int]*qbclient.InsertRecordsInputData, int, error) {
+	if ir.fmap == nil {
+		return nil, 0, io.EOF
+	}
+
+	row, line, err := ir.read()
+	if err != nil {
+		return nil, line, err
+	}
+
+	record := make(map[int]*qbclient.InsertRecordsInputData)
+	for idx, data := range row {
+
+		// TODO defensive coding ...
+		fid := ir.fmap[idx]
+		if ir.mapper.skip(fid) {
+			continue
+		}
+
+		// TODO defensive coding ...
+		ftype := ir.mapper.fields[fid].Type
+
+		// Create a *qbclient.Value from the string value and field type.
+		val, err := qbclient.NewValueFromString(data, ftype)
+		if err != nil {
+			return nil, line, fmt.Errorf("value invalid for field %v: %w", fid, err)
+		}
+
+		// Add the value to the record .
+		record[fid] = &qbclient.InsertRecordsInputData{Value: val}
+	}
+
+	return record, line, nil
+}
+
+// lineReader counts the lines read from r. At most one line is returned by
+// each call to Read, so a buffered reader on top of it, e.g., the one in
+// csv.Reader, never reads past the end of the record it is parsing.
+type lineReader struct {
+	r   *bufio.Reader
+	buf []byte
+	err error
+
+	// n is the number of line breaks read, and eol is whether the data read
+	// so far ends with one.
+	n   int
+	eol bool
+}
+
+// Read implements io.Reader.
+func (lr *lineReader) Read(p []byte) (int, error) {
+	if len(lr.buf) == 0 {
+		if lr.err != nil {
+			return 0, lr.err
+		}
+		if lr.buf, lr.err = lr.r.ReadSlice('\n'); lr.err == bufio.ErrBufferFull {
+			lr.err = nil
+		}
+		if len(lr.buf) == 0 {
+			return 0, lr.err
+		}
+	}
+
+	n := copy(p, lr.buf)
+	lr.n += bytes.Count(lr.buf[:n], []byte{'\n'})
+	lr.eol = lr.buf[n-1] == '\n'
+	lr.buf = lr.buf[n:]
+	return n, nil
+}
+
+// jsonlImportReader reads records from JSON Lines data, where each line is an
+// object keyed by field label. The values are decoded according to the type
+// of the field, so they are the inverse of qbclient.Value.MarshalJSON.
+type jsonlImportReader struct {
+	scanner *bufio.Scanner
+	mapper  *importFieldMapper
+	line    int
+}
+
+func newJSONLImportReader(r io.Reader, mapper *importFieldMapper) *jsonlImportReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	return &jsonlImportReader{scanner: scanner, mapper: mapper}
+}
+
+// Read implements importReader.Read.
+func (ir *jsonlImportReader) Read() (map[int]*qbclient.InsertRecordsInputData, int, error) {
+
+	// Skip blank lines.
+	var b []byte
+	for len(b) == 0 {
+		if !ir.scanner.Scan() {
+			if err := ir.scanner.Err(); err != nil {
+				return nil, ir.line + 1, fmt.Errorf("error reading line %v: %w", ir.line+1, err)
+			}
+			return nil, ir.line, io.EOF
+		}
+		ir.line++
+		b = bytes.TrimSpace(ir.scanner.Bytes())
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, ir.line, fmt.Errorf("error parsing line %v: %w", ir.line, err)
+	}
+
+	record := make(map[int]*qbclient.InsertRecordsInputData)
+	for label, data := range obj {
+		fid, err := ir.mapper.fieldID(label)
+		if err != nil {
+			return nil, ir.line, err
+		}
+		if ir.mapper.skip(fid) || bytes.Equal(data, []byte("null")) {
+			continue
+		}
+
+		val, err := qbclient.NewValueFromJSON(data, ir.mapper.fields[fid].Type)
+		if err != nil {
+			return nil, ir.line, fmt.Errorf("value invalid for field %v on line %v: %w", fid, ir.line, err)
+		}
+
+		record[fid] = &qbclient.InsertRecordsInputData{Value: val}
+	}
+
+	return record, ir.line, nil
+}
+
+// exportWriter writes the records being exported.
+type exportWriter interface {
+
+	// WriteHeader writes the header, if the format has one.
+	WriteHeader() error
+
+	// WriteRecords writes the records and flushes any buffered data.
+	WriteRecords(records []map[int]*qbclient.RecordsData) error
+
+	// Close finishes writing the data after every record has been written.
+	Close() error
+}
+
+// csvExportWriter writes records as CSV data, with the field labels as the
+// header and the values formatted with qbclient.Value.String.
+type csvExportWriter struct {
+	writer *csv.Writer
+	fields FieldMap
+	fids   []int
+}
+
+func newCSVExportWriter(w io.Writer, fields FieldMap, fids []int) *csvExportWriter {
+	return &csvExportWriter{writer: csv.NewWriter(w), fields: fields, fids: fids}
+}
+
+// WriteHeader implements exportWriter.WriteHeader.
+func (ew *csvExportWriter) WriteHeader() error {
+	header := make([]string, len(ew.fids))
+	for idx, fid := range ew.fids {
+		header[idx] = ew.fields[fid].Label
+	}
+	ew.writer.Write(header)
+
+	ew.writer.Flush()
+	return ew.writer.Error()
+}
+
+// Close implements exportWriter.Close.
+func (ew *csvExportWriter) Close() error { return nil }
+
+// WriteRecords implements exportWriter.WriteRecords.
+func (ew *csvExportWriter) WriteRecords(records []map[int]*qbclient.RecordsData) error {
+	for _, record := range records {
+		row := make([]string, len(ew.fids))
+		for idx, fid := range ew.fids {
+			row[idx] = record[fid].Value.String()
+		}
+		ew.writer.Write(row)
+	}
+
+	// Flush the buffer.
+	ew.writer.Flush()
+	return ew.writer.Error()
+}
+
+// jsonlExportWriter writes records as JSON Lines, where each line is an object
+// keyed by field label in fid order. The values are encoded with
+// qbclient.Value.MarshalJSON, which preserves their types.
+type jsonlExportWriter struct {
+	writer *bufio.Writer
+	fields FieldMap
+	fids   []int
+}
+
+func newJSONLExportWriter(w io.Writer, fields FieldMap, fids []int) *jsonlExportWriter {
+	return &jsonlExportWriter{writer: bufio.NewWriter(w), fields: fields, fids: fids}
+}
+
+// WriteHeader implements exportWriter.WriteHeader. JSON Lines have no header.
+func (ew *jsonlExportWriter) WriteHeader() error { return nil }
+
+// Close implements exportWriter.Close.
+func (ew *jsonlExportWriter) Close() error { return nil }
+
+// WriteRecords implements exportWriter.WriteRecords.
+func (ew *jsonlExportWriter) WriteRecords(records []map[int]*qbclient.RecordsData) error {
+	for _, record := range records {
+		ew.writer.WriteByte('{')
+		for idx, fid := range ew.fids {
+			if idx > 0 {
+				ew.writer.WriteByte(',')
+			}
+
+			label, _ := json.Marshal(ew.fields[fid].Label)
+			ew.writer.Write(label)
+			ew.writer.WriteByte(':')
+
+			var b []byte
+			var err error
+			if data, ok := record[fid]; ok && data.Value != nil {
+				if b, err = data.Value.MarshalJSON(); err != nil {
+					return fmt.Errorf("error encoding field %v: %w", fid, err)
+				}
+			} else {
+				b = []byte("null")
+			}
+			ew.writer.Write(b)
+		}
+		ew.writer.WriteString("}\n")
+	}
+
+	return ew.writer.Flush()
+}
@@ -0,0 +1,363 @@
+package qbcli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
+	"github.com/QuickBase/quickbase-cli/qbformula"
+	"github.com/cpliakas/cliutil"
+)
+
+// FieldDep* constants contain the kinds of dependencies between fields.
+// Lookup and summary fields also depend on the reference field of their
+// relationship.
+const (
+	FieldDepFormula   = "formula"
+	FieldDepLookup    = "lookup"
+	FieldDepSummary   = "summary"
+	FieldDepReference = "reference"
+)
+
+// FormulaDepsFormatDOT is the format that renders the graph in the Graphviz
+// DOT language.
+const FormulaDepsFormatDOT = "dot"
+
+// FormulaDepsInput contains the options for building a dependency graph. The
+// whole app is scanned if the app ID is set, otherwise only the table is,
+// which misses the fields in other tables that depend on it.
+type FormulaDepsInput struct {
+	AppID   string `cliutil:"option=app-id"`
+	TableID string `cliutil:"option=table-id"`
+}
+
+// FieldDeps is a graph of the dependencies between fields. Edges point from a
+// field to a field it depends on, and only fields with dependencies or
+// dependents are nodes.
+type FieldDeps struct {
+	Nodes    []*FieldDepsNode `json:"nodes"`
+	Edges    []*FieldDepsEdge `json:"edges"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+// FieldDepsNode is a field in the dependency graph. The ID is the table and
+// field IDs, e.g., bqgruir7z.6.
+type FieldDepsNode struct {
+	ID      string `json:"id"`
+	TableID string `json:"tableId"`
+	Table   string `json:"table,omitempty"`
+	FieldID int    `json:"fieldId"`
+	Label   string `json:"label,omitempty"`
+	Type    string `json:"type,omitempty"`
+}
+
+// FieldDepsEdge is a dependency of the From field on the To field.
+type FieldDepsEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// fieldDepsBuilder accumulates the graph while the tables are scanned.
+type fieldDepsBuilder struct {
+	deps   *FieldDeps
+	tables map[string]string
+	fields map[string]map[int]*qbclient.ListFieldsOutputField
+	nodes  map[string]*FieldDepsNode
+	edges  map[FieldDepsEdge]bool
+}
+
+// FormulaDependencies builds a graph of the fields that formulas, lookups,
+// and summaries depend on.
+func FormulaDependencies(ctx context.Context, qb *qbclient.Client, in *FormulaDepsInput) (*FieldDeps, error) {
+	b := &fieldDepsBuilder{
+		deps:   &FieldDeps{Nodes: []*FieldDepsNode{}, Edges: []*FieldDepsEdge{}},
+		tables: map[string]string{},
+		fields: map[string]map[int]*qbclient.ListFieldsOutputField{},
+		nodes:  map[string]*FieldDepsNode{},
+		edges:  map[FieldDepsEdge]bool{},
+	}
+
+	var tableIDs []string
+	switch {
+	case in.AppID != "":
+		tables, err := qb.ListTablesByAppIDWithContext(ctx, in.AppID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing tables: %w", err)
+		}
+		for _, t := range tables.Tables {
+			b.tables[t.TableID] = t.Name
+			tableIDs = append(tableIDs, t.TableID)
+		}
+	case in.TableID != "":
+		tableIDs = []string{in.TableID}
+	default:
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "app or table ID required")
+	}
+
+	// Every table's fields are retrieved first, because relationships refer
+	// to the fields in both the parent and child tables.
+	for _, tableID := range tableIDs {
+		fields, err := qb.ListFieldsByTableIDWithContext(ctx, tableID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing fields in table %s: %w", tableID, err)
+		}
+		b.fields[tableID] = newFieldMap(fields.Fields)
+	}
+
+	for _, tableID := range tableIDs {
+		relationships, err := qb.ListRelationshipsByTableIDWithContext(ctx, tableID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing relationships in table %s: %w", tableID, err)
+		}
+		b.addFormulas(tableID)
+		for _, r := range relationships.Relationships {
+			b.addRelationship(r)
+		}
+	}
+
+	return b.build(), nil
+}
+
+// addFormulas adds the fields referenced by the formulas in a table.
+func (b *fieldDepsBuilder) addFormulas(tableID string) {
+	labels := map[string]int{}
+	for fid, f := range b.fields[tableID] {
+		labels[strings.ToLower(f.Label)] = fid
+	}
+
+	for _, fid := range sortedFieldIDs(b.fields[tableID]) {
+		f := b.fields[tableID][fid]
+		if f.Properties == nil || f.Properties.Formula == "" {
+			continue
+		}
+
+		from := deployFieldName(tableID, fid)
+		tokens, err := qbformula.Tokenize(f.Properties.Formula)
+		if err != nil {
+			b.warnf("%s: formula not parsed: %s", from, err)
+			continue
+		}
+
+		for _, tok := range tokens {
+			if tok.Kind != qbformula.TokenField {
+				continue
+			}
+			if to, ok := labels[strings.ToLower(tok.Value)]; ok {
+				b.addEdge(tableID, fid, tableID, to, FieldDepFormula)
+			} else {
+				b.warnf("%s: [%s] field not found", from, tok.Value)
+			}
+		}
+	}
+}
+
+// addRelationship adds the fields that the relationship's lookup and summary
+// fields depend on. Lookup fields are in the child table and look up fields
+// in the parent, and summary fields are in the parent and summarize fields in
+// the child.
+func (b *fieldDepsBuilder) addRelationship(r *qbclient.Relationship) {
+	fk := 0
+	if r.ForeignKeyField != nil {
+		fk = r.ForeignKeyField.FieldID
+	}
+
+	for _, lf := range r.LookupFields {
+		if fk != 0 {
+			b.addEdge(r.ChildTableID, lf.FieldID, r.ChildTableID, fk, FieldDepReference)
+		}
+		if f, ok := b.fields[r.ChildTableID][lf.FieldID]; ok && f.Properties != nil && f.Properties.LookupTargetFieldID != 0 {
+			b.addEdge(r.ChildTableID, lf.FieldID, r.ParentTableID, f.Properties.LookupTargetFieldID, FieldDepLookup)
+		}
+	}
+
+	for _, sf := range r.SummaryFields {
+		if fk != 0 {
+			b.addEdge(r.ParentTableID, sf.FieldID, r.ChildTableID, fk, FieldDepReference)
+		}
+		if f, ok := b.fields[r.ParentTableID][sf.FieldID]; ok && f.Properties != nil && f.Properties.SummaryTargetFieldID != 0 {
+			b.addEdge(r.ParentTableID, sf.FieldID, r.ChildTableID, f.Properties.SummaryTargetFieldID, FieldDepSummary)
+		}
+	}
+}
+
+func (b *fieldDepsBuilder) warnf(format string, a ...interface{}) {
+	b.deps.Warnings = append(b.deps.Warnings, fmt.Sprintf(format, a...))
+}
+
+func (b *fieldDepsBuilder) node(tableID string, fieldID int) *FieldDepsNode {
+	id := deployFieldName(tableID, fieldID)
+	if n, ok := b.nodes[id]; ok {
+		return n
+	}
+
+	n := &FieldDepsNode{ID: id, TableID: tableID, Table: b.tables[tableID], FieldID: fieldID}
+	if f, ok := b.fields[tableID][fieldID]; ok {
+		n.Label, n.Type = f.Label, f.Type
+	}
+	b.nodes[id] = n
+	return n
+}
+
+func (b *fieldDepsBuilder) addEdge(fromTable string, fromField int, toTable string, toField int, kind string) {
+	e := FieldDepsEdge{From: b.node(fromTable, fromField).ID, To: b.node(toTable, toField).ID, Kind: kind}
+	if e.From != e.To && !b.edges[e] {
+		b.edges[e] = true
+		b.deps.Edges = append(b.deps.Edges, &e)
+	}
+}
+
+// build sorts the graph so the output is stable.
+func (b *fieldDepsBuilder) build() *FieldDeps {
+	for _, n := range b.nodes {
+		b.deps.Nodes = append(b.deps.Nodes, n)
+	}
+	sort.Slice(b.deps.Nodes, func(i, j int) bool {
+		return fieldDepsLess(b.deps.Nodes[i].ID, b.deps.Nodes[j].ID)
+	})
+	sort.Slice(b.deps.Edges, func(i, j int) bool {
+		a, c := b.deps.Edges[i], b.deps.Edges[j]
+		if a.From != c.From {
+			return fieldDepsLess(a.From, c.From)
+		}
+		if a.To != c.To {
+			return fieldDepsLess(a.To, c.To)
+		}
+		return a.Kind < c.Kind
+	})
+	return b.deps
+}
+
+// fieldDepsLess sorts node IDs by table ID, then numerically by field ID.
+func fieldDepsLess(a, b string) bool {
+	at, af := splitFieldDepsID(a)
+	bt, bf := splitFieldDepsID(b)
+	if at != bt {
+		return at < bt
+	}
+	return af < bf
+}
+
+func splitFieldDepsID(id string) (string, int) {
+	idx := strings.LastIndex(id, ".")
+	fid, _ := strconv.Atoi(id[idx+1:])
+	return id[:idx], fid
+}
+
+func sortedFieldIDs(fields map[int]*qbclient.ListFieldsOutputField) []int {
+	fids := make([]int, 0, len(fields))
+	for fid := range fields {
+		fids = append(fids, fid)
+	}
+	sort.Ints(fids)
+	return fids
+}
+
+// Dependents returns the fields that depend on a field, directly or through
+// other fields, e.g., a formula that references a lookup of the field.
+func (d *FieldDeps) Dependents(tableID string, fieldID int) []*FieldDepsNode {
+	nodes := make(map[string]*FieldDepsNode, len(d.Nodes))
+	for _, n := range d.Nodes {
+		nodes[n.ID] = n
+	}
+
+	var dependents []*FieldDepsNode
+	seen := map[string]bool{}
+	queue := []string{deployFieldName(tableID, fieldID)}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, e := range d.Edges {
+			if e.To == id && !seen[e.From] {
+				seen[e.From] = true
+				dependents = append(dependents, nodes[e.From])
+				queue = append(queue, e.From)
+			}
+		}
+	}
+
+	sort.Slice(dependents, func(i, j int) bool { return fieldDepsLess(dependents[i].ID, dependents[j].ID) })
+	return dependents
+}
+
+func (n *FieldDepsNode) String() string {
+	if n.Label == "" {
+		return n.ID
+	}
+	return fmt.Sprintf("%s [%s]", n.ID, n.Label)
+}
+
+// WriteFieldDepsDOT writes the graph in the Graphviz DOT language, grouping
+// the fields by table.
+func WriteFieldDepsDOT(w io.Writer, d *FieldDeps) error {
+	var buf bytes.Buffer
+	buf.WriteString("digraph dependencies {\n  rankdir=LR;\n  node [shape=box];\n")
+
+	var tableIDs []string
+	byTable := map[string][]*FieldDepsNode{}
+	for _, n := range d.Nodes {
+		if _, ok := byTable[n.TableID]; !ok {
+			tableIDs = append(tableIDs, n.TableID)
+		}
+		byTable[n.TableID] = append(byTable[n.TableID], n)
+	}
+
+	for _, tableID := range tableIDs {
+		nodes := byTable[tableID]
+		label := tableID
+		if nodes[0].Table != "" {
+			label = nodes[0].Table
+		}
+
+		fmt.Fprintf(&buf, "  subgraph %s {\n    label=%s;\n", strconv.Quote("cluster_"+tableID), strconv.Quote(label))
+		for _, n := range nodes {
+			label := strconv.Itoa(n.FieldID)
+			if n.Label != "" {
+				label = fmt.Sprintf("%s (%d)", n.Label, n.FieldID)
+			}
+			fmt.Fprintf(&buf, "    %s [label=%s];\n", strconv.Quote(n.ID), strconv.Quote(label))
+		}
+		buf.WriteString("  }\n")
+	}
+
+	for _, e := range d.Edges {
+		fmt.Fprintf(&buf, "  %s -> %s [label=%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strconv.Quote(e.Kind))
+	}
+
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// LogFieldDependents logs the fields that depend on the passed fields, e.g.,
+// before they are deleted. Failing to build the graph is logged, but it isn't
+// fatal, since the check is advisory.
+func LogFieldDependents(ctx context.Context, logger *cliutil.LeveledLogger, qb *qbclient.Client, appID, tableID string, fieldIDs []int) {
+	deps, err := FormulaDependencies(ctx, qb, &FormulaDepsInput{AppID: appID, TableID: tableID})
+	if err != nil {
+		logger.Error(ctx, "error finding dependent fields", err)
+		return
+	}
+
+	for _, fid := range fieldIDs {
+		dependents := deps.Dependents(tableID, fid)
+		if len(dependents) == 0 {
+			continue
+		}
+
+		names := make([]string, len(dependents))
+		for idx, n := range dependents {
+			names[idx] = n.String()
+		}
+
+		fctx := cliutil.ContextWithLogTag(ctx, "field", deployFieldName(tableID, fid))
+		fctx = cliutil.ContextWithLogTag(fctx, "dependents", strings.Join(names, ", "))
+		logger.Notice(fctx, "field has dependents that will break")
+	}
+}
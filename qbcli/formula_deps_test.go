@@ -0,0 +1,109 @@
+package qbcli_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+func TestFormulaDependencies(t *testing.T) {
+	responses := map[string]string{
+		"/tables?appId=bqgruir3g": `[{"id":"bqgruir7z","name":"Projects"},{"id":"bqgruir9k","name":"Tasks"}]`,
+		"/fields?tableId=bqgruir7z": `[
+			{"id":6,"label":"Name","fieldType":"text"},
+			{"id":10,"label":"Total Hours","fieldType":"numeric","properties":{"summaryTargetFieldId":8}},
+			{"id":11,"label":"Title","fieldType":"text","properties":{"formula":"[name] & \" (\" & [Total Hours] & \")\""}}
+		]`,
+		"/fields?tableId=bqgruir9k": `[
+			{"id":6,"label":"Title","fieldType":"text"},
+			{"id":7,"label":"Related Project","fieldType":"numeric"},
+			{"id":8,"label":"Hours","fieldType":"numeric"},
+			{"id":9,"label":"Project Name","fieldType":"text","properties":{"lookupTargetFieldId":6}},
+			{"id":12,"label":"Summary","fieldType":"text","properties":{"formula":"[Project Name] & [Title] & [Missing]"}}
+		]`,
+		"/tables/bqgruir7z/relationships": `{"relationships":[]}`,
+		"/tables/bqgruir9k/relationships": `{"relationships":[{"id":7,"parentTableId":"bqgruir7z","childTableId":"bqgruir9k",
+			"foreignKeyField":{"id":7},"lookupFields":[{"id":9}],"summaryFields":[{"id":10}]}]}`,
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	deps, err := qbcli.FormulaDependencies(context.Background(), qb, &qbcli.FormulaDepsInput{AppID: "bqgruir3g"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var edges []string
+	for _, e := range deps.Edges {
+		edges = append(edges, e.From+" -> "+e.To+" "+e.Kind)
+	}
+	expected := []string{
+		"bqgruir7z.10 -> bqgruir9k.7 reference",
+		"bqgruir7z.10 -> bqgruir9k.8 summary",
+		"bqgruir7z.11 -> bqgruir7z.6 formula",
+		"bqgruir7z.11 -> bqgruir7z.10 formula",
+		"bqgruir9k.9 -> bqgruir7z.6 lookup",
+		"bqgruir9k.9 -> bqgruir9k.7 reference",
+		"bqgruir9k.12 -> bqgruir9k.6 formula",
+		"bqgruir9k.12 -> bqgruir9k.9 formula",
+	}
+	if strings.Join(edges, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got edges:\n%s\nexpected:\n%s", strings.Join(edges, "\n"), strings.Join(expected, "\n"))
+	}
+
+	if len(deps.Warnings) != 1 || deps.Warnings[0] != "bqgruir9k.12: [Missing] field not found" {
+		t.Errorf("unexpected warnings: %q", deps.Warnings)
+	}
+
+	tests := []struct {
+		tableID  string
+		fieldID  int
+		expected string
+	}{
+		{"bqgruir7z", 6, "bqgruir7z.11 [Title], bqgruir9k.9 [Project Name], bqgruir9k.12 [Summary]"},
+		{"bqgruir9k", 8, "bqgruir7z.10 [Total Hours], bqgruir7z.11 [Title]"},
+		{"bqgruir9k", 12, ""},
+	}
+
+	for _, tt := range tests {
+		var names []string
+		for _, n := range deps.Dependents(tt.tableID, tt.fieldID) {
+			names = append(names, n.String())
+		}
+		if actual := strings.Join(names, ", "); actual != tt.expected {
+			t.Errorf("%s.%d: got dependents %q, expected %q", tt.tableID, tt.fieldID, actual, tt.expected)
+		}
+	}
+
+	var buf bytes.Buffer
+	qbcli.WriteFieldDepsDOT(&buf, deps)
+	for _, want := range []string{
+		`subgraph "cluster_bqgruir9k" {` + "\n    label=\"Tasks\";",
+		`"bqgruir9k.9" [label="Project Name (9)"];`,
+		`"bqgruir9k.9" -> "bqgruir7z.6" [label="lookup"];`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected DOT output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package qbcli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/QuickBase/quickbase-cli/qberrors"
+	"github.com/QuickBase/quickbase-cli/qbformula"
+)
+
+// LintFormulaInput contains the options for linting formulas. The formulas
+// are read from Files if set, otherwise from the deploy and test sections of
+// the quickbase.yml file.
+type LintFormulaInput struct {
+	Files []string
+
+	File    string `cliutil:"option=file default=quickbase.yml usage='quickbase file whose formulas are linted if no formula files are passed'"`
+	Env     string `cliutil:"option=env usage='environment in the quickbase file whose variables replace placeholders'"`
+	TableID string `cliutil:"option=table-id"`
+	Schema  string `cliutil:"option=schema usage='app schema export that fields are read from instead of the schema cache'"`
+}
+
+// LintFormulaOutput contains the problems found in the formulas.
+type LintFormulaOutput struct {
+	Diagnostics []*FormulaDiagnostic `json:"diagnostics"`
+	Errors      int                  `json:"errors"`
+	Warnings    int                  `json:"warnings"`
+}
+
+// FormulaDiagnostic is a problem found in a formula file. Inline formulas in
+// the quickbase.yml file are named by their position in the test section.
+type FormulaDiagnostic struct {
+	File string `json:"file"`
+	*qbformula.Diagnostic
+}
+
+func (d *FormulaDiagnostic) String() string { return d.File + ":" + d.Diagnostic.String() }
+
+// lintTarget is a formula and the table whose fields it references. FieldID
+// is the field the formula is deployed to, if any.
+type lintTarget struct {
+	name    string
+	formula string
+	tableID string
+	fieldID int
+}
+
+// LintFormula checks formulas for syntax errors, references to fields that
+// don't exist, unknown functions, and mismatched types. It runs offline, so
+// the fields are read from an app schema export or from the schema cache,
+// which is not consulted if cache is nil.
+func LintFormula(in *LintFormulaInput, cache *SchemaCache) (out *LintFormulaOutput, err error) {
+	var targets []*lintTarget
+	if targets, err = lintTargets(in); err != nil {
+		return
+	}
+
+	schema := &lintSchema{cache: cache}
+	if in.Schema != "" {
+		if schema.app, err = ReadAppSchema(in.Schema); err != nil {
+			return
+		}
+	}
+
+	out = &LintFormulaOutput{Diagnostics: []*FormulaDiagnostic{}}
+	add := func(name string, d *qbformula.Diagnostic) {
+		out.Diagnostics = append(out.Diagnostics, &FormulaDiagnostic{File: name, Diagnostic: d})
+		if d.Severity == qbformula.SeverityError {
+			out.Errors++
+		} else {
+			out.Warnings++
+		}
+	}
+
+	for _, t := range targets {
+		table, ok := schema.table(t.tableID)
+		if !ok {
+			add(t.name, &qbformula.Diagnostic{
+				Pos:      qbformula.Pos{Line: 1, Col: 1},
+				Severity: qbformula.SeverityWarning,
+				Message:  schema.missing(t.tableID),
+			})
+		}
+
+		diags, typ := qbformula.Lint(t.formula, table.labels)
+		for _, d := range diags {
+			add(t.name, d)
+		}
+
+		if ft, ok := table.ids[t.fieldID]; ok && t.fieldID != 0 && !typ.Compatible(ft) {
+			add(t.name, &qbformula.Diagnostic{
+				Pos:      qbformula.Pos{Line: 1, Col: 1},
+				Severity: qbformula.SeverityError,
+				Message:  fmt.Sprintf("formula result is %s, field %d is %s", typ, t.fieldID, ft),
+			})
+		}
+	}
+
+	return
+}
+
+// lintTargets returns the formulas that are linted.
+func lintTargets(in *LintFormulaInput) ([]*lintTarget, error) {
+	var targets []*lintTarget
+
+	if len(in.Files) > 0 {
+		for _, name := range in.Files {
+			b, err := ioutil.ReadFile(name)
+			if err != nil {
+				return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "error reading file: %w", err)
+			}
+			targets = append(targets, &lintTarget{name: name, formula: string(b), tableID: in.TableID})
+		}
+		return targets, nil
+	}
+
+	file, err := ParseQuickbaseFileEnv(in.File, in.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Deploy != nil {
+		for _, f := range file.Deploy.Formulas {
+			b, err := file.ReadFile(f.File, f.Substitute)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, &lintTarget{name: f.File, formula: string(b), tableID: f.TableID, fieldID: f.FieldID})
+		}
+	}
+
+	if file.Test != nil {
+		for idx, f := range file.Test.Formulas {
+			t := &lintTarget{name: f.File, formula: f.Formula, tableID: f.TableID}
+			if f.File == "" {
+				t.name = fmt.Sprintf("%s[test.formulas.%d]", in.File, idx)
+			} else {
+				b, err := file.ReadFile(f.File, f.Substitute)
+				if err != nil {
+					return nil, err
+				}
+				t.formula = string(b)
+			}
+			targets = append(targets, t)
+		}
+	}
+
+	return targets, nil
+}
+
+// lintSchema reads the fields of tables from an app schema export, or from the
+// schema cache if no export was passed.
+type lintSchema struct {
+	app   *AppSchema
+	cache *SchemaCache
+}
+
+// lintTable contains the types of a table's fields keyed by label and ID.
+type lintTable struct {
+	labels map[string]qbformula.Type
+	ids    map[int]qbformula.Type
+}
+
+// table returns the fields of the table, which is identified by ID, or by
+// alias or name in an app schema export. An export with one table is used
+// if no table is passed.
+func (s *lintSchema) table(tableID string) (t *lintTable, ok bool) {
+	t = &lintTable{}
+
+	if s.app != nil {
+		for _, st := range s.app.Tables {
+			if tableID == st.TableID || tableID == st.Alias || tableID == st.Name || tableID == "" && len(s.app.Tables) == 1 {
+				t.labels = make(map[string]qbformula.Type, len(st.Fields))
+				t.ids = make(map[int]qbformula.Type, len(st.Fields))
+				for _, f := range st.Fields {
+					t.labels[f.Label] = qbformula.FieldType(f.Type)
+					t.ids[f.FieldID] = qbformula.FieldType(f.Type)
+				}
+				return t, true
+			}
+		}
+		return t, false
+	}
+
+	if tableID == "" {
+		return t, false
+	}
+
+	fmap, ok := s.cache.Get(tableID)
+	if !ok {
+		return t, false
+	}
+
+	t.labels = make(map[string]qbformula.Type, len(fmap))
+	t.ids = make(map[int]qbformula.Type, len(fmap))
+	for fid, f := range fmap {
+		t.labels[f.Label] = qbformula.FieldType(f.Type)
+		t.ids[fid] = qbformula.FieldType(f.Type)
+	}
+	return t, true
+}
+
+// missing returns a message explaining why a table's fields aren't available.
+func (s *lintSchema) missing(tableID string) string {
+	switch {
+	case tableID == "":
+		return "table required to check field references, e.g., --table-id"
+	case s.app != nil:
+		return fmt.Sprintf("table %s: not found in app schema, field references not checked", tableID)
+	}
+	return fmt.Sprintf("table %s: schema not cached, field references not checked; run cache refresh or pass --schema", tableID)
+}
+
+// WriteFormulaLint writes the diagnostics in the file:line:col: severity:
+// message format understood by most editors.
+func WriteFormulaLint(w io.Writer, out *LintFormulaOutput) error {
+	var buf bytes.Buffer
+	for _, d := range out.Diagnostics {
+		fmt.Fprintln(&buf, d.String())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,107 @@
+package qbcli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+func TestLintFormula(t *testing.T) {
+	dir := writeDeployFiles(t, map[string]string{
+		"schema.yml": `
+name: Projects
+tables:
+  - id: bqgruir7z
+    name: Tasks
+    alias: _DBID_TASKS
+    fields:
+      - id: 6
+        label: Name
+        type: text
+      - id: 7
+        label: Due Date
+        type: date
+      - id: 8
+        label: Days Left
+        type: numeric
+`,
+		"days_left.formula": "ToDays([Due Date] - Today())\n",
+		"status.formula":    "If([Days Left] < 0,\n  \"Overdue\",\n  Lenght([Nmae]))\n",
+		"quickbase.yml": `
+deploy:
+  formulas:
+    - file: DIR/status.formula
+      table_id: bqgruir7z
+      field_id: 8
+test:
+  formulas:
+    - formula: Left([Name] 3)
+      table_id: bqgruir7z
+      record_id: 1
+      expected: a
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	name := qbclient.Filepath(dir, "quickbase.yml")
+	b, _ := ioutil.ReadFile(name)
+	ioutil.WriteFile(name, []byte(strings.Replace(string(b), "DIR", dir, 1)), 0644)
+
+	schema := qbclient.Filepath(dir, "schema.yml")
+	status := qbclient.Filepath(dir, "status.formula")
+
+	in := &qbcli.LintFormulaInput{
+		Files:   []string{qbclient.Filepath(dir, "days_left.formula"), status},
+		TableID: "_DBID_TASKS",
+		Schema:  schema,
+	}
+	out, err := qbcli.LintFormula(in, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	qbcli.WriteFormulaLint(&buf, out)
+	expected := status + ":3:3: error: Lenght: unknown function\n" +
+		status + ":3:10: error: [Nmae]: field not found, did you mean [Name]?\n"
+	if buf.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	// Formulas are read from the quickbase.yml file if no files are passed,
+	// and are checked against the fields they are deployed to.
+	in = &qbcli.LintFormulaInput{File: name, Schema: schema}
+	if out, err = qbcli.LintFormula(in, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var messages []string
+	for _, d := range out.Diagnostics {
+		messages = append(messages, d.String())
+	}
+	for _, want := range []string{
+		status + ":1:1: error: formula result is text, field 8 is number",
+		name + "[test.formulas.0]:1:13: error: expecting ) to close ( at 1:5, got number \"3\"",
+	} {
+		if !strings.Contains(strings.Join(messages, "\n"), want) {
+			t.Errorf("expected diagnostic %q, got %q", want, messages)
+		}
+	}
+	if out.Errors != 4 || out.Warnings != 0 {
+		t.Errorf("got %d errors and %d warnings, expected 4 and 0", out.Errors, out.Warnings)
+	}
+
+	// Field references aren't checked without a schema.
+	in = &qbcli.LintFormulaInput{Files: []string{status}, TableID: "bqgruir7z"}
+	if out, err = qbcli.LintFormula(in, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if out.Errors != 1 || out.Warnings != 1 {
+		t.Errorf("got %d errors and %d warnings, expected 1 and 1", out.Errors, out.Warnings)
+	}
+}
@@ -0,0 +1,448 @@
+package qbcli
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
+	"github.com/QuickBase/quickbase-cli/qbformula"
+)
+
+type TestFormulaInput struct {
+	File      string `cliutil:"option=file default=quickbase.yml"`
+	Env       string `cliutil:"option=env usage='environment in the quickbase file whose variables replace placeholders'"`
+	JUnitFile string `cliutil:"option=junit-file usage='file a JUnit XML report of the results is written to'"`
+	Offline   bool   `cliutil:"option=offline usage='evaluate formulas against the records in the quickbase file instead of the realm'"`
+}
+
+// TestFormulaOutput contains the results of the formula tests. Passed and
+// Failed contain the indexes of the formulas in the quickbase.yml file, and a
+// formula fails if any of its cases fail. Results contains the outcome of
+// every case.
+type TestFormulaOutput struct {
+	Passed  []int                `json:"passed"`
+	Failed  map[int]string       `json:"failed"`
+	Results []*TestFormulaResult `json:"results"`
+}
+
+// TestFormulaResult is the outcome of running a formula against a record.
+type TestFormulaResult struct {
+	Name     string `json:"name"`
+	TableID  string `json:"tableId"`
+	RecordID int    `json:"recordId"`
+	Passed   bool   `json:"passed"`
+	Result   string `json:"result,omitempty"`
+	Failure  string `json:"failure,omitempty"`
+
+	elapsed time.Duration
+}
+
+// formulaRunner runs a formula against a record and returns the result.
+type formulaRunner func(ctx context.Context, formula, tableID string, recordID int) (string, error)
+
+// TestFormula runs the formulas in the test section of a quickbase.yml file
+// and checks the results. Formulas are run in the realm, or evaluated against
+// the records in the test section if in.Offline is set, in which case qb
+// isn't used and may be nil.
+func TestFormula(qb *qbclient.Client, in *TestFormulaInput) (*TestFormulaOutput, error) {
+	return TestFormulaWithContext(context.Background(), qb, in)
+}
+
+// TestFormulaWithContext is TestFormula using the passed context.
+func TestFormulaWithContext(ctx context.Context, qb *qbclient.Client, in *TestFormulaInput) (out *TestFormulaOutput, err error) {
+	out = &TestFormulaOutput{
+		Passed:  []int{},
+		Failed:  map[int]string{},
+		Results: []*TestFormulaResult{},
+	}
+
+	var file *QuickbaseFile
+	file, err = ParseQuickbaseFileEnv(in.File, in.Env)
+	if err != nil {
+		return
+	}
+	if file.Test == nil {
+		err = qberrors.Client(nil).Safef(qberrors.InvalidInput, "quickbase file has no test section")
+		return
+	}
+
+	runner := remoteFormulaRunner(qb)
+	if in.Offline {
+		runner = file.Test.offlineFormulaRunner()
+	}
+
+	for idx, f := range file.Test.Formulas {
+
+		formula := f.Formula
+		if f.File != "" {
+			b, ferr := file.ReadFile(f.File, f.Substitute)
+			if ferr != nil {
+				err = ferr
+				return
+			}
+			formula = string(b)
+		}
+
+		var failures []string
+		cases := f.cases()
+		for _, c := range cases {
+			result := f.run(ctx, runner, idx, formula, c)
+			out.Results = append(out.Results, result)
+
+			if !result.Passed {
+				msg := result.Failure
+				if len(cases) > 1 {
+					msg = fmt.Sprintf("record %d: %s", c.RecordID, msg)
+				}
+				failures = append(failures, msg)
+			}
+		}
+
+		if len(failures) == 0 {
+			out.Passed = append(out.Passed, idx)
+		} else {
+			out.Failed[idx] = strings.Join(failures, "; ")
+		}
+	}
+
+	return
+}
+
+// cases returns the records the formula is tested against.
+func (f *QuickbaseFileTestFormula) cases() []*QuickbaseFileTestCase {
+	if len(f.Cases) == 0 {
+		return []*QuickbaseFileTestCase{{RecordID: f.RecordID, QuickbaseFileTestAssertion: f.QuickbaseFileTestAssertion}}
+	}
+
+	cases := make([]*QuickbaseFileTestCase, len(f.Cases))
+	for idx, c := range f.Cases {
+		cases[idx] = c
+		if !c.isSet() {
+			cases[idx] = &QuickbaseFileTestCase{RecordID: c.RecordID, QuickbaseFileTestAssertion: f.QuickbaseFileTestAssertion}
+		}
+	}
+	return cases
+}
+
+// remoteFormulaRunner returns a runner that runs formulas in the realm.
+func remoteFormulaRunner(qb *qbclient.Client) formulaRunner {
+	return func(ctx context.Context, formula, tableID string, recordID int) (string, error) {
+		out, err := qb.RunFormulaWithContext(ctx, &qbclient.RunFormulaInput{
+			From:     tableID,
+			RecordID: recordID,
+			Formula:  formula,
+		})
+		if err != nil {
+			return "", err
+		}
+		return out.Result, nil
+	}
+}
+
+// offlineFormulaRunner returns a runner that evaluates formulas against the
+// records in the test section. Null results are empty.
+func (t *QuickbaseFileTest) offlineFormulaRunner() formulaRunner {
+	env := &qbformula.Env{}
+	if t.Now != "" {
+		// The format was checked when the file was parsed.
+		env.Now, _ = time.Parse(time.RFC3339, t.Now)
+	}
+
+	return func(ctx context.Context, formula, tableID string, recordID int) (string, error) {
+		record, err := t.record(tableID, recordID)
+		if err != nil {
+			return "", err
+		}
+
+		v, err := qbformula.Eval(formula, record, env)
+		if err != nil || v == nil {
+			return "", err
+		}
+		return v.String(), nil
+	}
+}
+
+// record returns the fields of a record in the test section keyed by label.
+func (t *QuickbaseFileTest) record(tableID string, recordID int) (map[string]*qbformula.Field, error) {
+	for _, table := range t.Tables {
+		if table.TableID != tableID {
+			continue
+		}
+		for _, r := range table.Records {
+			if r.RecordID == recordID {
+				return table.fields(r)
+			}
+		}
+	}
+	return nil, fmt.Errorf("record %d: not found in table %s in the test section", recordID, tableID)
+}
+
+// fields converts the values of a record to fields, adding the declared
+// fields that the record omits as null, and the Record ID# field.
+func (t *QuickbaseFileTestTable) fields(r *QuickbaseFileTestRecord) (map[string]*qbformula.Field, error) {
+	fields := map[string]*qbformula.Field{
+		"Record ID#": {Type: qbclient.FieldRecordID, Value: qbclient.NewRecordIDValue(float64(r.RecordID))},
+	}
+	for label, ftype := range t.Fields {
+		fields[label] = &qbformula.Field{Type: ftype}
+	}
+
+	for label, val := range r.Values {
+		ftype, ok := t.Fields[label]
+		if !ok {
+			ftype = qbclient.FieldText
+			if _, err := strconv.ParseFloat(val, 64); err == nil {
+				ftype = qbclient.FieldNumeric
+			}
+		}
+
+		field := &qbformula.Field{Type: ftype}
+		if val != "" {
+			v, err := qbclient.NewValueFromString(val, ftype)
+			if err != nil {
+				return nil, fmt.Errorf("record %d: [%s]: %w", r.RecordID, label, err)
+			}
+			field.Value = v
+		}
+		fields[label] = field
+	}
+
+	return fields, nil
+}
+
+// validate checks that the values of the table's records can be converted to
+// the types of their fields.
+func (t *QuickbaseFileTestTable) validate() error {
+	for _, r := range t.Records {
+		if _, err := t.fields(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs the formula against the case's record and checks the assertion.
+func (f *QuickbaseFileTestFormula) run(ctx context.Context, runner formulaRunner, idx int, formula string, c *QuickbaseFileTestCase) *TestFormulaResult {
+	name := f.Name
+	if name == "" {
+		name = f.File
+	}
+	if name == "" {
+		name = fmt.Sprintf("formula %d", idx)
+	}
+
+	result := &TestFormulaResult{Name: name, TableID: f.TableID, RecordID: c.RecordID}
+
+	start := time.Now()
+	res, rerr := runner(ctx, formula, f.TableID, c.RecordID)
+	result.elapsed = time.Since(start)
+
+	if rerr == nil {
+		result.Result = res
+	}
+
+	if cerr := c.check(result.Result, rerr); cerr != nil {
+		result.Failure = cerr.Error()
+	} else {
+		result.Passed = true
+	}
+
+	return result
+}
+
+// validate checks the combinations of properties that can't be expressed as
+// validation tags.
+func (f *QuickbaseFileTestFormula) validate() error {
+	if f.File != "" && f.Formula != "" {
+		return errors.New("file and formula are mutually exclusive")
+	}
+	if len(f.Cases) > 0 && f.RecordID != 0 {
+		return errors.New("record_id and cases are mutually exclusive")
+	}
+
+	if err := f.QuickbaseFileTestAssertion.validate(); err != nil {
+		return err
+	}
+
+	for _, c := range f.Cases {
+		if !c.isSet() && !f.isSet() {
+			return fmt.Errorf("record %d: assertion required", c.RecordID)
+		}
+		if err := c.QuickbaseFileTestAssertion.validate(); err != nil {
+			return fmt.Errorf("record %d: %w", c.RecordID, err)
+		}
+	}
+	if len(f.Cases) == 0 && !f.isSet() {
+		return errors.New("assertion required, e.g., expected")
+	}
+
+	return nil
+}
+
+func (a *QuickbaseFileTestAssertion) isSet() bool {
+	return a.Expected != nil || a.Match != "" || a.ExpectedError != nil
+}
+
+func (a *QuickbaseFileTestAssertion) validate() error {
+	if a.ExpectedError != nil && (a.Expected != nil || a.Match != "") {
+		return errors.New("expected_error can't be combined with other assertions")
+	}
+
+	if a.Tolerance != nil {
+		if a.Expected == nil {
+			return errors.New("tolerance requires expected")
+		}
+		if _, err := strconv.ParseFloat(*a.Expected, 64); err != nil {
+			return fmt.Errorf("expected %q: tolerance requires a number", *a.Expected)
+		}
+	}
+
+	if a.Match != "" {
+		if _, err := regexp.Compile(a.Match); err != nil {
+			return fmt.Errorf("match: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// check returns an error describing why the result or error returned by
+// running a formula doesn't satisfy the assertion.
+func (a *QuickbaseFileTestAssertion) check(result string, rerr error) error {
+	if a.ExpectedError != nil {
+		if rerr == nil {
+			return fmt.Errorf("expected error, got %q", result)
+		}
+		if !strings.Contains(rerr.Error(), *a.ExpectedError) {
+			return fmt.Errorf("expected error containing %q, got %q", *a.ExpectedError, rerr.Error())
+		}
+		return nil
+	}
+
+	if rerr != nil {
+		return rerr
+	}
+
+	if a.Match != "" {
+		if !regexp.MustCompile(a.Match).MatchString(result) {
+			return fmt.Errorf("expected match for %q, got %q", a.Match, result)
+		}
+	}
+
+	if a.Expected == nil {
+		return nil
+	}
+
+	if a.Tolerance == nil {
+		if result != *a.Expected {
+			return fmt.Errorf("expected %q, got %q", *a.Expected, result)
+		}
+		return nil
+	}
+
+	expected, _ := strconv.ParseFloat(*a.Expected, 64)
+	actual, err := strconv.ParseFloat(result, 64)
+	if err != nil {
+		return fmt.Errorf("expected a number, got %q", result)
+	}
+	if math.Abs(actual-expected) > *a.Tolerance {
+		return fmt.Errorf("expected %s ± %v, got %q", *a.Expected, *a.Tolerance, result)
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteFormulaTestReport writes the results of the formula tests as a JUnit
+// XML report, which most CI systems can display. Each formula is a test
+// suite, and each record it is tested against is a test case.
+func WriteFormulaTestReport(w io.Writer, out *TestFormulaOutput) error {
+	report := &junitTestSuites{}
+
+	var elapsed time.Duration
+	suites := map[string]*junitTestSuite{}
+	suiteElapsed := map[*junitTestSuite]time.Duration{}
+	for _, r := range out.Results {
+		suite, ok := suites[r.Name]
+		if !ok {
+			suite = &junitTestSuite{Name: r.Name}
+			suites[r.Name] = suite
+			report.Suites = append(report.Suites, suite)
+		}
+
+		tc := &junitTestCase{
+			Name:      fmt.Sprintf("record %d", r.RecordID),
+			ClassName: r.TableID,
+			Time:      junitTime(r.elapsed),
+		}
+		if !r.Passed {
+			tc.Failure = &junitFailure{Message: r.Failure, Text: r.Failure}
+			suite.Failures++
+			report.Failures++
+		}
+
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+		report.Tests++
+		suiteElapsed[suite] += r.elapsed
+		elapsed += r.elapsed
+	}
+
+	for _, suite := range report.Suites {
+		suite.Time = junitTime(suiteElapsed[suite])
+	}
+	report.Time = junitTime(elapsed)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,208 @@
+package qbcli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+func TestTestFormula(t *testing.T) {
+	results := map[int]string{1: "3.14159", 2: "Overdue by 3 days"}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			RecordID int `json:"rid"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+
+		result, ok := results[in.RecordID]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message":"Bad Request","description":"Formula syntax error"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"result":%q}`, result)
+	}))
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	dir := writeDeployFiles(t, map[string]string{
+		"quickbase.yml": `
+test:
+  formulas:
+    - name: pi
+      formula: 22/7
+      table_id: bqgruir7z
+      record_id: 1
+      expected: "3.14"
+      tolerance: 0.01
+    - name: status
+      formula: '"Overdue by " & [Days]'
+      table_id: bqgruir7z
+      match: ^Overdue
+      cases:
+        - record_id: 2
+        - record_id: 1
+        - record_id: 3
+          expected_error: syntax error
+    - name: exact
+      formula: 22/7
+      table_id: bqgruir7z
+      record_id: 1
+      expected: "3.14"
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	in := &qbcli.TestFormulaInput{File: qbclient.Filepath(dir, "quickbase.yml")}
+	out, err := qbcli.TestFormulaWithContext(context.Background(), qb, in)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(out.Passed, []int{0}) {
+		t.Errorf("got passed %v, expected [0]", out.Passed)
+	}
+	expected := map[int]string{
+		1: `record 1: expected match for "^Overdue", got "3.14159"`,
+		2: `expected "3.14", got "3.14159"`,
+	}
+	if !reflect.DeepEqual(out.Failed, expected) {
+		t.Errorf("got failed %v, expected %v", out.Failed, expected)
+	}
+	if len(out.Results) != 5 {
+		t.Fatalf("got %d results, expected 5", len(out.Results))
+	}
+
+	var buf bytes.Buffer
+	if err := qbcli.WriteFormulaTestReport(&buf, out); err != nil {
+		t.Fatalf("unexpected error writing report: %s", err)
+	}
+	report := buf.String()
+	for _, want := range []string{
+		`<testsuites tests="5" failures="2"`,
+		`<testsuite name="status" tests="3" failures="1"`,
+		`<testcase name="record 3" classname="bqgruir7z"`,
+		`<failure message="expected &#34;3.14&#34;, got &#34;3.14159&#34;">`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
+
+func TestTestFormulaInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		formula  string
+		expected string
+	}{
+		{"no assertion", "record_id: 1", "assertion required"},
+		{"file and formula", "record_id: 1\n      file: a.formula\n      expected: a", "mutually exclusive"},
+		{"tolerance", "record_id: 1\n      expected: a\n      tolerance: 1", "tolerance requires a number"},
+		{"match", "record_id: 1\n      match: '('", "match: error parsing regexp"},
+		{"expected error", "record_id: 1\n      expected: a\n      expected_error: ''", "can't be combined"},
+		{"case assertion", "cases:\n        - record_id: 2", "record 2: assertion required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeDeployFiles(t, map[string]string{
+				"quickbase.yml": "test:\n  formulas:\n    - formula: 1\n      table_id: bqgruir7z\n      " + tt.formula + "\n",
+			})
+			defer os.RemoveAll(dir)
+
+			_, err := qbcli.ParseQuickbaseFile(qbclient.Filepath(dir, "quickbase.yml"))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("got error %v, expected %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTestFormulaOffline(t *testing.T) {
+	dir := writeDeployFiles(t, map[string]string{
+		"days_left.formula": "var number days = ToDays([Due Date] - Today());\nIf($days < 0, \"Overdue\", $days & \" days left\")\n",
+		"quickbase.yml": `
+test:
+  now: 2021-06-01T12:00:00Z
+  tables:
+    - table_id: bqgruir7z
+      fields:
+        Due Date: date
+        Discount: numeric
+      records:
+        - record_id: 1
+          values:
+            Due Date: 2021-06-04
+            Price: 10
+        - record_id: 2
+          values:
+            Due Date: 2021-05-30
+            Discount: 2.5
+  formulas:
+    - file: DIR/days_left.formula
+      table_id: bqgruir7z
+      cases:
+        - record_id: 1
+          expected: 3 days left
+        - record_id: 2
+          expected: Overdue
+    - name: total
+      formula: '[Price] - Nz([Discount])'
+      table_id: bqgruir7z
+      record_id: 1
+      expected: "10"
+    - name: missing
+      formula: '[Record ID#] * 2'
+      table_id: bqgruir7z
+      record_id: 3
+      expected: "6"
+    - name: invalid
+      formula: '[Price] & Lenght("a")'
+      table_id: bqgruir7z
+      record_id: 1
+      expected_error: unknown function
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	name := qbclient.Filepath(dir, "quickbase.yml")
+	b, _ := ioutil.ReadFile(name)
+	ioutil.WriteFile(name, []byte(strings.Replace(string(b), "DIR", dir, 1)), 0644)
+
+	// No client is needed to run the tests offline.
+	in := &qbcli.TestFormulaInput{File: name, Offline: true}
+	out, err := qbcli.TestFormulaWithContext(context.Background(), nil, in)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(out.Passed, []int{0, 1, 3}) {
+		t.Errorf("got passed %v, expected [0 1 3]", out.Passed)
+	}
+	expected := map[int]string{2: "record 3: not found in table bqgruir7z in the test section"}
+	if !reflect.DeepEqual(out.Failed, expected) {
+		t.Errorf("got failed %v, expected %v", out.Failed, expected)
+	}
+
+	// Values that can't be converted to their field's type are caught when
+	// the file is parsed.
+	ioutil.WriteFile(name, []byte("test:\n  tables:\n    - table_id: bqgruir7z\n      fields: {Due Date: date}\n      records:\n        - record_id: 1\n          values: {Due Date: soon}\n"), 0644)
+	if _, err := qbcli.ParseQuickbaseFile(name); err == nil || !strings.Contains(err.Error(), "record 1: [Due Date]") {
+		t.Errorf("got error %v, expected invalid date", err)
+	}
+}
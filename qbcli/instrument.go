@@ -0,0 +1,329 @@
+package qbcli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
+	"github.com/cpliakas/cliutil"
+)
+
+// Error classes reported by InstrumentPlugin, which mirror the qberrors
+// error types.
+const (
+	ErrorClassClient   = "client"
+	ErrorClassService  = "service"
+	ErrorClassInternal = "internal"
+)
+
+// InstrumentPlugin implements qbclient.Middleware and collects per-endpoint
+// request counts, latencies, retries, and error classes, as well as a span
+// for each request. The metrics are written as a summary by WriteSummary and
+// exported to an OpenTelemetry collector by ExportOTLP.
+//
+// Errors returned by the XML API in successful responses aren't classified,
+// because the response body isn't parsed until after the middleware returns.
+type InstrumentPlugin struct {
+	transid string
+	traceID string
+	start   time.Time
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointMetrics
+	spans     []*requestSpan
+}
+
+// endpointMetrics are the metrics collected for an endpoint.
+type endpointMetrics struct {
+	requests  int
+	retries   int
+	errors    map[string]int
+	durations []time.Duration
+}
+
+// requestSpan models a request sent to the API.
+type requestSpan struct {
+	spanID     string
+	endpoint   string
+	method     string
+	url        string
+	start      time.Time
+	end        time.Time
+	statusCode int
+	retries    int
+	errorClass string
+	err        string
+}
+
+// NewInstrumentPlugin returns an InstrumentPlugin, which implements
+// qbclient.Middleware. The spans are tagged with the transaction ID.
+func NewInstrumentPlugin(transid string) *InstrumentPlugin {
+	return &InstrumentPlugin{
+		transid:   transid,
+		traceID:   randomHex(16),
+		start:     time.Now(),
+		endpoints: make(map[string]*endpointMetrics),
+	}
+}
+
+// InstrumentClient adds an InstrumentPlugin to qb if metricsFile or
+// otlpEndpoint is set, so that requests are only instrumented when the
+// metrics are flushed by FlushMetrics. The spans are tagged with the
+// transaction ID set in ctx by NewLogger.
+func InstrumentClient(ctx context.Context, qb *qbclient.Client, metricsFile, otlpEndpoint string) {
+	if metricsFile != "" || otlpEndpoint != "" {
+		qb.AddMiddleware(NewInstrumentPlugin(transactionID(ctx)))
+	}
+}
+
+// GetInstrumentPlugin returns the InstrumentPlugin added to the client by
+// InstrumentClient, or nil if there isn't one.
+func GetInstrumentPlugin(qb *qbclient.Client) *InstrumentPlugin {
+	for _, m := range qb.Middleware {
+		if p, ok := m.(*InstrumentPlugin); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// RoundTrip implements qbclient.Middleware.RoundTrip.
+func (p *InstrumentPlugin) RoundTrip(req *http.Request, next qbclient.RoundTripFunc) (*http.Response, error) {
+	ctx, attempts := qbclient.ContextWithAttempts(req.Context())
+
+	span := &requestSpan{
+		spanID:   randomHex(8),
+		endpoint: endpointName(req),
+		method:   req.Method,
+		url:      req.URL.String(),
+		start:    time.Now(),
+	}
+
+	resp, err := next(req.WithContext(ctx))
+
+	span.end = time.Now()
+	if n := attempts(); n > 1 {
+		span.retries = n - 1
+	}
+	if resp != nil {
+		span.statusCode = resp.StatusCode
+	}
+	span.errorClass = errorClass(resp, err)
+	if err != nil {
+		span.err = err.Error()
+	} else if span.errorClass != "" {
+		span.err = resp.Status
+	}
+
+	p.record(span)
+	return resp, err
+}
+
+// record adds the span and updates its endpoint's metrics.
+func (p *InstrumentPlugin) record(span *requestSpan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m, ok := p.endpoints[span.endpoint]
+	if !ok {
+		m = &endpointMetrics{errors: make(map[string]int)}
+		p.endpoints[span.endpoint] = m
+	}
+
+	m.requests++
+	m.retries += span.retries
+	m.durations = append(m.durations, span.end.Sub(span.start))
+	if span.errorClass != "" {
+		m.errors[span.errorClass]++
+	}
+
+	p.spans = append(p.spans, span)
+}
+
+// endpointName returns the name requests to the same endpoint are grouped
+// by. XML API requests are named by their action, and the IDs in JSON API
+// request paths are replaced with placeholders, e.g., "GET /v1/apps/{id}".
+func endpointName(req *http.Request) string {
+	if action := req.Header.Get("QUICKBASE-ACTION"); action != "" {
+		return action
+	}
+
+	segments := strings.Split(req.URL.Path, "/")
+	for i, s := range segments {
+		if isIdentifier(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return req.Method + " " + strings.Join(segments, "/")
+}
+
+// isIdentifier returns whether a path segment is a numeric ID, e.g., a field
+// or report ID, or an app or table ID, which are nine characters prefixed
+// with "b".
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	if len(s) == 9 && s[0] == 'b' {
+		return true
+	}
+	return strings.Trim(s, "0123456789") == ""
+}
+
+// errorClass classifies the result of a request the same way the client
+// does, returning an empty string if the request succeeded.
+func errorClass(resp *http.Response, err error) string {
+	var (
+		cerr *qberrors.ErrClient
+		ierr *qberrors.ErrInternal
+	)
+
+	switch {
+	case err == nil && resp == nil:
+		return ErrorClassInternal
+	case err == nil && resp.StatusCode >= 500:
+		return ErrorClassService
+	case err == nil && resp.StatusCode >= 400:
+		return ErrorClassClient
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded), errors.As(err, &cerr):
+		return ErrorClassClient
+	case errors.As(err, &ierr):
+		return ErrorClassInternal
+	default:
+		return ErrorClassService
+	}
+}
+
+// MetricsSummary summarizes the requests sent to the API.
+type MetricsSummary struct {
+	TransactionID string             `json:"transactionId"`
+	Started       time.Time          `json:"started"`
+	Duration      float64            `json:"duration"`
+	Requests      int                `json:"requests"`
+	Retries       int                `json:"retries"`
+	Errors        map[string]int     `json:"errors,omitempty"`
+	Endpoints     []*EndpointSummary `json:"endpoints"`
+}
+
+// EndpointSummary summarizes the requests sent to an endpoint. Latencies are
+// in milliseconds.
+type EndpointSummary struct {
+	Endpoint    string         `json:"endpoint"`
+	Requests    int            `json:"requests"`
+	Retries     int            `json:"retries"`
+	Errors      map[string]int `json:"errors,omitempty"`
+	LatencyMin  float64        `json:"latencyMin"`
+	LatencyMean float64        `json:"latencyMean"`
+	LatencyP95  float64        `json:"latencyP95"`
+	LatencyMax  float64        `json:"latencyMax"`
+}
+
+// Summary returns a summary of the requests sent so far, sorted by endpoint.
+func (p *InstrumentPlugin) Summary() *MetricsSummary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := &MetricsSummary{
+		TransactionID: p.transid,
+		Started:       p.start,
+		Duration:      time.Since(p.start).Seconds(),
+		Errors:        make(map[string]int),
+		Endpoints:     []*EndpointSummary{},
+	}
+
+	for name, m := range p.endpoints {
+		es := &EndpointSummary{
+			Endpoint: name,
+			Requests: m.requests,
+			Retries:  m.retries,
+			Errors:   make(map[string]int, len(m.errors)),
+		}
+		for class, n := range m.errors {
+			es.Errors[class] = n
+			s.Errors[class] += n
+		}
+
+		ms := make([]float64, len(m.durations))
+		for i, d := range m.durations {
+			ms[i] = milliseconds(d)
+			es.LatencyMean += ms[i] / float64(len(ms))
+		}
+		sort.Float64s(ms)
+		es.LatencyMin = ms[0]
+		es.LatencyP95 = ms[int(math.Ceil(0.95*float64(len(ms))))-1]
+		es.LatencyMax = ms[len(ms)-1]
+		es.LatencyMean = math.Round(es.LatencyMean*1000) / 1000
+
+		s.Requests += m.requests
+		s.Retries += m.retries
+		s.Endpoints = append(s.Endpoints, es)
+	}
+
+	sort.Slice(s.Endpoints, func(i, j int) bool { return s.Endpoints[i].Endpoint < s.Endpoints[j].Endpoint })
+	return s
+}
+
+// WriteSummary writes the summary as JSON to the file at filepath.
+func (p *InstrumentPlugin) WriteSummary(filepath string) error {
+	b, err := json.MarshalIndent(p.Summary(), "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath, append(b, '\n'), 0644)
+}
+
+// FlushMetrics writes a summary of the client's requests to metricsFile and
+// exports its metrics and spans to otlpEndpoint, either of which is skipped
+// if empty. Errors are logged instead of returned so that they don't mask
+// the command's result. The export isn't bound to ctx, so the metrics of
+// interrupted commands are still exported.
+func FlushMetrics(ctx context.Context, logger *cliutil.LeveledLogger, qb *qbclient.Client, metricsFile, otlpEndpoint string) {
+	p := GetInstrumentPlugin(qb)
+	if p == nil {
+		return
+	}
+
+	if metricsFile != "" {
+		ctx := cliutil.ContextWithLogTag(ctx, "file", metricsFile)
+		if err := p.WriteSummary(metricsFile); err == nil {
+			logger.Debug(ctx, "wrote metrics summary")
+		} else {
+			logger.Error(ctx, "error writing metrics summary", err)
+		}
+	}
+
+	if otlpEndpoint != "" {
+		ctx := cliutil.ContextWithLogTag(ctx, "endpoint", otlpEndpoint)
+		if err := p.ExportOTLP(context.Background(), otlpEndpoint); err == nil {
+			logger.Debug(ctx, "exported metrics and traces")
+		} else {
+			logger.Error(ctx, "error exporting metrics and traces", err)
+		}
+	}
+}
+
+// milliseconds converts d to milliseconds, rounded to the microsecond.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// randomHex returns n random bytes as a hex string, which is how trace and
+// span IDs are encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,139 @@
+package qbcli_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+func TestInstrumentPlugin(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests++; {
+		case strings.HasPrefix(r.URL.Path, "/apps/"):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"App not found","description":"App not found."}`)
+		case requests == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := viper.New()
+	cfg.Set(qbclient.OptionRetryWaitMin, time.Millisecond)
+	cfg.Set(qbclient.OptionRetryWaitMax, 10*time.Millisecond)
+	qb := qbclient.New(qbclient.NewConfig(cfg))
+	qb.URL = ts.URL
+
+	p := qbcli.NewInstrumentPlugin("c0ffee")
+	qb.AddMiddleware(p)
+
+	// The first request is retried.
+	for i := 0; i < 2; i++ {
+		if _, err := qb.ListFields(&qbclient.ListFieldsInput{TableID: "bqgruir7z"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if _, err := qb.GetApp(&qbclient.GetAppInput{AppID: "bqgruir3g"}); err == nil {
+		t.Fatal("expected an error getting the app")
+	}
+
+	s := p.Summary()
+	if s.TransactionID != "c0ffee" || s.Requests != 3 || s.Retries != 1 || s.Errors["client"] != 1 {
+		t.Errorf("got summary %+v, expected 3 requests, 1 retry, and 1 client error", s)
+	}
+	if len(s.Endpoints) != 2 {
+		t.Fatalf("got %d endpoints, expected 2", len(s.Endpoints))
+	}
+	if e := s.Endpoints[0]; e.Endpoint != "GET /apps/{id}" || e.Requests != 1 || e.Errors["client"] != 1 {
+		t.Errorf("got endpoint %+v, expected one failed app request", e)
+	}
+	if e := s.Endpoints[1]; e.Endpoint != "GET /fields" || e.Requests != 2 || e.Retries != 1 || e.LatencyMax < e.LatencyMin {
+		t.Errorf("got endpoint %+v, expected two field requests with one retry", e)
+	}
+
+	// Export the metrics and traces to a collector.
+	var mu sync.Mutex
+	exported := make(map[string]string)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		exported[r.URL.Path] = string(b)
+		mu.Unlock()
+		if !json.Valid(b) || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer collector.Close()
+
+	if err := p.ExportOTLP(context.Background(), collector.URL+"/"); err != nil {
+		t.Fatalf("unexpected error exporting: %s", err)
+	}
+
+	for path, want := range map[string][]string{
+		"/v1/metrics": {
+			`"name":"quickbase.api.requests"`,
+			`"name":"quickbase.api.request.duration"`,
+			`"name":"quickbase.api.retries"`,
+			`{"key":"error.type","value":{"stringValue":"client"}}`,
+		},
+		"/v1/traces": {
+			`{"key":"transid","value":{"stringValue":"c0ffee"}}`,
+			`"name":"GET /fields"`,
+			`{"key":"http.response.status_code","value":{"intValue":"404"}}`,
+			`"status":{"code":2`,
+		},
+	} {
+		for _, s := range want {
+			if !strings.Contains(exported[path], s) {
+				t.Errorf("expected %s export to contain %s, got:\n%s", path, s, exported[path])
+			}
+		}
+	}
+
+	// Write the summary to a file.
+	file, err := ioutil.TempFile("", "metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	if err := p.WriteSummary(file.Name()); err != nil {
+		t.Fatalf("unexpected error writing summary: %s", err)
+	}
+	b, _ := ioutil.ReadFile(file.Name())
+	var written qbcli.MetricsSummary
+	if err := json.Unmarshal(b, &written); err != nil || written.Requests != 3 {
+		t.Errorf("got summary %s, expected 3 requests", b)
+	}
+}
+
+func TestInstrumentClient(t *testing.T) {
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+
+	qbcli.InstrumentClient(context.Background(), qb, "", "")
+	if qbcli.GetInstrumentPlugin(qb) != nil {
+		t.Fatal("expected no instrumentation without a metrics file or OTLP endpoint")
+	}
+
+	qbcli.InstrumentClient(context.Background(), qb, "metrics.json", "")
+	if qbcli.GetInstrumentPlugin(qb) == nil {
+		t.Error("expected instrumentation with a metrics file")
+	}
+}
@@ -207,7 +207,7 @@ func init() {
 	cliutil.SetOptionMetadata("batch-size", map[string]string{"usage": "the number of rows processed in each batch"})
 	cliutil.SetOptionMetadata("child-table-id", map[string]string{"usage": "the child table's unique identifier, e.g., bqgruir7z"})
 	cliutil.SetOptionMetadata("data", map[string]string{"usage": "the record data in key=value format, e.g., '6=\"Another Record\" 7=3'"})
-	cliutil.SetOptionMetadata("delay", map[string]string{"usage": "delay between batches in milliseconds"})
+	cliutil.SetOptionMetadata("delay", map[string]string{"usage": "delay between batches in milliseconds, see also the rate_limit profile setting"})
 	cliutil.SetOptionMetadata("field-id", map[string]string{"usage": "the fields's unique identifier, e.g., 6"})
 	cliutil.SetOptionMetadata("fields-to-return", map[string]string{"usage": "the list/range of fields to return, e.g., 6,7,10:15"})
 	cliutil.SetOptionMetadata("from", map[string]string{"usage": "the table's unique identifier, e.g., bqgruir7z"})
@@ -0,0 +1,326 @@
+package qbcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// otlpDurationBounds are the explicit bucket boundaries of the request
+// duration histogram, in seconds.
+var otlpDurationBounds = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// otlpScope is the instrumentation scope of the exported telemetry.
+const otlpScope = "github.com/QuickBase/quickbase-cli/qbcli"
+
+// The otlp* types model the OTLP/HTTP JSON encoding of metrics and traces.
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type (
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+
+	otlpInstrumentationScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	otlpMetricsRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+
+	otlpScopeMetrics struct {
+		Scope   otlpInstrumentationScope `json:"scope"`
+		Metrics []otlpMetric             `json:"metrics"`
+	}
+
+	otlpMetric struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Unit        string         `json:"unit"`
+		Sum         *otlpSum       `json:"sum,omitempty"`
+		Histogram   *otlpHistogram `json:"histogram,omitempty"`
+	}
+
+	otlpSum struct {
+		DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+		AggregationTemporality int                   `json:"aggregationTemporality"`
+		IsMonotonic            bool                  `json:"isMonotonic"`
+	}
+
+	otlpNumberDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		AsInt             string          `json:"asInt"`
+	}
+
+	otlpHistogram struct {
+		DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+		AggregationTemporality int                      `json:"aggregationTemporality"`
+	}
+
+	otlpHistogramDataPoint struct {
+		Attributes        []otlpAttribute `json:"attributes"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		TimeUnixNano      string          `json:"timeUnixNano"`
+		Count             string          `json:"count"`
+		Sum               float64         `json:"sum"`
+		BucketCounts      []string        `json:"bucketCounts"`
+		ExplicitBounds    []float64       `json:"explicitBounds"`
+		Min               float64         `json:"min"`
+		Max               float64         `json:"max"`
+	}
+
+	otlpTracesRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpInstrumentationScope `json:"scope"`
+		Spans []otlpSpan               `json:"spans"`
+	}
+
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes"`
+		Status            otlpStatus      `json:"status"`
+	}
+
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// OTLP enum values.
+const (
+	otlpTemporalityCumulative = 2
+	otlpSpanKindClient        = 3
+	otlpStatusOK              = 1
+	otlpStatusError           = 2
+)
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int) otlpAttribute {
+	s := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// ExportOTLP exports the metrics and spans to an OpenTelemetry collector
+// using the OTLP/HTTP JSON encoding. The endpoint is the collector's base
+// URL, e.g., http://localhost:4318, that the signal paths are appended to.
+func (p *InstrumentPlugin) ExportOTLP(ctx context.Context, endpoint string) error {
+	metrics, traces := p.otlpRequests(time.Now())
+
+	endpoint = strings.TrimRight(endpoint, "/")
+	if err := postOTLP(ctx, endpoint+"/v1/metrics", metrics); err != nil {
+		return fmt.Errorf("error exporting metrics: %w", err)
+	}
+	if err := postOTLP(ctx, endpoint+"/v1/traces", traces); err != nil {
+		return fmt.Errorf("error exporting traces: %w", err)
+	}
+	return nil
+}
+
+// otlpRequests returns the export requests for the metrics and spans.
+func (p *InstrumentPlugin) otlpRequests(now time.Time) (*otlpMetricsRequest, *otlpTracesRequest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resource := otlpResource{Attributes: []otlpAttribute{
+		otlpString("service.name", "quickbase-cli"),
+		otlpString("service.version", qbclient.Version),
+		otlpString("transid", p.transid),
+	}}
+	scope := otlpInstrumentationScope{Name: otlpScope, Version: qbclient.Version}
+	start, end := otlpTime(p.start), otlpTime(now)
+
+	names := make([]string, 0, len(p.endpoints))
+	for name := range p.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	requests := &otlpSum{AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true}
+	retries := &otlpSum{AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true}
+	errs := &otlpSum{AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true}
+	durations := &otlpHistogram{AggregationTemporality: otlpTemporalityCumulative}
+
+	for _, name := range names {
+		m := p.endpoints[name]
+		attrs := []otlpAttribute{otlpString("endpoint", name)}
+
+		requests.DataPoints = append(requests.DataPoints, otlpNumberDataPoint{
+			Attributes:        attrs,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			AsInt:             strconv.Itoa(m.requests),
+		})
+		retries.DataPoints = append(retries.DataPoints, otlpNumberDataPoint{
+			Attributes:        attrs,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			AsInt:             strconv.Itoa(m.retries),
+		})
+
+		classes := make([]string, 0, len(m.errors))
+		for class := range m.errors {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			errs.DataPoints = append(errs.DataPoints, otlpNumberDataPoint{
+				Attributes:        append(attrs[:1:1], otlpString("error.type", class)),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      end,
+				AsInt:             strconv.Itoa(m.errors[class]),
+			})
+		}
+
+		durations.DataPoints = append(durations.DataPoints, otlpHistogramPoint(attrs, start, end, m.durations))
+	}
+
+	metrics := &otlpMetricsRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: resource,
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope: scope,
+			Metrics: []otlpMetric{
+				{Name: "quickbase.api.requests", Description: "Number of requests sent to the API.", Unit: "{request}", Sum: requests},
+				{Name: "quickbase.api.request.duration", Description: "Duration of requests sent to the API, including retries.", Unit: "s", Histogram: durations},
+				{Name: "quickbase.api.retries", Description: "Number of times requests were retried.", Unit: "{retry}", Sum: retries},
+				{Name: "quickbase.api.errors", Description: "Number of requests that failed, by error class.", Unit: "{request}", Sum: errs},
+			},
+		}},
+	}}}
+
+	spans := make([]otlpSpan, len(p.spans))
+	for i, s := range p.spans {
+		spans[i] = otlpSpan{
+			TraceID:           p.traceID,
+			SpanID:            s.spanID,
+			Name:              s.endpoint,
+			Kind:              otlpSpanKindClient,
+			StartTimeUnixNano: otlpTime(s.start),
+			EndTimeUnixNano:   otlpTime(s.end),
+			Attributes: []otlpAttribute{
+				otlpString("transid", p.transid),
+				otlpString("http.request.method", s.method),
+				otlpString("url.full", s.url),
+				otlpInt("http.response.status_code", s.statusCode),
+				otlpInt("quickbase.retries", s.retries),
+			},
+			Status: otlpStatus{Code: otlpStatusOK},
+		}
+		if s.errorClass != "" {
+			spans[i].Attributes = append(spans[i].Attributes, otlpString("error.type", s.errorClass))
+			spans[i].Status = otlpStatus{Code: otlpStatusError, Message: s.err}
+		}
+	}
+
+	traces := &otlpTracesRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   resource,
+		ScopeSpans: []otlpScopeSpans{{Scope: scope, Spans: spans}},
+	}}}
+
+	return metrics, traces
+}
+
+// otlpHistogramPoint returns a histogram data point for the durations.
+func otlpHistogramPoint(attrs []otlpAttribute, start, end string, durations []time.Duration) otlpHistogramDataPoint {
+	counts := make([]int, len(otlpDurationBounds)+1)
+	pt := otlpHistogramDataPoint{
+		Attributes:        attrs,
+		StartTimeUnixNano: start,
+		TimeUnixNano:      end,
+		Count:             strconv.Itoa(len(durations)),
+		ExplicitBounds:    otlpDurationBounds,
+	}
+
+	for i, d := range durations {
+		s := d.Seconds()
+		pt.Sum += s
+		if i == 0 || s < pt.Min {
+			pt.Min = s
+		}
+		if s > pt.Max {
+			pt.Max = s
+		}
+		counts[sort.SearchFloat64s(otlpDurationBounds, s)]++
+	}
+
+	pt.BucketCounts = make([]string, len(counts))
+	for i, n := range counts {
+		pt.BucketCounts[i] = strconv.Itoa(n)
+	}
+	return pt
+}
+
+// postOTLP sends an export request to the collector.
+func postOTLP(ctx context.Context, url string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -16,37 +16,37 @@ import (
 	"github.com/cpliakas/cliutil"
 )
 
-// LoggerPlugin implements qbclient.Plugin and logs requests.
+// LoggerPlugin implements qbclient.Middleware and logs requests.
 type LoggerPlugin struct {
 	ctx    context.Context
 	logger *cliutil.LeveledLogger
 }
 
-// NewLoggerPlugin returns a LoggerPlugin, which implements qbclient.Plugin.
-func NewLoggerPlugin(ctx context.Context, logger *cliutil.LeveledLogger) qbclient.Plugin {
+// NewLoggerPlugin returns a LoggerPlugin, which implements qbclient.Middleware.
+func NewLoggerPlugin(ctx context.Context, logger *cliutil.LeveledLogger) qbclient.Middleware {
 	return LoggerPlugin{ctx: ctx, logger: logger}
 }
 
-// PreRequest implements qbclient.Plugin.PreRequest.
-func (p LoggerPlugin) PreRequest(req *http.Request) {
+// RoundTrip implements qbclient.Middleware.RoundTrip.
+func (p LoggerPlugin) RoundTrip(req *http.Request, next qbclient.RoundTripFunc) (*http.Response, error) {
 	ctx := p.ctx
 	ctx = cliutil.ContextWithLogTag(ctx, "method", req.Method)
 	ctx = cliutil.ContextWithLogTag(ctx, "url", req.URL.String())
 	p.logger.Debug(ctx, "api request constructed")
-}
 
-// PostResponse implements qbclient.Plugin.PostResponse.
-func (p LoggerPlugin) PostResponse(resp *http.Response) {
-	if resp != nil {
-		ctx := p.ctx
-		ctx = cliutil.ContextWithLogTag(ctx, "method", resp.Request.Method)
-		ctx = cliutil.ContextWithLogTag(ctx, "url", resp.Request.URL.String())
+	resp, err := next(req)
+	if err != nil {
+		// The error is logged by the command that made the request.
+		p.logger.Debug(ctx, "api request failed")
+	} else if resp != nil {
 		ctx = cliutil.ContextWithLogTag(ctx, "status", resp.Status)
 		p.logger.Info(ctx, "api response returned")
 	}
+
+	return resp, err
 }
 
-// DumpPlugin implements qbclient.Plugin and dumps requests and responses to
+// DumpPlugin implements qbclient.Middleware and dumps requests and responses to
 // files in a directory.
 type DumpPlugin struct {
 	ctx       context.Context
@@ -55,14 +55,26 @@ type DumpPlugin struct {
 	transid   string
 }
 
-// NewDumpPlugin returns a DumpPlugin, which implements qbclient.Plugin.
-func NewDumpPlugin(ctx context.Context, logger *cliutil.LeveledLogger, transid string, directory string) qbclient.Plugin {
+// NewDumpPlugin returns a DumpPlugin, which implements qbclient.Middleware.
+func NewDumpPlugin(ctx context.Context, logger *cliutil.LeveledLogger, transid string, directory string) qbclient.Middleware {
 	dir := strings.TrimRight(directory, string(os.PathSeparator))
 	return DumpPlugin{ctx: ctx, logger: logger, transid: transid, directory: dir}
 }
 
-// PreRequest implements qbclient.Plugin.PreRequest.
-func (p DumpPlugin) PreRequest(req *http.Request) {
+// RoundTrip implements qbclient.Middleware.RoundTrip.
+func (p DumpPlugin) RoundTrip(req *http.Request, next qbclient.RoundTripFunc) (*http.Response, error) {
+	p.dumpRequest(req)
+
+	resp, err := next(req)
+	if err == nil && resp != nil {
+		p.dumpResponse(resp)
+	}
+
+	return resp, err
+}
+
+// dumpRequest writes the request to a dump file.
+func (p DumpPlugin) dumpRequest(req *http.Request) {
 	ctx, file, err := p.openDumpFile("request")
 	if err != nil {
 		return
@@ -105,8 +117,8 @@ func (p DumpPlugin) PreRequest(req *http.Request) {
 	req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 }
 
-// PostResponse implements qbclient.Plugin.PostResponse.
-func (p DumpPlugin) PostResponse(resp *http.Response) {
+// dumpResponse writes the response to a dump file.
+func (p DumpPlugin) dumpResponse(resp *http.Response) {
 	ctx, file, err := p.openDumpFile("response")
 	if err != nil {
 		return
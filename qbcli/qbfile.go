@@ -2,8 +2,12 @@ package qbcli
 
 import (
 	"bytes"
-	"fmt"
+	"context"
+	"io"
 	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/QuickBase/quickbase-cli/qbclient"
 	"github.com/QuickBase/quickbase-cli/qberrors"
@@ -13,32 +17,146 @@ import (
 
 // QuickbaseFile models a quickbase.yml file.
 type QuickbaseFile struct {
-	Test   *QuickbaseFileTest   `yaml:"test"`
-	Deploy *QuickbaseFileDeploy `yaml:"deploy"`
+	Environments map[string]*QuickbaseFileEnvironment `validate:"dive" yaml:"environments"`
+	Test         *QuickbaseFileTest                   `yaml:"test"`
+	Deploy       *QuickbaseFileDeploy                 `yaml:"deploy"`
+
+	// vars are the variables of the selected environment.
+	vars map[string]string
+}
+
+// QuickbaseFileEnvironment is a named environment, e.g., prod. The variables
+// replace ${name} placeholders in the quickbase.yml file and the contents of
+// the files it references with substitute set, and the profile is used to
+// connect to the environment's realm.
+type QuickbaseFileEnvironment struct {
+	Profile string            `yaml:"profile"`
+	Vars    map[string]string `yaml:"vars"`
 }
 
+// QuickbaseFileTest contains the formula tests. Tables contains the records
+// formulas are evaluated against when the tests are run offline, and Now is
+// the time Now() and Today() return, which defaults to the current time.
 type QuickbaseFileTest struct {
-	Formulas []*QuickbaseFileTestFormula `yaml:"formulas"`
+	Now      string                      `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" yaml:"now"`
+	Tables   []*QuickbaseFileTestTable   `validate:"dive" yaml:"tables"`
+	Formulas []*QuickbaseFileTestFormula `validate:"dive" yaml:"formulas"`
+}
+
+// QuickbaseFileTestTable contains records that formulas are evaluated against
+// offline. Fields maps field labels to field types, e.g., date. The types of
+// fields that aren't listed are inferred from their values, which are numeric
+// if they are numbers and text otherwise.
+type QuickbaseFileTestTable struct {
+	TableID string                     `validate:"required" yaml:"table_id"`
+	Fields  map[string]string          `yaml:"fields"`
+	Records []*QuickbaseFileTestRecord `validate:"dive" yaml:"records"`
+}
+
+// QuickbaseFileTestRecord is a record in the test section. Values are keyed
+// by field label, and fields whose values are empty or omitted are null.
+type QuickbaseFileTestRecord struct {
+	RecordID int               `validate:"required" yaml:"record_id"`
+	Values   map[string]string `yaml:"values"`
 }
 
+// QuickbaseFileTestFormula is a formula that is run against one or more
+// records. The formula is either read from a file or set inline. The
+// assertion is checked against the record ID, or against each case if the
+// formula is tested against multiple records. Placeholders in the file's
+// contents are only replaced if Substitute is set.
 type QuickbaseFileTestFormula struct {
-	File     string `validate:"required" yaml:"file"`
-	TableID  string `validate:"required" yaml:"table_id"`
-	RecordID int    `validate:"required" yaml:"record_id"`
-	Expected string `validate:"required" yaml:"expected"`
+	Name       string `yaml:"name"`
+	File       string `validate:"required_without=Formula" yaml:"file"`
+	Substitute bool   `yaml:"substitute"`
+	Formula    string `validate:"required_without=File" yaml:"formula"`
+	TableID    string `validate:"required" yaml:"table_id"`
+	RecordID   int    `validate:"required_without=Cases" yaml:"record_id"`
+
+	QuickbaseFileTestAssertion `yaml:",inline"`
+
+	Cases []*QuickbaseFileTestCase `validate:"dive" yaml:"cases"`
+}
+
+// QuickbaseFileTestCase is a record a formula is tested against. Cases
+// without an assertion use the formula's assertion.
+type QuickbaseFileTestCase struct {
+	RecordID int `validate:"required" yaml:"record_id"`
+
+	QuickbaseFileTestAssertion `yaml:",inline"`
+}
+
+// QuickbaseFileTestAssertion is the expected outcome of running a formula.
+// The result must equal Expected, or be within Tolerance of it if set, and it
+// must match the Match regular expression. Formulas that are expected to fail
+// set ExpectedError to text contained in the error, or to an empty string to
+// accept any error.
+type QuickbaseFileTestAssertion struct {
+	Expected      *string  `yaml:"expected"`
+	Match         string   `yaml:"match"`
+	Tolerance     *float64 `validate:"omitempty,gte=0" yaml:"tolerance"`
+	ExpectedError *string  `yaml:"expected_error"`
 }
 
 type QuickbaseFileDeploy struct {
-	Formulas []*QuickbaseFileDeployFormula `yaml:"formulas"`
+	AppID     string                        `yaml:"app_id"`
+	Formulas  []*QuickbaseFileDeployFormula `validate:"dive" yaml:"formulas"`
+	Pages     []*QuickbaseFileDeployPage    `validate:"dive" yaml:"pages"`
+	Variables map[string]string             `yaml:"variables"`
+	Fields    []*QuickbaseFileDeployField   `validate:"dive" yaml:"fields"`
 }
 
+// QuickbaseFileDeployFormula is a formula deployed to a field. Placeholders
+// in the file's contents are only replaced if Substitute is set.
 type QuickbaseFileDeployFormula struct {
-	File    string `validate:"required" yaml:"file"`
-	TableID string `validate:"required" yaml:"table_id"`
-	FieldID int    `validate:"required" yaml:"field_id"`
+	File       string `validate:"required" yaml:"file"`
+	Substitute bool   `yaml:"substitute"`
+	TableID    string `validate:"required" yaml:"table_id"`
+	FieldID    int    `validate:"required" yaml:"field_id"`
+}
+
+// QuickbaseFileDeployPage is a code page. The page is replaced if the page ID
+// is set, otherwise it is added with the name. Placeholders in the file's
+// contents are only replaced if Substitute is set, because code pages often
+// contain JavaScript template literals.
+type QuickbaseFileDeployPage struct {
+	File       string `validate:"required" yaml:"file"`
+	Substitute bool   `yaml:"substitute"`
+	Name       string `validate:"required_without=PageID" yaml:"name"`
+	PageID     int    `yaml:"page_id"`
+	Type       int    `yaml:"type"`
+}
+
+// QuickbaseFileDeployField contains the properties set on a field. Properties
+// that are omitted are left unchanged.
+type QuickbaseFileDeployField struct {
+	TableID         string   `validate:"required" yaml:"table_id"`
+	FieldID         int      `validate:"required" yaml:"field_id"`
+	Label           string   `yaml:"label"`
+	HelpText        *string  `yaml:"help_text"`
+	Required        *bool    `yaml:"required"`
+	Unique          *bool    `yaml:"unique"`
+	DefaultValue    *string  `yaml:"default_value"`
+	Choices         []string `yaml:"choices"`
+	AllowNewChoices *bool    `yaml:"allow_new_choices"`
+	SortAsGiven     *bool    `yaml:"sort_as_given"`
 }
 
+// placeholderRegexp matches ${name} placeholders, as well as $${name}, which
+// is an escaped, literal ${name}.
+var placeholderRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z0-9_.-]+)\}`)
+
+// ParseQuickbaseFile parses a quickbase.yml file without an environment, so
+// it returns an error if the file contains placeholders.
 func ParseQuickbaseFile(file string) (f *QuickbaseFile, err error) {
+	return ParseQuickbaseFileEnv(file, "")
+}
+
+// ParseQuickbaseFileEnv parses a quickbase.yml file, replacing placeholders
+// with the variables of the passed environment. It returns an error if any
+// placeholder in the file, or in the referenced files with substitute set,
+// can't be resolved, so mistakes are caught before any API call is made.
+func ParseQuickbaseFileEnv(file, env string) (f *QuickbaseFile, err error) {
 	f = &QuickbaseFile{}
 
 	// Read the quickbase.yml file.
@@ -48,74 +166,208 @@ func ParseQuickbaseFile(file string) (f *QuickbaseFile, err error) {
 		return
 	}
 
+	// Parse the document so placeholders can be replaced in its values.
+	var doc yaml.Node
+	if derr := yaml.Unmarshal(b, &doc); derr != nil {
+		err = qberrors.Client(nil).Safef(qberrors.InvalidSyntax, "yaml not valid: %w", derr)
+		return
+	}
+
+	if f.vars, err = environmentVars(&doc, env); err != nil {
+		return
+	}
+
+	unresolved := map[string]bool{}
+	f.replaceNode(&doc, false, unresolved)
+	if err = unresolvedError(file, unresolved); err != nil {
+		return
+	}
+
 	// Parse the yaml file using strict settings.
+	if b, err = yaml.Marshal(&doc); err != nil {
+		return
+	}
 	dec := yaml.NewDecoder(bytes.NewBuffer(b))
 	dec.KnownFields(true)
-	derr := dec.Decode(f)
-	if derr != nil {
+	if derr := dec.Decode(f); derr != nil && derr != io.EOF {
 		err = qberrors.Client(nil).Safef(qberrors.InvalidSyntax, "yaml not valid: %w", derr)
+		return
 	}
 
 	// Validate the decoded file.
 	if verr := validator.New().Struct(f); verr != nil {
 		err = qberrors.HandleErrorValidation(verr)
+		return
 	}
 
-	return
-}
+	if f.Test != nil {
+		for idx, t := range f.Test.Formulas {
+			if err = t.validate(); err != nil {
+				err = qberrors.Client(nil).Safef(qberrors.InvalidInput, "test formula %d: %s", idx, err)
+				return
+			}
+		}
+		for _, t := range f.Test.Tables {
+			if err = t.validate(); err != nil {
+				err = qberrors.Client(nil).Safef(qberrors.InvalidInput, "test table %s: %s", t.TableID, err)
+				return
+			}
+		}
+	}
 
-type TestFormulaInput struct {
-	File string `cliutil:"option=file default=quickbase.yml"`
+	// Validate the placeholders in the referenced files.
+	for _, name := range f.substitutedFiles() {
+		if _, err = f.ReadFile(name, true); err != nil {
+			return
+		}
+	}
+
+	return
 }
 
-type TestFormulaOutput struct {
-	Passed []int          `json:"passed"`
-	Failed map[int]string `json:"failed"`
+// QuickbaseFileProfile returns the profile configured for an environment in
+// a quickbase.yml file, which is empty if the environment has no profile.
+func QuickbaseFileProfile(file, env string) (string, error) {
+	f, err := ParseQuickbaseFileEnv(file, env)
+	if err != nil || env == "" {
+		return "", err
+	}
+	return f.Environments[env].Profile, nil
 }
 
-func TestFormula(qb *qbclient.Client, in *TestFormulaInput) (out *TestFormulaOutput, err error) {
-	out = &TestFormulaOutput{
-		Passed: []int{},
-		Failed: map[int]string{},
+// environmentVars returns the variables of the environment, which must be
+// defined in the document's environments section if set.
+func environmentVars(doc *yaml.Node, env string) (map[string]string, error) {
+	if env == "" {
+		return map[string]string{}, nil
 	}
 
-	var file *QuickbaseFile
-	file, err = ParseQuickbaseFile(in.File)
-	if err != nil {
-		return
+	var envs struct {
+		Environments map[string]*QuickbaseFileEnvironment `yaml:"environments"`
+	}
+	if err := doc.Decode(&envs); err != nil {
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidSyntax, "yaml not valid: %w", err)
 	}
 
-	for idx, f := range file.Test.Formulas {
+	e, ok := envs.Environments[env]
+	if !ok || e == nil {
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "%s: environment not defined in quickbase file", env)
+	}
+	if e.Vars == nil {
+		return map[string]string{}, nil
+	}
+	return e.Vars, nil
+}
 
-		b, ferr := ioutil.ReadFile(f.File)
-		if ferr != nil {
-			err = qberrors.Client(nil).Safef(qberrors.InvalidInput, "error reading formula file: %w", ferr)
-			return
+// replaceNode replaces placeholders in the scalar values of a document,
+// except for the top-level environments section that defines the variables.
+// The tags of replaced values are reset so that, e.g., "field_id: ${status}"
+// is decoded as an int.
+func (f *QuickbaseFile) replaceNode(n *yaml.Node, top bool, unresolved map[string]bool) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			f.replaceNode(c, true, unresolved)
 		}
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(n.Content); idx += 2 {
+			if top && n.Content[idx].Value == "environments" {
+				continue
+			}
+			f.replaceNode(n.Content[idx+1], false, unresolved)
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			f.replaceNode(c, false, unresolved)
+		}
+	case yaml.ScalarNode:
+		if v := f.replace(n.Value, unresolved); v != n.Value {
+			n.Value = v
+			if n.Style == 0 {
+				n.Tag = ""
+			}
+		}
+	}
+}
 
-		rfi := &qbclient.RunFormulaInput{
-			From:     f.TableID,
-			RecordID: f.RecordID,
-			Formula:  string(b),
+// replace replaces the placeholders in s, recording those that don't resolve.
+// Escaped placeholders are unescaped.
+func (f *QuickbaseFile) replace(s string, unresolved map[string]bool) string {
+	return placeholderRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "$$") {
+			return m[1:]
+		}
+		name := placeholderRegexp.FindStringSubmatch(m)[1]
+		if v, ok := f.vars[name]; ok {
+			return v
 		}
+		unresolved[name] = true
+		return m
+	})
+}
+
+func unresolvedError(file string, unresolved map[string]bool) error {
+	if len(unresolved) == 0 {
+		return nil
+	}
 
-		rfo, rerr := qb.RunFormula(rfi)
-		if rerr == nil {
-			if f.Expected == rfo.Result {
-				out.Passed = append(out.Passed, idx)
-			} else {
-				out.Failed[idx] = fmt.Sprintf("expected %q, got %q", f.Expected, rfo.Result)
+	names := make([]string, 0, len(unresolved))
+	for name := range unresolved {
+		names = append(names, "${"+name+"}")
+	}
+	sort.Strings(names)
+
+	return qberrors.Client(nil).Safef(qberrors.InvalidInput, "%s: placeholders not resolved: %s", file, strings.Join(names, ", "))
+}
+
+// substitutedFiles returns the formula and page files referenced by the file
+// whose placeholders are replaced.
+func (f *QuickbaseFile) substitutedFiles() (files []string) {
+	if f.Test != nil {
+		for _, t := range f.Test.Formulas {
+			if t.File != "" && t.Substitute {
+				files = append(files, t.File)
+			}
+		}
+	}
+	if f.Deploy != nil {
+		for _, d := range f.Deploy.Formulas {
+			if d.Substitute {
+				files = append(files, d.File)
+			}
+		}
+		for _, p := range f.Deploy.Pages {
+			if p.Substitute {
+				files = append(files, p.File)
 			}
-		} else {
-			out.Failed[idx] = rerr.Error()
 		}
 	}
-
 	return
 }
 
+// ReadFile reads a file referenced by the quickbase.yml file, replacing the
+// placeholders in its contents if substitute is set.
+func (f *QuickbaseFile) ReadFile(name string, substitute bool) ([]byte, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "error reading file: %w", err)
+	}
+	if !substitute {
+		return b, nil
+	}
+
+	unresolved := map[string]bool{}
+	s := f.replace(string(b), unresolved)
+	if err := unresolvedError(name, unresolved); err != nil {
+		return nil, err
+	}
+
+	return []byte(s), nil
+}
+
 type DeployFormulaInput struct {
 	File string `cliutil:"option=file default=quickbase.yml"`
+	Env  string `cliutil:"option=env usage='environment in the quickbase file whose variables replace placeholders'"`
 }
 
 type DeployFormulaOutput struct {
@@ -123,23 +375,30 @@ type DeployFormulaOutput struct {
 	Errors   map[string]map[int]string `json:"errors"`
 }
 
-func DeployFormula(qb *qbclient.Client, in *DeployFormulaInput) (out *DeployFormulaOutput, err error) {
+// DeployFormula deploys the formulas in the deploy section of a quickbase.yml
+// file.
+func DeployFormula(qb *qbclient.Client, in *DeployFormulaInput) (*DeployFormulaOutput, error) {
+	return DeployFormulaWithContext(context.Background(), qb, in)
+}
+
+// DeployFormulaWithContext is DeployFormula using the passed context.
+func DeployFormulaWithContext(ctx context.Context, qb *qbclient.Client, in *DeployFormulaInput) (out *DeployFormulaOutput, err error) {
 	out = &DeployFormulaOutput{
 		Deployed: map[string][]int{},
 		Errors:   map[string]map[int]string{},
 	}
 
 	var file *QuickbaseFile
-	file, err = ParseQuickbaseFile(in.File)
+	file, err = ParseQuickbaseFileEnv(in.File, in.Env)
 	if err != nil {
 		return
 	}
 
 	for _, f := range file.Deploy.Formulas {
 
-		b, ferr := ioutil.ReadFile(f.File)
+		b, ferr := file.ReadFile(f.File, f.Substitute)
 		if ferr != nil {
-			err = qberrors.Client(nil).Safef(qberrors.InvalidInput, "error reading formula file: %w", ferr)
+			err = ferr
 			return
 		}
 
@@ -154,8 +413,12 @@ func DeployFormula(qb *qbclient.Client, in *DeployFormulaInput) (out *DeployForm
 		ufi.AddToNewReports = true
 		ufi.Searchable = true
 
-		_, uerr := qb.UpdateField(ufi)
+		_, uerr := qb.UpdateFieldWithContext(ctx, ufi)
 		if uerr == nil {
+			// A stale schema is refreshed when the cache expires, so the
+			// formula is still reported as deployed if this fails.
+			InvalidateTableSchema(f.TableID)
+
 			if _, ok := out.Deployed[f.TableID]; !ok {
 				out.Deployed[f.TableID] = []int{}
 			}
@@ -0,0 +1,93 @@
+package qbcli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+func TestParseQuickbaseFileEnvironments(t *testing.T) {
+	dir := writeDeployFiles(t, map[string]string{
+		"quickbase.yml": `
+environments:
+  dev:
+    vars:
+      tasks: bqgruir7z
+      days_fid: "8"
+  prod:
+    profile: prod
+    vars:
+      tasks: bqgruir9k
+      days_fid: "18"
+      due: "[Due Date]"
+  broken:
+    vars:
+      tasks: bqgruir9k
+deploy:
+  formulas:
+    - file: DIR/days.formula
+      substitute: true
+      table_id: ${tasks}
+      field_id: ${days_fid}
+  pages:
+    - file: DIR/app.js
+      name: $${page}.js
+`,
+		"days.formula": "${due} - Today()",
+		"app.js":       "`Hello ${name}`",
+	})
+	defer os.RemoveAll(dir)
+
+	// Referenced files are relative to the working directory.
+	b, _ := ioutil.ReadFile(qbclient.Filepath(dir, "quickbase.yml"))
+	ioutil.WriteFile(qbclient.Filepath(dir, "quickbase.yml"), []byte(strings.Replace(string(b), "DIR", dir, -1)), 0644)
+	file := qbclient.Filepath(dir, "quickbase.yml")
+
+	f, err := qbcli.ParseQuickbaseFileEnv(file, "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	formula := f.Deploy.Formulas[0]
+	if formula.TableID != "bqgruir9k" || formula.FieldID != 18 {
+		t.Errorf("got table %q and field %d, expected the prod IDs", formula.TableID, formula.FieldID)
+	}
+	if b, _ := f.ReadFile(formula.File, formula.Substitute); string(b) != "[Due Date] - Today()" {
+		t.Errorf("got formula %q, expected placeholders to be replaced", b)
+	}
+
+	// Placeholders aren't replaced in files without substitute set, and
+	// escaped placeholders are literal.
+	page := f.Deploy.Pages[0]
+	if b, _ := f.ReadFile(page.File, page.Substitute); string(b) != "`Hello ${name}`" {
+		t.Errorf("got page %q, expected it to be unchanged", b)
+	}
+	if page.Name != "${page}.js" {
+		t.Errorf("got page name %q, expected the escaped placeholder to be literal", page.Name)
+	}
+
+	if profile, _ := qbcli.QuickbaseFileProfile(file, "prod"); profile != "prod" {
+		t.Errorf("got profile %q, expected prod", profile)
+	}
+
+	tests := []struct {
+		env      string
+		expected string
+	}{
+		{"", "placeholders not resolved: ${days_fid}, ${tasks}"},
+		{"dev", "days.formula: placeholders not resolved: ${due}"},
+		{"broken", "placeholders not resolved: ${days_fid}"},
+		{"staging", "staging: environment not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			_, err := qbcli.ParseQuickbaseFileEnv(file, tt.env)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("got error %v, expected %q", err, tt.expected)
+			}
+		})
+	}
+}
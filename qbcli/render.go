@@ -1,9 +1,13 @@
 package qbcli
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"reflect"
 
 	"github.com/QuickBase/quickbase-cli/qbclient"
@@ -24,10 +28,7 @@ func Render(
 ) {
 
 	// Render the error.
-	if err != nil {
-		ctx = cliutil.ContextWithLogTag(ctx, "code", fmt.Sprintf("%v", qberrors.StatusCode(err)))
-		HandleError(ctx, logger, qberrors.SafeMessage(err), errors.New(qberrors.SafeDetail(err)))
-	}
+	renderError(ctx, logger, err)
 
 	// Do not return output.
 	if cfg.Quiet() {
@@ -35,7 +36,7 @@ func Render(
 	}
 
 	// Try to render a table.
-	if cfg.Format() == "table" || cfg.Format() == "csv" || cfg.Format() == "markdown" {
+	if isTableFormat(cfg.Format()) {
 		rerr := renderTable(v, cfg.Format())
 		HandleError(ctx, logger, "error rendering table", rerr)
 		return
@@ -46,6 +47,82 @@ func Render(
 	HandleError(ctx, logger, "JMESPath filter not valid", rerr)
 }
 
+// RenderRecords renders the records returned by the iterator as JSON Lines,
+// i.e., one JSON object per record, writing each page as it is fetched so that
+// large result sets aren't buffered in memory. The JMESPath filter is applied
+// to each record. Tables are sized to fit every record, so they are rendered
+// once all records are fetched.
+func RenderRecords(
+	ctx context.Context,
+	logger *cliutil.LeveledLogger,
+	cmd *cobra.Command,
+	cfg GlobalConfig,
+	it *qbclient.RecordIterator,
+) {
+	if isTableFormat(cfg.Format()) {
+		records, err := it.All()
+		Render(ctx, logger, cmd, cfg, &struct{ qbclient.Records }{*records}, err)
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if cfg.Quiet() {
+		w = nil
+	}
+	renderError(ctx, logger, WriteRecords(w, it, cfg.JMESPathFilter()))
+}
+
+// WriteRecords writes the records returned by the iterator to w as JSON Lines,
+// applying the JMESPath filter to each record if it is set. The records are
+// only consumed if w is nil.
+func WriteRecords(w io.Writer, it *qbclient.RecordIterator, filter string) error {
+	for it.Next() {
+		if w == nil {
+			continue
+		}
+
+		b, err := json.Marshal(it.Record())
+		if err != nil {
+			return err
+		}
+
+		if filter != "" {
+			var v interface{}
+			if err := json.Unmarshal(b, &v); err != nil {
+				return err
+			}
+			s, err := cliutil.FormatJSONWithFilter(v, filter)
+			if err != nil {
+				return qberrors.Client(nil).Safef(qberrors.InvalidInput, "JMESPath filter not valid: %w", err)
+			}
+
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, []byte(s)); err != nil {
+				return err
+			}
+			b = buf.Bytes()
+		}
+
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// renderError writes an error log if err is not nil.
+func renderError(ctx context.Context, logger *cliutil.LeveledLogger, err error) {
+	if err != nil {
+		ctx = cliutil.ContextWithLogTag(ctx, "code", fmt.Sprintf("%v", qberrors.StatusCode(err)))
+		HandleError(ctx, logger, qberrors.SafeMessage(err), errors.New(qberrors.SafeDetail(err)))
+	}
+}
+
+// isTableFormat returns whether the output format is rendered as a table.
+func isTableFormat(format string) bool {
+	return format == "table" || format == "csv" || format == "markdown"
+}
+
 func renderTable(a interface{}, format string) error {
 	tw := table.NewWriter()
 
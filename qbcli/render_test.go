@@ -0,0 +1,67 @@
+package qbcli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+func TestWriteRecords(t *testing.T) {
+	const total = 5
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input qbclient.QueryRecordsInput
+		json.NewDecoder(r.Body).Decode(&input)
+
+		data := []string{}
+		for rid := input.Options.Skip + 1; rid <= total && len(data) < input.Options.Top; rid++ {
+			data = append(data, fmt.Sprintf(`{"3":{"value":%d}}`, rid))
+		}
+
+		fmt.Fprintf(w, `{"data":[%s],"fields":[{"id":3,"label":"Record ID#","type":"recordid"}],`, strings.Join(data, ","))
+		fmt.Fprintf(w, `"metadata":{"totalRecords":%d,"numRecords":%d,"numFields":1,"skip":%d}}`, total, len(data), input.Options.Skip)
+	}))
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	tests := []struct {
+		filter   string
+		expected string
+	}{
+		{"", `{"3":{"value":1}}`},
+		{`"3".value`, `1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			input := &qbclient.QueryRecordsInput{
+				Select:  []int{3},
+				From:    "bqgruir7z",
+				Options: &qbclient.QueryRecordsInputOptions{Top: 2},
+			}
+
+			var buf bytes.Buffer
+			if err := qbcli.WriteRecords(&buf, qb.QueryRecordsAll(input), tt.filter); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != total {
+				t.Fatalf("got %v lines, expected %v:\n%s", len(lines), total, buf.String())
+			}
+			if lines[0] != tt.expected {
+				t.Errorf("got first line %s, expected %s", lines[0], tt.expected)
+			}
+		})
+	}
+}
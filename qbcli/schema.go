@@ -0,0 +1,466 @@
+package qbcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaFormat* constants contain the formats app schemas are written in.
+const (
+	SchemaFormatJSON = "json"
+	SchemaFormatYAML = "yaml"
+)
+
+// SchemaExportOptions are the options read through the command line.
+type SchemaExportOptions struct {
+	AppID    string `validate:"required" cliutil:"option=app-id"`
+	Filepath string `cliutil:"option=file usage='file the schema is written to, defaults to stdout'"`
+	Format   string `cliutil:"option=schema-format usage='format of the schema (yaml or json) detected from the file extension by default'"`
+}
+
+// SchemaExport writes the structure of an app to a file or stdout.
+func SchemaExport(ctx context.Context, qb *qbclient.Client, opts *SchemaExportOptions) error {
+	format, err := schemaFormat(opts.Format, opts.Filepath)
+	if err != nil {
+		return err
+	}
+
+	schema, err := ExportAppSchema(ctx, qb, opts.AppID)
+	if err != nil {
+		return err
+	}
+
+	if opts.Filepath == "" {
+		return WriteAppSchema(os.Stdout, schema, format)
+	}
+
+	f, err := os.Create(opts.Filepath)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer f.Close()
+
+	return WriteAppSchema(f, schema, format)
+}
+
+// AppSchema is a declarative description of an app's structure. Everything is
+// sorted so that exporting the same app always produces the same document,
+// and properties that change as the app is used, e.g., the next record ID,
+// are omitted so that diffs between exports only contain structural changes.
+type AppSchema struct {
+	AppID       string            `yaml:"app_id,omitempty" json:"app_id,omitempty"`
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	TimeZone    string            `yaml:"time_zone,omitempty" json:"time_zone,omitempty"`
+	DateFormat  string            `yaml:"date_format,omitempty" json:"date_format,omitempty"`
+	Variables   map[string]string `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Tables      []*SchemaTable    `yaml:"tables,omitempty" json:"tables,omitempty"`
+}
+
+// SchemaTable describes a table.
+type SchemaTable struct {
+	TableID            string                `yaml:"id,omitempty" json:"id,omitempty"`
+	Name               string                `yaml:"name" json:"name"`
+	Alias              string                `yaml:"alias,omitempty" json:"alias,omitempty"`
+	Description        string                `yaml:"description,omitempty" json:"description,omitempty"`
+	SingleRecordName   string                `yaml:"single_record_name,omitempty" json:"single_record_name,omitempty"`
+	PluralRecordName   string                `yaml:"plural_record_name,omitempty" json:"plural_record_name,omitempty"`
+	KeyFieldID         int                   `yaml:"key_field_id,omitempty" json:"key_field_id,omitempty"`
+	DefaultSortFieldID int                   `yaml:"default_sort_field_id,omitempty" json:"default_sort_field_id,omitempty"`
+	DefaultSortOrder   string                `yaml:"default_sort_order,omitempty" json:"default_sort_order,omitempty"`
+	Fields             []*SchemaField        `yaml:"fields,omitempty" json:"fields,omitempty"`
+	Relationships      []*SchemaRelationship `yaml:"relationships,omitempty" json:"relationships,omitempty"`
+	Reports            []*SchemaReport       `yaml:"reports,omitempty" json:"reports,omitempty"`
+
+	// set contains the properties set in a desired schema, see ReadAppSchema.
+	set schemaProperties
+}
+
+// SchemaField describes a field. Searchable and AddToReports are pointers
+// because they default to true, so false must be distinguished from unset.
+type SchemaField struct {
+	FieldID      int    `yaml:"id,omitempty" json:"id,omitempty"`
+	Label        string `yaml:"label" json:"label"`
+	Type         string `yaml:"type" json:"type"`
+	Required     bool   `yaml:"required,omitempty" json:"required,omitempty"`
+	Unique       bool   `yaml:"unique,omitempty" json:"unique,omitempty"`
+	Bold         bool   `yaml:"bold,omitempty" json:"bold,omitempty"`
+	NoWrap       bool   `yaml:"no_wrap,omitempty" json:"no_wrap,omitempty"`
+	AutoFill     bool   `yaml:"auto_fill,omitempty" json:"auto_fill,omitempty"`
+	Searchable   *bool  `yaml:"searchable,omitempty" json:"searchable,omitempty"`
+	AddToReports *bool  `yaml:"add_to_reports,omitempty" json:"add_to_reports,omitempty"`
+	TrackField   bool   `yaml:"track_field,omitempty" json:"track_field,omitempty"`
+	HelpText     string `yaml:"help_text,omitempty" json:"help_text,omitempty"`
+
+	DefaultValue       string   `yaml:"default_value,omitempty" json:"default_value,omitempty"`
+	Choices            []string `yaml:"choices,omitempty,flow" json:"choices,omitempty"`
+	AllowNewChoices    bool     `yaml:"allow_new_choices,omitempty" json:"allow_new_choices,omitempty"`
+	SortChoicesAsGiven bool     `yaml:"sort_as_given,omitempty" json:"sort_as_given,omitempty"`
+	NumberOfLines      int      `yaml:"num_lines,omitempty" json:"num_lines,omitempty"`
+	MaxCharacters      int      `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	WidthOfInputBox    int      `yaml:"width,omitempty" json:"width,omitempty"`
+	ExactMatch         bool     `yaml:"exact,omitempty" json:"exact,omitempty"`
+	ForeignKey         bool     `yaml:"foreign_key,omitempty" json:"foreign_key,omitempty"`
+	PrimaryKey         bool     `yaml:"primary_key,omitempty" json:"primary_key,omitempty"`
+	ParentTable        string   `yaml:"parent_table,omitempty" json:"parent_table,omitempty"`
+	RelatedField       int      `yaml:"related_field,omitempty" json:"related_field,omitempty"`
+	Formula            string   `yaml:"formula,omitempty" json:"formula,omitempty"`
+	Comments           string   `yaml:"comments,omitempty" json:"comments,omitempty"`
+
+	// set contains the properties set in a desired schema, see ReadAppSchema.
+	set schemaProperties
+}
+
+// SchemaRelationship describes a relationship from the child table's side.
+// The parent table is referenced by alias if it is in the same app.
+type SchemaRelationship struct {
+	RelationshipID  int                        `yaml:"id,omitempty" json:"id,omitempty"`
+	ParentTable     string                     `yaml:"parent_table" json:"parent_table"`
+	ForeignKeyField *SchemaRelationshipField   `yaml:"foreign_key_field,omitempty" json:"foreign_key_field,omitempty"`
+	LookupFields    []*SchemaRelationshipField `yaml:"lookup_fields,omitempty" json:"lookup_fields,omitempty"`
+	SummaryFields   []*SchemaRelationshipField `yaml:"summary_fields,omitempty" json:"summary_fields,omitempty"`
+}
+
+// SchemaRelationshipField describes a foreign key, lookup, or summary field.
+// ParentField is the label of the field in the parent table that a lookup
+// field looks up, and SummaryField is the label of the field in the child
+// table that a summary field summarizes. The API doesn't return a summary
+// field's filter, so Where is only read from desired schemas.
+type SchemaRelationshipField struct {
+	FieldID          int    `yaml:"id,omitempty" json:"id,omitempty"`
+	Label            string `yaml:"label" json:"label"`
+	ParentField      string `yaml:"parent_field,omitempty" json:"parent_field,omitempty"`
+	SummaryField     string `yaml:"summary_field,omitempty" json:"summary_field,omitempty"`
+	AccumulationType string `yaml:"accumulation_type,omitempty" json:"accumulation_type,omitempty"`
+	Where            string `yaml:"where,omitempty" json:"where,omitempty"`
+}
+
+// SchemaReport describes a report.
+type SchemaReport struct {
+	ReportID    string               `yaml:"id,omitempty" json:"id,omitempty"`
+	Name        string               `yaml:"name" json:"name"`
+	Type        string               `yaml:"type" json:"type"`
+	Description string               `yaml:"description,omitempty" json:"description,omitempty"`
+	Filter      string               `yaml:"filter,omitempty" json:"filter,omitempty"`
+	Fields      []int                `yaml:"fields,omitempty,flow" json:"fields,omitempty"`
+	SortBy      []*SchemaReportOrder `yaml:"sort_by,omitempty" json:"sort_by,omitempty"`
+	GroupBy     []*SchemaReportOrder `yaml:"group_by,omitempty" json:"group_by,omitempty"`
+}
+
+// SchemaReportOrder describes how a report is sorted or grouped by a field.
+type SchemaReportOrder struct {
+	FieldID  int    `yaml:"field_id" json:"field_id"`
+	Order    string `yaml:"order,omitempty" json:"order,omitempty"`
+	Grouping string `yaml:"grouping,omitempty" json:"grouping,omitempty"`
+}
+
+// ExportAppSchema retrieves the structure of an app.
+func ExportAppSchema(ctx context.Context, qb *qbclient.Client, appID string) (*AppSchema, error) {
+	app, err := qb.GetAppByIDWithContext(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting app: %w", err)
+	}
+
+	schema := &AppSchema{
+		AppID:       app.AppID,
+		Name:        app.Name,
+		Description: app.Description,
+		TimeZone:    app.TimeZone,
+		DateFormat:  app.DateFormat,
+	}
+
+	if len(app.Variables) > 0 {
+		schema.Variables = make(map[string]string, len(app.Variables))
+		for _, v := range app.Variables {
+			schema.Variables[v.Name] = v.Value
+		}
+	}
+
+	tables, err := qb.ListTablesByAppIDWithContext(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tables: %w", err)
+	}
+
+	// Map table IDs to aliases so relationships can refer to parent tables in
+	// a way that doesn't change between copies of the app.
+	aliases := make(map[string]string, len(tables.Tables))
+	for _, t := range tables.Tables {
+		aliases[t.TableID] = t.Alias
+	}
+
+	// Fields are listed for every table first, because lookup and summary
+	// fields are described by fields in both tables of a relationship.
+	fields := make(map[string][]*qbclient.ListFieldsOutputField, len(tables.Tables))
+	for _, t := range tables.Tables {
+		out, err := qb.ListFieldsByTableIDWithContext(ctx, t.TableID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing fields in table %s: %w", t.TableID, err)
+		}
+		fields[t.TableID] = out.Fields
+	}
+
+	for _, t := range tables.Tables {
+		table, err := exportTableSchema(ctx, qb, t, aliases, fields)
+		if err != nil {
+			return nil, err
+		}
+		schema.Tables = append(schema.Tables, table)
+	}
+
+	schema.Sort()
+	return schema, nil
+}
+
+func exportTableSchema(
+	ctx context.Context,
+	qb *qbclient.Client,
+	t *qbclient.ListTablesOutputTable,
+	aliases map[string]string,
+	fields map[string][]*qbclient.ListFieldsOutputField,
+) (*SchemaTable, error) {
+	table := &SchemaTable{
+		TableID:            t.TableID,
+		Name:               t.Name,
+		Alias:              t.Alias,
+		Description:        t.Description,
+		SingleRecordName:   t.SingleRecordName,
+		PluralRecordName:   t.PluralRecordName,
+		KeyFieldID:         t.KeyFieldID,
+		DefaultSortFieldID: t.DefaultSortFieldID,
+		DefaultSortOrder:   t.DefaultSortOrder,
+	}
+
+	for _, f := range fields[t.TableID] {
+		table.Fields = append(table.Fields, newSchemaField(f))
+	}
+
+	relationships, err := qb.ListRelationshipsByTableIDWithContext(ctx, t.TableID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing relationships in table %s: %w", t.TableID, err)
+	}
+	for _, r := range relationships.Relationships {
+		table.Relationships = append(table.Relationships, newSchemaRelationship(r, aliases, fields))
+	}
+
+	reports, err := qb.ListReportsWithContext(ctx, &qbclient.ListReportsInput{TableID: t.TableID})
+	if err != nil {
+		return nil, fmt.Errorf("error listing reports in table %s: %w", t.TableID, err)
+	}
+	for _, r := range reports.Reports {
+		table.Reports = append(table.Reports, newSchemaReport(r))
+	}
+
+	return table, nil
+}
+
+func newSchemaField(f *qbclient.ListFieldsOutputField) *SchemaField {
+	field := &SchemaField{
+		FieldID:      f.FieldID,
+		Label:        f.Label,
+		Type:         f.Type,
+		Required:     f.Required,
+		Unique:       f.Unique,
+		Bold:         f.DisplayInBold,
+		NoWrap:       f.DisplayWithoutWrapping,
+		AutoFill:     f.AutoFill,
+		Searchable:   &f.Searchable,
+		AddToReports: &f.AddToNewReports,
+		TrackField:   f.TrackField,
+		HelpText:     f.FieldHelpText,
+	}
+
+	if p := f.Properties; p != nil {
+		field.DefaultValue = p.DefaultValue
+		field.Choices = p.Choices
+		field.AllowNewChoices = p.AllowNewChoices
+		field.SortChoicesAsGiven = p.SortChoicesAsGiven
+		field.NumberOfLines = p.NumberOfLines
+		field.MaxCharacters = p.MaxCharacters
+		field.WidthOfInputBox = p.WidthOfInputBox
+		field.ExactMatch = p.ExactMatch
+		field.ForeignKey = p.ForeignKey
+		field.PrimaryKey = p.PrimaryKey
+		field.ParentTable = p.ParentTable
+		field.RelatedField = p.RelatedField
+		field.Formula = p.Formula
+		field.Comments = p.Comments
+	}
+
+	return field
+}
+
+// newSchemaRelationship converts a relationship. The fields of every table in
+// the app are passed so the fields that lookup and summary fields are based on
+// can be described by label. Parent tables in other apps aren't in fields, so
+// their lookups are described without a parent field.
+func newSchemaRelationship(
+	r *qbclient.Relationship,
+	aliases map[string]string,
+	fields map[string][]*qbclient.ListFieldsOutputField,
+) *SchemaRelationship {
+	parent := r.ParentTableID
+	if alias, ok := aliases[parent]; ok && alias != "" {
+		parent = alias
+	}
+
+	childFields, parentFields := fields[r.ChildTableID], fields[r.ParentTableID]
+
+	rel := &SchemaRelationship{RelationshipID: r.RelationshipID, ParentTable: parent}
+	if r.ForeignKeyField != nil {
+		rel.ForeignKeyField = &SchemaRelationshipField{FieldID: r.ForeignKeyField.FieldID, Label: r.ForeignKeyField.Label}
+	}
+
+	for _, f := range r.LookupFields {
+		lf := &SchemaRelationshipField{FieldID: f.FieldID, Label: f.Label}
+		if p := schemaFieldProperties(childFields, f.FieldID); p != nil {
+			lf.ParentField = schemaFieldLabel(parentFields, p.LookupTargetFieldID)
+		}
+		rel.LookupFields = append(rel.LookupFields, lf)
+	}
+
+	for _, f := range r.SummaryFields {
+		sf := &SchemaRelationshipField{FieldID: f.FieldID, Label: f.Label}
+		if p := schemaFieldProperties(parentFields, f.FieldID); p != nil {
+			sf.SummaryField = schemaFieldLabel(childFields, p.SummaryTargetFieldID)
+			sf.AccumulationType = p.SummaryFunction
+		}
+		rel.SummaryFields = append(rel.SummaryFields, sf)
+	}
+
+	return rel
+}
+
+// schemaFieldProperties returns the properties of the field with the passed
+// ID, or nil if the field isn't found or doesn't have properties.
+func schemaFieldProperties(fields []*qbclient.ListFieldsOutputField, fid int) *qbclient.FieldProperties {
+	for _, f := range fields {
+		if f.FieldID == fid && f.Properties != nil {
+			return &f.Properties.FieldProperties
+		}
+	}
+	return nil
+}
+
+// schemaFieldLabel returns the label of the field with the passed ID, or an
+// empty string if the field isn't found.
+func schemaFieldLabel(fields []*qbclient.ListFieldsOutputField, fid int) string {
+	for _, f := range fields {
+		if fid != 0 && f.FieldID == fid {
+			return f.Label
+		}
+	}
+	return ""
+}
+
+func newSchemaReport(r *qbclient.ReportWithDescripton) *SchemaReport {
+	report := &SchemaReport{
+		ReportID:    r.ReportID,
+		Name:        r.Name,
+		Type:        r.Type,
+		Description: r.Description,
+	}
+
+	if q := r.Query; q != nil {
+		report.Filter = q.Filter
+		report.Fields = q.Fields
+		for _, s := range q.SortBy {
+			report.SortBy = append(report.SortBy, &SchemaReportOrder{FieldID: s.FieldID, Order: s.Order})
+		}
+		for _, g := range q.GroupBy {
+			report.GroupBy = append(report.GroupBy, &SchemaReportOrder{FieldID: g.FieldID, Grouping: g.Grouping})
+		}
+	}
+
+	return report
+}
+
+// Sort sorts the tables by alias, the fields and relationships by ID, and the
+// reports by ID. The order of report columns, sorting, and grouping is
+// meaningful, so it is preserved.
+func (s *AppSchema) Sort() {
+	sort.SliceStable(s.Tables, func(i, j int) bool {
+		if s.Tables[i].Alias != s.Tables[j].Alias {
+			return s.Tables[i].Alias < s.Tables[j].Alias
+		}
+		return s.Tables[i].TableID < s.Tables[j].TableID
+	})
+
+	for _, t := range s.Tables {
+		sort.SliceStable(t.Fields, func(i, j int) bool { return t.Fields[i].FieldID < t.Fields[j].FieldID })
+		sort.SliceStable(t.Relationships, func(i, j int) bool {
+			return t.Relationships[i].RelationshipID < t.Relationships[j].RelationshipID
+		})
+		sort.SliceStable(t.Reports, func(i, j int) bool { return reportIDLess(t.Reports[i].ReportID, t.Reports[j].ReportID) })
+
+		for _, r := range t.Relationships {
+			sort.SliceStable(r.LookupFields, func(i, j int) bool { return r.LookupFields[i].FieldID < r.LookupFields[j].FieldID })
+			sort.SliceStable(r.SummaryFields, func(i, j int) bool { return r.SummaryFields[i].FieldID < r.SummaryFields[j].FieldID })
+		}
+	}
+}
+
+// reportIDLess compares report IDs numerically, falling back to comparing
+// them as strings.
+func reportIDLess(a, b string) bool {
+	ai, aerr := strconv.Atoi(a)
+	bi, berr := strconv.Atoi(b)
+	if aerr == nil && berr == nil {
+		return ai < bi
+	}
+	return a < b
+}
+
+// schemaFormat returns the format of a schema file, which is detected from
+// the file extension if not explicitly set. It defaults to YAML.
+func schemaFormat(format, filename string) (string, error) {
+	if format == "" {
+		if strings.ToLower(filepath.Ext(filename)) == ".json" {
+			return SchemaFormatJSON, nil
+		}
+		return SchemaFormatYAML, nil
+	}
+
+	switch f := strings.ToLower(format); f {
+	case SchemaFormatJSON, SchemaFormatYAML:
+		return f, nil
+	case "yml":
+		return SchemaFormatYAML, nil
+	default:
+		return "", fmt.Errorf("%s: schema format not valid", format)
+	}
+}
+
+// WriteAppSchema writes the schema to w in the passed format.
+func WriteAppSchema(w io.Writer, schema *AppSchema, format string) error {
+	var buf bytes.Buffer
+	switch format {
+	case SchemaFormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(schema); err != nil {
+			return fmt.Errorf("error encoding schema: %w", err)
+		}
+	default:
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(schema); err != nil {
+			return fmt.Errorf("error encoding schema: %w", err)
+		}
+		enc.Close()
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,137 @@
+package qbcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// SchemaCache caches table schemas on disk so they are shared across
+// invocations of the CLI. Schemas are stored in a file per table, in a
+// directory per realm under the configuration directory. The cache is
+// disabled unless TTL is positive.
+type SchemaCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// NewSchemaCache returns a *SchemaCache configured from cfg.
+func NewSchemaCache(cfg GlobalConfig) *SchemaCache {
+	realm := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(cfg.RealmHostname())
+	return &SchemaCache{
+		Dir: qbclient.Filepath(cfg.ConfigDir(), "cache", realm),
+		TTL: cfg.SchemaCacheTTL(),
+	}
+}
+
+// Enabled returns whether schemas are cached on disk.
+func (c *SchemaCache) Enabled() bool { return c != nil && c.TTL > 0 }
+
+// schemaCacheEntry is the cached schema of a table.
+type schemaCacheEntry struct {
+	TableID  string                            `json:"tableId"`
+	CachedAt time.Time                         `json:"cachedAt"`
+	Fields   []*qbclient.ListFieldsOutputField `json:"fields"`
+}
+
+func (c *SchemaCache) filename(tableID string) string {
+	return qbclient.Filepath(c.Dir, tableID+".json")
+}
+
+// Get returns the cached schema of a table, or false if the schema is not
+// cached or has expired.
+func (c *SchemaCache) Get(tableID string) (FieldMap, bool) {
+	if !c.Enabled() {
+		return nil, false
+	}
+
+	b, err := ioutil.ReadFile(c.filename(tableID))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry schemaCacheEntry
+	if err = json.Unmarshal(b, &entry); err != nil || entry.TableID != tableID {
+		return nil, false
+	}
+	if time.Since(entry.CachedAt) > c.TTL {
+		return nil, false
+	}
+
+	return newFieldMap(entry.Fields), true
+}
+
+// Set caches the schema of a table, replacing the file atomically so that
+// concurrent invocations never read a partial schema.
+func (c *SchemaCache) Set(tableID string, fields []*qbclient.ListFieldsOutputField) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if !qbclient.DirExists(c.Dir) {
+		if err := os.MkdirAll(c.Dir, 0755); err != nil {
+			return fmt.Errorf("error creating schema cache directory: %w", err)
+		}
+	}
+
+	entry := &schemaCacheEntry{TableID: tableID, CachedAt: time.Now(), Fields: fields}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error encoding schema: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(c.Dir, tableID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing schema cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.filename(tableID))
+	}
+	if err != nil {
+		return fmt.Errorf("error writing schema cache: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the cached schema of a table.
+func (c *SchemaCache) Delete(tableID string) error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.filename(tableID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing cached schema: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the cached schema of every table in the realm.
+func (c *SchemaCache) Clear() error {
+	if c == nil {
+		return nil
+	}
+
+	files, err := filepath.Glob(qbclient.Filepath(c.Dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error listing cached schemas: %w", err)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing cached schema: %w", err)
+		}
+	}
+
+	return nil
+}
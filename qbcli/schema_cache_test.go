@@ -0,0 +1,149 @@
+package qbcli_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+func TestSchemaCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qbcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := &qbcli.SchemaCache{Dir: qbclient.Filepath(dir, "cache"), TTL: time.Minute}
+	fields := []*qbclient.ListFieldsOutputField{{FieldID: 6, Field: qbclient.Field{Label: "Name", Type: qbclient.FieldText}}}
+
+	if _, ok := cache.Get("bqgruir8e"); ok {
+		t.Fatal("expected a miss before the schema is cached")
+	}
+
+	if err := cache.Set("bqgruir8e", fields); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	m, ok := cache.Get("bqgruir8e")
+	if !ok {
+		t.Fatal("expected a hit after the schema is cached")
+	}
+	if m[6] == nil || m[6].Label != "Name" || m[6].Type != qbclient.FieldText {
+		t.Errorf("got %+v, expected field 6 to be cached", m[6])
+	}
+
+	expired := &qbcli.SchemaCache{Dir: cache.Dir, TTL: time.Nanosecond}
+	time.Sleep(time.Millisecond)
+	if _, ok := expired.Get("bqgruir8e"); ok {
+		t.Error("expected a miss after the schema expires")
+	}
+
+	if err := cache.Delete("bqgruir8e"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := cache.Get("bqgruir8e"); ok {
+		t.Error("expected a miss after the schema is deleted")
+	}
+
+	// A cache without a TTL is disabled, so it neither writes nor reads.
+	disabled := &qbcli.SchemaCache{Dir: cache.Dir}
+	if disabled.Enabled() {
+		t.Error("expected a cache without a TTL to be disabled")
+	}
+	disabled.Set("bqgruir8e", fields)
+	if _, ok := cache.Get("bqgruir8e"); ok {
+		t.Error("expected a disabled cache not to write the schema")
+	}
+	cache.Set("bqgruir8e", fields)
+	if _, ok := disabled.Get("bqgruir8e"); ok {
+		t.Error("expected a disabled cache not to read the schema")
+	}
+
+	cache.Set("bqgruir8f", fields)
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := cache.Get("bqgruir8f"); ok {
+		t.Error("expected a miss after the cache is cleared")
+	}
+}
+
+func TestGetTableSchemaDiskCache(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `[{"id":6,"label":"Name","fieldType":"text"}]`)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "qbcli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	qbcli.SetSchemaCache(&qbcli.SchemaCache{Dir: dir, TTL: time.Minute})
+	defer qbcli.SetSchemaCache(nil)
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	// A schema cached by a previous invocation is read from disk.
+	fields := []*qbclient.ListFieldsOutputField{{FieldID: 7, Field: qbclient.Field{Label: "Cached", Type: qbclient.FieldText}}}
+	(&qbcli.SchemaCache{Dir: dir, TTL: time.Minute}).Set("bqgruir8g", fields)
+
+	m, err := qbcli.GetTableSchemaWithContext(context.Background(), qb, "bqgruir8g")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m[7] == nil || requests != 0 {
+		t.Errorf("got %v fields and %v requests, expected the cached schema", len(m), requests)
+	}
+
+	// Invalidating the schema forces it to be retrieved again.
+	if err := qbcli.InvalidateTableSchema("bqgruir8g"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m, err = qbcli.GetTableSchemaWithContext(context.Background(), qb, "bqgruir8g"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m[6] == nil || requests != 1 {
+		t.Errorf("got %v fields and %v requests, expected the schema to be retrieved", len(m), requests)
+	}
+
+	// The retrieved schema is written to disk for the next invocation.
+	if m, ok := (&qbcli.SchemaCache{Dir: dir, TTL: time.Minute}).Get("bqgruir8g"); !ok || m[6] == nil {
+		t.Error("expected the retrieved schema to be cached on disk")
+	}
+}
+
+func TestRefreshTableSchemaDisabled(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `[{"id":6,"label":"Name","fieldType":"text"}]`)
+	}))
+	defer ts.Close()
+
+	qbcli.SetSchemaCache(&qbcli.SchemaCache{})
+	defer qbcli.SetSchemaCache(nil)
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	if err := qbcli.RefreshTableSchema(context.Background(), qb, "bqgruir8h"); err == nil {
+		t.Error("expected an error refreshing a disabled cache")
+	}
+	if requests != 0 {
+		t.Errorf("got %v requests, expected none", requests)
+	}
+}
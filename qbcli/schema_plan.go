@@ -0,0 +1,1245 @@
+package qbcli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaChange* constants contain the actions in a schema plan.
+const (
+	SchemaChangeCreate = "create"
+	SchemaChangeUpdate = "update"
+	SchemaChangeDelete = "delete"
+)
+
+// SchemaResource* constants contain the resources changed by a schema plan.
+const (
+	SchemaResourceTable        = "table"
+	SchemaResourceField        = "field"
+	SchemaResourceRelationship = "relationship"
+	SchemaResourceVariable     = "variable"
+)
+
+// builtinFieldIDs are the fields Quickbase creates in every table.
+var builtinFieldIDs = map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true}
+
+// builtinFieldLabels are the default labels of the built-in fields, which
+// are used to recognize them in tables that don't exist yet.
+var builtinFieldLabels = map[string]bool{
+	"Date Created":     true,
+	"Date Modified":    true,
+	"Record ID#":       true,
+	"Record Owner":     true,
+	"Last Modified By": true,
+}
+
+// SchemaPlanOptions are the options read through the command line.
+type SchemaPlanOptions struct {
+	AppID        string `validate:"required" cliutil:"option=app-id"`
+	Filepath     string `validate:"required" cliutil:"option=file usage='file containing the desired schema in yaml or json'"`
+	AllowDestroy bool   `cliutil:"option=allow-destroy usage='delete tables, fields, and relationships that are not in the desired schema'"`
+}
+
+// SchemaPlan is the set of changes that make an app match a desired schema.
+// Changes are ordered so that they can be applied in sequence: tables are
+// created before their fields, fields before the relationships that look
+// them up, and everything is deleted last.
+type SchemaPlan struct {
+	AppID    string          `json:"appId"`
+	Changes  []*SchemaChange `json:"changes"`
+	Warnings []string        `json:"warnings,omitempty"`
+
+	state *schemaApplyState
+}
+
+// SchemaChange is a change to a single table, field, relationship, or
+// variable. Skipped changes are deletions that require --allow-destroy.
+type SchemaChange struct {
+	Action   string                `json:"action"`
+	Resource string                `json:"resource"`
+	Table    string                `json:"table,omitempty"`
+	Name     string                `json:"name"`
+	Diff     []*SchemaPropertyDiff `json:"diff,omitempty"`
+	Skipped  bool                  `json:"skipped,omitempty"`
+	Applied  bool                  `json:"applied,omitempty"`
+
+	apply func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error
+}
+
+// SchemaPropertyDiff is a property whose value changes.
+type SchemaPropertyDiff struct {
+	Property string      `json:"property"`
+	Old      interface{} `json:"old"`
+	New      interface{} `json:"new"`
+}
+
+// Count returns the number of changes for an action, excluding skipped ones.
+func (p *SchemaPlan) Count(action string) (n int) {
+	for _, c := range p.Changes {
+		if c.Action == action && !c.Skipped {
+			n++
+		}
+	}
+	return
+}
+
+// Skipped returns the number of skipped changes.
+func (p *SchemaPlan) Skipped() (n int) {
+	for _, c := range p.Changes {
+		if c.Skipped {
+			n++
+		}
+	}
+	return
+}
+
+// Applied returns the number of applied changes.
+func (p *SchemaPlan) Applied() (n int) {
+	for _, c := range p.Changes {
+		if c.Applied {
+			n++
+		}
+	}
+	return
+}
+
+// ReadAppSchema reads a desired schema from a YAML or JSON file.
+func ReadAppSchema(file string) (*AppSchema, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "error reading schema file: %w", err)
+	}
+
+	// JSON is valid YAML, so both formats are parsed using strict settings.
+	schema := &AppSchema{}
+	dec := yaml.NewDecoder(bytes.NewBuffer(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(schema); err != nil && err != io.EOF {
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidSyntax, "schema not valid: %w", err)
+	}
+
+	// Record the properties set in the file, so that zero values turn them
+	// off or clear them instead of being ignored.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err == nil {
+		recordSchemaProperties(&doc, schema)
+	}
+
+	for _, t := range schema.Tables {
+		if t.Name == "" && t.Alias == "" {
+			return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "schema not valid: tables require a name or alias")
+		}
+		for _, f := range t.Fields {
+			if f.Label == "" {
+				return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "schema not valid: fields in table %q require a label", schemaTableKey(t))
+			}
+		}
+		for _, r := range t.Relationships {
+			if r.ParentTable == "" {
+				return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "schema not valid: relationships in table %q require a parent table", schemaTableKey(t))
+			}
+		}
+	}
+
+	return schema, nil
+}
+
+// SchemaPlanFromFile reads the desired schema and plans the changes that
+// make the app match it.
+func SchemaPlanFromFile(ctx context.Context, qb *qbclient.Client, opts *SchemaPlanOptions) (*SchemaPlan, error) {
+	desired, err := ReadAppSchema(opts.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	return PlanAppSchema(ctx, qb, opts.AppID, desired, opts.AllowDestroy)
+}
+
+// PlanAppSchema compares the desired schema against the live app and
+// returns the changes that make the app match it. Nothing is changed.
+//
+// Tables are matched by alias, then by name. Fields are matched by label,
+// then by ID so that renamed fields are updated instead of recreated.
+// Properties that are omitted from the desired schema are left unchanged.
+// Deletions are only planned if allowDestroy is true, otherwise they are
+// reported as skipped.
+func PlanAppSchema(ctx context.Context, qb *qbclient.Client, appID string, desired *AppSchema, allowDestroy bool) (*SchemaPlan, error) {
+	live, err := ExportAppSchema(ctx, qb, appID)
+	if err != nil {
+		return nil, err
+	}
+	return planAppSchema(appID, live, desired, allowDestroy), nil
+}
+
+// schemaPlanner accumulates the changes in a plan.
+type schemaPlanner struct {
+	plan         *SchemaPlan
+	live         *AppSchema
+	desired      *AppSchema
+	allowDestroy bool
+
+	// matched maps desired tables to the live tables they correspond to.
+	matched map[*SchemaTable]*SchemaTable
+}
+
+func planAppSchema(appID string, live, desired *AppSchema, allowDestroy bool) *SchemaPlan {
+	p := &schemaPlanner{
+		plan:         &SchemaPlan{AppID: appID, Changes: []*SchemaChange{}},
+		live:         live,
+		desired:      desired,
+		allowDestroy: allowDestroy,
+		matched:      make(map[*SchemaTable]*SchemaTable, len(desired.Tables)),
+	}
+
+	for _, dt := range desired.Tables {
+		if lt := p.matchTable(dt); lt != nil {
+			p.matched[dt] = lt
+		}
+	}
+	p.plan.state = newSchemaApplyState(appID, live, p.matched)
+
+	p.planTables()
+	p.planFields()
+	p.planRelationships()
+	p.planVariables()
+	p.planDeletes()
+
+	return p.plan
+}
+
+func (p *schemaPlanner) add(c *SchemaChange) {
+	if c.Action == SchemaChangeDelete && !p.allowDestroy {
+		c.Skipped = true
+	}
+	p.plan.Changes = append(p.plan.Changes, c)
+}
+
+func (p *schemaPlanner) warn(format string, a ...interface{}) {
+	p.plan.Warnings = append(p.plan.Warnings, fmt.Sprintf(format, a...))
+}
+
+// matchTable returns the live table matching a desired table by alias or
+// name, or nil if the table doesn't exist.
+func (p *schemaPlanner) matchTable(dt *SchemaTable) *SchemaTable {
+	if dt.Alias != "" {
+		for _, lt := range p.live.Tables {
+			if strings.EqualFold(lt.Alias, dt.Alias) {
+				return lt
+			}
+		}
+	}
+	if dt.Name != "" {
+		for _, lt := range p.live.Tables {
+			if lt.Name == dt.Name {
+				return lt
+			}
+		}
+	}
+	return nil
+}
+
+func (p *schemaPlanner) planTables() {
+	for _, dt := range p.desired.Tables {
+		dt := dt
+		key := schemaTableKey(dt)
+
+		lt, ok := p.matched[dt]
+		if !ok {
+			if dt.Name == "" {
+				p.warn("table %q cannot be created without a name", key)
+				continue
+			}
+			p.add(&SchemaChange{
+				Action:   SchemaChangeCreate,
+				Resource: SchemaResourceTable,
+				Name:     dt.Name,
+				apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+					out, err := qb.CreateTableWithContext(ctx, &qbclient.CreateTableInput{
+						AppID:        s.appID,
+						Name:         dt.Name,
+						Description:  dt.Description,
+						SingularNoun: dt.SingleRecordName,
+						PluralNoun:   dt.PluralRecordName,
+					})
+					if err == nil {
+						s.setTableID(dt, out.TableID)
+					}
+					return err
+				},
+			})
+			continue
+		}
+
+		if dt.KeyFieldID != 0 && dt.KeyFieldID != lt.KeyFieldID {
+			p.warn("table %q: the key field cannot be changed through the API", key)
+		}
+
+		diff := diffSchemaProperties(lt, dt, "TableID", "Alias", "KeyFieldID", "DefaultSortFieldID", "DefaultSortOrder")
+		if len(diff) == 0 {
+			continue
+		}
+
+		// The description is the only property that can be empty.
+		var clear []string
+		if dt.set["description"] && dt.Description == "" {
+			clear = append(clear, "description")
+		}
+
+		tableID := lt.TableID
+		p.add(&SchemaChange{
+			Action:   SchemaChangeUpdate,
+			Resource: SchemaResourceTable,
+			Name:     lt.Name,
+			Diff:     diff,
+			apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+				_, err := qb.UpdateTableWithContext(ctx, &qbclient.UpdateTableInput{
+					AppID:        s.appID,
+					TableID:      tableID,
+					Name:         dt.Name,
+					Description:  dt.Description,
+					SingularNoun: dt.SingleRecordName,
+					PluralNoun:   dt.PluralRecordName,
+					Clear:        clear,
+				})
+				return err
+			},
+		})
+	}
+}
+
+// relationshipLabels returns the labels of the fields in a table that are
+// created by relationships rather than directly, i.e., foreign keys and
+// lookups in child tables and summaries in parent tables.
+func (p *schemaPlanner) relationshipLabels(t *SchemaTable) map[string]bool {
+	labels := make(map[string]bool)
+	for _, r := range t.Relationships {
+		if r.ForeignKeyField != nil {
+			labels[r.ForeignKeyField.Label] = true
+		}
+		for _, f := range r.LookupFields {
+			labels[f.Label] = true
+		}
+	}
+	for _, child := range p.desired.Tables {
+		for _, r := range child.Relationships {
+			if p.isTable(t, r.ParentTable) {
+				for _, f := range r.SummaryFields {
+					labels[f.Label] = true
+				}
+			}
+		}
+	}
+	return labels
+}
+
+// isTable returns true if ref refers to the desired table t.
+func (p *schemaPlanner) isTable(t *SchemaTable, ref string) bool {
+	if ref == "" {
+		return false
+	}
+	if strings.EqualFold(ref, t.Alias) || ref == t.Name {
+		return true
+	}
+	lt, ok := p.matched[t]
+	return ok && ref == lt.TableID
+}
+
+func (p *schemaPlanner) planFields() {
+	for _, dt := range p.desired.Tables {
+		dt := dt
+		key := schemaTableKey(dt)
+		lt := p.matched[dt]
+		derived := p.relationshipLabels(dt)
+
+		for _, df := range dt.Fields {
+			df := df
+			if derived[df.Label] {
+				continue
+			}
+
+			var lf *SchemaField
+			if lt != nil {
+				lf = p.matchField(lt.Fields, df)
+			}
+
+			if lf == nil {
+				if builtinFieldIDs[df.FieldID] || builtinFieldLabels[df.Label] {
+					continue
+				}
+				if df.Type == "" {
+					p.warn("field %q in table %q cannot be created without a type", df.Label, key)
+					continue
+				}
+				p.add(&SchemaChange{
+					Action:   SchemaChangeCreate,
+					Resource: SchemaResourceField,
+					Table:    key,
+					Name:     df.Label,
+					apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+						tableID := s.tableID(dt)
+						field, props := newSchemaFieldInput(df)
+						field.Create = true
+						_, err := qb.CreateFieldWithContext(ctx, &qbclient.CreateFieldInput{
+							Field:      field,
+							TableID:    tableID,
+							Properties: &qbclient.CreateFieldInputProperties{FieldProperties: props},
+						})
+						s.invalidate(tableID)
+						return err
+					},
+				})
+				continue
+			}
+
+			if df.Type != "" && df.Type != lf.Type {
+				p.warn("field %q in table %q: the type cannot be changed from %s to %s", lf.Label, key, lf.Type, df.Type)
+			}
+
+			// Relationship properties are managed by relationships.
+			diff := diffSchemaProperties(lf, df, "FieldID", "Type", "ForeignKey", "PrimaryKey", "ParentTable", "RelatedField")
+			if len(diff) == 0 {
+				continue
+			}
+
+			field, props := newSchemaFieldUpdate(mergeSchemaField(lf, df))
+			lfield, lprops := newSchemaFieldUpdate(lf)
+			clear := append(clearedSchemaProperties(&lfield, &field), clearedSchemaProperties(&lprops, &props)...)
+
+			fieldID := lf.FieldID
+			p.add(&SchemaChange{
+				Action:   SchemaChangeUpdate,
+				Resource: SchemaResourceField,
+				Table:    key,
+				Name:     lf.Label,
+				Diff:     diff,
+				apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+					tableID := s.tableID(dt)
+					_, err := qb.UpdateFieldWithContext(ctx, &qbclient.UpdateFieldInput{
+						Field:      field,
+						TableID:    tableID,
+						FieldID:    fieldID,
+						Properties: &qbclient.UpdateFieldInputProperties{FieldProperties: props},
+						Clear:      clear,
+					})
+					s.invalidate(tableID)
+					return err
+				},
+			})
+		}
+	}
+}
+
+// matchField returns the live field matching df by label. Field IDs are
+// assigned per app, so fields are only matched by ID if the desired schema
+// was exported from the app being planned, e.g., after a field is relabeled.
+func (p *schemaPlanner) matchField(fields []*SchemaField, df *SchemaField) *SchemaField {
+	for _, f := range fields {
+		if f.Label == df.Label {
+			return f
+		}
+	}
+	if df.FieldID != 0 && p.desired.AppID != "" && p.desired.AppID == p.plan.AppID {
+		for _, f := range fields {
+			if f.FieldID == df.FieldID {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// matchSchemaRelationship returns the relationship in the live table with
+// the same parent and, if set, the same foreign key label.
+func (p *schemaPlanner) matchSchemaRelationship(lt *SchemaTable, dr *SchemaRelationship) *SchemaRelationship {
+	for _, lr := range lt.Relationships {
+		if !strings.EqualFold(lr.ParentTable, p.liveTableRef(dr.ParentTable)) {
+			continue
+		}
+		if dr.ForeignKeyField == nil || lr.ForeignKeyField == nil || lr.ForeignKeyField.Label == dr.ForeignKeyField.Label {
+			return lr
+		}
+	}
+	return nil
+}
+
+// liveTableRef returns how the live schema refers to the table that ref
+// refers to in the desired schema, i.e., its alias or table ID.
+func (p *schemaPlanner) liveTableRef(ref string) string {
+	for _, dt := range p.desired.Tables {
+		if !p.isTable(dt, ref) {
+			continue
+		}
+		if lt, ok := p.matched[dt]; ok {
+			if lt.Alias != "" {
+				return lt.Alias
+			}
+			return lt.TableID
+		}
+	}
+	return ref
+}
+
+func (p *schemaPlanner) planRelationships() {
+	for _, dt := range p.desired.Tables {
+		dt := dt
+		key := schemaTableKey(dt)
+		lt := p.matched[dt]
+
+		for _, dr := range dt.Relationships {
+			dr := dr
+
+			var lr *SchemaRelationship
+			if lt != nil {
+				lr = p.matchSchemaRelationship(lt, dr)
+			}
+
+			lookups := p.missingLookups(key, dr, lr)
+			summaries := p.missingSummaries(key, dr, lr)
+
+			if lr == nil {
+				p.add(&SchemaChange{
+					Action:   SchemaChangeCreate,
+					Resource: SchemaResourceRelationship,
+					Table:    key,
+					Name:     dr.ParentTable,
+					apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+						childID, parentID := s.tableID(dt), s.tableRef(dr.ParentTable)
+						lfids, sfs, err := resolveRelationshipFields(ctx, qb, childID, parentID, lookups, summaries)
+						if err != nil {
+							return err
+						}
+
+						in := &qbclient.CreateRelationshipInput{
+							ChildTableID:   childID,
+							ParentTableID:  parentID,
+							LookupFieldIDs: lfids,
+							SummaryFields:  sfs,
+						}
+						if dr.ForeignKeyField != nil {
+							in.ForeignKeyField = &qbclient.CreateRelationshipInputForeignKeyField{Label: dr.ForeignKeyField.Label}
+						}
+
+						_, err = qb.CreateRelationshipWithContext(ctx, in)
+						s.invalidate(childID, parentID)
+						return err
+					},
+				})
+				continue
+			}
+
+			if len(lookups) == 0 && len(summaries) == 0 {
+				continue
+			}
+
+			diff := []*SchemaPropertyDiff{}
+			for _, f := range lookups {
+				diff = append(diff, &SchemaPropertyDiff{Property: "lookup_fields", New: f.Label})
+			}
+			for _, f := range summaries {
+				diff = append(diff, &SchemaPropertyDiff{Property: "summary_fields", New: f.Label})
+			}
+
+			relationshipID := lr.RelationshipID
+			p.add(&SchemaChange{
+				Action:   SchemaChangeUpdate,
+				Resource: SchemaResourceRelationship,
+				Table:    key,
+				Name:     dr.ParentTable,
+				Diff:     diff,
+				apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+					childID, parentID := s.tableID(dt), s.tableRef(dr.ParentTable)
+					lfids, sfs, err := resolveRelationshipFields(ctx, qb, childID, parentID, lookups, summaries)
+					if err != nil {
+						return err
+					}
+
+					_, err = qb.UpdateRelationshipWithContext(ctx, &qbclient.UpdateRelationshipInput{
+						ChildTableID:   childID,
+						RelationshipID: relationshipID,
+						LookupFieldIDs: lfids,
+						SummaryFields:  sfs,
+					})
+					s.invalidate(childID, parentID)
+					return err
+				},
+			})
+		}
+	}
+}
+
+// missingLookups returns the lookup fields in the desired relationship that
+// don't exist in the live one. Lookups can only be created if the field in
+// the parent table they look up is known.
+func (p *schemaPlanner) missingLookups(table string, dr, lr *SchemaRelationship) (missing []*SchemaRelationshipField) {
+	for _, f := range dr.LookupFields {
+		if lr != nil && hasSchemaRelationshipField(lr.LookupFields, f.Label) {
+			continue
+		}
+		if f.ParentField == "" {
+			p.warn("lookup field %q in table %q cannot be created without a parent_field", f.Label, table)
+			continue
+		}
+		missing = append(missing, f)
+	}
+	return
+}
+
+// missingSummaries returns the summary fields in the desired relationship
+// that don't exist in the live one. Summaries can only be created if the
+// field in the child table they summarize and the accumulation type are known.
+func (p *schemaPlanner) missingSummaries(table string, dr, lr *SchemaRelationship) (missing []*SchemaRelationshipField) {
+	for _, f := range dr.SummaryFields {
+		if lr != nil && hasSchemaRelationshipField(lr.SummaryFields, f.Label) {
+			continue
+		}
+		if f.AccumulationType == "" {
+			p.warn("summary field %q in table %q cannot be created without an accumulation_type", f.Label, table)
+			continue
+		}
+		missing = append(missing, f)
+	}
+	return
+}
+
+func hasSchemaRelationshipField(fields []*SchemaRelationshipField, label string) bool {
+	for _, f := range fields {
+		if f.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRelationshipFields looks up the IDs of the parent fields that are
+// looked up and the child fields that are summarized. They are resolved when
+// the change is applied because the fields might have been created by an
+// earlier change in the plan.
+func resolveRelationshipFields(
+	ctx context.Context,
+	qb *qbclient.Client,
+	childID, parentID string,
+	lookups, summaries []*SchemaRelationshipField,
+) (lfids []int, sfs []*qbclient.RelationshipSummaryField, err error) {
+	if len(lookups) > 0 {
+		var fids map[string]int
+		if fids, err = fieldIDsByLabel(ctx, qb, parentID); err != nil {
+			return
+		}
+		for _, f := range lookups {
+			fid, ok := fids[f.ParentField]
+			if !ok {
+				err = fmt.Errorf("lookup field %q: field %q not in parent table", f.Label, f.ParentField)
+				return
+			}
+			lfids = append(lfids, fid)
+		}
+	}
+
+	if len(summaries) > 0 {
+		var fids map[string]int
+		if fids, err = fieldIDsByLabel(ctx, qb, childID); err != nil {
+			return
+		}
+		for _, f := range summaries {
+			sf := &qbclient.RelationshipSummaryField{
+				Label:            f.Label,
+				AccumulationType: f.AccumulationType,
+				Where:            f.Where,
+			}
+			if f.SummaryField != "" {
+				fid, ok := fids[f.SummaryField]
+				if !ok {
+					err = fmt.Errorf("summary field %q: field %q not in child table", f.Label, f.SummaryField)
+					return
+				}
+				sf.SummaryFieldID = fid
+			}
+			sfs = append(sfs, sf)
+		}
+	}
+
+	return
+}
+
+func fieldIDsByLabel(ctx context.Context, qb *qbclient.Client, tableID string) (map[string]int, error) {
+	out, err := qb.ListFieldsByTableIDWithContext(ctx, tableID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing fields in table %s: %w", tableID, err)
+	}
+	fids := make(map[string]int, len(out.Fields))
+	for _, f := range out.Fields {
+		fids[f.Label] = f.FieldID
+	}
+	return fids, nil
+}
+
+func (p *schemaPlanner) planVariables() {
+	names := make([]string, 0, len(p.desired.Variables))
+	for name := range p.desired.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		name, value := name, p.desired.Variables[name]
+		old, ok := p.live.Variables[name]
+		if ok && old == value {
+			continue
+		}
+
+		c := &SchemaChange{
+			Action:   SchemaChangeCreate,
+			Resource: SchemaResourceVariable,
+			Name:     name,
+			Diff:     []*SchemaPropertyDiff{{Property: "value", New: value}},
+			apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+				_, err := qb.SetVariableWithContext(ctx, &qbclient.SetVariableInput{AppID: s.appID, Name: name, Value: value})
+				return err
+			},
+		}
+		if ok {
+			c.Action = SchemaChangeUpdate
+			c.Diff[0].Old = old
+		}
+		p.add(c)
+	}
+}
+
+// planDeletes plans deleting the relationships, fields, and tables that are
+// not in the desired schema. Built-in fields and fields created by desired
+// relationships are never deleted. Variables cannot be deleted through the
+// API, so they are left as is.
+func (p *schemaPlanner) planDeletes() {
+	kept := make(map[*SchemaTable]bool, len(p.matched))
+
+	for _, dt := range p.desired.Tables {
+		lt, ok := p.matched[dt]
+		if !ok {
+			continue
+		}
+		kept[lt] = true
+		key := schemaTableKey(dt)
+
+		for _, lr := range lt.Relationships {
+			lr := lr
+			found := false
+			for _, dr := range dt.Relationships {
+				if p.matchSchemaRelationship(lt, dr) == lr {
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+			p.add(&SchemaChange{
+				Action:   SchemaChangeDelete,
+				Resource: SchemaResourceRelationship,
+				Table:    key,
+				Name:     lr.ParentTable,
+				apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+					_, err := qb.DeleteRelationshipWithContext(ctx, &qbclient.DeleteRelationshipInput{
+						ChildTableID:   lt.TableID,
+						RelationshipID: lr.RelationshipID,
+					})
+					s.invalidate(lt.TableID)
+					return err
+				},
+			})
+		}
+
+		derived := p.relationshipLabels(dt)
+		for _, lf := range lt.Fields {
+			lf := lf
+			if builtinFieldIDs[lf.FieldID] || derived[lf.Label] {
+				continue
+			}
+			found := false
+			for _, df := range dt.Fields {
+				if p.matchField(lt.Fields, df) == lf {
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+			p.add(&SchemaChange{
+				Action:   SchemaChangeDelete,
+				Resource: SchemaResourceField,
+				Table:    key,
+				Name:     lf.Label,
+				apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+					_, err := qb.DeleteFieldsWithContext(ctx, &qbclient.DeleteFieldsInput{
+						TableID:  lt.TableID,
+						FieldIDs: []int{lf.FieldID},
+					})
+					s.invalidate(lt.TableID)
+					return err
+				},
+			})
+		}
+	}
+
+	for _, lt := range p.live.Tables {
+		lt := lt
+		if kept[lt] {
+			continue
+		}
+		p.add(&SchemaChange{
+			Action:   SchemaChangeDelete,
+			Resource: SchemaResourceTable,
+			Name:     lt.Name,
+			apply: func(ctx context.Context, qb *qbclient.Client, s *schemaApplyState) error {
+				_, err := qb.DeleteTableWithContext(ctx, &qbclient.DeleteTableInput{AppID: s.appID, TableID: lt.TableID})
+				s.invalidate(lt.TableID)
+				return err
+			},
+		})
+	}
+}
+
+// schemaTableKey returns how a table is referred to in a plan.
+func schemaTableKey(t *SchemaTable) string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Name
+}
+
+// diffSchemaProperties returns the scalar and list properties set in desired
+// whose values differ from live. Properties that are not set are not managed,
+// so they are never part of the diff.
+func diffSchemaProperties(live, desired interface{}, skip ...string) []*SchemaPropertyDiff {
+	return compareSchemaProperties(live, desired, true, skip...)
+}
+
+// compareSchemaProperties returns the scalar properties and lists of strings
+// whose values differ between a and b, which must be pointers to the same type
+// of struct. If onlySet is true, properties that are not set in b are ignored.
+func compareSchemaProperties(a, b interface{}, onlySet bool, skip ...string) []*SchemaPropertyDiff {
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	lv, dv := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	rt := dv.Type()
+	set := setSchemaProperties(b)
+
+	var diff []*SchemaPropertyDiff
+	for idx := 0; idx < rt.NumField(); idx++ {
+		sf := rt.Field(idx)
+		if skipped[sf.Name] || sf.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		d, l := dv.Field(idx), lv.Field(idx)
+		if onlySet && !set.isSet(name, d) {
+			continue
+		}
+
+		if d.Kind() == reflect.Ptr {
+			if d.IsNil() && l.IsNil() {
+				continue
+			}
+			d, l = schemaPropertyElem(d, l), schemaPropertyElem(l, d)
+		}
+
+		switch d.Kind() {
+		case reflect.Bool, reflect.Int, reflect.String:
+			if d.Interface() == l.Interface() {
+				continue
+			}
+		case reflect.Slice:
+			ds, ok := d.Interface().([]string)
+			ls, _ := l.Interface().([]string)
+			if !ok || (len(ds) == 0 && len(ls) == 0) || reflect.DeepEqual(ds, ls) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		diff = append(diff, &SchemaPropertyDiff{
+			Property: name,
+			Old:      l.Interface(),
+			New:      d.Interface(),
+		})
+	}
+
+	return diff
+}
+
+// schemaPropertyElem dereferences a pointer property, returning the zero value
+// of the type other points to if it is nil.
+func schemaPropertyElem(v, other reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return reflect.Zero(other.Type().Elem())
+	}
+	return v.Elem()
+}
+
+// schemaProperties contains the names of the properties set in a desired
+// schema. Zero values can't be told apart from omitted properties once a
+// schema is decoded, so ReadAppSchema records which keys were present.
+type schemaProperties map[string]bool
+
+// isSet returns whether the property with value v is set. Nothing is recorded
+// for schemas that weren't read from a file, so their properties are set if
+// they aren't zero values.
+func (p schemaProperties) isSet(name string, v reflect.Value) bool {
+	if p == nil {
+		return !v.IsZero()
+	}
+	return p[name]
+}
+
+// setSchemaProperties returns the properties recorded for a table or field.
+func setSchemaProperties(v interface{}) schemaProperties {
+	switch v := v.(type) {
+	case *SchemaTable:
+		return v.set
+	case *SchemaField:
+		return v.set
+	}
+	return nil
+}
+
+// recordSchemaProperties records the properties set in the tables and fields
+// of a schema decoded from doc.
+func recordSchemaProperties(doc *yaml.Node, schema *AppSchema) {
+	tables := yamlSequence(yamlMapping(doc)["tables"])
+	for i, t := range schema.Tables {
+		if i >= len(tables) {
+			return
+		}
+		tm := yamlMapping(tables[i])
+		t.set = yamlKeys(tm)
+
+		fields := yamlSequence(tm["fields"])
+		for j, f := range t.Fields {
+			if j < len(fields) {
+				f.set = yamlKeys(yamlMapping(fields[j]))
+			}
+		}
+	}
+}
+
+// yamlMapping returns the values of a mapping node by key, including the ones
+// merged with "<<". Documents and aliases are resolved to their content.
+func yamlMapping(n *yaml.Node) map[string]*yaml.Node {
+	m := make(map[string]*yaml.Node)
+	n = yamlResolve(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return m
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i].Value, n.Content[i+1]
+		if key != "<<" {
+			m[key] = value
+			continue
+		}
+
+		merged := []*yaml.Node{value}
+		if v := yamlResolve(value); v != nil && v.Kind == yaml.SequenceNode {
+			merged = v.Content
+		}
+		for _, mn := range merged {
+			for k, v := range yamlMapping(mn) {
+				if _, ok := m[k]; !ok {
+					m[k] = v
+				}
+			}
+		}
+	}
+	return m
+}
+
+// yamlSequence returns the items of a sequence node.
+func yamlSequence(n *yaml.Node) []*yaml.Node {
+	if n = yamlResolve(n); n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// yamlResolve resolves documents and aliases to the node they contain.
+func yamlResolve(n *yaml.Node) *yaml.Node {
+	for n != nil {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			if len(n.Content) == 0 {
+				return nil
+			}
+			n = n.Content[0]
+		case yaml.AliasNode:
+			n = n.Alias
+		default:
+			return n
+		}
+	}
+	return nil
+}
+
+// yamlKeys returns the keys of a mapping.
+func yamlKeys(m map[string]*yaml.Node) schemaProperties {
+	keys := make(schemaProperties, len(m))
+	for k := range m {
+		keys[k] = true
+	}
+	return keys
+}
+
+// mergeSchemaField returns a copy of live with the properties set in desired.
+// Updates are sent with every property because the API resets searchable
+// and add to reports if they are omitted.
+func mergeSchemaField(live, desired *SchemaField) *SchemaField {
+	merged := *live
+	mv, dv := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(desired).Elem()
+	for idx := 0; idx < dv.NumField(); idx++ {
+		sf := dv.Type().Field(idx)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if d := dv.Field(idx); desired.set.isSet(name, d) {
+			mv.Field(idx).Set(d)
+		}
+	}
+	return &merged
+}
+
+// clearedSchemaProperties returns the JSON names of the properties that are
+// set in live but zero values in desired, which must be pointers to the same
+// type of struct. They have to be cleared explicitly, because zero values are
+// omitted from update requests.
+func clearedSchemaProperties(live, desired interface{}) []string {
+	var cleared []string
+	lv, dv := reflect.ValueOf(live).Elem(), reflect.ValueOf(desired).Elem()
+	for idx := 0; idx < dv.NumField(); idx++ {
+		d, l := dv.Field(idx), lv.Field(idx)
+		if schemaPropertyEmpty(d) && !schemaPropertyEmpty(l) {
+			cleared = append(cleared, strings.Split(dv.Type().Field(idx).Tag.Get("json"), ",")[0])
+		}
+	}
+	return cleared
+}
+
+// schemaPropertyEmpty returns true if v is a zero value or an empty list.
+func schemaPropertyEmpty(v reflect.Value) bool {
+	if v.Kind() == reflect.Slice {
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// newSchemaFieldInput converts a field in a schema to API input. Fields are
+// searchable and added to new reports unless the schema says otherwise.
+func newSchemaFieldInput(f *SchemaField) (qbclient.Field, qbclient.FieldProperties) {
+	field := qbclient.Field{
+		Label:                  f.Label,
+		Type:                   f.Type,
+		Required:               f.Required,
+		Unique:                 f.Unique,
+		DisplayInBold:          f.Bold,
+		DisplayWithoutWrapping: f.NoWrap,
+		AutoFill:               f.AutoFill,
+		Searchable:             f.Searchable == nil || *f.Searchable,
+		AddToNewReports:        f.AddToReports == nil || *f.AddToReports,
+		FieldHelpText:          f.HelpText,
+		TrackField:             f.TrackField,
+	}
+
+	props := qbclient.FieldProperties{
+		DefaultValue:       f.DefaultValue,
+		Choices:            f.Choices,
+		AllowNewChoices:    f.AllowNewChoices,
+		SortChoicesAsGiven: f.SortChoicesAsGiven,
+		NumberOfLines:      f.NumberOfLines,
+		MaxCharacters:      f.MaxCharacters,
+		WidthOfInputBox:    f.WidthOfInputBox,
+		ExactMatch:         f.ExactMatch,
+		ForeignKey:         f.ForeignKey,
+		Formula:            f.Formula,
+		ParentTable:        f.ParentTable,
+		PrimaryKey:         f.PrimaryKey,
+		RelatedField:       f.RelatedField,
+		Comments:           f.Comments,
+	}
+
+	return field, props
+}
+
+// newSchemaFieldUpdate converts a field in a schema to API input for updating
+// the field. The type can't be changed, and relationship properties are
+// managed by relationships.
+func newSchemaFieldUpdate(f *SchemaField) (qbclient.Field, qbclient.FieldProperties) {
+	field, props := newSchemaFieldInput(f)
+	field.Type = ""
+	props.ForeignKey, props.PrimaryKey, props.ParentTable, props.RelatedField = false, false, "", 0
+	return field, props
+}
+
+// schemaApplyState tracks the IDs of tables as a plan is applied, so that
+// changes can refer to tables created by earlier changes.
+type schemaApplyState struct {
+	appID    string
+	tableIDs map[string]string
+}
+
+func newSchemaApplyState(appID string, live *AppSchema, matched map[*SchemaTable]*SchemaTable) *schemaApplyState {
+	s := &schemaApplyState{appID: appID, tableIDs: make(map[string]string)}
+	for _, lt := range live.Tables {
+		if lt.Alias != "" {
+			s.tableIDs[strings.ToUpper(lt.Alias)] = lt.TableID
+		}
+	}
+	for dt, lt := range matched {
+		s.setTableID(dt, lt.TableID)
+	}
+	return s
+}
+
+func (s *schemaApplyState) setTableID(t *SchemaTable, tableID string) {
+	if t.Alias != "" {
+		s.tableIDs[strings.ToUpper(t.Alias)] = tableID
+	}
+	if t.Name != "" {
+		s.tableIDs[t.Name] = tableID
+	}
+}
+
+// tableID returns the ID of a desired table.
+func (s *schemaApplyState) tableID(t *SchemaTable) string {
+	if t.Alias != "" {
+		if id, ok := s.tableIDs[strings.ToUpper(t.Alias)]; ok {
+			return id
+		}
+	}
+	return s.tableIDs[t.Name]
+}
+
+// tableRef returns the ID of the table referred to by alias or name,
+// assuming that anything else is a table ID, e.g., a table in another app.
+func (s *schemaApplyState) tableRef(ref string) string {
+	if id, ok := s.tableIDs[strings.ToUpper(ref)]; ok {
+		return id
+	}
+	if id, ok := s.tableIDs[ref]; ok {
+		return id
+	}
+	return ref
+}
+
+// invalidate removes changed tables from the schema cache. A stale schema is
+// refreshed when the cache expires, so errors are ignored.
+func (s *schemaApplyState) invalidate(tableIDs ...string) {
+	InvalidateTableSchema(tableIDs...)
+}
+
+// SchemaApply plans the changes that make the app match the desired schema
+// and applies them.
+//
+// Dry-run mode isn't supported, because later changes depend on the IDs of the
+// tables and fields created by earlier ones. Use SchemaPlanFromFile instead.
+func SchemaApply(ctx context.Context, qb *qbclient.Client, opts *SchemaPlanOptions) (*SchemaPlan, error) {
+	if qb.DryRun {
+		return nil, qberrors.Client(nil).Safef(qberrors.InvalidInput, "dry-run mode is not supported when applying a schema, use app schema plan to preview the changes")
+	}
+
+	plan, err := SchemaPlanFromFile(ctx, qb, opts)
+	if err != nil {
+		return nil, err
+	}
+	return plan, ApplySchemaPlan(ctx, qb, plan)
+}
+
+// ApplySchemaPlan applies the changes in a plan in order, stopping at the
+// first error. Changes that were applied are marked as such.
+func ApplySchemaPlan(ctx context.Context, qb *qbclient.Client, plan *SchemaPlan) error {
+	for _, c := range plan.Changes {
+		if c.Skipped {
+			continue
+		}
+		if err := c.apply(ctx, qb, plan.state); err != nil {
+			return fmt.Errorf("error applying change to %s %q: %w", c.Resource, c.Name, err)
+		}
+		c.Applied = true
+	}
+	return nil
+}
+
+// WriteSchemaPlan writes a human readable description of a plan to w.
+func WriteSchemaPlan(w io.Writer, plan *SchemaPlan) error {
+	var buf bytes.Buffer
+
+	for _, c := range plan.Changes {
+		symbol := map[string]string{
+			SchemaChangeCreate: "+",
+			SchemaChangeUpdate: "~",
+			SchemaChangeDelete: "-",
+		}[c.Action]
+
+		name := fmt.Sprintf("%q", c.Name)
+		if c.Table != "" {
+			name = fmt.Sprintf("%q in table %q", c.Name, c.Table)
+		}
+
+		fmt.Fprintf(&buf, "%s %s %s %s", symbol, c.Action, c.Resource, name)
+		if c.Skipped {
+			buf.WriteString(" (skipped, pass --allow-destroy to delete)")
+		}
+		buf.WriteString("\n")
+
+		writeSchemaPropertyDiffs(&buf, c.Diff)
+	}
+
+	for _, warning := range plan.Warnings {
+		fmt.Fprintf(&buf, "! %s\n", warning)
+	}
+
+	switch {
+	case len(plan.Changes) == 0:
+		buf.WriteString("No changes. The app matches the desired schema.\n")
+	case plan.Applied() > 0:
+		fmt.Fprintf(&buf, "\nApplied %d of %d changes, %d skipped.\n",
+			plan.Applied(), len(plan.Changes)-plan.Skipped(), plan.Skipped())
+	default:
+		fmt.Fprintf(&buf, "\nPlan: %d to create, %d to update, %d to delete, %d skipped.\n",
+			plan.Count(SchemaChangeCreate), plan.Count(SchemaChangeUpdate), plan.Count(SchemaChangeDelete), plan.Skipped())
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeSchemaPropertyDiffs writes the old and new values of properties.
+func writeSchemaPropertyDiffs(buf *bytes.Buffer, diff []*SchemaPropertyDiff) {
+	for _, d := range diff {
+		if d.Old == nil {
+			fmt.Fprintf(buf, "    %s: %s\n", d.Property, formatSchemaValue(d.New))
+		} else {
+			fmt.Fprintf(buf, "    %s: %s => %s\n", d.Property, formatSchemaValue(d.Old), formatSchemaValue(d.New))
+		}
+	}
+}
+
+func formatSchemaValue(v interface{}) string {
+	switch v.(type) {
+	case string, []string:
+		return fmt.Sprintf("%q", v)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,235 @@
+package qbcli_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+const desiredSchema = `
+name: Projects
+variables:
+  a: "1"
+  c: "3"
+tables:
+  - name: Projects
+    alias: _DBID_PROJECTS
+    description: All projects
+    fields:
+      - label: Record ID#
+        type: recordid
+      - label: Name
+        type: text
+      - label: Stage
+        type: text-multiple-choice
+        choices: [Planned, Active]
+  - name: Tasks
+    alias: _DBID_TASKS
+    description: ""
+    fields:
+      - label: Name
+        type: text
+        required: false
+        help_text: What needs to be done
+      - label: Due
+        type: date
+    relationships:
+      - parent_table: _DBID_PROJECTS
+        foreign_key_field:
+          label: Related Project
+        lookup_fields:
+          - label: Project - Name
+          - label: Project - Owner
+          - label: Project - Title
+            parent_field: Name
+        summary_fields:
+          - label: Total Days Left
+  - name: Milestones
+    fields:
+      - label: Record ID#
+        type: recordid
+      - label: Title
+        type: text
+`
+
+func readDesiredSchema(t *testing.T) *qbcli.AppSchema {
+	f, err := ioutil.TempFile("", "schema.*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(desiredSchema)
+	f.Close()
+
+	schema, err := qbcli.ReadAppSchema(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return schema
+}
+
+func TestPlanAppSchema(t *testing.T) {
+	ts := newSchemaServer(t)
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	plan, err := qbcli.PlanAppSchema(context.Background(), qb, "bqgruir3g", readDesiredSchema(t), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	changes := []string{}
+	for _, c := range plan.Changes {
+		changes = append(changes, fmt.Sprintf("%s %s %s/%s skipped=%v", c.Action, c.Resource, c.Table, c.Name, c.Skipped))
+	}
+
+	expected := []string{
+		"update table /Projects skipped=false",
+		"update table /Tasks skipped=false",
+		"create table /Milestones skipped=false",
+		"update field _DBID_TASKS/Name skipped=false",
+		"create field _DBID_TASKS/Due skipped=false",
+		"create field Milestones/Title skipped=false",
+		"update relationship _DBID_TASKS/_DBID_PROJECTS skipped=false",
+		"create variable /c skipped=false",
+		"delete field _DBID_TASKS/Days Left skipped=true",
+	}
+	if strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got changes:\n%s\nexpected:\n%s", strings.Join(changes, "\n"), strings.Join(expected, "\n"))
+	}
+
+	var buf bytes.Buffer
+	qbcli.WriteSchemaPlan(&buf, plan)
+	for _, line := range []string{
+		`    description: "" => "All projects"`,
+		`    description: "Things to do" => ""`,
+		`    required: true => false`,
+		`- delete field "Days Left" in table "_DBID_TASKS" (skipped, pass --allow-destroy to delete)`,
+		`Plan: 4 to create, 4 to update, 0 to delete, 1 skipped.`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected plan to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+
+	plan, _ = qbcli.PlanAppSchema(context.Background(), qb, "bqgruir3g", readDesiredSchema(t), true)
+	if last := plan.Changes[len(plan.Changes)-1]; last.Skipped {
+		t.Error("expected deletes not to be skipped when destroy is allowed")
+	}
+}
+
+func TestPlanAppSchemaFieldIDs(t *testing.T) {
+	ts := newSchemaServer(t)
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	// Field 8 is "Days Left" in the live Tasks table.
+	plan := func(appID string) string {
+		desired := readDesiredSchema(t)
+		desired.AppID = appID
+		desired.Tables[1].Fields = append(desired.Tables[1].Fields, &qbcli.SchemaField{FieldID: 8, Label: "Remaining", Type: "numeric"})
+
+		plan, err := qbcli.PlanAppSchema(context.Background(), qb, "bqgruir3g", desired, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		changes := []string{}
+		for _, c := range plan.Changes {
+			if c.Resource == qbcli.SchemaResourceField {
+				changes = append(changes, fmt.Sprintf("%s %s", c.Action, c.Name))
+			}
+		}
+		return strings.Join(changes, ", ")
+	}
+
+	// Field IDs from another app don't match unrelated live fields.
+	if have, want := plan("bqgruir4h"), "update Name, create Due, create Remaining, create Title, delete Days Left"; have != want {
+		t.Errorf("got %q, expected %q", have, want)
+	}
+
+	// Field IDs from the same app match relabeled fields.
+	if have, want := plan("bqgruir3g"), "update Name, create Due, update Days Left, create Title"; have != want {
+		t.Errorf("got %q, expected %q", have, want)
+	}
+}
+
+func TestApplySchemaPlan(t *testing.T) {
+	schema := newSchemaHandler(t)
+	requests := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			schema(w, r)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.String()+" "+strings.TrimSpace(string(body)))
+
+		switch {
+		case r.URL.Path == "/tables":
+			fmt.Fprint(w, `{"id":"bqgruir9m","name":"Milestones"}`)
+		case r.URL.Path == "/fields" || strings.HasPrefix(r.URL.Path, "/fields/"):
+			fmt.Fprint(w, `{"id":6}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	// Variables are set through the XML API, which isn't faked.
+	desired := readDesiredSchema(t)
+	delete(desired.Variables, "c")
+
+	plan, err := qbcli.PlanAppSchema(context.Background(), qb, "bqgruir3g", desired, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := qbcli.ApplySchemaPlan(context.Background(), qb, plan); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		`POST /tables/bqgruir8a?appId=bqgruir3g {"name":"Projects","description":"All projects"}`,
+		`POST /tables/bqgruir7z?appId=bqgruir3g {"description":"","name":"Tasks"}`,
+		`POST /tables?appId=bqgruir3g {"name":"Milestones","description":"","iconName":"","singularNoun":"","pluralNoun":""}`,
+		`POST /fields/6?tableId=bqgruir7z {"appearsByDefault":true,"fieldHelp":"What needs to be done","findEnabled":true,"label":"Name","properties":{},"required":false}`,
+		`POST /fields?tableId=bqgruir7z {"label":"Due","fieldType":"date","findEnabled":true,"appearsByDefault":true,"properties":{}}`,
+		`POST /fields?tableId=bqgruir9m {"label":"Title","fieldType":"text","findEnabled":true,"appearsByDefault":true,"properties":{}}`,
+		`POST /tables/bqgruir7z/relationship/7 {"lookupFieldIds":[6]}`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got requests:\n%s\nexpected:\n%s", strings.Join(requests, "\n"), strings.Join(expected, "\n"))
+	}
+	if plan.Applied() != len(expected) {
+		t.Errorf("got %v applied changes, expected %v", plan.Applied(), len(expected))
+	}
+
+	// Applying a schema in dry-run mode is rejected before any request is
+	// made, since later changes need the IDs of the tables and fields created.
+	requests = requests[:0]
+	qb.DryRun = true
+	if _, err := qbcli.SchemaApply(context.Background(), qb, &qbcli.SchemaPlanOptions{}); err == nil || !strings.Contains(err.Error(), "app schema plan") {
+		t.Errorf("got error %v, expected dry-run mode to be rejected", err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("got requests %v, expected none", requests)
+	}
+}
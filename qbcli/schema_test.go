@@ -0,0 +1,151 @@
+package qbcli_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbcli"
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+// newSchemaServer returns a fake API for an app with a projects table and a
+// tasks table related to it. Lists are returned in random order.
+func newSchemaServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(newSchemaHandler(t))
+}
+
+func newSchemaHandler(t *testing.T) http.HandlerFunc {
+	shuffle := func(items ...string) string {
+		rand.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+		return "[" + strings.Join(items, ",") + "]"
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		tableID := r.URL.Query().Get("tableId")
+		switch {
+		case r.URL.Path == "/apps/bqgruir3g":
+			fmt.Fprint(w, `{"id":"bqgruir3g","name":"Projects","description":"Tracks projects",`)
+			fmt.Fprint(w, `"updated":"2021-01-02T03:04:05Z","variables":[{"name":"b","value":"2"},{"name":"a","value":"1"}]}`)
+
+		case r.URL.Path == "/tables":
+			fmt.Fprint(w, shuffle(
+				`{"id":"bqgruir7z","name":"Tasks","alias":"_DBID_TASKS","description":"Things to do","nextRecordId":50,"keyFieldId":3}`,
+				`{"id":"bqgruir8a","name":"Projects","alias":"_DBID_PROJECTS","nextRecordId":10,"keyFieldId":3}`,
+			))
+
+		case r.URL.Path == "/fields" && tableID == "bqgruir7z":
+			fmt.Fprint(w, shuffle(
+				`{"id":3,"label":"Record ID#","fieldType":"recordid","findEnabled":true,"appearsByDefault":true}`,
+				`{"id":6,"label":"Name","fieldType":"text","required":true,"findEnabled":true,"appearsByDefault":true}`,
+				`{"id":7,"label":"Related Project","fieldType":"numeric","findEnabled":true,"appearsByDefault":true}`,
+				`{"id":8,"label":"Days Left","fieldType":"numeric","properties":{"formula":"[Due] - Today()"},"findEnabled":true}`,
+				`{"id":9,"label":"Project - Owner","fieldType":"user","properties":{"lookupReferenceFieldId":7}}`,
+				`{"id":10,"label":"Project - Name","fieldType":"text","properties":{"lookupReferenceFieldId":7,"lookupTargetFieldId":6}}`,
+			))
+
+		case r.URL.Path == "/fields":
+			fmt.Fprint(w, shuffle(
+				`{"id":3,"label":"Record ID#","fieldType":"recordid","findEnabled":true,"appearsByDefault":true}`,
+				`{"id":6,"label":"Name","fieldType":"text","findEnabled":true,"appearsByDefault":true}`,
+				`{"id":11,"label":"Stage","fieldType":"text-multiple-choice","properties":{"choices":["Planned","Active"]}}`,
+				`{"id":12,"label":"Total Days Left","fieldType":"numeric","properties":{"summaryReferenceFieldId":7,"summaryTargetFieldId":8,"summaryFunction":"SUM"}}`,
+			))
+
+		case r.URL.Path == "/tables/bqgruir7z/relationships":
+			fmt.Fprint(w, `{"relationships":[{"id":7,"parentTableId":"bqgruir8a","childTableId":"bqgruir7z",`)
+			fmt.Fprint(w, `"foreignKeyField":{"id":7,"label":"Related Project"},"lookupFields":[{"id":10,"label":"Project - Name"},{"id":9,"label":"Project - Owner"}],`)
+			fmt.Fprint(w, `"summaryFields":[{"id":12,"label":"Total Days Left"}]}]}`)
+
+		case strings.HasPrefix(r.URL.Path, "/tables/"):
+			fmt.Fprint(w, `{"relationships":[]}`)
+
+		case r.URL.Path == "/reports" && tableID == "bqgruir7z":
+			fmt.Fprint(w, shuffle(
+				`{"id":"10","name":"Overdue","type":"table","query":{"filter":"{8.LT.0}","fields":[8,6,3]}}`,
+				`{"id":"2","name":"List All","type":"table","usedCount":42,"query":{"fields":[6,8]}}`,
+			))
+
+		case r.URL.Path == "/reports":
+			fmt.Fprint(w, `[]`)
+
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+	}
+}
+
+func TestExportAppSchema(t *testing.T) {
+	ts := newSchemaServer(t)
+	defer ts.Close()
+
+	qb := qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.URL = ts.URL
+
+	for _, format := range []string{qbcli.SchemaFormatYAML, qbcli.SchemaFormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var first []byte
+			for i := 0; i < 5; i++ {
+				schema, err := qbcli.ExportAppSchema(context.Background(), qb, "bqgruir3g")
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				var buf bytes.Buffer
+				if err := qbcli.WriteAppSchema(&buf, schema, format); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				if first == nil {
+					first = buf.Bytes()
+				} else if !bytes.Equal(first, buf.Bytes()) {
+					t.Fatalf("export %d differs from the first:\n%s\n%s", i+1, first, buf.Bytes())
+				}
+			}
+		})
+	}
+
+	schema, _ := qbcli.ExportAppSchema(context.Background(), qb, "bqgruir3g")
+	if schema.Tables[0].Alias != "_DBID_PROJECTS" || schema.Tables[1].Alias != "_DBID_TASKS" {
+		t.Errorf("expected tables to be sorted by alias")
+	}
+
+	tasks := schema.Tables[1]
+	fids := []int{}
+	for _, f := range tasks.Fields {
+		fids = append(fids, f.FieldID)
+	}
+	if fmt.Sprint(fids) != "[3 6 7 8 9 10]" {
+		t.Errorf("got fields %v, expected them to be sorted by ID", fids)
+	}
+	if tasks.Fields[3].Formula != "[Due] - Today()" {
+		t.Errorf("got formula %q, expected the field's formula", tasks.Fields[3].Formula)
+	}
+	if tasks.Relationships[0].ParentTable != "_DBID_PROJECTS" {
+		t.Errorf("got parent table %q, expected the parent's alias", tasks.Relationships[0].ParentTable)
+	}
+
+	// Lookup and summary fields are described by the fields they're based on.
+	rel := tasks.Relationships[0]
+	if lf := rel.LookupFields; lf[0].ParentField != "" || lf[1].ParentField != "Name" {
+		t.Errorf("got parent fields %q and %q, expected none and Name", lf[0].ParentField, lf[1].ParentField)
+	}
+	if sf := rel.SummaryFields[0]; sf.SummaryField != "Days Left" || sf.AccumulationType != qbclient.AccumulationTypeSum {
+		t.Errorf("got summary of %q with %q, expected the SUM of Days Left", sf.SummaryField, sf.AccumulationType)
+	}
+	if choices := schema.Tables[0].Fields[2].Choices; fmt.Sprint(choices) != "[Planned Active]" {
+		t.Errorf("got choices %v, expected the field's choices in order", choices)
+	}
+	if tasks.Reports[0].ReportID != "2" || fmt.Sprint(tasks.Reports[1].Fields) != "[8 6 3]" {
+		t.Errorf("expected reports to be sorted by ID with their columns in order")
+	}
+	if schema.Variables["a"] != "1" || schema.Variables["b"] != "2" {
+		t.Errorf("got variables %v, expected a=1 and b=2", schema.Variables)
+	}
+}
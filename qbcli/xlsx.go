@@ -0,0 +1,299 @@
+package qbcli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/xuri/excelize/v2"
+)
+
+// Number formats applied to date/time cells in exported spreadsheets. They are
+// unambiguous so the values can be parsed again on import.
+const (
+	xlsxFormatDate     = "yyyy-mm-dd"
+	xlsxFormatDateTime = "yyyy-mm-dd hh:mm:ss"
+)
+
+// xlsxSheet returns the sheet to read or write, which defaults to the first
+// sheet in the workbook.
+func xlsxSheet(f *excelize.File, sheet string) (string, error) {
+	if sheet == "" {
+		return f.GetSheetName(0), nil
+	}
+
+	for _, name := range f.GetSheetList() {
+		if name == sheet {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: sheet not found in workbook", sheet)
+}
+
+// xlsxImportReader reads records from a sheet in an Excel workbook. The first
+// row is the header, which contains the field labels. Line numbers are the
+// row numbers in the sheet, and empty rows are skipped.
+//
+// Cells are read twice, once with number formats applied and once without, so
+// that numbers and dates are parsed from the values stored in the workbook
+// instead of how they are displayed, e.g., "1,234.57" or "3/4/21".
+type xlsxImportReader struct {
+	file     *excelize.File
+	rows     *excelize.Rows
+	raw      *excelize.Rows
+	mapper   *importFieldMapper
+	fmap     []int
+	line     int
+	date1904 bool
+}
+
+func newXLSXImportReader(r io.Reader, sheet string, mapper *importFieldMapper) (*xlsxImportReader, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error opening workbook: %w", err)
+	}
+
+	if sheet, err = xlsxSheet(f, sheet); err != nil {
+		return nil, err
+	}
+
+	ir := &xlsxImportReader{file: f, mapper: mapper}
+	if ir.rows, err = f.Rows(sheet); err == nil {
+		ir.raw, err = f.Rows(sheet)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading sheet: %w", err)
+	}
+	if f.WorkBook != nil && f.WorkBook.WorkbookPr != nil {
+		ir.date1904 = f.WorkBook.WorkbookPr.Date1904
+	}
+
+	// Map the header to field IDs.
+	header, _, err := ir.next()
+	if err == io.EOF {
+		return ir, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, label := range header {
+		fid, err := mapper.fieldID(label)
+		if err != nil {
+			return nil, err
+		}
+		ir.fmap = append(ir.fmap, fid)
+	}
+
+	return ir, nil
+}
+
+// Read implements importReader.Read.
+func (ir *xlsxImportReader) Read() (map[int]*qbclient.InsertRecordsInputData, int, error) {
+	if ir.fmap == nil {
+		return nil, ir.line, io.EOF
+	}
+
+	for {
+		row, raw, err := ir.next()
+		if err != nil {
+			return nil, ir.line, err
+		}
+		if strings.Join(row, "") == "" {
+			continue
+		}
+		if len(row) > len(ir.fmap) {
+			return nil, ir.line, fmt.Errorf("row %v has more columns than the header", ir.line)
+		}
+
+		record := make(map[int]*qbclient.InsertRecordsInputData)
+		for idx, data := range row {
+			fid := ir.fmap[idx]
+			if ir.mapper.skip(fid) || data == "" {
+				continue
+			}
+
+			val, err := ir.value(data, raw[idx], ir.mapper.fields[fid].Type)
+			if err != nil {
+				return nil, ir.line, fmt.Errorf("value invalid for field %v in row %v: %w", fid, ir.line, err)
+			}
+
+			record[fid] = &qbclient.InsertRecordsInputData{Value: val}
+		}
+
+		return record, ir.line, nil
+	}
+}
+
+// next returns the formatted and raw cell values in the next row.
+func (ir *xlsxImportReader) next() (row, raw []string, err error) {
+	if !ir.rows.Next() || !ir.raw.Next() {
+		return nil, nil, io.EOF
+	}
+	ir.line++
+
+	if row, err = ir.rows.Columns(); err == nil {
+		raw, err = ir.rawColumns()
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading row %v: %w", ir.line, err)
+	}
+
+	// Both iterators read the same cells, but guard against a short row anyway.
+	for len(raw) < len(row) {
+		raw = append(raw, "")
+	}
+
+	return row, raw, nil
+}
+
+// rawColumns returns the current row's cell values without their number
+// formats. excelize v2.4.1 has no option for this, so the cell formats are
+// hidden from it while the row is read.
+func (ir *xlsxImportReader) rawColumns() ([]string, error) {
+	if xfs := ir.file.Styles.CellXfs; xfs != nil {
+		saved := xfs.Xf
+		xfs.Xf = nil
+		defer func() { xfs.Xf = saved }()
+	}
+	return ir.raw.Columns()
+}
+
+// value returns the value of a cell. Numbers and dates are parsed from the raw
+// cell value, and everything else from the formatted value.
+func (ir *xlsxImportReader) value(data, raw, ftype string) (*qbclient.Value, error) {
+	switch ftype {
+	case qbclient.FieldRecordID, qbclient.FieldNumeric, qbclient.FieldNumericCurrency,
+		qbclient.FieldNumericPercent, qbclient.FieldNumericRating:
+		return qbclient.NewValueFromString(raw, ftype)
+
+	case qbclient.FieldDate, qbclient.FieldDateTime:
+		// Dates stored as text are parsed from the formatted value below.
+		serial, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			break
+		}
+
+		// Excel stores times as fractional days, so round off the float error.
+		t, err := excelize.ExcelDateToTime(serial, ir.date1904)
+		if err != nil {
+			return nil, err
+		}
+		t = t.Round(time.Millisecond)
+
+		if ftype == qbclient.FieldDate {
+			return qbclient.NewDateValue(t), nil
+		}
+		return qbclient.NewDateTimeValue(t), nil
+	}
+
+	return qbclient.NewValueFromString(data, ftype)
+}
+
+// xlsxExportWriter writes records to a sheet in an Excel workbook. Numbers,
+// dates, and checkboxes are written as native cell types, and everything else
+// is formatted with qbclient.Value.String. The workbook is written to the
+// underlying io.Writer when it is closed.
+type xlsxExportWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	fields FieldMap
+	fids   []int
+	row    int
+
+	dateStyle     int
+	dateTimeStyle int
+}
+
+func newXLSXExportWriter(w io.Writer, sheet string, fields FieldMap, fids []int) (*xlsxExportWriter, error) {
+	f := excelize.NewFile()
+	if sheet != "" {
+		f.SetSheetName(f.GetSheetName(0), sheet)
+	}
+
+	stream, err := f.NewStreamWriter(f.GetSheetName(0))
+	if err != nil {
+		return nil, fmt.Errorf("error creating sheet: %w", err)
+	}
+
+	ew := &xlsxExportWriter{w: w, file: f, stream: stream, fields: fields, fids: fids}
+
+	formatDate, formatDateTime := xlsxFormatDate, xlsxFormatDateTime
+	if ew.dateStyle, err = f.NewStyle(&excelize.Style{CustomNumFmt: &formatDate}); err != nil {
+		return nil, fmt.Errorf("error creating date style: %w", err)
+	}
+	if ew.dateTimeStyle, err = f.NewStyle(&excelize.Style{CustomNumFmt: &formatDateTime}); err != nil {
+		return nil, fmt.Errorf("error creating date/time style: %w", err)
+	}
+
+	return ew, nil
+}
+
+// WriteHeader implements exportWriter.WriteHeader.
+func (ew *xlsxExportWriter) WriteHeader() error {
+	header := make([]interface{}, len(ew.fids))
+	for idx, fid := range ew.fids {
+		header[idx] = ew.fields[fid].Label
+	}
+	return ew.writeRow(header)
+}
+
+// WriteRecords implements exportWriter.WriteRecords.
+func (ew *xlsxExportWriter) WriteRecords(records []map[int]*qbclient.RecordsData) error {
+	for _, record := range records {
+		row := make([]interface{}, len(ew.fids))
+		for idx, fid := range ew.fids {
+			if data, ok := record[fid]; ok && data.Value != nil {
+				row[idx] = ew.cell(data.Value, ew.fields[fid].Type)
+			}
+		}
+		if err := ew.writeRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements exportWriter.Close by writing the workbook.
+func (ew *xlsxExportWriter) Close() error {
+	if err := ew.stream.Flush(); err != nil {
+		return fmt.Errorf("error writing sheet: %w", err)
+	}
+	if err := ew.file.Write(ew.w); err != nil {
+		return fmt.Errorf("error writing workbook: %w", err)
+	}
+	return nil
+}
+
+func (ew *xlsxExportWriter) writeRow(row []interface{}) error {
+	ew.row++
+	axis, err := excelize.CoordinatesToCellName(1, ew.row)
+	if err == nil {
+		err = ew.stream.SetRow(axis, row)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing row %v: %w", ew.row, err)
+	}
+	return nil
+}
+
+// cell returns the value written to a cell based on the field's type.
+func (ew *xlsxExportWriter) cell(v *qbclient.Value, ftype string) interface{} {
+	switch ftype {
+	case qbclient.FieldRecordID, qbclient.FieldNumeric, qbclient.FieldNumericCurrency,
+		qbclient.FieldNumericPercent, qbclient.FieldNumericRating:
+		return v.Float64
+	case qbclient.FieldDate:
+		return excelize.Cell{StyleID: ew.dateStyle, Value: v.Time.UTC()}
+	case qbclient.FieldDateTime:
+		return excelize.Cell{StyleID: ew.dateTimeStyle, Value: v.Time.UTC()}
+	case qbclient.FieldCheckbox:
+		return v.Bool
+	default:
+		return v.String()
+	}
+}
@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"io"
 	"net/http"
 )
@@ -25,6 +26,7 @@ func (i *ListAppsInput) method() string               { return http.MethodPost }
 func (i *ListAppsInput) url() string                  { return i.u }
 func (i *ListAppsInput) addHeaders(req *http.Request) { addHeadersXML(req, i.c, "API_GrantedDBs") }
 func (i *ListAppsInput) encode() ([]byte, error)      { return marshalXML(i, i.c) }
+func (i *ListAppsInput) readOnly()                    {}
 
 // ListAppsOutput models the XML API response returned by API_GrantedDBs.
 // See https://help.quickbase.com/api-guide/granteddbs.html
@@ -46,10 +48,17 @@ func (o *ListAppsOutput) decode(body io.ReadCloser) error { return unmarshalXML(
 
 // ListApps sends an XML API request to API_GrantedDBs.
 // See https://help.quickbase.com/api-guide/granteddbs.html
-func (c *Client) ListApps(input *ListAppsInput) (output *ListAppsOutput, err error) {
+func (c *Client) ListApps(input *ListAppsInput) (*ListAppsOutput, error) {
+	return c.ListAppsWithContext(context.Background(), input)
+}
+
+// ListAppsWithContext sends an XML API request to API_GrantedDBs,
+// using the passed context.
+// See https://help.quickbase.com/api-guide/granteddbs.html
+func (c *Client) ListAppsWithContext(ctx context.Context, input *ListAppsInput) (output *ListAppsOutput, err error) {
 	input.c = c
 	input.u = "https://" + c.ReamlHostname + "/db/main"
 	output = &ListAppsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"encoding/base64"
 	"io"
 	"net/http"
@@ -51,7 +52,13 @@ type CreateFileOutputField struct {
 
 // CreateFile makes an API_UploadFile call.
 // See https://help.quickbase.com/api-guide/index.html#uploadfile.html
-func (c *Client) CreateFile(input *CreateFileInput) (output *CreateFileOutput, err error) {
+func (c *Client) CreateFile(input *CreateFileInput) (*CreateFileOutput, error) {
+	return c.CreateFileWithContext(context.Background(), input)
+}
+
+// CreateFileWithContext makes an API_UploadFile call using the passed context.
+// See https://help.quickbase.com/api-guide/index.html#uploadfile.html
+func (c *Client) CreateFileWithContext(ctx context.Context, input *CreateFileInput) (output *CreateFileOutput, err error) {
 	input.c = c
 	input.u = "https://" + c.ReamlHostname + "/db/" + url.PathEscape(input.TableID)
 
@@ -60,6 +67,6 @@ func (c *Client) CreateFile(input *CreateFileInput) (output *CreateFileOutput, e
 	}
 
 	output = &CreateFileOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
@@ -47,11 +48,18 @@ func (o *CreatePageOutput) decode(body io.ReadCloser) error { return unmarshalXM
 
 // CreatePage sends an XML API request to API_AddReplaceDBPage.
 // See https://help.quickbase.com/api-guide/index.html#add_replace_dbpage.html
-func (c *Client) CreatePage(input *CreatePageInput) (output *CreatePageOutput, err error) {
+func (c *Client) CreatePage(input *CreatePageInput) (*CreatePageOutput, error) {
+	return c.CreatePageWithContext(context.Background(), input)
+}
+
+// CreatePageWithContext sends an XML API request to API_AddReplaceDBPage,
+// using the passed context.
+// See https://help.quickbase.com/api-guide/index.html#add_replace_dbpage.html
+func (c *Client) CreatePageWithContext(ctx context.Context, input *CreatePageInput) (output *CreatePageOutput, err error) {
 	input.c = c
 	input.u = "https://" + url.PathEscape(c.ReamlHostname) + "/db/" + url.PathEscape(input.AppID)
 	output = &CreatePageOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -72,6 +80,7 @@ func (i *GetPageInput) method() string               { return http.MethodPost }
 func (i *GetPageInput) url() string                  { return i.u }
 func (i *GetPageInput) addHeaders(req *http.Request) { addHeadersXML(req, i.c, "API_GetDBPage") }
 func (i *GetPageInput) encode() ([]byte, error)      { return marshalXML(i, i.c) }
+func (i *GetPageInput) readOnly()                    {}
 
 // GetPageOutput models the XML API response returned by API_GetDBPage
 // See https://help.quickbase.com/api-guide/index.html#get_db_page.html
@@ -85,11 +94,17 @@ func (o *GetPageOutput) decode(body io.ReadCloser) error { return unmarshalXML(b
 
 // GetPage makes an API_GetDBPage call.
 // See https://help.quickbase.com/api-guide/index.html#get_db_page.html
-func (c *Client) GetPage(input *GetPageInput) (output *GetPageOutput, err error) {
+func (c *Client) GetPage(input *GetPageInput) (*GetPageOutput, error) {
+	return c.GetPageWithContext(context.Background(), input)
+}
+
+// GetPageWithContext makes an API_GetDBPage call using the passed context.
+// See https://help.quickbase.com/api-guide/index.html#get_db_page.html
+func (c *Client) GetPageWithContext(ctx context.Context, input *GetPageInput) (output *GetPageOutput, err error) {
 	input.c = c
 	input.u = "https://" + url.PathEscape(c.ReamlHostname) + "/db/" + url.PathEscape(input.AppID)
 	output = &GetPageOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -132,12 +147,19 @@ func (o *UpdatePageOutput) decode(body io.ReadCloser) error { return unmarshalXM
 
 // UpdatePage sends an XML API request to API_AddReplaceDBPage.
 // See https://help.quickbase.com/api-guide/index.html#add_replace_dbpage.html
-func (c *Client) UpdatePage(input *UpdatePageInput) (output *UpdatePageOutput, err error) {
+func (c *Client) UpdatePage(input *UpdatePageInput) (*UpdatePageOutput, error) {
+	return c.UpdatePageWithContext(context.Background(), input)
+}
+
+// UpdatePageWithContext sends an XML API request to API_AddReplaceDBPage,
+// using the passed context.
+// See https://help.quickbase.com/api-guide/index.html#add_replace_dbpage.html
+func (c *Client) UpdatePageWithContext(ctx context.Context, input *UpdatePageInput) (output *UpdatePageOutput, err error) {
 	input.c = c
 	input.u = "https://" + url.PathEscape(c.ReamlHostname) + "/db/" + url.PathEscape(input.AppID)
 	output = &UpdatePageOutput{}
 	if input.PageID != 0 || input.Name != "" {
-		err = c.Do(input, output)
+		err = c.DoWithContext(ctx, input, output)
 	} else {
 		err = qberrors.Client(nil).Safef(qberrors.BadRequest, "%s", "ID or name required")
 	}
@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
@@ -23,6 +24,7 @@ func (i *GetVariableInput) method() string               { return http.MethodPos
 func (i *GetVariableInput) url() string                  { return i.u }
 func (i *GetVariableInput) addHeaders(req *http.Request) { addHeadersXML(req, i.c, "API_GetDBvar") }
 func (i *GetVariableInput) encode() ([]byte, error)      { return marshalXML(i, i.c) }
+func (i *GetVariableInput) readOnly()                    {}
 
 // GetVariableOutput models the XML API response returned by API_GetDBvar.
 // See https://help.quickbase.com/api-guide/index.html#getdbvar.html
@@ -35,12 +37,19 @@ func (o *GetVariableOutput) decode(body io.ReadCloser) error { return unmarshalX
 
 // GetVariable sends an XML API request to API_GetDBvar.
 // See https://help.quickbase.com/api-guide/index.html#getdbvar.html
-func (c *Client) GetVariable(input *GetVariableInput) (output *GetVariableOutput, err error) {
+func (c *Client) GetVariable(input *GetVariableInput) (*GetVariableOutput, error) {
+	return c.GetVariableWithContext(context.Background(), input)
+}
+
+// GetVariableWithContext sends an XML API request to API_GetDBvar,
+// using the passed context.
+// See https://help.quickbase.com/api-guide/index.html#getdbvar.html
+func (c *Client) GetVariableWithContext(ctx context.Context, input *GetVariableInput) (output *GetVariableOutput, err error) {
 	input.c = c
 	input.u = "https://" + url.PathEscape(c.ReamlHostname) + "/db/" + url.PathEscape(input.AppID)
 
 	output = &GetVariableOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	if err == nil {
 		output.Name = input.Name
 	}
@@ -80,12 +89,19 @@ func (o *SetVariableOutput) decode(body io.ReadCloser) error { return unmarshalX
 
 // SetVariable sends an XML API request to API_SetDBvar.
 // See https://help.quickbase.com/api-guide/index.html#setdbvar.html
-func (c *Client) SetVariable(input *SetVariableInput) (output *SetVariableOutput, err error) {
+func (c *Client) SetVariable(input *SetVariableInput) (*SetVariableOutput, error) {
+	return c.SetVariableWithContext(context.Background(), input)
+}
+
+// SetVariableWithContext sends an XML API request to API_SetDBvar,
+// using the passed context.
+// See https://help.quickbase.com/api-guide/index.html#setdbvar.html
+func (c *Client) SetVariableWithContext(ctx context.Context, input *SetVariableInput) (output *SetVariableOutput, err error) {
 	input.c = c
 	input.u = "https://" + url.PathEscape(c.ReamlHostname) + "/db/" + url.PathEscape(input.AppID)
 
 	output = &SetVariableOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	if err == nil {
 		output.Name = input.Name
 		output.Value = input.Value
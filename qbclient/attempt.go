@@ -0,0 +1,25 @@
+package qbclient
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type attemptsKey struct{}
+
+// ContextWithAttempts returns a copy of ctx that counts the attempts made to
+// send the requests bound to it, including retries, and a function that
+// returns the count. Requests served without being sent, e.g., in dry-run
+// mode or from a cassette, aren't counted.
+func ContextWithAttempts(ctx context.Context) (context.Context, func() int) {
+	n := new(int64)
+	ctx = context.WithValue(ctx, attemptsKey{}, n)
+	return ctx, func() int { return int(atomic.LoadInt64(n)) }
+}
+
+// countAttempt increments the attempt count in ctx, if any.
+func countAttempt(ctx context.Context) {
+	if n, ok := ctx.Value(attemptsKey{}).(*int64); ok {
+		atomic.AddInt64(n, 1)
+	}
+}
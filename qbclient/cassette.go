@@ -0,0 +1,311 @@
+package qbclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// ErrInteractionNotFound is returned when replaying a cassette that has no
+// interaction matching a request.
+var ErrInteractionNotFound = errors.New("no recorded interaction matches the request")
+
+var reXMLUserToken = regexp.MustCompile(`<usertoken>[^<]*</usertoken>`)
+
+// Interaction models a request and the response it returned. Cassette files
+// contain one JSON encoded Interaction per line, in the order the requests
+// were sent.
+type Interaction struct {
+	Request  *InteractionRequest  `json:"request"`
+	Response *InteractionResponse `json:"response"`
+}
+
+// InteractionRequest models the parts of a request that are matched when
+// replaying a cassette. User tokens are redacted from the body.
+type InteractionRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Action string `json:"action,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// InteractionResponse models a recorded response. User tokens are masked in
+// the body.
+type InteractionResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// matches returns whether the recorded request matches other. The host isn't
+// matched, so cassettes recorded against one realm can be replayed against
+// another.
+func (r *InteractionRequest) matches(other *InteractionRequest) bool {
+	return r.Method == other.Method &&
+		r.Action == other.Action &&
+		r.Body == other.Body &&
+		requestPath(r.URL) == requestPath(other.URL)
+}
+
+// requestPath returns the path and query string of a URL.
+func requestPath(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return u.RequestURI()
+}
+
+// newInteractionRequest returns the InteractionRequest for req. The body is
+// read and put back so that it can be sent.
+func newInteractionRequest(req *http.Request) (*InteractionRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	body = reXMLUserToken.ReplaceAll(body, []byte(`<usertoken>********</usertoken>`))
+	return &InteractionRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Action: req.Header.Get("QUICKBASE-ACTION"),
+		Body:   string(MaskUserToken(body)),
+	}, nil
+}
+
+// RecordTransport is an http.RoundTripper that sends requests with Transport
+// and appends the interactions to a cassette file, which can be replayed by
+// ReplayTransport.
+type RecordTransport struct {
+	Transport http.RoundTripper
+
+	path string
+	mu   *sync.Mutex
+}
+
+// NewRecordTransport returns a RecordTransport that records interactions to
+// the cassette file at path, which is created or truncated.
+func NewRecordTransport(path string, next http.RoundTripper) (*RecordTransport, error) {
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		return nil, err
+	}
+	return &RecordTransport{Transport: next, path: path, mu: &sync.Mutex{}}, nil
+}
+
+// With returns a RecordTransport that sends requests with next and appends the
+// interactions to the same cassette file as t, which isn't truncated again.
+func (t *RecordTransport) With(next http.RoundTripper) *RecordTransport {
+	return &RecordTransport{Transport: next, path: t.path, mu: t.mu}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ireq, err := newInteractionRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the response body and put it back so it can be decoded.
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	i := &Interaction{
+		Request: ireq,
+		Response: &InteractionResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       string(MaskUserToken(body)),
+		},
+	}
+
+	if err := t.write(i); err != nil {
+		return nil, fmt.Errorf("error writing cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// write appends the interaction to the cassette file. Requests are sent
+// concurrently when importing data, so interactions are written one at a time.
+func (t *RecordTransport) write(i *Interaction) error {
+	b, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	file, err := os.OpenFile(t.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(b, '\n'))
+	return err
+}
+
+// ReplayTransport is an http.RoundTripper that serves recorded responses
+// instead of sending requests. Requests are matched by method, URL, action,
+// and body. Matching interactions are replayed in the order they were
+// recorded, and the last one is replayed again once they are all used.
+type ReplayTransport struct {
+	Interactions []*Interaction
+
+	replayed map[*Interaction]bool
+	mu       sync.Mutex
+}
+
+// NewReplayTransport returns a ReplayTransport that replays the interactions
+// in the cassette file at path.
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	t := &ReplayTransport{replayed: make(map[*Interaction]bool)}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		i := &Interaction{}
+		if err := json.Unmarshal(scanner.Bytes(), i); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if i.Request == nil || i.Response == nil {
+			return nil, fmt.Errorf("line %d: %w", line, errors.New("request and response required"))
+		}
+		t.Interactions = append(t.Interactions, i)
+	}
+
+	return t, scanner.Err()
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ireq, err := newInteractionRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	i := t.next(ireq)
+	if i == nil {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrInteractionNotFound)
+	}
+
+	header := http.Header{}
+	for k, v := range i.Response.Header {
+		header[k] = v
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewBufferString(i.Response.Body)),
+		ContentLength: int64(len(i.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// next returns the interaction to replay for the request, or nil if none
+// match.
+func (t *ReplayTransport) next(req *InteractionRequest) *Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var last *Interaction
+	for _, i := range t.Interactions {
+		if !i.Request.matches(req) {
+			continue
+		}
+		if !t.replayed[i] {
+			t.replayed[i] = true
+			return i
+		}
+		last = i
+	}
+
+	return last
+}
+
+// Record records the client's interactions to the cassette file at path,
+// which is created or truncated.
+func (c *Client) Record(path string) error {
+	t, err := NewRecordTransport(path, c.httpTransport())
+	if err == nil {
+		c.setTransport(t)
+	}
+	return err
+}
+
+// Replay serves responses from the interactions in the cassette file at path
+// instead of sending requests.
+func (c *Client) Replay(path string) error {
+	t, err := NewReplayTransport(path)
+	if err == nil {
+		c.setTransport(t)
+	}
+	return err
+}
+
+// UseCassette records interactions to, or replays them from, the same cassette
+// as other if it was set up with Record or Replay. This is useful when a
+// command sends requests with clients for several profiles.
+func (c *Client) UseCassette(other *Client) {
+	switch t := other.httpTransport().(type) {
+	case *RecordTransport:
+		c.setTransport(t.With(c.httpTransport()))
+	case *ReplayTransport:
+		c.setTransport(t)
+	}
+}
+
+// httpTransport returns the transport requests are sent with.
+func (c *Client) httpTransport() http.RoundTripper {
+	if c.HTTPClient != nil && c.HTTPClient.Transport != nil {
+		return c.HTTPClient.Transport
+	}
+	return http.DefaultTransport
+}
+
+// setTransport sets the transport requests are sent with. The HTTP client is
+// copied, because it might be shared with other clients.
+func (c *Client) setTransport(t http.RoundTripper) {
+	hc := &http.Client{}
+	if c.HTTPClient != nil {
+		*hc = *c.HTTPClient
+	}
+	hc.Transport = t
+	c.HTTPClient = hc
+}
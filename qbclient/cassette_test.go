@@ -0,0 +1,104 @@
+package qbclient_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
+	"github.com/QuickBase/quickbase-cli/qbtest"
+	"github.com/spf13/viper"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qbclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cassette := qbclient.Filepath(dir, "cassette.jsonl")
+
+	// Record interactions with a fake server, then shut it down.
+	s := qbtest.NewServer()
+	qb := s.Client()
+	qb.UserToken = "b12345_abcd_0_recordedusertoken"
+	if err := qb.Record(cassette); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	app, err := qb.CreateApp(&qbclient.CreateAppInput{Name: "Projects"})
+	if err != nil {
+		t.Fatalf("unexpected error creating app: %s", err)
+	}
+	apps, err := qb.ListApps(&qbclient.ListAppsInput{})
+	if err != nil {
+		t.Fatalf("unexpected error listing apps: %s", err)
+	}
+
+	// Another client records to the same cassette without truncating it.
+	other := s.Client()
+	other.UseCassette(qb)
+	if _, err := other.GetApp(&qbclient.GetAppInput{AppID: app.AppID}); err != nil {
+		t.Fatalf("unexpected error getting app: %s", err)
+	}
+	_, recordedErr := qb.GetApp(&qbclient.GetAppInput{AppID: "bqmissing"})
+	if recordedErr == nil {
+		t.Fatal("expected an error getting a missing app")
+	}
+	s.Close()
+
+	b, err := ioutil.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), qb.UserToken) {
+		t.Error("cassette contains the user token")
+	}
+
+	// Replay the interactions against another realm with another token.
+	qb = qbclient.New(qbclient.NewConfig(viper.New()))
+	qb.ReamlHostname = "example.quickbase.com"
+	qb.UserToken = "b67890_efgh_0_replayedusertoken"
+	if err := qb.Replay(cassette); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		out, err := qb.CreateApp(&qbclient.CreateAppInput{Name: "Projects"})
+		if err != nil {
+			t.Fatalf("unexpected error creating app: %s", err)
+		}
+		if out.AppID != app.AppID {
+			t.Errorf("got app ID %q, expected %q", out.AppID, app.AppID)
+		}
+	}
+
+	out, err := qb.ListApps(&qbclient.ListAppsInput{})
+	if err != nil {
+		t.Fatalf("unexpected error listing apps: %s", err)
+	}
+	if len(out.Databases) != len(apps.Databases) {
+		t.Errorf("got %d apps, expected %d", len(out.Databases), len(apps.Databases))
+	}
+
+	other = qbclient.New(qbclient.NewConfig(viper.New()))
+	other.UseCassette(qb)
+	if got, err := other.GetApp(&qbclient.GetAppInput{AppID: app.AppID}); err != nil || got.Name != "Projects" {
+		t.Errorf("got app %v and error %v, expected the recorded app", got, err)
+	}
+
+	_, err = qb.GetApp(&qbclient.GetAppInput{AppID: "bqmissing"})
+	if err == nil || err.Error() != recordedErr.Error() {
+		t.Errorf("got error %v, expected %v", err, recordedErr)
+	}
+
+	// Requests that weren't recorded fail.
+	_, err = qb.CreateApp(&qbclient.CreateAppInput{Name: "Other"})
+	var serr *qberrors.ErrService
+	if !errors.As(err, &serr) || !errors.Is(serr.Upstream(), qbclient.ErrInteractionNotFound) {
+		t.Errorf("got error %v, expected %v", err, qbclient.ErrInteractionNotFound)
+	}
+}
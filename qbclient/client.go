@@ -2,9 +2,14 @@ package qbclient
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"runtime"
+	"sync"
 
 	"github.com/QuickBase/quickbase-cli/qberrors"
 	"github.com/go-playground/validator/v10"
@@ -15,11 +20,21 @@ import (
 // Client makes requests to the Quick Base API.
 type Client struct {
 	HTTPClient    *http.Client
+	Middleware    []Middleware
 	Plugins       []Plugin
+	RateLimiter   *RateLimiter
 	ReamlHostname string
 	URL           string
 	UserAgent     string
 	UserToken     string
+
+	// DryRun prints requests that change data to DryRunWriter, or os.Stderr
+	// if not set, instead of sending them. A synthetic successful response
+	// is returned in their place.
+	DryRun       bool
+	DryRunWriter io.Writer
+
+	dryRunMu sync.Mutex
 }
 
 // New returns a new Client.
@@ -27,15 +42,19 @@ func New(cfg ConfigIface) *Client {
 	c := &Client{
 		ReamlHostname: cfg.RealmHostname(),
 		URL:           "https://api.quickbase.com/v1",
+		RateLimiter:   NewRateLimiterFromConfig(cfg),
 		UserAgent:     userAgent(),
 		UserToken:     cfg.UserToken(),
 	}
 
 	// Configure and set the retry handler.
 	rh := retryablehttp.NewClient()
-	rh.RetryMax = 2
+	NewRetryPolicy(cfg).configure(rh)
 	rh.Logger = nil
 	rh.ErrorHandler = c.errorHandler
+
+	// Wait for the rate limiter before each attempt, including retries.
+	rh.HTTPClient.Transport = &rateLimitTransport{c: c, next: rh.HTTPClient.Transport}
 	c.HTTPClient = rh.StandardClient()
 
 	return c
@@ -61,9 +80,22 @@ func (c *Client) AddPlugin(p Plugin) {
 	c.Plugins = append(c.Plugins, p)
 }
 
+// AddMiddleware adds Middleware to the stack. Middleware added first is the
+// outermost, i.e., it sees the request first and the response last.
+func (c *Client) AddMiddleware(m Middleware) {
+	c.Middleware = append(c.Middleware, m)
+}
+
 // Do sends an arbitrary request to the Quick Base API.
-// TODO Improve the error handling.
 func (c *Client) Do(input Input, output Output) error {
+	return c.DoWithContext(context.Background(), input, output)
+}
+
+// DoWithContext sends an arbitrary request to the Quick Base API. The request
+// is bound to ctx, so cancelling ctx or exceeding its deadline aborts the
+// request, including any backoff between retries.
+// TODO Improve the error handling.
+func (c *Client) DoWithContext(ctx context.Context, input Input, output Output) error {
 
 	// Validate the input.
 	if err := validator.New().Struct(input); err != nil {
@@ -77,7 +109,7 @@ func (c *Client) Do(input Input, output Output) error {
 	}
 
 	// Create the request, using the marshalled input as the body.
-	req, err := http.NewRequest(input.method(), input.url(), bytes.NewBuffer(b))
+	req, err := http.NewRequestWithContext(ctx, input.method(), input.url(), bytes.NewBuffer(b))
 	if err != nil {
 		serr := qberrors.ErrSafe{Message: "error creating request"}
 		return qberrors.Internal(err).Safe(serr)
@@ -86,18 +118,30 @@ func (c *Client) Do(input Input, output Output) error {
 	// Add HTTP headers using Input.addHeaders.
 	input.addHeaders(req)
 
-	// Invoke each plugin's PreRequest hook.
-	c.invokePreRequest(req)
+	// Print requests that change data instead of sending them in dry-run
+	// mode. Reads are still sent, since they are often needed to build the
+	// requests that change data.
+	if c.DryRun && changesData(input) {
+		return c.dryRun(req, output)
+	}
 
-	// Do the HTTP request.
-	resp, err := c.HTTPClient.Do(req)
+	// Do the HTTP request through the middleware stack.
+	resp, err := c.roundTrip(req)
 	if err != nil {
+		if cerr := ctx.Err(); cerr != nil {
+			return contextError(cerr)
+		}
+		if _, ok := err.(*url.Error); !ok && qberrors.IsSafe(err) {
+			return err
+		}
 		serr := qberrors.ErrSafe{Message: "error executing request"}
 		return qberrors.Service(err).Safe(serr)
 	}
 
-	// Invoke each plugin's PostResponse hook.
-	c.invokePostResponse(resp)
+	if resp == nil {
+		serr := qberrors.ErrSafe{Message: "error executing request"}
+		return qberrors.Internal(errors.New("middleware returned no response")).Safe(serr)
+	}
 
 	// Parse the response body. We do our best to handle this gracefully if
 	// an error is thrown outside of the API's control plane, e.g., from
@@ -140,6 +184,43 @@ func (c *Client) errorHandler(resp *http.Response, err error, numTries int) (*ht
 	return nil, qberrors.Service(err).Safe(serr)
 }
 
+// contextError converts a context error into an error that won't be retried.
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		serr := qberrors.ErrSafe{Message: "request deadline exceeded", StatusCode: http.StatusGatewayTimeout}
+		return qberrors.Client(err).Safe(serr)
+	}
+	serr := qberrors.ErrSafe{Message: "request canceled", StatusCode: StatusClientClosedRequest}
+	return qberrors.Client(err).Safe(serr)
+}
+
+// roundTrip sends the request through the middleware stack. The innermost
+// handler invokes the plugins' hooks around sending the request.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	next := c.send
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		m, inner := c.Middleware[i], next
+		next = func(req *http.Request) (*http.Response, error) {
+			return m.RoundTrip(req, inner)
+		}
+	}
+	return next(req)
+}
+
+// send sends the request, invoking each plugin's PreRequest hook before and
+// PostResponse hook after. Transport errors are returned as-is.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	c.invokePreRequest(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invokePostResponse(resp)
+	return resp, nil
+}
+
 func (c *Client) invokePreRequest(req *http.Request) {
 	for _, plugin := range c.Plugins {
 		plugin.PreRequest(req)
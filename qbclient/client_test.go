@@ -1,9 +1,18 @@
 package qbclient_test
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
 	"github.com/spf13/viper"
 )
 
@@ -20,3 +29,216 @@ func TestInvalidInput(t *testing.T) {
 		t.Fatal("got nil, expected error")
 	}
 }
+
+func TestDoWithContextCanceled(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	cfg := qbclient.NewConfig(viper.New())
+	client := qbclient.New(cfg)
+	client.URL = ts.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	input := &qbclient.ListFieldsInput{TableID: "bqgruir7z"}
+	_, err := client.ListFieldsWithContext(ctx, input)
+
+	if err == nil {
+		t.Fatal("got nil, expected error")
+	}
+	if code := qberrors.StatusCode(err); code != http.StatusGatewayTimeout {
+		t.Errorf("got status %v, expected %v", code, http.StatusGatewayTimeout)
+	}
+	if qerr, ok := err.(qberrors.Error); ok && qerr.Retry() {
+		t.Error("expected canceled request not to be retryable")
+	}
+}
+
+func TestRetryTooManyRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		throttle func(w http.ResponseWriter)
+	}{
+		{"Retry-After", func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}},
+		{"XML errcode 104", func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/xml")
+			w.Header().Set("Retry-After", "0")
+			fmt.Fprint(w, `<qdbapi><errcode>104</errcode><errtext>Too many requests</errtext></qdbapi>`)
+			fmt.Fprint(w, strings.Repeat(" ", 8192))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests++; requests == 1 {
+					tt.throttle(w)
+					return
+				}
+				fmt.Fprint(w, `[]`)
+			}))
+			defer ts.Close()
+
+			// The backoff is longer than the wait requested by Retry-After,
+			// so a quick retry means the header was honored.
+			cfg := viper.New()
+			cfg.Set(qbclient.OptionRetryWaitMin, time.Second)
+			cfg.Set(qbclient.OptionRetryWaitMax, 2*time.Second)
+			client := qbclient.New(qbclient.NewConfig(cfg))
+			client.URL = ts.URL
+
+			start := time.Now()
+			input := &qbclient.ListFieldsInput{TableID: "bqgruir7z"}
+			if _, err := client.ListFields(input); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if requests != 2 {
+				t.Errorf("got %v requests, expected 2", requests)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("retried after %s, expected Retry-After to be honored", elapsed)
+			}
+		})
+	}
+}
+
+func TestRetryDisabled(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	cfg := viper.New()
+	cfg.Set(qbclient.OptionRetryMax, 0)
+	client := qbclient.New(qbclient.NewConfig(cfg))
+	client.URL = ts.URL
+
+	input := &qbclient.ListFieldsInput{TableID: "bqgruir7z"}
+	if _, err := client.ListFields(input); err == nil {
+		t.Fatal("got nil, expected error")
+	}
+	if requests != 1 {
+		t.Errorf("got %v requests, expected 1", requests)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		fmt.Fprint(w, `{"fields":[]}`)
+	}))
+	defer ts.Close()
+
+	token := "b123ab_cdef_0123456789abcdefghijklmnop"
+
+	var buf bytes.Buffer
+	client := qbclient.New(qbclient.NewConfig(viper.New()))
+	client.URL = ts.URL
+	client.ReamlHostname = "example.quickbase.com"
+	client.UserToken = token
+	client.DryRun = true
+	client.DryRunWriter = &buf
+
+	if _, err := client.ListFields(&qbclient.ListFieldsInput{TableID: "bqgruir7z"}); err != nil {
+		t.Fatalf("unexpected error listing fields: %v", err)
+	}
+	if _, err := client.DeleteFields(&qbclient.DeleteFieldsInput{TableID: "bqgruir7z", FieldIDs: []int{6}}); err != nil {
+		t.Fatalf("unexpected error deleting fields: %v", err)
+	}
+	if _, err := client.SetVariable(&qbclient.SetVariableInput{AppID: "bqgruir3g", Name: "env", Value: "prod"}); err != nil {
+		t.Fatalf("unexpected error setting variable: %v", err)
+	}
+
+	if len(requests) != 1 || requests[0] != "GET /fields" {
+		t.Errorf("got requests %v, expected only the field list to be sent", requests)
+	}
+
+	dump := buf.String()
+	for _, want := range []string{
+		"DELETE /fields?tableId=bqgruir7z",
+		`"fieldIds":[6]`,
+		"POST /db/bqgruir3g",
+		"Quickbase-Action: API_SetDBvar",
+		"<value>prod</value>",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("expected dump to contain %q, got:\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, token) {
+		t.Error("expected user token to be masked")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"fields":[]}`)
+	}))
+	defer ts.Close()
+
+	client := qbclient.New(qbclient.NewConfig(viper.New()))
+	client.URL = ts.URL
+
+	// Middleware runs in the order it was added, wrapping the request.
+	var calls []string
+	for _, name := range []string{"outer", "inner"} {
+		name := name
+		client.AddMiddleware(qbclient.MiddlewareFunc(func(req *http.Request, next qbclient.RoundTripFunc) (*http.Response, error) {
+			calls = append(calls, name+" before")
+			resp, err := next(req)
+			calls = append(calls, name+" after")
+			return resp, err
+		}))
+	}
+
+	if _, err := client.ListFields(&qbclient.ListFieldsInput{TableID: "bqgruir7z"}); err != nil {
+		t.Fatalf("unexpected error listing fields: %v", err)
+	}
+	if got, want := strings.Join(calls, ", "), "outer before, inner before, inner after, outer after"; got != want {
+		t.Errorf("got calls %q, expected %q", got, want)
+	}
+
+	// Middleware can serve responses without sending the request.
+	client.Middleware = []qbclient.Middleware{qbclient.MiddlewareFunc(func(req *http.Request, next qbclient.RoundTripFunc) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"id":6,"label":"Cached"}]`)),
+			Request:    req,
+		}, nil
+	})}
+
+	output, err := client.ListFields(&qbclient.ListFieldsInput{TableID: "bqgruir7z"})
+	if err != nil {
+		t.Fatalf("unexpected error listing fields: %v", err)
+	}
+	if len(output.Fields) != 1 || output.Fields[0].Label != "Cached" {
+		t.Errorf("got fields %v, expected the cached field", output.Fields)
+	}
+	if requests != 1 {
+		t.Errorf("got %v requests, expected 1", requests)
+	}
+
+	// Middleware can abort requests with classified errors.
+	abort := qberrors.Client(nil).Safef(qberrors.BadRequest, "request aborted")
+	client.Middleware = []qbclient.Middleware{qbclient.MiddlewareFunc(func(req *http.Request, next qbclient.RoundTripFunc) (*http.Response, error) {
+		return nil, abort
+	})}
+
+	if _, err := client.ListFields(&qbclient.ListFieldsInput{TableID: "bqgruir7z"}); err != abort {
+		t.Errorf("got error %v, expected %v", err, abort)
+	}
+}
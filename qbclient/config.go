@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
@@ -18,14 +19,22 @@ const ConfigFilename = "config.yml"
 
 // Option* constants contain CLI options.
 const (
-	OptionAppID          = "app-id"
-	OptionConfigDir      = "config-dir"
-	OptionFieldID        = "field-id"
-	OptionProfile        = "profile"
-	OptionRealmHostname  = "realm-hostname"
-	OptionRelationshipID = "relationship-id"
-	OptionTableID        = "table-id"
-	OptionUserToken      = "user-token"
+	OptionAppID           = "app-id"
+	OptionConfigDir       = "config-dir"
+	OptionFieldID         = "field-id"
+	OptionProfile         = "profile"
+	OptionRateLimit       = "rate-limit"
+	OptionRateLimitBurst  = "rate-limit-burst"
+	OptionRateLimitShared = "rate-limit-shared"
+	OptionRealmHostname   = "realm-hostname"
+	OptionRelationshipID  = "relationship-id"
+	OptionRetryJitter     = "retry-jitter"
+	OptionRetryMax        = "retry-max"
+	OptionRetryWaitMax    = "retry-wait-max"
+	OptionRetryWaitMin    = "retry-wait-min"
+	OptionSchemaCacheTTL  = "schema-cache-ttl"
+	OptionTableID         = "table-id"
+	OptionUserToken       = "user-token"
 )
 
 // ConfigIface is implemented by structs used to configure the cleint.
@@ -78,9 +87,50 @@ func (c Config) DefaultTableID() string { return c.cfg.GetString(OptionTableID)
 // Profile returns the configured profile.
 func (c Config) Profile() string { return c.cfg.GetString(OptionProfile) }
 
+// RateLimit returns the maximum number of requests per second.
+func (c Config) RateLimit() float64 { return c.cfg.GetFloat64(OptionRateLimit) }
+
+// RateLimitBurst returns the maximum number of requests sent in a burst.
+func (c Config) RateLimitBurst() int { return c.cfg.GetInt(OptionRateLimitBurst) }
+
+// RateLimitShared returns whether the rate limit is shared across processes.
+func (c Config) RateLimitShared() bool { return c.cfg.GetBool(OptionRateLimitShared) }
+
 // RealmHostname returns the configured realm hostname.
 func (c Config) RealmHostname() string { return c.cfg.GetString(OptionRealmHostname) }
 
+// RetryJitter returns whether to randomize the backoff between retries.
+func (c Config) RetryJitter() bool {
+	if !c.cfg.IsSet(OptionRetryJitter) {
+		return DefaultRetryJitter
+	}
+	return c.cfg.GetBool(OptionRetryJitter)
+}
+
+// RetryMax returns the maximum number of retries.
+func (c Config) RetryMax() int {
+	if !c.cfg.IsSet(OptionRetryMax) {
+		return DefaultRetryMax
+	}
+	return c.cfg.GetInt(OptionRetryMax)
+}
+
+// RetryWaitMax returns the maximum time to wait between retries.
+func (c Config) RetryWaitMax() time.Duration {
+	if !c.cfg.IsSet(OptionRetryWaitMax) {
+		return DefaultRetryWaitMax
+	}
+	return c.cfg.GetDuration(OptionRetryWaitMax)
+}
+
+// RetryWaitMin returns the minimum time to wait between retries.
+func (c Config) RetryWaitMin() time.Duration {
+	if !c.cfg.IsSet(OptionRetryWaitMin) {
+		return DefaultRetryWaitMin
+	}
+	return c.cfg.GetDuration(OptionRetryWaitMin)
+}
+
 // UserToken returns the configured log level.
 func (c Config) UserToken() string { return c.cfg.GetString(OptionUserToken) }
 
@@ -114,6 +164,27 @@ func ReadInConfig(cfg *viper.Viper) error {
 		cfg.SetDefault(OptionAppID, config.AppID)
 		cfg.SetDefault(OptionTableID, config.TableID)
 		cfg.SetDefault(OptionFieldID, config.FieldID)
+
+		cfg.SetDefault(OptionRateLimit, config.RateLimit)
+		cfg.SetDefault(OptionRateLimitBurst, config.RateLimitBurst)
+		cfg.SetDefault(OptionRateLimitShared, config.RateLimitShared)
+
+		// Only set the retry policy if configured so the client defaults apply.
+		if config.RetryMax != nil {
+			cfg.SetDefault(OptionRetryMax, *config.RetryMax)
+		}
+		if config.RetryWaitMin != "" {
+			cfg.SetDefault(OptionRetryWaitMin, config.RetryWaitMin)
+		}
+		if config.RetryWaitMax != "" {
+			cfg.SetDefault(OptionRetryWaitMax, config.RetryWaitMax)
+		}
+		if config.RetryJitter != nil {
+			cfg.SetDefault(OptionRetryJitter, *config.RetryJitter)
+		}
+		if config.SchemaCacheTTL != "" {
+			cfg.SetDefault(OptionSchemaCacheTTL, config.SchemaCacheTTL)
+		}
 	}
 
 	return nil
@@ -161,10 +232,18 @@ type ConfigFile map[string]*ConfigFileProfile
 
 // ConfigFileProfile models the configuration for a profile.
 type ConfigFileProfile struct {
-	RealmHostname  string `yaml:"realm_hostname,omitempty" json:"realm_hostname,omitempty"`
-	UserToken      string `yaml:"user_token,omitempty" json:"user_token,omitempty"`
-	TemporaryToken string `yaml:"temp_token,omitempty" json:"temp_token,omitempty"`
-	AppID          string `yaml:"app_id,omitempty" json:"app_id,omitempty"`
-	TableID        string `yaml:"table_id,omitempty" json:"table_id,omitempty"`
-	FieldID        int    `yaml:"field_id,omitempty" json:"field_id,omitempty"`
+	RealmHostname   string  `yaml:"realm_hostname,omitempty" json:"realm_hostname,omitempty"`
+	UserToken       string  `yaml:"user_token,omitempty" json:"user_token,omitempty"`
+	TemporaryToken  string  `yaml:"temp_token,omitempty" json:"temp_token,omitempty"`
+	AppID           string  `yaml:"app_id,omitempty" json:"app_id,omitempty"`
+	TableID         string  `yaml:"table_id,omitempty" json:"table_id,omitempty"`
+	FieldID         int     `yaml:"field_id,omitempty" json:"field_id,omitempty"`
+	RateLimit       float64 `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+	RateLimitBurst  int     `yaml:"rate_limit_burst,omitempty" json:"rate_limit_burst,omitempty"`
+	RateLimitShared bool    `yaml:"rate_limit_shared,omitempty" json:"rate_limit_shared,omitempty"`
+	RetryMax        *int    `yaml:"retry_max,omitempty" json:"retry_max,omitempty"`
+	RetryWaitMin    string  `yaml:"retry_wait_min,omitempty" json:"retry_wait_min,omitempty"`
+	RetryWaitMax    string  `yaml:"retry_wait_max,omitempty" json:"retry_wait_max,omitempty"`
+	RetryJitter     *bool   `yaml:"retry_jitter,omitempty" json:"retry_jitter,omitempty"`
+	SchemaCacheTTL  string  `yaml:"schema_cache_ttl,omitempty" json:"schema_cache_ttl,omitempty"`
 }
@@ -61,3 +61,7 @@ const (
 	SortByASC  = "ASC"
 	SortByDESC = "DESC"
 )
+
+// StatusClientClosedRequest is the non-standard status code associated with
+// requests that were canceled by the client before a response was returned.
+const StatusClientClosedRequest = 499
@@ -0,0 +1,64 @@
+package qbclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+
+	"github.com/QuickBase/quickbase-cli/qberrors"
+)
+
+// dryRun prints the request with user tokens masked, then decodes a synthetic
+// successful response into output.
+func (c *Client) dryRun(req *http.Request, output Output) error {
+	dump, err := httputil.DumpRequest(req, true)
+	if err != nil {
+		serr := qberrors.ErrSafe{Message: "error dumping request"}
+		return qberrors.Internal(err).Safe(serr)
+	}
+
+	w := c.DryRunWriter
+	if w == nil {
+		w = os.Stderr
+	}
+
+	// Requests are sent concurrently when importing data, so the dumps are
+	// written one at a time to keep them from being interleaved.
+	c.dryRunMu.Lock()
+	_, err = fmt.Fprintf(w, "%s\n\n", bytes.TrimSpace(MaskUserToken(dump)))
+	c.dryRunMu.Unlock()
+	if err != nil {
+		serr := qberrors.ErrSafe{Message: "error writing request"}
+		return qberrors.Internal(err).Safe(serr)
+	}
+
+	resp := dryRunResponse(req)
+	if err := output.decode(resp.Body); err != nil {
+		serr := qberrors.ErrSafe{Message: "error decoding response"}
+		return qberrors.Internal(err).Safe(serr)
+	}
+
+	return output.handleError(output, resp)
+}
+
+// dryRunResponse returns the response of a successful request that has no
+// data, i.e., an empty object for JSON API requests, and a response with no
+// error code for XML API requests.
+func dryRunResponse(req *http.Request) *http.Response {
+	var body io.Reader = bytes.NewBufferString(`{}`)
+	if action := req.Header.Get("QUICKBASE-ACTION"); action != "" {
+		body = bytes.NewBufferString(fmt.Sprintf(`<qdbapi><action>%s</action><errcode>0</errcode><errtext>No error</errtext></qdbapi>`, action))
+	}
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(body),
+		Request:    req,
+	}
+}
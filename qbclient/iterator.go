@@ -0,0 +1,184 @@
+package qbclient
+
+import (
+	"context"
+)
+
+// pageFunc fetches the page of records starting at skip.
+type pageFunc func(ctx context.Context, skip int) (*Records, error)
+
+// RecordIterator pages through the records returned by a query or report,
+// requesting the next page from the API only when the current one has been
+// consumed.
+//
+// Example usage:
+//
+//	it := qb.QueryRecordsAll(input)
+//	for it.Next() {
+//		record := it.Record()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type RecordIterator struct {
+	ctx   context.Context
+	fetch pageFunc
+
+	page   *Records
+	idx    int
+	skip   int
+	done   bool
+	err    error
+	fields []*RecordsField
+	total  int
+}
+
+func newRecordIterator(ctx context.Context, skip int, fetch pageFunc) *RecordIterator {
+	return &RecordIterator{ctx: ctx, fetch: fetch, skip: skip, idx: -1}
+}
+
+// Next advances the iterator to the next record, fetching the next page if
+// needed. It returns false when there are no more records or an error occurs.
+func (it *RecordIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	// Advance within the current page.
+	if it.page != nil && it.idx+1 < len(it.page.Data) {
+		it.idx++
+		return true
+	}
+
+	if it.done {
+		return false
+	}
+
+	// Fetch pages until we find one with data, or we run out of records.
+	for {
+		page, err := it.fetch(it.ctx, it.skip)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.page = page
+		it.idx = -1
+		if it.fields == nil {
+			it.fields = page.Fields
+		}
+
+		// We are on the last page if the API returned no records or we have
+		// now read everything the metadata says matches the query.
+		n := len(page.Data)
+		it.skip += n
+		if page.Metadata != nil {
+			it.total = page.Metadata.TotalRecords
+		}
+		if n == 0 || page.Metadata == nil || it.skip >= page.Metadata.TotalRecords {
+			it.done = true
+		}
+
+		if n > 0 {
+			it.idx = 0
+			return true
+		}
+		if it.done {
+			return false
+		}
+	}
+}
+
+// Record returns the current record. It must only be called after a call to
+// Next returns true.
+func (it *RecordIterator) Record() map[int]*RecordsData {
+	return it.page.Data[it.idx]
+}
+
+// Fields returns the fields in the result set. It is populated after the
+// first call to Next.
+func (it *RecordIterator) Fields() []*RecordsField {
+	return it.fields
+}
+
+// TotalRecords returns the total number of records matched by the query, as
+// reported in the metadata of the most recently fetched page.
+func (it *RecordIterator) TotalRecords() int {
+	return it.total
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *RecordIterator) Err() error {
+	return it.err
+}
+
+// All consumes the iterator and returns every record in a single Records
+// struct, with the metadata adjusted to reflect the full result set.
+func (it *RecordIterator) All() (*Records, error) {
+	records := &Records{Data: []map[int]*RecordsData{}}
+	for it.Next() {
+		records.Data = append(records.Data, it.Record())
+	}
+	if it.err != nil {
+		return records, it.err
+	}
+
+	records.Fields = it.fields
+	records.Metadata = &RecordsMetadata{
+		TotalRecords: it.total,
+		NumRecords:   len(records.Data),
+		NumFields:    len(it.fields),
+	}
+
+	return records, nil
+}
+
+// QueryRecordsAll returns a *RecordIterator that pages through every record
+// matched by the query. The input's Skip option sets where iteration starts,
+// and its Top option sets the page size. The input is not modified.
+func (c *Client) QueryRecordsAll(input *QueryRecordsInput) *RecordIterator {
+	return c.QueryRecordsAllWithContext(context.Background(), input)
+}
+
+// QueryRecordsAllWithContext is QueryRecordsAll using the passed context.
+func (c *Client) QueryRecordsAllWithContext(ctx context.Context, input *QueryRecordsInput) *RecordIterator {
+	opts := QueryRecordsInputOptions{}
+	if input.Options != nil {
+		opts = *input.Options
+	}
+
+	return newRecordIterator(ctx, opts.Skip, func(ctx context.Context, skip int) (*Records, error) {
+		in := *input
+		o := opts
+		o.Skip = skip
+		in.Options = &o
+
+		output, err := c.QueryRecordsWithContext(ctx, &in)
+		if err != nil {
+			return nil, err
+		}
+		return &output.Records, nil
+	})
+}
+
+// RunReportAll returns a *RecordIterator that pages through every record
+// returned by the report. The input's Skip property sets where iteration
+// starts, and its Top property sets the page size. The input is not modified.
+func (c *Client) RunReportAll(input *RunReportInput) *RecordIterator {
+	return c.RunReportAllWithContext(context.Background(), input)
+}
+
+// RunReportAllWithContext is RunReportAll using the passed context.
+func (c *Client) RunReportAllWithContext(ctx context.Context, input *RunReportInput) *RecordIterator {
+	return newRecordIterator(ctx, input.Skip, func(ctx context.Context, skip int) (*Records, error) {
+		in := *input
+		in.Skip = skip
+
+		output, err := c.RunReportWithContext(ctx, &in)
+		if err != nil {
+			return nil, err
+		}
+		return &output.Records, nil
+	})
+}
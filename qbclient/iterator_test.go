@@ -0,0 +1,70 @@
+package qbclient_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+func TestQueryRecordsAll(t *testing.T) {
+	const total, pageSize = 7, 3
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var input struct {
+			Options struct {
+				Skip int `json:"skip"`
+				Top  int `json:"top"`
+			} `json:"options"`
+		}
+		json.NewDecoder(r.Body).Decode(&input)
+
+		data := []string{}
+		for rid := input.Options.Skip + 1; rid <= total && len(data) < input.Options.Top; rid++ {
+			data = append(data, fmt.Sprintf(`{"3":{"value":%d}}`, rid))
+		}
+
+		fmt.Fprintf(w, `{"data":[%s],"fields":[{"id":3,"label":"Record ID#","type":"recordid"}],`, strings.Join(data, ","))
+		fmt.Fprintf(w, `"metadata":{"totalRecords":%d,"numRecords":%d,"numFields":1,"skip":%d}}`, total, len(data), input.Options.Skip)
+	}))
+	defer ts.Close()
+
+	client := qbclient.New(qbclient.NewConfig(viper.New()))
+	client.URL = ts.URL
+
+	input := &qbclient.QueryRecordsInput{
+		Select:  []int{3},
+		From:    "bqgruir7z",
+		Options: &qbclient.QueryRecordsInputOptions{Top: pageSize},
+	}
+
+	it := client.QueryRecordsAll(input)
+	want := 1
+	for it.Next() {
+		if have := int(it.Record()[3].Value.Float64); have != want {
+			t.Errorf("have record %v, want %v", have, want)
+		}
+		want++
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want-1 != total {
+		t.Errorf("have %v records, want %v", want-1, total)
+	}
+	if requests != 3 {
+		t.Errorf("have %v requests, want 3", requests)
+	}
+	if input.Options.Skip != 0 {
+		t.Error("expected input not to be modified")
+	}
+}
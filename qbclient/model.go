@@ -44,8 +44,9 @@ type FieldProperties struct {
 	DefaultValue string `json:"defaultValue,omitempty" cliutil:"option=default"`
 
 	// Text - Multiple Choice field options
-	AllowNewChoices    bool `json:"allowNewChoices,omitempty" cliutil:"option=allow-new-choices"`
-	SortChoicesAsGiven bool `json:"sortAsGiven,omitempty" cliutil:"option=sort-as-given"`
+	Choices            []string `json:"choices,omitempty"`
+	AllowNewChoices    bool     `json:"allowNewChoices,omitempty" cliutil:"option=allow-new-choices"`
+	SortChoicesAsGiven bool     `json:"sortAsGiven,omitempty" cliutil:"option=sort-as-given"`
 
 	// Display
 	NumberOfLines   int `json:"numLines,omitempty" cliutil:"option=num-lines"`
@@ -61,6 +62,13 @@ type FieldProperties struct {
 	PrimaryKey   bool   `json:"primaryKey,omitempty" cliutil:"option=primary-key"`
 	RelatedField int    `json:"targetFieldId,omitempty" cliutil:"option=related-field"`
 
+	// Lookup and summary fields, which are read-only
+	LookupReferenceFieldID  int    `json:"lookupReferenceFieldId,omitempty"`
+	LookupTargetFieldID     int    `json:"lookupTargetFieldId,omitempty"`
+	SummaryReferenceFieldID int    `json:"summaryReferenceFieldId,omitempty"`
+	SummaryTargetFieldID    int    `json:"summaryTargetFieldId,omitempty"`
+	SummaryFunction         string `json:"summaryFunction,omitempty"`
+
 	// Comments
 	Comments string `json:"comments,omitempty" cliutil:"option=comments"`
 }
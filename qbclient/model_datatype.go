@@ -357,7 +357,7 @@ func NewCheckboxValue(val bool) *Value {
 // given a passed string.
 func NewCheckboxValueFromString(val string) (v *Value, err error) {
 	var b bool
-	if b, err = strconv.ParseBool(val); err != nil {
+	if b, err = strconv.ParseBool(val); err == nil {
 		v = NewCheckboxValue(b)
 	}
 	return
@@ -448,7 +448,7 @@ func NewURLValue(val *url.URL) *Value {
 // NewURLValueFromString returns a new Value of the FieldURL type.
 func NewURLValueFromString(val string) (v *Value, err error) {
 	var u *url.URL
-	if u, err = url.Parse(val); err != nil {
+	if u, err = url.Parse(val); err == nil {
 		v = &Value{URL: u, QuickBaseType: FieldURL}
 	}
 	return
@@ -664,6 +664,16 @@ func (v *Value) MarshalJSON() ([]byte, error) {
 	}
 }
 
+// NewValueFromJSON returns a new Value given the JSON encoded value of a field
+// of the ftype type. It is the inverse of Value.MarshalJSON.
+func NewValueFromJSON(data json.RawMessage, ftype string) (*Value, error) {
+	val, err := unmarshalField(0, ftype, &data)
+	if err == nil && val != nil {
+		val.QuickBaseType = ftype
+	}
+	return val, err
+}
+
 func unmarshalField(fid int, ftype string, data *json.RawMessage) (val *Value, err error) {
 	switch ftype {
 
@@ -842,9 +852,9 @@ func unmarshalField(fid int, ftype string, data *json.RawMessage) (val *Value, e
 	case FieldvCard:
 		var v string
 		if data == nil {
-			val = NewPredecessorValue(v)
+			val = NewvCardValue(v)
 		} else if err = json.Unmarshal(*data, &v); err == nil {
-			val = NewPredecessorValue(v)
+			val = NewvCardValue(v)
 		}
 
 	case FieldPredecessor:
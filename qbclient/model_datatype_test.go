@@ -0,0 +1,77 @@
+package qbclient_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+func TestNewCheckboxValueFromString(t *testing.T) {
+	tables := []struct {
+		val  string
+		want bool
+	}{
+		{"true", true},
+		{"1", true},
+		{"false", false},
+		{"0", false},
+	}
+
+	for _, tt := range tables {
+		v, err := qbclient.NewCheckboxValueFromString(tt.val)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.val, err)
+		}
+		if v == nil {
+			t.Fatalf("%q: expected a checkbox value", tt.val)
+		}
+		if v.Bool != tt.want {
+			t.Errorf("%q: have %t, want %t", tt.val, v.Bool, tt.want)
+		}
+	}
+
+	if _, err := qbclient.NewCheckboxValueFromString("maybe"); err == nil {
+		t.Error("expected an error for an invalid boolean")
+	}
+}
+
+func TestNewURLValueFromString(t *testing.T) {
+	v, err := qbclient.NewURLValueFromString("https://example.com/path?q=1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v == nil || v.URL == nil {
+		t.Fatal("expected a URL value")
+	}
+	if have, want := v.URL.String(), "https://example.com/path?q=1"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+	if v.QuickBaseType != qbclient.FieldURL {
+		t.Errorf("have type %q, want %q", v.QuickBaseType, qbclient.FieldURL)
+	}
+
+	if _, err = qbclient.NewURLValueFromString("%zz"); err == nil {
+		t.Error("expected an error for an invalid URL")
+	}
+}
+
+func TestUnmarshalvCardValue(t *testing.T) {
+	b := []byte(`{
+		"data": [{"6": {"value": "BEGIN:VCARD"}}],
+		"fields": [{"id": 6, "label": "Contact", "type": "vCardButton"}]
+	}`)
+
+	var output qbclient.QueryRecordsOutput
+	if err := json.Unmarshal(b, &output); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v := output.Data[0][6].Value
+	if v.QuickBaseType != qbclient.FieldvCard {
+		t.Errorf("have type %q, want %q", v.QuickBaseType, qbclient.FieldvCard)
+	}
+	if v.Str != "BEGIN:VCARD" {
+		t.Errorf("have %q, want %q", v.Str, "BEGIN:VCARD")
+	}
+}
@@ -21,6 +21,22 @@ type Input interface {
 	encode() ([]byte, error)
 }
 
+// readOnlyInput is implemented by inputs whose requests don't change data
+// even though they aren't sent with GET, e.g., record queries and XML API
+// read actions.
+type readOnlyInput interface {
+	readOnly()
+}
+
+// changesData returns whether the request modeled by input changes data.
+func changesData(input Input) bool {
+	if input.method() == http.MethodGet {
+		return false
+	}
+	_, ok := input.(readOnlyInput)
+	return !ok
+}
+
 // Output models the payload of API responses.
 type Output interface {
 
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/QuickBase/quickbase-cli/qberrors"
 )
@@ -54,6 +56,61 @@ func marshalJSON(input interface{}) (b []byte, err error) {
 	return
 }
 
+// marshalJSONClear marshals the API request into a map of JSON properties,
+// adding the properties named in clear that were omitted because they are zero
+// values. This is how properties are turned off or cleared by update requests.
+func marshalJSONClear(input interface{}, clear []string) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(clear))
+	for _, name := range clear {
+		names[name] = true
+	}
+
+	err = addJSONZeroValues(m, reflect.TypeOf(input).Elem(), names)
+	return m, err
+}
+
+// addJSONZeroValues adds the zero values of the properties of t named in names
+// that are not in m. Embedded structs are walked like encoding/json does.
+func addJSONZeroValues(m map[string]json.RawMessage, t reflect.Type, names map[string]bool) error {
+	for idx := 0; idx < t.NumField(); idx++ {
+		sf := t.Field(idx)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if err := addJSONZeroValues(m, sf.Type, names); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if _, ok := m[name]; ok || !names[name] || sf.PkgPath != "" {
+			continue
+		}
+
+		// Lists are cleared with an empty list instead of null.
+		zero := reflect.Zero(sf.Type)
+		if sf.Type.Kind() == reflect.Slice {
+			zero = reflect.MakeSlice(sf.Type, 0, 0)
+		}
+
+		b, err := json.Marshal(zero.Interface())
+		if err != nil {
+			return err
+		}
+		m[name] = b
+	}
+	return nil
+}
+
 // unmarshalJSON unmarshals a JSON API response into an Output. This function
 // is intended to be used in Output.unmarshal implementations.
 func unmarshalJSON(body io.ReadCloser, output interface{}) error {
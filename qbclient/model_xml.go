@@ -106,6 +106,12 @@ func (p *XMLResponseParameters) handleError(output Output, resp *http.Response)
 		serr.StatusCode = http.StatusUnprocessableEntity
 	}
 
+	// Transient errors such as throttling are safe to retry.
+	if xmlRetryable(p.ErrorCode) {
+		err = qberrors.Service(nil).Safef(serr, "%s", output.errorDetail())
+		return
+	}
+
 	err = qberrors.Client(nil).Safef(serr, "%s", output.errorDetail())
 	return
 }
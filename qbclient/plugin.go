@@ -8,3 +8,27 @@ type Plugin interface {
 	PreRequest(req *http.Request)
 	PostResponse(resp *http.Response)
 }
+
+// RoundTripFunc sends a request and returns its response.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware is implemented by plugins that wrap sending requests to the
+// Quick Base API. RoundTrip calls next to send the request, and it may modify
+// the request before, or the response after, doing so. Returning without
+// calling next aborts the request or serves a response in its place, e.g.,
+// from a cache.
+//
+// Errors that are already classified by the qberrors package are returned to
+// the caller as-is. Other errors are treated as errors executing the request.
+type Middleware interface {
+	RoundTrip(req *http.Request, next RoundTripFunc) (*http.Response, error)
+}
+
+// MiddlewareFunc is an adapter that allows a function to be used as
+// Middleware.
+type MiddlewareFunc func(req *http.Request, next RoundTripFunc) (*http.Response, error)
+
+// RoundTrip implements Middleware.RoundTrip by calling f.
+func (f MiddlewareFunc) RoundTrip(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+	return f(req, next)
+}
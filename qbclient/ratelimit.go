@@ -0,0 +1,218 @@
+package qbclient
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Lock file settings used when coordinating the rate limit across processes.
+const (
+	rateLimitLockWait  = 10 * time.Millisecond
+	rateLimitLockStale = 10 * time.Second
+)
+
+// RateLimiter is a token bucket that caps the rate of requests sent to the
+// Quick Base API. Every attempt, including retries, consumes a token.
+//
+// If a state file is set, the bucket is stored in the file and guarded by a
+// lock file so that concurrent processes share the same limit.
+type RateLimiter struct {
+	rate  float64
+	burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	stateFile string
+}
+
+// rateLimitState models the bucket stored in the shared state file.
+type rateLimitState struct {
+	Tokens float64   `json:"tokens"`
+	Last   time.Time `json:"last"`
+}
+
+// NewRateLimiter returns a *RateLimiter that allows rate requests per second
+// with bursts of up to burst requests. A burst less than 1 defaults to the
+// rate rounded up.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &RateLimiter{rate: rate, burst: burst, tokens: float64(burst)}
+}
+
+// RateLimitConfigIface is implemented by configs that limit the request rate.
+type RateLimitConfigIface interface {
+
+	// RateLimit returns the maximum number of requests per second.
+	RateLimit() float64
+
+	// RateLimitBurst returns the maximum number of requests sent in a burst.
+	RateLimitBurst() int
+
+	// RateLimitShared returns whether the rate limit is shared across
+	// processes through a lock file in the configuration directory.
+	RateLimitShared() bool
+}
+
+// NewRateLimiterFromConfig returns a *RateLimiter from the passed config, or
+// nil if rate limiting is disabled or the config doesn't implement
+// RateLimitConfigIface. Shared limiters store their state in the config
+// directory, keyed by realm hostname.
+func NewRateLimiterFromConfig(cfg ConfigIface) *RateLimiter {
+	rc, ok := cfg.(RateLimitConfigIface)
+	if !ok || rc.RateLimit() <= 0 {
+		return nil
+	}
+
+	l := NewRateLimiter(rc.RateLimit(), rc.RateLimitBurst())
+	if rc.RateLimitShared() && cfg.ConfigDir() != "" {
+		realm := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(cfg.RealmHostname())
+		l.SetStateFile(Filepath(cfg.ConfigDir(), "ratelimit-"+realm+".json"))
+	}
+
+	return l
+}
+
+// SetStateFile sets the file the bucket is stored in, which coordinates the
+// limit across processes. A lock file with the ".lock" suffix is created next
+// to it while the bucket is updated.
+func (l *RateLimiter) SetStateFile(filename string) {
+	l.mu.Lock()
+	l.stateFile = filename
+	l.mu.Unlock()
+}
+
+// Wait blocks until a request is allowed or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		wait := l.reserve(time.Now())
+		if wait <= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// reserve takes a token if one is available and returns 0, otherwise it
+// returns how long to wait until the next token is available.
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stateFile != "" {
+		if wait, err := l.reserveShared(now); err == nil {
+			return wait
+		}
+		// Fall back to the local bucket if the state can't be shared.
+	}
+
+	state := rateLimitState{Tokens: l.tokens, Last: l.last}
+	wait := l.take(&state, now)
+	l.tokens, l.last = state.Tokens, state.Last
+	return wait
+}
+
+// reserveShared is reserve using the bucket in the shared state file.
+func (l *RateLimiter) reserveShared(now time.Time) (time.Duration, error) {
+	unlock, err := lockFile(l.stateFile + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	state := rateLimitState{Tokens: float64(l.burst)}
+	if b, err := ioutil.ReadFile(l.stateFile); err == nil {
+		json.Unmarshal(b, &state)
+	}
+
+	wait := l.take(&state, now)
+
+	b, _ := json.Marshal(state)
+	if err := ioutil.WriteFile(l.stateFile, b, 0600); err != nil {
+		return 0, err
+	}
+
+	return wait, nil
+}
+
+// take refills the bucket for the time elapsed since it was last updated, then
+// takes a token if one is available.
+func (l *RateLimiter) take(state *rateLimitState, now time.Time) time.Duration {
+	if !state.Last.IsZero() {
+		if elapsed := now.Sub(state.Last).Seconds(); elapsed > 0 {
+			state.Tokens += elapsed * l.rate
+		}
+	}
+	if state.Tokens > float64(l.burst) {
+		state.Tokens = float64(l.burst)
+	}
+	state.Last = now
+
+	if state.Tokens >= 1 {
+		state.Tokens--
+		return 0
+	}
+
+	return time.Duration((1 - state.Tokens) / l.rate * float64(time.Second))
+}
+
+// lockFile acquires an exclusive lock by creating filename, returning a func
+// that releases it. Locks older than rateLimitLockStale are assumed to have
+// been abandoned by a crashed process and are removed.
+func lockFile(filename string) (unlock func(), err error) {
+	deadline := time.Now().Add(rateLimitLockStale)
+	for {
+		f, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(filename) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, serr := os.Stat(filename); serr == nil && time.Since(info.ModTime()) > rateLimitLockStale {
+			os.Remove(filename)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+
+		time.Sleep(rateLimitLockWait)
+	}
+}
+
+// rateLimitTransport is an http.RoundTripper that waits for the client's rate
+// limiter before sending each request. It is invoked for every attempt, so it
+// also counts them for ContextWithAttempts.
+type rateLimitTransport struct {
+	c    *Client
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	countAttempt(req.Context())
+	if l := t.c.RateLimiter; l != nil {
+		if err := l.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(req)
+}
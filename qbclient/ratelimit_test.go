@@ -0,0 +1,52 @@
+package qbclient_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+func TestRateLimiter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qbclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Two limiters sharing a state file behave as a single bucket.
+	limiters := []*qbclient.RateLimiter{
+		qbclient.NewRateLimiter(50, 2),
+		qbclient.NewRateLimiter(50, 2),
+	}
+	for _, l := range limiters {
+		l.SetStateFile(qbclient.Filepath(dir, "ratelimit.json"))
+	}
+
+	// The burst is available immediately, then each request waits 20ms.
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiters[i%2].Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("got %v elapsed, expected at least 35ms", elapsed)
+	}
+}
+
+func TestRateLimiterCanceled(t *testing.T) {
+	l := qbclient.NewRateLimiter(0.001, 1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx); err == nil {
+		t.Fatal("got nil, expected error")
+	}
+}
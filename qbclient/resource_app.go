@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -35,11 +36,18 @@ func (o *CreateAppOutput) decode(body io.ReadCloser) error { return unmarshalJSO
 
 // CreateApp sends a request to POST /v1/apps.
 // See https://developer.quickbase.com/operation/getApp
-func (c *Client) CreateApp(input *CreateAppInput) (output *CreateAppOutput, err error) {
+func (c *Client) CreateApp(input *CreateAppInput) (*CreateAppOutput, error) {
+	return c.CreateAppWithContext(context.Background(), input)
+}
+
+// CreateAppWithContext sends a request to POST /v1/apps,
+// using the passed context.
+// See https://developer.quickbase.com/operation/getApp
+func (c *Client) CreateAppWithContext(ctx context.Context, input *CreateAppInput) (output *CreateAppOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/apps"
 	output = &CreateAppOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -68,18 +76,30 @@ func (o *GetAppOutput) decode(body io.ReadCloser) error { return unmarshalJSON(b
 
 // GetApp sends a request to GET /v1/apps/{appId}.
 // See https://developer.quickbase.com/operation/getApp
-func (c *Client) GetApp(input *GetAppInput) (output *GetAppOutput, err error) {
+func (c *Client) GetApp(input *GetAppInput) (*GetAppOutput, error) {
+	return c.GetAppWithContext(context.Background(), input)
+}
+
+// GetAppWithContext sends a request to GET /v1/apps/{appId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/getApp
+func (c *Client) GetAppWithContext(ctx context.Context, input *GetAppInput) (output *GetAppOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/apps/" + url.PathEscape(input.AppID)
 	output = &GetAppOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
 // GetAppByID sends a request to GET /v1/apps/{appId} and gets an app by ID.
 // See https://developer.quickbase.com/operation/getApp
 func (c *Client) GetAppByID(id string) (*GetAppOutput, error) {
-	return c.GetApp(&GetAppInput{AppID: id})
+	return c.GetAppByIDWithContext(context.Background(), id)
+}
+
+// GetAppByIDWithContext is GetAppByID using the passed context.
+func (c *Client) GetAppByIDWithContext(ctx context.Context, id string) (*GetAppOutput, error) {
+	return c.GetAppWithContext(ctx, &GetAppInput{AppID: id})
 }
 
 // UpdateAppInput models the input sent to POST /v1/apps/{appId}.
@@ -110,11 +130,18 @@ func (o *UpdateAppOutput) decode(body io.ReadCloser) error { return unmarshalJSO
 
 // UpdateApp sends a request to POST /v1/apps/{appId}.
 // See https://developer.quickbase.com/operation/updateApp
-func (c *Client) UpdateApp(input *UpdateAppInput) (output *UpdateAppOutput, err error) {
+func (c *Client) UpdateApp(input *UpdateAppInput) (*UpdateAppOutput, error) {
+	return c.UpdateAppWithContext(context.Background(), input)
+}
+
+// UpdateAppWithContext sends a request to POST /v1/apps/{appId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/updateApp
+func (c *Client) UpdateAppWithContext(ctx context.Context, input *UpdateAppInput) (output *UpdateAppOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/apps/" + url.PathEscape(input.AppID)
 	output = &UpdateAppOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -145,11 +172,18 @@ func (o *DeleteAppOutput) decode(body io.ReadCloser) error { return unmarshalJSO
 
 // DeleteApp sends a request to DELETE /v1/apps/{appId}.
 // See https://developer.quickbase.com/operation/deleteApp
-func (c *Client) DeleteApp(input *DeleteAppInput) (output *DeleteAppOutput, err error) {
+func (c *Client) DeleteApp(input *DeleteAppInput) (*DeleteAppOutput, error) {
+	return c.DeleteAppWithContext(context.Background(), input)
+}
+
+// DeleteAppWithContext sends a request to DELETE /v1/apps/{appId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/deleteApp
+func (c *Client) DeleteAppWithContext(ctx context.Context, input *DeleteAppInput) (output *DeleteAppOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/apps/" + url.PathEscape(input.AppID)
 	output = &DeleteAppOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -206,11 +240,18 @@ type ListAppEventsOutputEvent struct {
 
 // ListAppEvents sends a request to GET /v1/apps/{appId}/events.
 // See https://developer.quickbase.com/operation/getAppEvents
-func (c *Client) ListAppEvents(input *ListAppEventsInput) (output *ListAppEventsOutput, err error) {
+func (c *Client) ListAppEvents(input *ListAppEventsInput) (*ListAppEventsOutput, error) {
+	return c.ListAppEventsWithContext(context.Background(), input)
+}
+
+// ListAppEventsWithContext sends a request to GET /v1/apps/{appId}/events,
+// using the passed context.
+// See https://developer.quickbase.com/operation/getAppEvents
+func (c *Client) ListAppEventsWithContext(ctx context.Context, input *ListAppEventsInput) (output *ListAppEventsOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/apps/" + url.PathEscape(input.AppID) + "/events"
 	output = &ListAppEventsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -252,10 +293,17 @@ func (o *CopyAppOutput) decode(body io.ReadCloser) error { return unmarshalJSON(
 
 // CopyApp sends a request to POST /v1/apps/{appId}/copy.
 // See https://developer.quickbase.com/operation/copyApp
-func (c *Client) CopyApp(input *CopyAppInput) (output *CopyAppOutput, err error) {
+func (c *Client) CopyApp(input *CopyAppInput) (*CopyAppOutput, error) {
+	return c.CopyAppWithContext(context.Background(), input)
+}
+
+// CopyAppWithContext sends a request to POST /v1/apps/{appId}/copy,
+// using the passed context.
+// See https://developer.quickbase.com/operation/copyApp
+func (c *Client) CopyAppWithContext(ctx context.Context, input *CopyAppInput) (output *CopyAppOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/apps/" + url.PathEscape(input.AppID) + "/copy"
 	output = &CopyAppOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
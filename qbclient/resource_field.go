@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -60,11 +61,18 @@ type ListFieldsOutputFieldProperties struct {
 
 // ListFields sends a request to GET /v1/fields?tableId={tableId}.
 // See https://developer.quickbase.com/operation/getFields
-func (c *Client) ListFields(input *ListFieldsInput) (output *ListFieldsOutput, err error) {
+func (c *Client) ListFields(input *ListFieldsInput) (*ListFieldsOutput, error) {
+	return c.ListFieldsWithContext(context.Background(), input)
+}
+
+// ListFieldsWithContext sends a request to GET /v1/fields?tableId={tableId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/getFields
+func (c *Client) ListFieldsWithContext(ctx context.Context, input *ListFieldsInput) (output *ListFieldsOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/fields?tableId=" + url.QueryEscape(input.TableID)
 	output = &ListFieldsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -72,7 +80,12 @@ func (c *Client) ListFields(input *ListFieldsInput) (output *ListFieldsOutput, e
 // lists fields for the passed table.
 // See https://developer.quickbase.com/operation/getFields
 func (c *Client) ListFieldsByTableID(tableID string) (*ListFieldsOutput, error) {
-	return c.ListFields(&ListFieldsInput{TableID: tableID})
+	return c.ListFieldsByTableIDWithContext(context.Background(), tableID)
+}
+
+// ListFieldsByTableIDWithContext is ListFieldsByTableID using the passed context.
+func (c *Client) ListFieldsByTableIDWithContext(ctx context.Context, tableID string) (*ListFieldsOutput, error) {
+	return c.ListFieldsWithContext(ctx, &ListFieldsInput{TableID: tableID})
 }
 
 // CreateFieldInput models the input sent to POST /v1/fields?tableId={tableId}.
@@ -116,12 +129,19 @@ type CreateFieldOutputProperties struct {
 
 // CreateField sends a request to POST /v1/fields?tableId={tableId}.
 // See https://developer.quickbase.com/operation/createField
-func (c *Client) CreateField(input *CreateFieldInput) (output *CreateFieldOutput, err error) {
+func (c *Client) CreateField(input *CreateFieldInput) (*CreateFieldOutput, error) {
+	return c.CreateFieldWithContext(context.Background(), input)
+}
+
+// CreateFieldWithContext sends a request to POST /v1/fields?tableId={tableId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/createField
+func (c *Client) CreateFieldWithContext(ctx context.Context, input *CreateFieldInput) (output *CreateFieldOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/fields?tableId=" + url.QueryEscape(input.TableID)
 	input.Create = true
 	output = &CreateFieldOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -153,11 +173,18 @@ func (o *DeleteFieldsOutput) decode(body io.ReadCloser) error { return unmarshal
 
 // DeleteFields sends a request to DELETE v1/fields?tableId={tableId}.
 // See https://developer.quickbase.com/operation/deleteFields
-func (c *Client) DeleteFields(input *DeleteFieldsInput) (output *DeleteFieldsOutput, err error) {
+func (c *Client) DeleteFields(input *DeleteFieldsInput) (*DeleteFieldsOutput, error) {
+	return c.DeleteFieldsWithContext(context.Background(), input)
+}
+
+// DeleteFieldsWithContext sends a request to DELETE v1/fields?tableId={tableId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/deleteFields
+func (c *Client) DeleteFieldsWithContext(ctx context.Context, input *DeleteFieldsInput) (output *DeleteFieldsOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/fields?tableId=" + url.QueryEscape(input.TableID)
 	output = &DeleteFieldsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -195,12 +222,19 @@ type GetFieldOutputProperties struct {
 
 // GetField sends a request to GET /v1/fields/{fieldId}?tableId={tableId}.
 // See https://developer.quickbase.com/operation/getField
-func (c *Client) GetField(input *GetFieldInput) (output *GetFieldOutput, err error) {
+func (c *Client) GetField(input *GetFieldInput) (*GetFieldOutput, error) {
+	return c.GetFieldWithContext(context.Background(), input)
+}
+
+// GetFieldWithContext sends a request to GET /v1/fields/{fieldId}?tableId={tableId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/getField
+func (c *Client) GetFieldWithContext(ctx context.Context, input *GetFieldInput) (output *GetFieldOutput, err error) {
 	input.c = c
 	fieldID := strconv.Itoa(input.FieldID)
 	input.u = c.URL + "/fields/" + url.PathEscape(fieldID) + "?tableId=" + url.QueryEscape(input.TableID)
 	output = &GetFieldOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -208,7 +242,12 @@ func (c *Client) GetField(input *GetFieldInput) (output *GetFieldOutput, err err
 // and gets a field by its ID.
 // See https://developer.quickbase.com/operation/getField
 func (c *Client) GetFieldByID(fid int) (*GetFieldOutput, error) {
-	return c.GetField(&GetFieldInput{FieldID: fid})
+	return c.GetFieldByIDWithContext(context.Background(), fid)
+}
+
+// GetFieldByIDWithContext is GetFieldByID using the passed context.
+func (c *Client) GetFieldByIDWithContext(ctx context.Context, fid int) (*GetFieldOutput, error) {
+	return c.GetFieldWithContext(ctx, &GetFieldInput{FieldID: fid})
 }
 
 // UpdateFieldInput models the input sent to POST /v1/fields/{fieldId}?tableId={tableId}.
@@ -222,12 +261,45 @@ type UpdateFieldInput struct {
 	TableID    string                      `json:"-" validate:"required" cliutil:"option=table-id"`
 	FieldID    int                         `json:"-" validate:"required" cliutil:"option=field-id"`
 	Properties *UpdateFieldInputProperties `json:"properties,omitempty"`
+
+	// Clear contains the JSON names of field settings and properties that are
+	// sent even if they are zero values, which turns them off or clears them.
+	// Zero values are omitted otherwise, which leaves them unchanged.
+	Clear []string `json:"-"`
 }
 
 func (i *UpdateFieldInput) url() string                  { return i.u }
 func (i *UpdateFieldInput) method() string               { return http.MethodPost }
 func (i *UpdateFieldInput) addHeaders(req *http.Request) { addHeadersJSON(req, i.c) }
-func (i *UpdateFieldInput) encode() ([]byte, error)      { return marshalJSON(i) }
+
+func (i *UpdateFieldInput) encode() ([]byte, error) {
+	if len(i.Clear) == 0 {
+		return marshalJSON(i)
+	}
+
+	input := *i
+	input.Properties = nil
+	m, err := marshalJSONClear(&input, i.Clear)
+	if err != nil {
+		return nil, err
+	}
+
+	props := &UpdateFieldInputProperties{}
+	if i.Properties != nil {
+		props = i.Properties
+	}
+	pm, err := marshalJSONClear(props, i.Clear)
+	if err != nil {
+		return nil, err
+	}
+	if len(pm) > 0 || i.Properties != nil {
+		if m["properties"], err = json.Marshal(pm); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(m)
+}
 
 // UpdateFieldInputProperties models the "properties" property.
 type UpdateFieldInputProperties struct {
@@ -248,12 +320,19 @@ func (o *UpdateFieldOutput) decode(body io.ReadCloser) error { return unmarshalJ
 
 // UpdateField sends a request to POST /v1/fields/{fieldId}?tableId={tableId}.
 // See https://developer.quickbase.com/operation/updateField
-func (c *Client) UpdateField(input *UpdateFieldInput) (output *UpdateFieldOutput, err error) {
+func (c *Client) UpdateField(input *UpdateFieldInput) (*UpdateFieldOutput, error) {
+	return c.UpdateFieldWithContext(context.Background(), input)
+}
+
+// UpdateFieldWithContext sends a request to POST /v1/fields/{fieldId}?tableId={tableId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/updateField
+func (c *Client) UpdateFieldWithContext(ctx context.Context, input *UpdateFieldInput) (output *UpdateFieldOutput, err error) {
 	input.c = c
 	fieldID := strconv.Itoa(input.FieldID)
 	input.u = c.URL + "/fields/" + url.PathEscape(fieldID) + "?tableId=" + url.QueryEscape(input.TableID)
 	input.Create = false
 	output = &UpdateFieldOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -46,10 +47,17 @@ type DeleteFileOutputCreator struct {
 
 // DeleteFile sends a request to DELETE /v1/files/{tableId}/{recordId}/{fieldId}/{versionNumber}.
 // See https://developer.quickbase.com/operation/deleteFile
-func (c *Client) DeleteFile(input *DeleteFileInput) (output *DeleteFileOutput, err error) {
+func (c *Client) DeleteFile(input *DeleteFileInput) (*DeleteFileOutput, error) {
+	return c.DeleteFileWithContext(context.Background(), input)
+}
+
+// DeleteFileWithContext sends a request to DELETE /v1/files/{tableId}/{recordId}/{fieldId}/{versionNumber},
+// using the passed context.
+// See https://developer.quickbase.com/operation/deleteFile
+func (c *Client) DeleteFileWithContext(ctx context.Context, input *DeleteFileInput) (output *DeleteFileOutput, err error) {
 	input.c = c
 	input.u = fmt.Sprintf("%s/files/%s/%v/%v/%v", c.URL, url.PathEscape(input.TableID), input.RecordID, input.FieldID, input.Version)
 	output = &DeleteFileOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
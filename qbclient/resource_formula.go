@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"io"
 	"net/http"
 )
@@ -20,6 +21,7 @@ func (i *RunFormulaInput) url() string                  { return i.u }
 func (i *RunFormulaInput) method() string               { return http.MethodPost }
 func (i *RunFormulaInput) addHeaders(req *http.Request) { addHeadersJSON(req, i.c) }
 func (i *RunFormulaInput) encode() ([]byte, error)      { return marshalJSON(i) }
+func (i *RunFormulaInput) readOnly()                    {}
 
 // RunFormulaOutput models the output returned by POST /v1/formula/run.
 // See https://developer.quickbase.com/operation/runFormula
@@ -33,10 +35,17 @@ func (o *RunFormulaOutput) decode(body io.ReadCloser) error { return unmarshalJS
 
 // RunFormula sends a request to POST /v1/formula/run.
 // See https://developer.quickbase.com/operation/runFormula
-func (c *Client) RunFormula(input *RunFormulaInput) (output *RunFormulaOutput, err error) {
+func (c *Client) RunFormula(input *RunFormulaInput) (*RunFormulaOutput, error) {
+	return c.RunFormulaWithContext(context.Background(), input)
+}
+
+// RunFormulaWithContext sends a request to POST /v1/formula/run,
+// using the passed context.
+// See https://developer.quickbase.com/operation/runFormula
+func (c *Client) RunFormulaWithContext(ctx context.Context, input *RunFormulaInput) (output *RunFormulaOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/formula/run"
 	output = &RunFormulaOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
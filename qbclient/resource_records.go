@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"io"
 	"net/http"
 )
@@ -48,11 +49,18 @@ type InsertRecordsOutputMetadata struct {
 
 // InsertRecords sends a request to POST /v1/records.
 // See https://developer.quickbase.com/operation/upsert
-func (c *Client) InsertRecords(input *InsertRecordsInput) (output *InsertRecordsOutput, err error) {
+func (c *Client) InsertRecords(input *InsertRecordsInput) (*InsertRecordsOutput, error) {
+	return c.InsertRecordsWithContext(context.Background(), input)
+}
+
+// InsertRecordsWithContext sends a request to POST /v1/records,
+// using the passed context.
+// See https://developer.quickbase.com/operation/upsert
+func (c *Client) InsertRecordsWithContext(ctx context.Context, input *InsertRecordsInput) (output *InsertRecordsOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/records"
 	output = &InsertRecordsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -83,11 +91,18 @@ func (o *DeleteRecordsOutput) decode(body io.ReadCloser) error { return unmarsha
 
 // DeleteRecords sends a request to DELETE /v1/records.
 // See https://developer.quickbase.com/operation/deleteRecords
-func (c *Client) DeleteRecords(input *DeleteRecordsInput) (output *DeleteRecordsOutput, err error) {
+func (c *Client) DeleteRecords(input *DeleteRecordsInput) (*DeleteRecordsOutput, error) {
+	return c.DeleteRecordsWithContext(context.Background(), input)
+}
+
+// DeleteRecordsWithContext sends a request to DELETE /v1/records,
+// using the passed context.
+// See https://developer.quickbase.com/operation/deleteRecords
+func (c *Client) DeleteRecordsWithContext(ctx context.Context, input *DeleteRecordsInput) (output *DeleteRecordsOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/records"
 	output = &DeleteRecordsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -109,6 +124,7 @@ func (i *QueryRecordsInput) url() string                  { return i.u }
 func (i *QueryRecordsInput) method() string               { return http.MethodPost }
 func (i *QueryRecordsInput) addHeaders(req *http.Request) { addHeadersJSON(req, i.c) }
 func (i *QueryRecordsInput) encode() ([]byte, error)      { return marshalJSON(i) }
+func (i *QueryRecordsInput) readOnly()                    {}
 
 // QueryRecordsInputGroupBy models the groupBy objects.
 type QueryRecordsInputGroupBy struct {
@@ -140,10 +156,17 @@ func (o *QueryRecordsOutput) decode(body io.ReadCloser) error { return unmarshal
 
 // QueryRecords sends a request to POST /v1/records/query.
 // See https://developer.quickbase.com/operation/runQuery
-func (c *Client) QueryRecords(input *QueryRecordsInput) (output *QueryRecordsOutput, err error) {
+func (c *Client) QueryRecords(input *QueryRecordsInput) (*QueryRecordsOutput, error) {
+	return c.QueryRecordsWithContext(context.Background(), input)
+}
+
+// QueryRecordsWithContext sends a request to POST /v1/records/query,
+// using the passed context.
+// See https://developer.quickbase.com/operation/runQuery
+func (c *Client) QueryRecordsWithContext(ctx context.Context, input *QueryRecordsInput) (output *QueryRecordsOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/records/query"
 	output = &QueryRecordsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
@@ -40,11 +41,18 @@ type ListRelationshipsOutputMetadata struct {
 
 // ListRelationships sends a request to GET /v1/tables/{tableId}/relationships.
 // See https://developer.quickbase.com/operation/getRelationships
-func (c *Client) ListRelationships(input *ListRelationshipsInput) (output *ListRelationshipsOutput, err error) {
+func (c *Client) ListRelationships(input *ListRelationshipsInput) (*ListRelationshipsOutput, error) {
+	return c.ListRelationshipsWithContext(context.Background(), input)
+}
+
+// ListRelationshipsWithContext sends a request to GET /v1/tables/{tableId}/relationships,
+// using the passed context.
+// See https://developer.quickbase.com/operation/getRelationships
+func (c *Client) ListRelationshipsWithContext(ctx context.Context, input *ListRelationshipsInput) (output *ListRelationshipsOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/tables/" + url.PathEscape(input.ChildTableID) + "/relationships"
 	output = &ListRelationshipsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -52,7 +60,12 @@ func (c *Client) ListRelationships(input *ListRelationshipsInput) (output *ListR
 // and gets a relationship by table ID.
 // See https://developer.quickbase.com/operation/getTable
 func (c *Client) ListRelationshipsByTableID(id string) (*ListRelationshipsOutput, error) {
-	return c.ListRelationships(&ListRelationshipsInput{ChildTableID: id})
+	return c.ListRelationshipsByTableIDWithContext(context.Background(), id)
+}
+
+// ListRelationshipsByTableIDWithContext is ListRelationshipsByTableID using the passed context.
+func (c *Client) ListRelationshipsByTableIDWithContext(ctx context.Context, id string) (*ListRelationshipsOutput, error) {
+	return c.ListRelationshipsWithContext(ctx, &ListRelationshipsInput{ChildTableID: id})
 }
 
 // CreateRelationshipInput models the input sent to POST /v1/tables/{tableId}/relationship.
@@ -91,11 +104,18 @@ func (o *CreateRelationshipOutput) decode(body io.ReadCloser) error {
 
 // CreateRelationship sends a request to POST /v1/tables/{tableId}/relationship.
 // See https://developer.quickbase.com/operation/createRelationship
-func (c *Client) CreateRelationship(input *CreateRelationshipInput) (output *CreateRelationshipOutput, err error) {
+func (c *Client) CreateRelationship(input *CreateRelationshipInput) (*CreateRelationshipOutput, error) {
+	return c.CreateRelationshipWithContext(context.Background(), input)
+}
+
+// CreateRelationshipWithContext sends a request to POST /v1/tables/{tableId}/relationship,
+// using the passed context.
+// See https://developer.quickbase.com/operation/createRelationship
+func (c *Client) CreateRelationshipWithContext(ctx context.Context, input *CreateRelationshipInput) (output *CreateRelationshipOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/tables/" + url.PathEscape(input.ChildTableID) + "/relationship"
 	output = &CreateRelationshipOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -129,11 +149,18 @@ func (o *UpdateRelationshipOutput) decode(body io.ReadCloser) error {
 
 // UpdateRelationship sends a request to POST /v1/tables/{tableId}/relationship/{relationshipId}.
 // See https://developer.quickbase.com/operation/updateRelationship
-func (c *Client) UpdateRelationship(input *UpdateRelationshipInput) (output *UpdateRelationshipOutput, err error) {
+func (c *Client) UpdateRelationship(input *UpdateRelationshipInput) (*UpdateRelationshipOutput, error) {
+	return c.UpdateRelationshipWithContext(context.Background(), input)
+}
+
+// UpdateRelationshipWithContext sends a request to POST /v1/tables/{tableId}/relationship/{relationshipId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/updateRelationship
+func (c *Client) UpdateRelationshipWithContext(ctx context.Context, input *UpdateRelationshipInput) (output *UpdateRelationshipOutput, err error) {
 	input.c = c
 	input.u = c.URL + relationshipPath(input.ChildTableID, input.RelationshipID)
 	output = &UpdateRelationshipOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -166,10 +193,17 @@ func (o *DeleteRelationshipOutput) decode(body io.ReadCloser) error {
 
 // DeleteRelationship sends a request to DELETE /v1/tables/{tableId}/relationship/{relationshipId}
 // See https://developer.quickbase.com/operation/deleteRelationship
-func (c *Client) DeleteRelationship(input *DeleteRelationshipInput) (output *DeleteRelationshipOutput, err error) {
+func (c *Client) DeleteRelationship(input *DeleteRelationshipInput) (*DeleteRelationshipOutput, error) {
+	return c.DeleteRelationshipWithContext(context.Background(), input)
+}
+
+// DeleteRelationshipWithContext sends a request to DELETE /v1/tables/{tableId}/relationship/{relationshipId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/deleteRelationship
+func (c *Client) DeleteRelationshipWithContext(ctx context.Context, input *DeleteRelationshipInput) (output *DeleteRelationshipOutput, err error) {
 	input.c = c
 	input.u = c.URL + relationshipPath(input.ChildTableID, input.RelationshipID)
 	output = &DeleteRelationshipOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -34,11 +35,18 @@ func (o *GetReportOutput) decode(body io.ReadCloser) error { return unmarshalJSO
 
 // GetReport sends a request to GET /v1/reports/{reportId}?tableId={tableId}.
 // See https://developer.quickbase.com/operation/getReport
-func (c *Client) GetReport(input *GetReportInput) (output *GetReportOutput, err error) {
+func (c *Client) GetReport(input *GetReportInput) (*GetReportOutput, error) {
+	return c.GetReportWithContext(context.Background(), input)
+}
+
+// GetReportWithContext sends a request to GET /v1/reports/{reportId}?tableId={tableId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/getReport
+func (c *Client) GetReportWithContext(ctx context.Context, input *GetReportInput) (output *GetReportOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/reports/" + url.PathEscape(input.ReportID) + "?tableId=" + url.QueryEscape(input.TableID)
 	output = &GetReportOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -85,11 +93,18 @@ func (o *ListReportsOutput) MarshalJSON() ([]byte, error) {
 
 // ListReports sends a request to GET /v1/reports?tableId={tableId}.
 // See https://developer.quickbase.com/operation/GetReportReports
-func (c *Client) ListReports(input *ListReportsInput) (output *ListReportsOutput, err error) {
+func (c *Client) ListReports(input *ListReportsInput) (*ListReportsOutput, error) {
+	return c.ListReportsWithContext(context.Background(), input)
+}
+
+// ListReportsWithContext sends a request to GET /v1/reports?tableId={tableId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/GetReportReports
+func (c *Client) ListReportsWithContext(ctx context.Context, input *ListReportsInput) (output *ListReportsOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/reports?tableId=" + url.QueryEscape(input.TableID)
 	output = &ListReportsOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -109,6 +124,7 @@ func (i *RunReportInput) url() string                  { return i.u }
 func (i *RunReportInput) method() string               { return http.MethodPost }
 func (i *RunReportInput) addHeaders(req *http.Request) { addHeadersJSON(req, i.c) }
 func (i *RunReportInput) encode() ([]byte, error)      { return marshalJSON(i) }
+func (i *RunReportInput) readOnly()                    {}
 
 // RunReportOutput models the output returned by POST /v1/reports/{reportId}/run?tableId={tableId}.
 // See https://developer.quickbase.com/operation/runReport
@@ -121,7 +137,14 @@ func (o *RunReportOutput) decode(body io.ReadCloser) error { return unmarshalJSO
 
 // RunReport sends a request to POST /v1/reports/{reportId}/run?tableId={tableId}.
 // See https://developer.quickbase.com/operation/runReport
-func (c *Client) RunReport(input *RunReportInput) (output *RunReportOutput, err error) {
+func (c *Client) RunReport(input *RunReportInput) (*RunReportOutput, error) {
+	return c.RunReportWithContext(context.Background(), input)
+}
+
+// RunReportWithContext sends a request to POST /v1/reports/{reportId}/run?tableId={tableId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/runReport
+func (c *Client) RunReportWithContext(ctx context.Context, input *RunReportInput) (output *RunReportOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/reports/" + url.PathEscape(input.ReportID) + "/run?tableId=" + url.QueryEscape(input.TableID)
 	if input.Skip != 0 {
@@ -131,6 +154,6 @@ func (c *Client) RunReport(input *RunReportInput) (output *RunReportOutput, err
 		input.u += "&top=" + strconv.Itoa(input.Top)
 	}
 	output = &RunReportOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
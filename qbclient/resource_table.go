@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -44,11 +45,18 @@ func (o *CreateTableOutput) decode(body io.ReadCloser) error { return unmarshalJ
 
 // CreateTable sends a request to POST /v1/tables?appId={appId}.
 // See https://developer.quickbase.com/operation/createTable
-func (c *Client) CreateTable(input *CreateTableInput) (output *CreateTableOutput, err error) {
+func (c *Client) CreateTable(input *CreateTableInput) (*CreateTableOutput, error) {
+	return c.CreateTableWithContext(context.Background(), input)
+}
+
+// CreateTableWithContext sends a request to POST /v1/tables?appId={appId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/createTable
+func (c *Client) CreateTableWithContext(ctx context.Context, input *CreateTableInput) (output *CreateTableOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/tables?appId=" + url.QueryEscape(input.AppID)
 	output = &CreateTableOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -117,11 +125,18 @@ type ListTablesOutputTable struct {
 
 // ListTables sends a request to GET /v1/tables?appId={appId}.
 // See https://developer.quickbase.com/operation/getAppTables
-func (c *Client) ListTables(input *ListTablesInput) (output *ListTablesOutput, err error) {
+func (c *Client) ListTables(input *ListTablesInput) (*ListTablesOutput, error) {
+	return c.ListTablesWithContext(context.Background(), input)
+}
+
+// ListTablesWithContext sends a request to GET /v1/tables?appId={appId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/getAppTables
+func (c *Client) ListTablesWithContext(ctx context.Context, input *ListTablesInput) (output *ListTablesOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/tables?appId=" + url.QueryEscape(input.AppID)
 	output = &ListTablesOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -129,7 +144,12 @@ func (c *Client) ListTables(input *ListTablesInput) (output *ListTablesOutput, e
 // list of tables in an app by its ID.
 // See https://developer.quickbase.com/operation/getAppTables
 func (c *Client) ListTablesByAppID(id string) (*ListTablesOutput, error) {
-	return c.ListTables(&ListTablesInput{AppID: id})
+	return c.ListTablesByAppIDWithContext(context.Background(), id)
+}
+
+// ListTablesByAppIDWithContext is ListTablesByAppID using the passed context.
+func (c *Client) ListTablesByAppIDWithContext(ctx context.Context, id string) (*ListTablesOutput, error) {
+	return c.ListTablesWithContext(ctx, &ListTablesInput{AppID: id})
 }
 
 // GetTableInput models the input sent to GET /v1/tables/{tableId}?appId={appId}s.
@@ -174,11 +194,18 @@ func (o *GetTableOutput) decode(body io.ReadCloser) error { return unmarshalJSON
 
 // GetTable sends a request to GET /v1/tables/{tableId}?appId={appId}.
 // See https://developer.quickbase.com/operation/getTable
-func (c *Client) GetTable(input *GetTableInput) (output *GetTableOutput, err error) {
+func (c *Client) GetTable(input *GetTableInput) (*GetTableOutput, error) {
+	return c.GetTableWithContext(context.Background(), input)
+}
+
+// GetTableWithContext sends a request to GET /v1/tables/{tableId}?appId={appId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/getTable
+func (c *Client) GetTableWithContext(ctx context.Context, input *GetTableInput) (output *GetTableOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/tables/" + url.PathEscape(input.TableID) + "?appId=" + url.QueryEscape(input.AppID)
 	output = &GetTableOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -195,12 +222,27 @@ type UpdateTableInput struct {
 	IconName     string `json:"iconName,omitempty" cliutil:"option=icon-name"`
 	SingularNoun string `json:"singularNoun,omitempty" cliutil:"option=singular-noun"`
 	PluralNoun   string `json:"pluralNoun,omitempty" cliutil:"option=plural-noun"`
+
+	// Clear contains the JSON names of properties that are sent even if they
+	// are empty, which clears them. Empty properties are omitted otherwise.
+	Clear []string `json:"-"`
 }
 
 func (i *UpdateTableInput) url() string                  { return i.u }
 func (i *UpdateTableInput) method() string               { return http.MethodPost }
 func (i *UpdateTableInput) addHeaders(req *http.Request) { addHeadersJSON(req, i.c) }
-func (i *UpdateTableInput) encode() ([]byte, error)      { return marshalJSON(i) }
+
+func (i *UpdateTableInput) encode() ([]byte, error) {
+	if len(i.Clear) == 0 {
+		return marshalJSON(i)
+	}
+
+	m, err := marshalJSONClear(i, i.Clear)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
 
 // UpdateTableOutput models the output returned by POST /v1/tables/{tableId}?appId={appId}.
 // See https://developer.quickbase.com/operation/updateTable
@@ -220,11 +262,18 @@ func (o *UpdateTableOutput) decode(body io.ReadCloser) error { return unmarshalJ
 
 // UpdateTable sends a request to POST /v1/tables/{tableId}?appId={appId}.
 // See https://developer.quickbase.com/operation/updateTable
-func (c *Client) UpdateTable(input *UpdateTableInput) (output *UpdateTableOutput, err error) {
+func (c *Client) UpdateTable(input *UpdateTableInput) (*UpdateTableOutput, error) {
+	return c.UpdateTableWithContext(context.Background(), input)
+}
+
+// UpdateTableWithContext sends a request to POST /v1/tables/{tableId}?appId={appId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/updateTable
+func (c *Client) UpdateTableWithContext(ctx context.Context, input *UpdateTableInput) (output *UpdateTableOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/tables/" + url.PathEscape(input.TableID) + "?appId=" + url.QueryEscape(input.AppID)
 	output = &UpdateTableOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -255,10 +304,17 @@ func (o *DeleteTableOutput) decode(body io.ReadCloser) error { return unmarshalJ
 
 // DeleteTable sends a request to DELETE /v1/tables/{tableId}?appId={appId}.
 // See https://developer.quickbase.com/operation/deleteTable
-func (c *Client) DeleteTable(input *DeleteTableInput) (output *DeleteTableOutput, err error) {
+func (c *Client) DeleteTable(input *DeleteTableInput) (*DeleteTableOutput, error) {
+	return c.DeleteTableWithContext(context.Background(), input)
+}
+
+// DeleteTableWithContext sends a request to DELETE /v1/tables/{tableId}?appId={appId},
+// using the passed context.
+// See https://developer.quickbase.com/operation/deleteTable
+func (c *Client) DeleteTableWithContext(ctx context.Context, input *DeleteTableInput) (output *DeleteTableOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/tables/" + url.PathEscape(input.TableID) + "?appId=" + url.QueryEscape(input.AppID)
 	output = &DeleteTableOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
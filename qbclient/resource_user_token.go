@@ -1,6 +1,7 @@
 package qbclient
 
 import (
+	"context"
 	"io"
 	"net/http"
 )
@@ -44,11 +45,18 @@ type CloneUserTokenOutputApp struct {
 
 // CloneUserToken sends a request to POST /v1/usertoken/clone.
 // See https://api.quickbase.com/v1/usertoken/clone
-func (c *Client) CloneUserToken(input *CloneUserTokenInput) (output *CloneUserTokenOutput, err error) {
+func (c *Client) CloneUserToken(input *CloneUserTokenInput) (*CloneUserTokenOutput, error) {
+	return c.CloneUserTokenWithContext(context.Background(), input)
+}
+
+// CloneUserTokenWithContext sends a request to POST /v1/usertoken/clone,
+// using the passed context.
+// See https://api.quickbase.com/v1/usertoken/clone
+func (c *Client) CloneUserTokenWithContext(ctx context.Context, input *CloneUserTokenInput) (output *CloneUserTokenOutput, err error) {
 	input.c = c
 	input.u = c.URL + "/usertoken/clone"
 	output = &CloneUserTokenOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -78,7 +86,14 @@ func (o *DeactivateUserTokenOutput) decode(body io.ReadCloser) error { return un
 
 // DeactivateUserToken sends a request to POST /v1/usertoken/deactivate.
 // See https://developer.quickbase.com/operation/deactivateUserToken
-func (c *Client) DeactivateUserToken(input *DeactivateUserTokenInput) (output *DeactivateUserTokenOutput, err error) {
+func (c *Client) DeactivateUserToken(input *DeactivateUserTokenInput) (*DeactivateUserTokenOutput, error) {
+	return c.DeactivateUserTokenWithContext(context.Background(), input)
+}
+
+// DeactivateUserTokenWithContext sends a request to POST /v1/usertoken/deactivate,
+// using the passed context.
+// See https://developer.quickbase.com/operation/deactivateUserToken
+func (c *Client) DeactivateUserTokenWithContext(ctx context.Context, input *DeactivateUserTokenInput) (output *DeactivateUserTokenOutput, err error) {
 	curToken := c.UserToken
 	c.UserToken = input.Token
 	defer func() { c.UserToken = curToken }()
@@ -86,7 +101,7 @@ func (c *Client) DeactivateUserToken(input *DeactivateUserTokenInput) (output *D
 	input.c = c
 	input.u = c.URL + "/usertoken/deactivate"
 	output = &DeactivateUserTokenOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
 
@@ -116,7 +131,14 @@ func (o *DeleteUserTokenOutput) decode(body io.ReadCloser) error { return unmars
 
 // DeleteUserToken sends a request to DELETE /v1/usertoken.
 // See https://developer.quickbase.com/operation/deleteUserToken
-func (c *Client) DeleteUserToken(input *DeleteUserTokenInput) (output *DeleteUserTokenOutput, err error) {
+func (c *Client) DeleteUserToken(input *DeleteUserTokenInput) (*DeleteUserTokenOutput, error) {
+	return c.DeleteUserTokenWithContext(context.Background(), input)
+}
+
+// DeleteUserTokenWithContext sends a request to DELETE /v1/usertoken,
+// using the passed context.
+// See https://developer.quickbase.com/operation/deleteUserToken
+func (c *Client) DeleteUserTokenWithContext(ctx context.Context, input *DeleteUserTokenInput) (output *DeleteUserTokenOutput, err error) {
 	curToken := c.UserToken
 	c.UserToken = input.Token
 	defer func() { c.UserToken = curToken }()
@@ -124,6 +146,6 @@ func (c *Client) DeleteUserToken(input *DeleteUserTokenInput) (output *DeleteUse
 	input.c = c
 	input.u = c.URL + "/usertoken"
 	output = &DeleteUserTokenOutput{}
-	err = c.Do(input, output)
+	err = c.DoWithContext(ctx, input, output)
 	return
 }
@@ -0,0 +1,305 @@
+package qbclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qberrors"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// Default* constants contain the default retry policy settings.
+const (
+	DefaultRetryMax     = 2
+	DefaultRetryWaitMin = time.Second
+	DefaultRetryWaitMax = 30 * time.Second
+	DefaultRetryJitter  = true
+)
+
+// xmlPeekLimit is the maximum number of bytes of an XML response body that are
+// read when checking for throttling error codes.
+const xmlPeekLimit = 4096
+
+// RetryPolicy controls how failed requests are retried.
+type RetryPolicy struct {
+
+	// Max is the maximum number of retries. Zero disables retries.
+	Max int
+
+	// WaitMin and WaitMax bound the time waited between attempts, including
+	// the wait requested by the Retry-After and rate-limit headers.
+	WaitMin time.Duration
+	WaitMax time.Duration
+
+	// Jitter randomizes the exponential backoff to prevent a thundering herd
+	// when several processes are throttled at the same time.
+	Jitter bool
+}
+
+// RetryConfigIface is implemented by configs that set the retry policy.
+type RetryConfigIface interface {
+
+	// RetryJitter returns whether to randomize the backoff between retries.
+	RetryJitter() bool
+
+	// RetryMax returns the maximum number of retries.
+	RetryMax() int
+
+	// RetryWaitMax returns the maximum time to wait between retries.
+	RetryWaitMax() time.Duration
+
+	// RetryWaitMin returns the minimum time to wait between retries.
+	RetryWaitMin() time.Duration
+}
+
+// NewRetryPolicy returns a RetryPolicy from the passed config. The default
+// policy is returned if the config doesn't implement RetryConfigIface.
+func NewRetryPolicy(cfg ConfigIface) RetryPolicy {
+	p := RetryPolicy{
+		Max:     DefaultRetryMax,
+		WaitMin: DefaultRetryWaitMin,
+		WaitMax: DefaultRetryWaitMax,
+		Jitter:  DefaultRetryJitter,
+	}
+
+	if rc, ok := cfg.(RetryConfigIface); ok {
+		p = RetryPolicy{
+			Max:     rc.RetryMax(),
+			WaitMin: rc.RetryWaitMin(),
+			WaitMax: rc.RetryWaitMax(),
+			Jitter:  rc.RetryJitter(),
+		}
+	}
+
+	if p.Max < 0 {
+		p.Max = 0
+	}
+	if p.WaitMin <= 0 {
+		p.WaitMin = DefaultRetryWaitMin
+	}
+	if p.WaitMax < p.WaitMin {
+		p.WaitMax = p.WaitMin
+	}
+
+	return p
+}
+
+// configure sets the policy on the retryablehttp client.
+func (p RetryPolicy) configure(rh *retryablehttp.Client) {
+	rh.RetryMax = p.Max
+	rh.RetryWaitMin = p.WaitMin
+	rh.RetryWaitMax = p.WaitMax
+	rh.CheckRetry = checkRetry
+	rh.Backoff = p.backoff
+}
+
+// checkRetry implements retryablehttp.CheckRetry. The response is classified
+// into a qberrors.Error, and qberrors.Error.Retry decides whether the request
+// is retried.
+func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if cerr := ctx.Err(); cerr != nil {
+		return false, cerr
+	}
+
+	rerr := classifyResponse(ctx, resp, err)
+	if rerr == nil {
+		return false, nil
+	}
+
+	var qerr qberrors.Error
+	if errors.As(rerr, &qerr) {
+		return qerr.Retry(), nil
+	}
+
+	return false, nil
+}
+
+// classifyResponse returns a qberrors.Error describing a failed request, or
+// nil if the request succeeded. Throttling is treated as a service error so
+// that it is retried.
+func classifyResponse(ctx context.Context, resp *http.Response, err error) error {
+	if err != nil {
+		if retry, _ := retryablehttp.DefaultRetryPolicy(ctx, resp, err); retry {
+			return qberrors.Service(err)
+		}
+		return qberrors.Client(err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		serr := qberrors.ErrSafe{Message: "too many requests", StatusCode: resp.StatusCode}
+		return qberrors.Service(serr).Safe(serr)
+	case resp.StatusCode == http.StatusNotImplemented:
+		serr := qberrors.ErrSafe{Message: "not implemented", StatusCode: resp.StatusCode}
+		return qberrors.Client(serr).Safe(serr)
+	case resp.StatusCode >= 500:
+		msg := strings.ToLower(http.StatusText(resp.StatusCode))
+		serr := qberrors.ErrSafe{Message: msg, StatusCode: resp.StatusCode}
+		return qberrors.Service(serr).Safe(serr)
+	case resp.StatusCode >= 400:
+		msg := strings.ToLower(http.StatusText(resp.StatusCode))
+		serr := qberrors.ErrSafe{Message: msg, StatusCode: resp.StatusCode}
+		return qberrors.Client(serr).Safe(serr)
+	}
+
+	// The XML API returns errors with a 200 status code, so we have to peek at
+	// the body to detect throttling.
+	if code := peekXMLErrorCode(resp); xmlRetryable(code) {
+		serr := qberrors.ErrSafe{Message: "xml api error", StatusCode: xmlStatusCode(code)}
+		return qberrors.Service(serr).Safe(serr)
+	}
+
+	return nil
+}
+
+// peekXMLErrorCode returns the errcode in an XML response body, restoring the
+// body so it can be read again. Only the start of the body is read, which is
+// where the errcode is, and it is kept so that the errcode can be peeked at
+// after the body is drained. It returns 0 for non-XML responses.
+func peekXMLErrorCode(resp *http.Response) int {
+	if resp.Body == nil || !strings.Contains(resp.Header.Get("Content-Type"), "xml") {
+		return 0
+	}
+
+	body, ok := resp.Body.(*peekedBody)
+	if !ok {
+		b, err := ioutil.ReadAll(io.LimitReader(resp.Body, xmlPeekLimit))
+		body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(b), resp.Body), Closer: resp.Body, peeked: b}
+		resp.Body = body
+		if err != nil {
+			return 0
+		}
+	}
+
+	// Decode token by token so a truncated body still yields the errcode.
+	var code int
+	dec := xml.NewDecoder(bytes.NewReader(body.peeked))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return code
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "errcode" {
+			var s string
+			if dec.DecodeElement(&s, &se) == nil {
+				code, _ = strconv.Atoi(strings.TrimSpace(s))
+			}
+			return code
+		}
+	}
+}
+
+// peekedBody is a response body whose start was read by peekXMLErrorCode.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+
+	peeked []byte
+}
+
+// xmlRetryable returns whether an XML API error code is transient, i.e., a
+// timeout, an unavailable service, or throttling.
+// See https://help.quickbase.com/api-guide/errorcodes.html
+func xmlRetryable(code int) bool {
+	switch code {
+	case 82, 100, 101, 104, 105:
+		return true
+	}
+	return false
+}
+
+// xmlStatusCode returns the HTTP status code for a transient XML API error.
+func xmlStatusCode(code int) int {
+	switch code {
+	case 82:
+		return http.StatusGatewayTimeout
+	case 104:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusServiceUnavailable
+}
+
+// backoff implements retryablehttp.Backoff. It honors the wait requested by
+// the server, otherwise it backs off exponentially with optional jitter.
+func (p RetryPolicy) backoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if wait, ok := retryAfter(resp, time.Now()); ok {
+		if wait > max {
+			wait = max
+		}
+		return wait
+	}
+
+	mult := math.Pow(2, float64(attemptNum)) * float64(min)
+	sleep := time.Duration(mult)
+	if float64(sleep) != mult || sleep > max {
+		sleep = max
+	}
+
+	// Use "equal jitter" so we always wait at least half the computed time.
+	if p.Jitter && sleep > 1 {
+		half := sleep / 2
+		sleep = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+
+	return sleep
+}
+
+// retryAfter returns the wait requested by a throttled response through the
+// Retry-After header or, failing that, the RateLimit-Reset header.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	// The XML API throttles requests with a 200 status code and an errcode.
+	status := resp.StatusCode
+	if status == http.StatusOK {
+		if code := peekXMLErrorCode(resp); xmlRetryable(code) {
+			status = xmlStatusCode(code)
+		}
+	}
+	if status != http.StatusTooManyRequests && status != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return nonNegative(t.Sub(now)), true
+		}
+	}
+
+	for _, h := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		secs, err := strconv.ParseInt(resp.Header.Get(h), 10, 64)
+		if err != nil || secs < 0 {
+			continue
+		}
+
+		// Some services send a Unix timestamp rather than a number of seconds.
+		if secs > now.Unix()/2 {
+			return nonNegative(time.Unix(secs, 0).Sub(now)), true
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
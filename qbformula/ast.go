@@ -0,0 +1,120 @@
+package qbformula
+
+// Node is a node in a formula's syntax tree.
+type Node interface {
+	Position() Pos
+}
+
+// Formula is a parsed formula, which declares zero or more variables that are
+// followed by the expression that produces the result.
+type Formula struct {
+	Vars []*VarDecl
+	Expr Node
+}
+
+// VarDecl declares a variable, e.g., var number total = [Price] * [Quantity];
+type VarDecl struct {
+	Pos   Pos
+	Type  Type
+	Name  string
+	Value Node
+}
+
+// NumberLit is a number, e.g., 1.5.
+type NumberLit struct {
+	Pos   Pos
+	Value float64
+}
+
+// TextLit is text enclosed in quotes.
+type TextLit struct {
+	Pos   Pos
+	Value string
+}
+
+// BoolLit is true or false.
+type BoolLit struct {
+	Pos   Pos
+	Value bool
+}
+
+// NullLit is null.
+type NullLit struct {
+	Pos Pos
+}
+
+// FieldRef is a reference to a field by its label, e.g., [Due Date].
+type FieldRef struct {
+	Pos   Pos
+	Label string
+}
+
+// VarRef is a reference to a variable, e.g., $total.
+type VarRef struct {
+	Pos  Pos
+	Name string
+}
+
+// Call is a function call, e.g., Left([Name], 3).
+type Call struct {
+	Pos  Pos
+	Name string
+	Args []Node
+}
+
+// Unary is an operation on one operand, e.g., -[Amount] or not [Done].
+type Unary struct {
+	Pos Pos
+	Op  string
+	X   Node
+}
+
+// Binary is an operation on two operands, e.g., [Price] * [Quantity].
+type Binary struct {
+	Pos Pos
+	Op  string
+	X   Node
+	Y   Node
+}
+
+func (n *VarDecl) Position() Pos   { return n.Pos }
+func (n *NumberLit) Position() Pos { return n.Pos }
+func (n *TextLit) Position() Pos   { return n.Pos }
+func (n *BoolLit) Position() Pos   { return n.Pos }
+func (n *NullLit) Position() Pos   { return n.Pos }
+func (n *FieldRef) Position() Pos  { return n.Pos }
+func (n *VarRef) Position() Pos    { return n.Pos }
+func (n *Call) Position() Pos      { return n.Pos }
+func (n *Unary) Position() Pos     { return n.Pos }
+func (n *Binary) Position() Pos    { return n.Pos }
+
+// Walk calls fn for each node in the tree rooted at n, parents first.
+func Walk(n Node, fn func(Node)) {
+	if n == nil {
+		return
+	}
+
+	fn(n)
+	switch n := n.(type) {
+	case *VarDecl:
+		Walk(n.Value, fn)
+	case *Call:
+		for _, arg := range n.Args {
+			Walk(arg, fn)
+		}
+	case *Unary:
+		Walk(n.X, fn)
+	case *Binary:
+		Walk(n.X, fn)
+		Walk(n.Y, fn)
+	}
+}
+
+// Walk calls fn for each node in the formula's variable declarations and
+// expression.
+func (f *Formula) Walk(fn func(Node)) {
+	for _, v := range f.Vars {
+		Walk(v, fn)
+	}
+	Walk(f.Expr, fn)
+}
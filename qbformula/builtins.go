@@ -0,0 +1,705 @@
+package qbformula
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// builtin evaluates a function whose arguments have been evaluated and whose
+// number and types of arguments have been checked by the linter.
+type builtin func(e *evaluator, args []value) (value, error)
+
+// builtins contains the functions that can be evaluated offline keyed by
+// their names in the function catalog. Functions that depend on the app,
+// e.g., Dbid, or on the realm's users, e.g., UserRoles, are left out.
+var builtins = map[string]builtin{
+	// Text functions.
+	"Begins":           textPredicate(func(s, t string) bool { return strings.HasPrefix(s, t) }),
+	"Contains":         textPredicate(strings.Contains),
+	"Ends":             textPredicate(func(s, t string) bool { return strings.HasSuffix(s, t) }),
+	"Find":             find,
+	"Left":             left,
+	"Length":           length,
+	"List":             list,
+	"Lower":            textFunc(strings.ToLower),
+	"Mid":              mid,
+	"NotLeft":          notLeft,
+	"NotRight":         notRight,
+	"Part":             part,
+	"Right":            right,
+	"SearchAndReplace": searchAndReplace,
+	"Split":            split,
+	"Trim":             textFunc(strings.TrimSpace),
+	"Upper":            textFunc(strings.ToUpper),
+	"URLDecode":        urlDecode,
+	"URLEncode":        textFunc(func(s string) string { return strings.Replace(url.QueryEscape(s), "+", "%20", -1) }),
+
+	// Conversion functions.
+	"ToDate":      toDate,
+	"ToDays":      durationIn(24 * time.Hour),
+	"ToHours":     durationIn(time.Hour),
+	"ToMinutes":   durationIn(time.Minute),
+	"ToMSeconds":  durationIn(time.Millisecond),
+	"ToNumber":    toNumber,
+	"ToSeconds":   durationIn(time.Second),
+	"ToText":      func(e *evaluator, args []value) (value, error) { return text(toText(args[0])), nil },
+	"ToTimeOfDay": toTimeOfDay,
+	"ToTimestamp": toTimestamp,
+	"ToWeeks":     durationIn(7 * 24 * time.Hour),
+
+	// Numeric functions.
+	"Abs":     numberFunc(math.Abs),
+	"Average": average,
+	"Ceil":    roundFunc(math.Ceil),
+	"Count":   count,
+	"Exp":     numberFunc(math.Exp),
+	"Floor":   roundFunc(math.Floor),
+	"Frac":    numberFunc(func(f float64) float64 { return f - math.Trunc(f) }),
+	"Int":     numberFunc(math.Trunc),
+	"Ln":      numberFunc(math.Log),
+	"Log":     numberFunc(math.Log10),
+	"Max":     extreme(1),
+	"Min":     extreme(-1),
+	"Mod":     mod,
+	"Round":   roundFunc(math.Round),
+	"Sign":    numberFunc(sign),
+	"Sqrt":    numberFunc(math.Sqrt),
+	"Sum":     sum,
+
+	// Logical functions.
+	"IsNull": func(e *evaluator, args []value) (value, error) { return boolean(args[0].null()), nil },
+	"Nz":     nz,
+
+	// Date, time, and duration functions.
+	"AdjustMonth":     adjustMonth(0, 1),
+	"AdjustYear":      adjustMonth(1, 0),
+	"Date":            dateFunc,
+	"Day":             datePart(func(t time.Time) int { return t.Day() }),
+	"DayOfWeek":       datePart(func(t time.Time) int { return int(t.Weekday()) }),
+	"DayOfYear":       datePart(func(t time.Time) int { return t.YearDay() }),
+	"Month":           datePart(func(t time.Time) int { return int(t.Month()) }),
+	"Year":            datePart(func(t time.Time) int { return t.Year() }),
+	"Hour":            datePart(func(t time.Time) int { return t.Hour() }),
+	"Minute":          datePart(func(t time.Time) int { return t.Minute() }),
+	"Second":          datePart(func(t time.Time) int { return t.Second() }),
+	"MSecond":         datePart(func(t time.Time) int { return t.Nanosecond() / int(time.Millisecond) }),
+	"Days":            durationOf(24 * time.Hour),
+	"Hours":           durationOf(time.Hour),
+	"Minutes":         durationOf(time.Minute),
+	"MSeconds":        durationOf(time.Millisecond),
+	"Seconds":         durationOf(time.Second),
+	"Weeks":           durationOf(7 * 24 * time.Hour),
+	"FirstDayOfMonth": dateFunc1(func(t time.Time) time.Time { return t.AddDate(0, 0, 1-t.Day()) }),
+	"FirstDayOfYear":  dateFunc1(func(t time.Time) time.Time { return t.AddDate(0, 0, 1-t.YearDay()) }),
+	"LastDayOfMonth":  dateFunc1(func(t time.Time) time.Time { return t.AddDate(0, 1, -t.Day()) }),
+	"LastDayOfYear":   dateFunc1(func(t time.Time) time.Time { return time.Date(t.Year(), 12, 31, 0, 0, 0, 0, time.UTC) }),
+	"IsWeekday":       isWeekday,
+	"NextDayOfWeek":   dayOfWeek(1),
+	"PrevDayOfWeek":   dayOfWeek(-1),
+	"Now":             func(e *evaluator, args []value) (value, error) { return datetime(e.env.Now), nil },
+	"Today":           func(e *evaluator, args []value) (value, error) { return date(e.env.Now), nil },
+	"WeekdayAdd":      weekdayAdd,
+	"WeekdaySub":      weekdaySub,
+
+	// User and list functions.
+	"Includes":    includes,
+	"Size":        size,
+	"User":        currentUser,
+	"UserToEmail": userFunc(func(u *qbclient.User) string { return u.Email }),
+	"UserToID":    userFunc(func(u *qbclient.User) string { return u.ID }),
+	"UserToName":  userFunc(func(u *qbclient.User) string { return u.Name }),
+}
+
+func textFunc(fn func(string) string) builtin {
+	return func(e *evaluator, args []value) (value, error) { return text(fn(args[0].str())), nil }
+}
+
+// textPredicate returns a function that compares text without regard to case.
+func textPredicate(fn func(s, t string) bool) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		return boolean(fn(strings.ToLower(args[0].str()), strings.ToLower(args[1].str()))), nil
+	}
+}
+
+// numberFunc returns a function of one number, which is null if the number is
+// null or the result isn't a number, e.g., Sqrt(-1).
+func numberFunc(fn func(float64) float64) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() {
+			return null(TypeNumber), nil
+		}
+		f := fn(args[0].num())
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return null(TypeNumber), nil
+		}
+		return number(f), nil
+	}
+}
+
+// roundFunc returns a function that rounds a number to a multiple of the
+// optional second argument, e.g., Round(1.234, 0.01) is 1.23.
+func roundFunc(fn func(float64) float64) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() {
+			return null(TypeNumber), nil
+		}
+
+		x, step := args[0].num(), 1.0
+		if len(args) > 1 && !args[1].null() {
+			step = args[1].num()
+		}
+		if step == 0 {
+			return number(x), nil
+		}
+
+		// The quotient is rounded to discard the representation error of
+		// decimal fractions before it is rounded to a whole number, e.g.,
+		// 2.345 / 0.01 is 234.49999999999997.
+		q := math.Round(x/step*1e9) / 1e9
+		if inv := 1 / step; inv == math.Trunc(inv) {
+			return number(fn(q) / inv), nil
+		}
+		return number(fn(q) * step), nil
+	}
+}
+
+func sign(f float64) float64 {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+func length(e *evaluator, args []value) (value, error) {
+	return number(float64(utf8.RuneCountInString(args[0].str()))), nil
+}
+
+// find returns the 1-based position of text, which is 0 if it isn't found.
+func find(e *evaluator, args []value) (value, error) {
+	s, t := []rune(args[0].str()), args[1].str()
+	start := 1
+	if len(args) > 2 && !args[2].null() {
+		start = int(args[2].num())
+	}
+	if start < 1 || start > len(s) {
+		return number(0), nil
+	}
+
+	idx := strings.Index(string(s[start-1:]), t)
+	if idx < 0 {
+		return number(0), nil
+	}
+	return number(float64(start + utf8.RuneCountInString(string(s[start-1:])[:idx]))), nil
+}
+
+// left returns the first n characters of text, or the characters before the
+// first of a set of delimiters if the second argument is text.
+func left(e *evaluator, args []value) (value, error) {
+	s := []rune(args[0].str())
+	if args[1].t == TypeText {
+		if idx := strings.IndexAny(string(s), args[1].str()); idx >= 0 {
+			return text(string(s)[:idx]), nil
+		}
+		return text(string(s)), nil
+	}
+	n := clampInt(int(args[1].num()), 0, len(s))
+	return text(string(s[:n])), nil
+}
+
+// right returns the last n characters of text, or the characters after the
+// last of a set of delimiters if the second argument is text.
+func right(e *evaluator, args []value) (value, error) {
+	s := []rune(args[0].str())
+	if args[1].t == TypeText {
+		if idx := strings.LastIndexAny(string(s), args[1].str()); idx >= 0 {
+			_, size := utf8.DecodeRuneInString(string(s)[idx:])
+			return text(string(s)[idx+size:]), nil
+		}
+		return text(string(s)), nil
+	}
+	n := clampInt(int(args[1].num()), 0, len(s))
+	return text(string(s[len(s)-n:])), nil
+}
+
+// notLeft returns the text that Left doesn't.
+func notLeft(e *evaluator, args []value) (value, error) {
+	s := []rune(args[0].str())
+	if args[1].t == TypeText {
+		if idx := strings.IndexAny(string(s), args[1].str()); idx >= 0 {
+			_, size := utf8.DecodeRuneInString(string(s)[idx:])
+			return text(string(s)[idx+size:]), nil
+		}
+		return text(""), nil
+	}
+	n := clampInt(int(args[1].num()), 0, len(s))
+	return text(string(s[n:])), nil
+}
+
+// notRight returns the text that Right doesn't.
+func notRight(e *evaluator, args []value) (value, error) {
+	s := []rune(args[0].str())
+	if args[1].t == TypeText {
+		if idx := strings.LastIndexAny(string(s), args[1].str()); idx >= 0 {
+			return text(string(s)[:idx]), nil
+		}
+		return text(""), nil
+	}
+	n := clampInt(int(args[1].num()), 0, len(s))
+	return text(string(s[:len(s)-n])), nil
+}
+
+// mid returns count characters starting at the 1-based position.
+func mid(e *evaluator, args []value) (value, error) {
+	s := []rune(args[0].str())
+	start := clampInt(int(args[1].num())-1, 0, len(s))
+	end := clampInt(start+int(args[2].num()), start, len(s))
+	return text(string(s[start:end])), nil
+}
+
+// part returns the nth part of text split by any of the delimiters.
+func part(e *evaluator, args []value) (value, error) {
+	delims := args[2].str()
+	parts := strings.FieldsFunc(args[0].str(), func(r rune) bool { return strings.ContainsRune(delims, r) })
+	if n := int(args[1].num()); n >= 1 && n <= len(parts) {
+		return text(parts[n-1]), nil
+	}
+	return text(""), nil
+}
+
+// list joins the values that aren't empty with the delimiter.
+func list(e *evaluator, args []value) (value, error) {
+	var parts []string
+	for _, arg := range args[1:] {
+		if s := toText(arg); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return text(strings.Join(parts, args[0].str())), nil
+}
+
+func searchAndReplace(e *evaluator, args []value) (value, error) {
+	if args[1].str() == "" {
+		return text(args[0].str()), nil
+	}
+	return text(strings.Replace(args[0].str(), args[1].str(), args[2].str(), -1)), nil
+}
+
+// split splits text into a list, which is delimited by semicolons by default.
+func split(e *evaluator, args []value) (value, error) {
+	delim := ";"
+	if len(args) > 1 && !args[1].null() {
+		delim = args[1].str()
+	}
+
+	var items []string
+	for _, item := range strings.Split(args[0].str(), delim) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return value{TypeTextList, qbclient.NewMultiSelectTextValue(items)}, nil
+}
+
+func urlDecode(e *evaluator, args []value) (value, error) {
+	s, err := url.QueryUnescape(args[0].str())
+	if err != nil {
+		return null(TypeText), nil
+	}
+	return text(s), nil
+}
+
+func clampInt(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// toDate converts text, e.g., 2021-06-01, or a date/time to a date. Text that
+// isn't a date is null.
+func toDate(e *evaluator, args []value) (value, error) {
+	switch {
+	case args[0].null():
+		return null(TypeDate), nil
+	case args[0].t == TypeDate || args[0].t == TypeDateTime:
+		return date(args[0].time()), nil
+	}
+
+	v, err := qbclient.NewDateValueFromString(args[0].str())
+	if err != nil {
+		return null(TypeDate), nil
+	}
+	return date(v.Time), nil
+}
+
+// toTimeOfDay converts text, e.g., 13:30, or a date/time to a time of day.
+func toTimeOfDay(e *evaluator, args []value) (value, error) {
+	switch {
+	case args[0].null():
+		return null(TypeTimeOfDay), nil
+	case args[0].t == TypeDateTime || args[0].t == TypeTimeOfDay:
+		return timeOfDay(args[0].time()), nil
+	}
+
+	v, err := qbclient.NewTimeOfDayValueFromString(args[0].str())
+	if err != nil {
+		return null(TypeTimeOfDay), nil
+	}
+	return timeOfDay(v.Time), nil
+}
+
+// toTimestamp converts a date and an optional time of day to a date/time.
+func toTimestamp(e *evaluator, args []value) (value, error) {
+	d, err := toDate(e, args[:1])
+	if d.null() {
+		return null(TypeDateTime), err
+	}
+
+	tm := d.time()
+	if len(args) > 1 && !args[1].null() {
+		tm = tm.Add(clock(args[1].time()))
+	}
+	return datetime(tm), nil
+}
+
+// toNumber converts text or a checkbox to a number. Text that isn't a number
+// is null.
+func toNumber(e *evaluator, args []value) (value, error) {
+	switch {
+	case args[0].null():
+		return null(TypeNumber), nil
+	case args[0].t == TypeNumber:
+		return args[0], nil
+	case args[0].t == TypeBool:
+		return number(float64(boolToNumber(args[0].bool()))), nil
+	}
+
+	f, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(args[0].str()), ",", "", -1), 64)
+	if err != nil {
+		return null(TypeNumber), nil
+	}
+	return number(f), nil
+}
+
+// durationIn returns a function that converts a duration to a number of units.
+func durationIn(unit time.Duration) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() {
+			return null(TypeNumber), nil
+		}
+		return number(float64(args[0].dur()) / float64(unit)), nil
+	}
+}
+
+// durationOf returns a function that converts a number of units to a duration.
+func durationOf(unit time.Duration) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() {
+			return null(TypeDuration), nil
+		}
+		return duration(time.Duration(args[0].num() * float64(unit))), nil
+	}
+}
+
+// nonNull returns the arguments that aren't null.
+func nonNull(args []value) []value {
+	var vals []value
+	for _, arg := range args {
+		if !arg.null() {
+			vals = append(vals, arg)
+		}
+	}
+	return vals
+}
+
+// sum adds numbers or durations, ignoring nulls.
+func sum(e *evaluator, args []value) (value, error) {
+	vals := nonNull(args)
+	if len(vals) == 0 {
+		return null(args[0].t), nil
+	}
+
+	if vals[0].t == TypeDuration {
+		var d time.Duration
+		for _, v := range vals {
+			d += v.dur()
+		}
+		return duration(d), nil
+	}
+
+	var f float64
+	for _, v := range vals {
+		if v.t != TypeNumber {
+			return value{}, errors.New("expecting numbers or durations")
+		}
+		f += v.num()
+	}
+	return number(f), nil
+}
+
+// average returns the average of numbers or durations, ignoring nulls.
+func average(e *evaluator, args []value) (value, error) {
+	total, err := sum(e, args)
+	if err != nil || total.null() {
+		return total, err
+	}
+
+	n := float64(len(nonNull(args)))
+	if total.t == TypeDuration {
+		return duration(time.Duration(float64(total.dur()) / n)), nil
+	}
+	return number(total.num() / n), nil
+}
+
+// count returns the number of arguments that aren't null, zero, or false.
+func count(e *evaluator, args []value) (value, error) {
+	n := 0
+	for _, v := range nonNull(args) {
+		if v.t == TypeNumber && v.num() == 0 || v.t == TypeBool && !v.bool() {
+			continue
+		}
+		n++
+	}
+	return number(float64(n)), nil
+}
+
+// extreme returns a function that returns the greatest value if dir is 1, or
+// the least if it is -1, ignoring nulls.
+func extreme(dir int) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		vals := nonNull(args)
+		if len(vals) == 0 {
+			return null(args[0].t), nil
+		}
+
+		best := vals[0]
+		for _, v := range vals[1:] {
+			if !v.t.Compatible(best.t) {
+				return value{}, errors.New("values have different types")
+			}
+			if compare(v, best) == dir {
+				best = v
+			}
+		}
+		return best, nil
+	}
+}
+
+func mod(e *evaluator, args []value) (value, error) {
+	if args[0].null() || args[1].null() || args[1].num() == 0 {
+		return null(TypeNumber), nil
+	}
+	return number(math.Mod(args[0].num(), args[1].num())), nil
+}
+
+// nz returns the second argument if the first is null, or zero for numbers
+// and durations if there is no second argument.
+func nz(e *evaluator, args []value) (value, error) {
+	switch {
+	case !args[0].null():
+		return args[0], nil
+	case len(args) > 1:
+		return args[1], nil
+	case args[0].t == TypeNumber:
+		return number(0), nil
+	case args[0].t == TypeDuration:
+		return duration(0), nil
+	case args[0].t == TypeText:
+		return text(""), nil
+	}
+	return args[0], nil
+}
+
+// dateFunc returns the date for a year, month, and day.
+func dateFunc(e *evaluator, args []value) (value, error) {
+	for _, arg := range args {
+		if arg.null() {
+			return null(TypeDate), nil
+		}
+	}
+
+	y, m, d := int(args[0].num()), int(args[1].num()), int(args[2].num())
+	if m < 1 || m > 12 || d < 1 || d > daysIn(time.Month(m), y) {
+		return null(TypeDate), nil
+	}
+	return date(time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)), nil
+}
+
+func daysIn(m time.Month, y int) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// adjustMonth returns a function that adds years and months to a date. The
+// day is the last day of the month if the month is shorter, e.g., adding a
+// month to January 31 is February 28.
+func adjustMonth(years, months int) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() || args[1].null() {
+			return null(TypeDate), nil
+		}
+
+		t, n := args[0].time(), int(args[1].num())
+		first := time.Date(t.Year()+years*n, t.Month()+time.Month(months*n), 1, 0, 0, 0, 0, time.UTC)
+		day := t.Day()
+		if days := daysIn(first.Month(), first.Year()); day > days {
+			day = days
+		}
+		return date(first.AddDate(0, 0, day-1)), nil
+	}
+}
+
+// datePart returns a function that returns part of a date or time, e.g., the
+// month, which is null if the date or time is null.
+func datePart(fn func(time.Time) int) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() {
+			return null(TypeNumber), nil
+		}
+		return number(float64(fn(args[0].time()))), nil
+	}
+}
+
+// dateFunc1 returns a function that maps a date to another date.
+func dateFunc1(fn func(time.Time) time.Time) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() {
+			return null(TypeDate), nil
+		}
+		return date(fn(date(args[0].time()).time())), nil
+	}
+}
+
+func weekday(t time.Time) bool {
+	return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+}
+
+func isWeekday(e *evaluator, args []value) (value, error) {
+	return boolean(!args[0].null() && weekday(args[0].time())), nil
+}
+
+// dayOfWeek returns a function that returns the next date after a date, or
+// the previous date if dir is -1, that falls on a day of the week, where 0
+// is Sunday.
+func dayOfWeek(dir int) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() || args[1].null() {
+			return null(TypeDate), nil
+		}
+
+		t, dow := args[0].time(), time.Weekday(args[1].num())
+		for t = t.AddDate(0, 0, dir); t.Weekday() != dow; t = t.AddDate(0, 0, dir) {
+		}
+		return date(t), nil
+	}
+}
+
+// weekdayAdd adds a number of weekdays to a date.
+func weekdayAdd(e *evaluator, args []value) (value, error) {
+	if args[0].null() || args[1].null() {
+		return null(TypeDate), nil
+	}
+
+	t, n, dir := args[0].time(), int(args[1].num()), 1
+	if n < 0 {
+		n, dir = -n, -1
+	}
+	for n > 0 {
+		if t = t.AddDate(0, 0, dir); weekday(t) {
+			n--
+		}
+	}
+	return date(t), nil
+}
+
+// weekdaySub returns the number of weekdays after the second date up to and
+// including the first, which is negative if the first date is earlier.
+func weekdaySub(e *evaluator, args []value) (value, error) {
+	if args[0].null() || args[1].null() {
+		return null(TypeNumber), nil
+	}
+
+	to, from, dir := args[0].time(), args[1].time(), 1
+	if to.Before(from) {
+		to, from, dir = from, to, -1
+	}
+
+	n := 0
+	for t := from.AddDate(0, 0, 1); !t.After(to); t = t.AddDate(0, 0, 1) {
+		if weekday(t) {
+			n++
+		}
+	}
+	return number(float64(n * dir)), nil
+}
+
+// includes returns whether a list contains a value, which is compared to the
+// IDs of users in user lists.
+func includes(e *evaluator, args []value) (value, error) {
+	if args[0].null() || args[1].null() {
+		return boolean(false), nil
+	}
+
+	want := args[1].str()
+	if args[1].t == TypeUser {
+		want = args[1].user().ID
+	}
+
+	switch args[0].t {
+	case TypeTextList:
+		for _, s := range args[0].list() {
+			if s == want {
+				return boolean(true), nil
+			}
+		}
+	case TypeUserList:
+		for _, u := range args[0].v.UserSlice {
+			if u.ID == want || u.Email == want {
+				return boolean(true), nil
+			}
+		}
+	default:
+		return value{}, errors.New("expecting a list")
+	}
+	return boolean(false), nil
+}
+
+func size(e *evaluator, args []value) (value, error) {
+	switch {
+	case args[0].null():
+		return number(0), nil
+	case args[0].t == TypeTextList:
+		return number(float64(len(args[0].list()))), nil
+	case args[0].t == TypeUserList:
+		return number(float64(len(args[0].v.UserSlice))), nil
+	}
+	return value{}, errors.New("expecting a list")
+}
+
+func currentUser(e *evaluator, args []value) (value, error) {
+	if e.env.User == nil {
+		return null(TypeUser), nil
+	}
+	return value{TypeUser, qbclient.NewUserValue(e.env.User)}, nil
+}
+
+func userFunc(fn func(*qbclient.User) string) builtin {
+	return func(e *evaluator, args []value) (value, error) {
+		if args[0].null() || args[0].user() == nil {
+			return null(TypeText), nil
+		}
+		return text(fn(args[0].user())), nil
+	}
+}
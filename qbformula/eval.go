@@ -0,0 +1,497 @@
+package qbformula
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// Env contains the values a formula is evaluated with besides the record's.
+type Env struct {
+	// Now is the time returned by Now() and Today(), which defaults to the
+	// current time.
+	Now time.Time
+
+	// User is the user returned by User(), which is null if not set.
+	User *qbclient.User
+}
+
+// Field is the value of a field in the record a formula is evaluated against.
+// Type is the Quickbase field type, e.g., numeric, and a nil Value is null.
+type Field struct {
+	Type  string
+	Value *qbclient.Value
+}
+
+// EvalError is an error evaluating a formula, e.g., a type mismatch or a
+// function that can't be evaluated offline.
+type EvalError struct {
+	Pos     Pos
+	Message string
+}
+
+func (e *EvalError) Error() string { return e.Pos.String() + ": " + e.Message }
+
+// Eval evaluates a formula against a record whose fields are keyed by label.
+// The formula is linted against the record's fields first, and the first
+// error is returned, so that formulas Quickbase would refuse to save aren't
+// evaluated. The result is nil if it is null.
+func Eval(formula string, record map[string]*Field, env *Env) (*qbclient.Value, error) {
+	f, err := Parse(formula)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]Type, len(record))
+	for label, field := range record {
+		types[label] = FieldType(field.Type)
+	}
+
+	diags, _ := Lint(formula, types)
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return nil, &EvalError{Pos: d.Pos, Message: d.Message}
+		}
+	}
+
+	if env == nil {
+		env = &Env{}
+	}
+	if env.Now.IsZero() {
+		env = &Env{Now: time.Now(), User: env.User}
+	}
+
+	e := &evaluator{
+		record: make(map[string]value, len(record)),
+		vars:   map[string]value{},
+		env:    env,
+	}
+	for label, field := range record {
+		e.record[strings.ToLower(label)] = fieldValue(field)
+	}
+
+	for _, v := range f.Vars {
+		val, err := e.eval(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		if val.null() {
+			val.t = v.Type
+		}
+		e.vars[v.Name] = val
+	}
+
+	val, err := e.eval(f.Expr)
+	if err != nil {
+		return nil, err
+	}
+	if val.null() {
+		return nil, nil
+	}
+	return val.v, nil
+}
+
+// value is a typed value. Null values have a type but no Value.
+type value struct {
+	t Type
+	v *qbclient.Value
+}
+
+// null returns whether the value is null. Empty text is null, like in
+// Quickbase.
+func (v value) null() bool {
+	return v.v == nil || v.t == TypeText && v.v.Str == ""
+}
+
+func (v value) num() float64         { return v.v.Float64 }
+func (v value) str() string          { return toText(v) }
+func (v value) bool() bool           { return !v.null() && v.v.Bool }
+func (v value) time() time.Time      { return v.v.Time }
+func (v value) dur() time.Duration   { return v.v.Duration }
+func (v value) list() []string       { return v.v.StrSlice }
+func (v value) user() *qbclient.User { return v.v.User }
+
+func null(t Type) value              { return value{t: t} }
+func number(f float64) value         { return value{TypeNumber, qbclient.NewNumericValue(f)} }
+func text(s string) value            { return value{TypeText, qbclient.NewTextValue(s)} }
+func boolean(b bool) value           { return value{TypeBool, qbclient.NewCheckboxValue(b)} }
+func duration(d time.Duration) value { return value{TypeDuration, qbclient.NewDurationValue(d)} }
+func datetime(t time.Time) value     { return value{TypeDateTime, qbclient.NewDateTimeValue(t.UTC())} }
+
+// date returns a date value, discarding the time of day.
+func date(t time.Time) value {
+	y, m, d := t.Date()
+	return value{TypeDate, qbclient.NewDateValue(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))}
+}
+
+// timeOfDay returns a time of day value, discarding the date.
+func timeOfDay(t time.Time) value {
+	return value{TypeTimeOfDay, qbclient.NewTimeOfDayValue(time.Time{}.Add(clock(t)))}
+}
+
+// clock returns the time elapsed since midnight.
+func clock(t time.Time) time.Duration {
+	return t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()))
+}
+
+// fieldValue converts the value of a field to the type formulas see it as,
+// e.g., email addresses are text.
+func fieldValue(f *Field) value {
+	t := FieldType(f.Type)
+	if f.Value == nil {
+		return null(t)
+	}
+
+	switch t {
+	case TypeNumber:
+		return number(f.Value.Float64)
+	case TypeBool:
+		return boolean(f.Value.Bool)
+	case TypeDate:
+		return date(f.Value.Time)
+	case TypeDateTime:
+		return datetime(f.Value.Time)
+	case TypeTimeOfDay:
+		return timeOfDay(f.Value.Time)
+	case TypeDuration:
+		return duration(f.Value.Duration)
+	case TypeUser, TypeTextList, TypeUserList:
+		return value{t, f.Value}
+	}
+	return text(f.Value.String())
+}
+
+// toText converts a value to text like ToText does. Null is empty text.
+func toText(v value) string {
+	switch {
+	case v.v == nil:
+		return ""
+	case v.t == TypeText:
+		return v.v.Str
+	case v.t == TypeNumber:
+		return strconv.FormatFloat(v.v.Float64, 'f', -1, 64)
+	case v.t == TypeTextList:
+		return strings.Join(v.v.StrSlice, "; ")
+	case v.t == TypeUser:
+		return v.v.User.Email
+	}
+	return v.v.String()
+}
+
+// evaluator evaluates the nodes of a formula that has been linted, so the
+// types of the operands are known to be valid.
+type evaluator struct {
+	record map[string]value
+	vars   map[string]value
+	env    *Env
+}
+
+func (e *evaluator) errorf(pos Pos, format string, a ...interface{}) error {
+	return &EvalError{Pos: pos, Message: fmt.Sprintf(format, a...)}
+}
+
+func (e *evaluator) eval(n Node) (value, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		return number(n.Value), nil
+	case *TextLit:
+		return text(n.Value), nil
+	case *BoolLit:
+		return boolean(n.Value), nil
+	case *NullLit:
+		return null(TypeAny), nil
+	case *FieldRef:
+		if v, ok := e.record[strings.ToLower(n.Label)]; ok {
+			return v, nil
+		}
+		return value{}, e.errorf(n.Pos, "[%s]: field not in record", n.Label)
+	case *VarRef:
+		return e.vars[n.Name], nil
+	case *Call:
+		return e.call(n)
+	case *Unary:
+		return e.unary(n)
+	case *Binary:
+		return e.binary(n)
+	}
+	return value{}, e.errorf(n.Position(), "unexpected node")
+}
+
+func (e *evaluator) unary(n *Unary) (value, error) {
+	x, err := e.eval(n.X)
+	if err != nil || n.Op == "+" {
+		return x, err
+	}
+
+	switch {
+	case n.Op == "not":
+		return boolean(!x.bool()), nil
+	case x.null():
+		return x, nil
+	case x.t == TypeDuration:
+		return duration(-x.dur()), nil
+	}
+	return number(-x.num()), nil
+}
+
+func (e *evaluator) binary(n *Binary) (value, error) {
+	x, err := e.eval(n.X)
+	if err != nil {
+		return x, err
+	}
+
+	// The operands of and and or are evaluated lazily.
+	switch n.Op {
+	case "and":
+		if !x.bool() {
+			return boolean(false), nil
+		}
+		y, err := e.eval(n.Y)
+		return boolean(y.bool()), err
+	case "or":
+		if x.bool() {
+			return boolean(true), nil
+		}
+		y, err := e.eval(n.Y)
+		return boolean(y.bool()), err
+	}
+
+	y, err := e.eval(n.Y)
+	if err != nil {
+		return y, err
+	}
+
+	switch n.Op {
+	case "=", "==":
+		return boolean(equal(x, y)), nil
+	case "!=", "<>":
+		return boolean(!equal(x, y)), nil
+	case "<", "<=", ">", ">=":
+		if x.null() || y.null() {
+			return boolean(false), nil
+		}
+		c := compare(x, y)
+		switch n.Op {
+		case "<":
+			return boolean(c < 0), nil
+		case "<=":
+			return boolean(c <= 0), nil
+		case ">":
+			return boolean(c > 0), nil
+		}
+		return boolean(c >= 0), nil
+	case "&":
+		return text(toText(x) + toText(y)), nil
+	}
+
+	return e.arithmetic(n, x, y)
+}
+
+// equal returns whether two values are equal. Null is only equal to null.
+func equal(x, y value) bool {
+	if x.null() || y.null() {
+		return x.null() && y.null()
+	}
+	return compare(x, y) == 0
+}
+
+// compare returns -1, 0, or 1 if x is less than, equal to, or greater than y,
+// which are not null and have compatible types.
+func compare(x, y value) int {
+	switch x.t.family() {
+	case TypeNumber:
+		return compareFloat(x.num(), y.num())
+	case TypeDuration:
+		return compareFloat(float64(x.dur()), float64(y.dur()))
+	case TypeDate:
+		return compareFloat(float64(x.time().Unix()), float64(y.time().Unix()))
+	case TypeTimeOfDay:
+		return compareFloat(float64(clock(x.time())), float64(clock(y.time())))
+	case TypeBool:
+		return compareFloat(float64(boolToNumber(x.bool())), float64(boolToNumber(y.bool())))
+	case TypeUser:
+		return strings.Compare(x.user().ID, y.user().ID)
+	}
+	return strings.Compare(toText(x), toText(y))
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolToNumber(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// arithmetic performs the operations the linter's arithmetic function allows.
+// The result is null if either operand is null, or if dividing by zero.
+func (e *evaluator) arithmetic(n *Binary, x, y value) (value, error) {
+	t, _ := arithmetic(n.Op, x.t, y.t)
+	if t == TypeText {
+		return text(toText(x) + toText(y)), nil
+	}
+	if x.null() || y.null() {
+		return null(t), nil
+	}
+
+	type operands struct{ x, y Type }
+	switch o := (operands{x.t.family(), y.t.family()}); {
+	case o == operands{TypeNumber, TypeNumber}:
+		switch n.Op {
+		case "+":
+			return number(x.num() + y.num()), nil
+		case "-":
+			return number(x.num() - y.num()), nil
+		case "*":
+			return number(x.num() * y.num()), nil
+		case "/":
+			if y.num() == 0 {
+				return null(TypeNumber), nil
+			}
+			return number(x.num() / y.num()), nil
+		case "^":
+			return number(math.Pow(x.num(), y.num())), nil
+		}
+
+	case o == operands{TypeDuration, TypeDuration}:
+		switch n.Op {
+		case "+":
+			return duration(x.dur() + y.dur()), nil
+		case "-":
+			return duration(x.dur() - y.dur()), nil
+		case "/":
+			if y.dur() == 0 {
+				return null(TypeNumber), nil
+			}
+			return number(float64(x.dur()) / float64(y.dur())), nil
+		}
+
+	case o == operands{TypeDuration, TypeNumber}, o == operands{TypeNumber, TypeDuration}:
+		d, f := x.dur(), y.num()
+		if x.t == TypeNumber {
+			d, f = y.dur(), x.num()
+		}
+		if n.Op == "/" {
+			if f == 0 {
+				return null(TypeDuration), nil
+			}
+			f = 1 / f
+		}
+		return duration(time.Duration(float64(d) * f)), nil
+
+	case o.y == TypeDuration || o.x == TypeDuration:
+		tm, d := x, y.dur()
+		if x.t == TypeDuration {
+			tm, d = y, x.dur()
+		}
+		if n.Op == "-" {
+			d = -d
+		}
+		return withTime(tm.t, tm.time().Add(d)), nil
+
+	case o.x == o.y:
+		// Subtracting dates, date/times, or times of day.
+		if x.t == TypeTimeOfDay {
+			return duration(clock(x.time()) - clock(y.time())), nil
+		}
+		return duration(x.time().Sub(y.time())), nil
+	}
+
+	return value{}, e.errorf(n.Pos, "%s: operator not valid for %s and %s", n.Op, x.t, y.t)
+}
+
+// withTime returns a value of type t, which is a date, date/time, or time of
+// day, with the passed time.
+func withTime(t Type, tm time.Time) value {
+	switch t {
+	case TypeDate:
+		return date(tm)
+	case TypeTimeOfDay:
+		return timeOfDay(tm)
+	}
+	return datetime(tm)
+}
+
+func (e *evaluator) call(n *Call) (value, error) {
+	fn := LookupFunction(n.Name)
+
+	// The arguments of If and Case are evaluated lazily, so that only the
+	// result that is returned is evaluated.
+	switch fn.Name {
+	case "If":
+		return e.ifCall(n)
+	case "Case":
+		return e.caseCall(n)
+	}
+
+	impl, ok := builtins[fn.Name]
+	if !ok {
+		return value{}, e.errorf(n.Pos, "%s: function can't be evaluated offline", fn.Name)
+	}
+
+	args := make([]value, len(n.Args))
+	for idx, arg := range n.Args {
+		var err error
+		if args[idx], err = e.eval(arg); err != nil {
+			return value{}, err
+		}
+	}
+
+	v, err := impl(e, args)
+	if err != nil {
+		return value{}, e.errorf(n.Pos, "%s: %s", fn.Name, err)
+	}
+	return v, nil
+}
+
+// ifCall evaluates If(condition, result, [condition, result, ...], [else]).
+func (e *evaluator) ifCall(n *Call) (value, error) {
+	for idx := 0; idx < len(n.Args); idx += 2 {
+		if idx+1 == len(n.Args) {
+			return e.eval(n.Args[idx])
+		}
+		cond, err := e.eval(n.Args[idx])
+		if err != nil {
+			return cond, err
+		}
+		if cond.bool() {
+			return e.eval(n.Args[idx+1])
+		}
+	}
+	return null(TypeAny), nil
+}
+
+// caseCall evaluates Case(value, match, result, [match, result, ...], [else]).
+func (e *evaluator) caseCall(n *Call) (value, error) {
+	x, err := e.eval(n.Args[0])
+	if err != nil {
+		return x, err
+	}
+
+	for idx := 1; idx < len(n.Args); idx += 2 {
+		if idx+1 == len(n.Args) {
+			return e.eval(n.Args[idx])
+		}
+		match, err := e.eval(n.Args[idx])
+		if err != nil {
+			return match, err
+		}
+		if equal(x, match) {
+			return e.eval(n.Args[idx+1])
+		}
+	}
+	return null(TypeAny), nil
+}
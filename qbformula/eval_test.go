@@ -0,0 +1,95 @@
+package qbformula_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qbformula"
+)
+
+func TestEval(t *testing.T) {
+	due := time.Date(2021, 6, 4, 0, 0, 0, 0, time.UTC)
+	record := map[string]*qbformula.Field{
+		"Name":     {Type: qbclient.FieldText, Value: qbclient.NewTextValue("Acme, Inc.")},
+		"Amount":   {Type: qbclient.FieldNumericCurrency, Value: qbclient.NewNumericCurrencyValue(2.345)},
+		"Discount": {Type: qbclient.FieldNumeric},
+		"Due Date": {Type: qbclient.FieldDate, Value: qbclient.NewDateValue(due)},
+		"Done":     {Type: qbclient.FieldCheckbox, Value: qbclient.NewCheckboxValue(false)},
+		"Tags":     {Type: qbclient.FieldMultiSelectText, Value: qbclient.NewMultiSelectTextValue([]string{"a", "b"})},
+	}
+	env := &qbformula.Env{Now: time.Date(2021, 6, 1, 13, 30, 0, 0, time.UTC)}
+
+	tests := []struct {
+		formula  string
+		expected string
+	}{
+		{`If([Done], "done", [Amount] > 2, "big", "small")`, "big"},
+		{`Case(Month([Due Date]), 5, "May", 6, "June", "other")`, "June"},
+		{`Round([Amount], 0.01)`, "2.35"},
+		{`Floor([Amount] * 10) / 10`, "2.3"},
+		{`Nz([Discount]) + 1`, "1"},
+		{`IsNull([Discount] * 2)`, "true"},
+		{`[Discount] * 2`, ""},
+		{`Left([Name], ",") & "|" & Right([Name], 4) & "|" & Mid([Name], 7, 3)`, "Acme|Inc.|Inc"},
+		{`Part("a-b-c", 2, "-") & NotLeft("abc", 1) & NotRight("abc", 1)`, "bbcab"},
+		{`Contains(Upper([Name]), "acme") and Begins([Name], "ac")`, "true"},
+		{`Find([Name], "Inc")`, "7"},
+		{`List(", ", "a", "", "b")`, "a, b"},
+		{`ToText([Amount]) & " " & ToNumber("1,200.5")`, "2.345 1200.5"},
+		{`ToDays([Due Date] - Today())`, "3"},
+		{`[Due Date] + Days(30)`, "2021-07-04"},
+		{`AdjustMonth(Date(2021, 1, 31), 1)`, "2021-02-28"},
+		{`LastDayOfMonth([Due Date])`, "2021-06-30"},
+		{`WeekdayAdd([Due Date], 1)`, "2021-06-07"},
+		{`WeekdaySub([Due Date], Today())`, "3"},
+		{`DayOfWeek(NextDayOfWeek([Due Date], 1))`, "1"},
+		{`Hour(ToTimeOfDay(Now())) * 60 + Minute(ToTimeOfDay(Now()))`, "810"},
+		{`Max(3, 7, 5) - Min(3, 7, 5) + Sum(1, 2) + Count(1, 0, null)`, "8"},
+		{"var number total = [Amount] * 2;\nvar text label = \"total: \";\n$label & $total", "total: 4.69"},
+		{`Includes([Tags], "b") and Size([Tags]) = 2`, "true"},
+		{`ToTimestamp([Due Date], ToTimeOfDay("13:30"))`, "2021-06-04T13:30:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.formula, func(t *testing.T) {
+			v, err := qbformula.Eval(tt.formula, record, env)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			actual := ""
+			if v != nil {
+				actual = v.String()
+			}
+			if actual != tt.expected {
+				t.Errorf("got %q, expected %q", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	record := map[string]*qbformula.Field{
+		"Name": {Type: qbclient.FieldText, Value: qbclient.NewTextValue("Acme")},
+	}
+
+	tests := []struct {
+		formula  string
+		expected string
+	}{
+		{`Left([Name], 2`, "1:5: unclosed (, expecting )"},
+		{`[Nmae]`, "1:1: [Nmae]: field not found, did you mean [Name]?"},
+		{`[Name] - 1`, "1:8: -: operator not valid for text and number"},
+		{`Dbid()`, "1:1: Dbid: function can't be evaluated offline"},
+	}
+
+	for _, tt := range tests {
+		_, err := qbformula.Eval(tt.formula, record, nil)
+		if err == nil {
+			t.Errorf("%s: expected error", tt.formula)
+		} else if err.Error() != tt.expected {
+			t.Errorf("%s: got error %q, expected %q", tt.formula, err, tt.expected)
+		}
+	}
+}
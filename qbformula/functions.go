@@ -0,0 +1,197 @@
+package qbformula
+
+import (
+	"strings"
+)
+
+// Function describes the signature of a formula function.
+type Function struct {
+	Name string
+
+	// Params are the types of the parameters. The first Required parameters
+	// must be passed, and the last parameter repeats if Variadic is true.
+	Params   []Type
+	Required int
+	Variadic bool
+
+	// Returns is the type of the result. If ReturnsArg is set, the result
+	// has the type of the argument at that 1-based position instead, e.g.,
+	// Max returns the type of the values it compares.
+	Returns    Type
+	ReturnsArg int
+}
+
+// signatures contains the signatures of the functions the linter knows about.
+// Parameters ending in ? are optional, and parameters ending in ... repeat.
+// A $N return type is the type of the Nth argument. If and Case, whose
+// signatures can't be described this way, are checked separately.
+var signatures = []string{
+	// Text functions.
+	"Begins(text, text) bool",
+	"Contains(text, text) bool",
+	"Ends(text, text) bool",
+	"Find(text, text, number?) number",
+	"Left(text, any) text",
+	"Length(text) number",
+	"List(text, any...) text",
+	"Lower(text) text",
+	"Mid(text, number, number) text",
+	"NotLeft(text, any) text",
+	"NotRight(text, any) text",
+	"Part(text, number, text) text",
+	"Right(text, any) text",
+	"SearchAndReplace(text, text, text) text",
+	"Split(text, text?) textlist",
+	"Trim(text) text",
+	"Upper(text) text",
+	"URLDecode(text) text",
+	"URLEncode(text) text",
+
+	// Conversion functions.
+	"ToDate(any) date",
+	"ToDays(duration) number",
+	"ToFormattedText(any, text) text",
+	"ToHours(duration) number",
+	"ToMinutes(duration) number",
+	"ToMSeconds(duration) number",
+	"ToNumber(any) number",
+	"ToSeconds(duration) number",
+	"ToText(any) text",
+	"ToTimeOfDay(any) timeofday",
+	"ToTimestamp(any, timeofday?) datetime",
+	"ToUser(text) user",
+	"ToUserList(user...) userlist",
+	"ToWeeks(duration) number",
+
+	// Numeric functions.
+	"Abs(number) number",
+	"Average(any, any...) $1",
+	"Ceil(number, number?) number",
+	"Count(any, any...) number",
+	"Exp(number) number",
+	"Floor(number, number?) number",
+	"Frac(number) number",
+	"Int(number) number",
+	"Ln(number) number",
+	"Log(number) number",
+	"Max(any, any...) $1",
+	"Min(any, any...) $1",
+	"Mod(number, number) number",
+	"Round(number, number?) number",
+	"Sign(number) number",
+	"Sqrt(number) number",
+	"Sum(any, any...) $1",
+
+	// Logical functions.
+	"IsNull(any) bool",
+	"Nz(any, any?) $1",
+
+	// Date, time, and duration functions.
+	"AdjustMonth(date, number) date",
+	"AdjustYear(date, number) date",
+	"Date(number, number, number) date",
+	"Day(date) number",
+	"DayOfWeek(date) number",
+	"DayOfYear(date) number",
+	"Days(number) duration",
+	"FirstDayOfMonth(date) date",
+	"FirstDayOfPeriod(date, duration, date) date",
+	"FirstDayOfYear(date) date",
+	"Hour(timeofday) number",
+	"Hours(number) duration",
+	"IsWeekday(date) bool",
+	"LastDayOfMonth(date) date",
+	"LastDayOfYear(date) date",
+	"Minute(timeofday) number",
+	"Minutes(number) duration",
+	"Month(date) number",
+	"MSecond(timeofday) number",
+	"MSeconds(number) duration",
+	"NextDayOfWeek(date, number) date",
+	"Now() datetime",
+	"PrevDayOfWeek(date, number) date",
+	"Second(timeofday) number",
+	"Seconds(number) duration",
+	"Today() date",
+	"WeekdayAdd(date, number) date",
+	"WeekdaySub(date, date) number",
+	"Weeks(number) duration",
+	"Year(date) number",
+
+	// User and list functions.
+	"Includes(any, any) bool",
+	"Size(any) number",
+	"User() user",
+	"UserListToEmails(userlist) text",
+	"UserListToIDs(userlist) text",
+	"UserListToNames(userlist, text?) text",
+	"UserRoles(text) textlist",
+	"UserToEmail(user) text",
+	"UserToID(user) text",
+	"UserToName(user, text?) text",
+
+	// App functions.
+	"AppID() text",
+	"Dbid() text",
+	"URLRoot() text",
+}
+
+// functions contains the known functions keyed by lowercase name, because
+// function names are case-insensitive.
+var functions map[string]*Function
+
+// LookupFunction returns the signature of a function, which is nil if the
+// function isn't known.
+func LookupFunction(name string) *Function {
+	return functions[strings.ToLower(name)]
+}
+
+// parseSignature parses a signature, e.g., Round(number, number?) number.
+func parseSignature(sig string) *Function {
+	open, close := strings.Index(sig, "("), strings.Index(sig, ")")
+	f := &Function{Name: sig[:open]}
+
+	if params := sig[open+1 : close]; params != "" {
+		for _, param := range strings.Split(params, ",") {
+			param = strings.TrimSpace(param)
+			switch {
+			case strings.HasSuffix(param, "..."):
+				param = strings.TrimSuffix(param, "...")
+				f.Variadic = true
+			case strings.HasSuffix(param, "?"):
+				param = strings.TrimSuffix(param, "?")
+			default:
+				f.Required++
+			}
+			f.Params = append(f.Params, parseType(param))
+		}
+	}
+
+	ret := strings.TrimSpace(sig[close+1:])
+	if strings.HasPrefix(ret, "$") {
+		f.ReturnsArg = int(ret[1] - '0')
+	} else {
+		f.Returns = parseType(ret)
+	}
+
+	return f
+}
+
+func parseType(s string) Type {
+	if s == "any" {
+		return TypeAny
+	}
+	return Type(s)
+}
+
+func init() {
+	functions = make(map[string]*Function, len(signatures)+2)
+	for _, sig := range signatures {
+		f := parseSignature(sig)
+		functions[strings.ToLower(f.Name)] = f
+	}
+
+	// If and Case have special signatures that are checked separately.
+	functions["if"] = &Function{Name: "If", Required: 2, Variadic: true}
+	functions["case"] = &Function{Name: "Case", Required: 3, Variadic: true}
+}
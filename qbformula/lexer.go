@@ -0,0 +1,314 @@
+// Package qbformula parses, analyzes, and evaluates Quickbase formulas
+// without consuming the Quickbase API.
+//
+// See https://help.quickbase.com/user-assistance/formula_intro.html
+package qbformula
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind is the kind of a lexical token.
+type TokenKind int
+
+// Token* constants contain the kinds of tokens in a formula.
+const (
+	TokenEOF TokenKind = iota
+	TokenNumber
+	TokenString
+	TokenField
+	TokenVariable
+	TokenIdent
+	TokenOperator
+	TokenLParen
+	TokenRParen
+	TokenComma
+	TokenSemicolon
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenEOF:
+		return "end of formula"
+	case TokenNumber:
+		return "number"
+	case TokenString:
+		return "text"
+	case TokenField:
+		return "field reference"
+	case TokenVariable:
+		return "variable"
+	case TokenIdent:
+		return "identifier"
+	case TokenOperator:
+		return "operator"
+	case TokenLParen:
+		return "("
+	case TokenRParen:
+		return ")"
+	case TokenComma:
+		return ","
+	case TokenSemicolon:
+		return ";"
+	}
+	return "unknown token"
+}
+
+// Pos is a position in a formula. Lines and columns start at 1, and columns
+// are counted in characters.
+type Pos struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+func (p Pos) String() string { return fmt.Sprintf("%d:%d", p.Line, p.Col) }
+
+// Token is a lexical token. Value is the unquoted text of strings, the label
+// of field references, and the name of variables.
+type Token struct {
+	Kind  TokenKind
+	Value string
+	Pos   Pos
+}
+
+// SyntaxError is an error in the syntax of a formula.
+type SyntaxError struct {
+	Pos     Pos
+	Message string
+}
+
+func (e *SyntaxError) Error() string { return e.Pos.String() + ": " + e.Message }
+
+// lexer splits a formula into tokens.
+type lexer struct {
+	src  string
+	off  int
+	line int
+	col  int
+}
+
+// Tokenize splits a formula into tokens, skipping whitespace and comments.
+// The last token is always TokenEOF.
+func Tokenize(formula string) ([]*Token, error) {
+	l := &lexer{src: formula, line: 1, col: 1}
+
+	var tokens []*Token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.Kind == TokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) pos() Pos { return Pos{Line: l.line, Col: l.col} }
+
+// peek returns the character at the offset from the current position.
+func (l *lexer) peek(n int) rune {
+	off := l.off
+	for ; n > 0 && off < len(l.src); n-- {
+		_, size := utf8.DecodeRuneInString(l.src[off:])
+		off += size
+	}
+	if off >= len(l.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[off:])
+	return r
+}
+
+// advance consumes a character.
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	l.off += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) done() bool { return l.off >= len(l.src) }
+
+// skip consumes whitespace and comments.
+func (l *lexer) skip() error {
+	for !l.done() {
+		switch r := l.peek(0); {
+		case unicode.IsSpace(r):
+			l.advance()
+		case r == '/' && l.peek(1) == '/':
+			for !l.done() && l.peek(0) != '\n' {
+				l.advance()
+			}
+		case r == '/' && l.peek(1) == '*':
+			start := l.pos()
+			l.advance()
+			l.advance()
+			for {
+				if l.done() {
+					return &SyntaxError{Pos: start, Message: "unclosed comment"}
+				}
+				if l.advance() == '*' && l.peek(0) == '/' {
+					l.advance()
+					break
+				}
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) next() (*Token, error) {
+	if err := l.skip(); err != nil {
+		return nil, err
+	}
+
+	tok := &Token{Pos: l.pos()}
+	if l.done() {
+		tok.Kind = TokenEOF
+		return tok, nil
+	}
+
+	start := l.off
+	switch r := l.peek(0); {
+	case r == '"' || r == '\'':
+		return l.string(tok, r)
+	case r == '[':
+		return l.field(tok)
+	case r == ']':
+		return nil, &SyntaxError{Pos: tok.Pos, Message: "unexpected ] without matching ["}
+	case r == '$':
+		l.advance()
+		for isIdentRune(l.peek(0)) {
+			l.advance()
+		}
+		if l.off-start == 1 {
+			return nil, &SyntaxError{Pos: tok.Pos, Message: "variable name required after $"}
+		}
+		tok.Kind, tok.Value = TokenVariable, l.src[start+1:l.off]
+	case unicode.IsDigit(r) || r == '.' && unicode.IsDigit(l.peek(1)):
+		for unicode.IsDigit(l.peek(0)) {
+			l.advance()
+		}
+		if l.peek(0) == '.' && unicode.IsDigit(l.peek(1)) {
+			l.advance()
+			for unicode.IsDigit(l.peek(0)) {
+				l.advance()
+			}
+		}
+		tok.Kind, tok.Value = TokenNumber, l.src[start:l.off]
+	case isIdentRune(r):
+		for isIdentRune(l.peek(0)) {
+			l.advance()
+		}
+		tok.Kind, tok.Value = TokenIdent, l.src[start:l.off]
+	case r == '(':
+		l.advance()
+		tok.Kind, tok.Value = TokenLParen, "("
+	case r == ')':
+		l.advance()
+		tok.Kind, tok.Value = TokenRParen, ")"
+	case r == ',':
+		l.advance()
+		tok.Kind, tok.Value = TokenComma, ","
+	case r == ';':
+		l.advance()
+		tok.Kind, tok.Value = TokenSemicolon, ";"
+	default:
+		op, ok := l.operator()
+		if !ok {
+			return nil, &SyntaxError{Pos: tok.Pos, Message: fmt.Sprintf("unexpected character %q", r)}
+		}
+		tok.Kind, tok.Value = TokenOperator, op
+	}
+
+	return tok, nil
+}
+
+// operators contains the operators, longest first so that, e.g., <= isn't
+// read as < followed by =.
+var operators = []string{"<=", ">=", "<>", "!=", "==", "+", "-", "*", "/", "^", "&", "=", "<", ">"}
+
+func (l *lexer) operator() (string, bool) {
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.off:], op) {
+			for range op {
+				l.advance()
+			}
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// string reads text enclosed in quotes. A backslash escapes the next
+// character, e.g., \" or \\.
+func (l *lexer) string(tok *Token, quote rune) (*Token, error) {
+	l.advance()
+
+	var b strings.Builder
+	for {
+		if l.done() {
+			return nil, &SyntaxError{Pos: tok.Pos, Message: "unclosed text, expecting " + string(quote)}
+		}
+		r := l.advance()
+		switch {
+		case r == quote:
+			tok.Kind, tok.Value = TokenString, b.String()
+			return tok, nil
+		case r == '\\' && !l.done():
+			switch e := l.advance(); e {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			default:
+				b.WriteRune(e)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// field reads a field reference, e.g., [Due Date].
+func (l *lexer) field(tok *Token) (*Token, error) {
+	l.advance()
+
+	start := l.off
+	for {
+		if l.done() || l.peek(0) == '\n' {
+			return nil, &SyntaxError{Pos: tok.Pos, Message: "unclosed [, expecting ]"}
+		}
+		if l.peek(0) == '[' {
+			return nil, &SyntaxError{Pos: l.pos(), Message: "unexpected [ in field reference"}
+		}
+		if l.peek(0) == ']' {
+			break
+		}
+		l.advance()
+	}
+
+	tok.Kind, tok.Value = TokenField, l.src[start:l.off]
+	l.advance()
+
+	if strings.TrimSpace(tok.Value) == "" {
+		return nil, &SyntaxError{Pos: tok.Pos, Message: "field label required between [ and ]"}
+	}
+	return tok, nil
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
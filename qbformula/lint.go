@@ -0,0 +1,376 @@
+package qbformula
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity* constants contain the severities of diagnostics.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found in a formula.
+type Diagnostic struct {
+	Pos
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
+
+// Lint parses a formula and checks it for unknown functions, misused
+// variables, and mismatched types. Field references are checked against
+// fields, which maps the labels of the table's fields to their types, unless
+// fields is nil. Lint returns the diagnostics sorted by position and the type
+// of the formula's result, which is TypeAny if it can't be determined.
+func Lint(formula string, fields map[string]Type) ([]*Diagnostic, Type) {
+	f, err := Parse(formula)
+	if err != nil {
+		serr := err.(*SyntaxError)
+		return []*Diagnostic{{Pos: serr.Pos, Severity: SeverityError, Message: serr.Message}}, TypeAny
+	}
+
+	c := &checker{vars: map[string]*checkerVar{}}
+	if fields != nil {
+		c.fields = make(map[string]Type, len(fields))
+		for label, t := range fields {
+			c.fields[strings.ToLower(label)] = t
+		}
+		c.labels = fields
+	}
+
+	t := c.check(f)
+
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i].Pos, c.diags[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+	})
+
+	return c.diags, t
+}
+
+// checker infers the types of the nodes in a formula, recording diagnostics
+// for the problems it finds along the way.
+type checker struct {
+	fields map[string]Type
+	labels map[string]Type
+	vars   map[string]*checkerVar
+	diags  []*Diagnostic
+}
+
+type checkerVar struct {
+	decl *VarDecl
+	used bool
+}
+
+func (c *checker) errorf(pos Pos, format string, a ...interface{}) {
+	c.diags = append(c.diags, &Diagnostic{Pos: pos, Severity: SeverityError, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *checker) warnf(pos Pos, format string, a ...interface{}) {
+	c.diags = append(c.diags, &Diagnostic{Pos: pos, Severity: SeverityWarning, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *checker) check(f *Formula) Type {
+	for _, v := range f.Vars {
+		if _, ok := c.vars[v.Name]; ok {
+			c.errorf(v.Pos, "$%s: variable already declared", v.Name)
+		}
+		if t := c.typeOf(v.Value); !t.Compatible(v.Type) {
+			c.errorf(v.Value.Position(), "$%s: variable is %s, got %s", v.Name, v.Type, t)
+		}
+		c.vars[v.Name] = &checkerVar{decl: v}
+	}
+
+	t := c.typeOf(f.Expr)
+
+	for _, v := range f.Vars {
+		if !c.vars[v.Name].used {
+			c.warnf(v.Pos, "$%s: variable declared but not used", v.Name)
+		}
+	}
+
+	return t
+}
+
+func (c *checker) typeOf(n Node) Type {
+	switch n := n.(type) {
+	case *NumberLit:
+		return TypeNumber
+	case *TextLit:
+		return TypeText
+	case *BoolLit:
+		return TypeBool
+	case *FieldRef:
+		return c.field(n)
+	case *VarRef:
+		v, ok := c.vars[n.Name]
+		if !ok {
+			c.errorf(n.Pos, "$%s: variable not declared", n.Name)
+			return TypeAny
+		}
+		v.used = true
+		return v.decl.Type
+	case *Call:
+		return c.call(n)
+	case *Unary:
+		return c.unary(n)
+	case *Binary:
+		return c.binary(n)
+	}
+	return TypeAny
+}
+
+func (c *checker) field(n *FieldRef) Type {
+	if c.fields == nil {
+		return TypeAny
+	}
+
+	t, ok := c.fields[strings.ToLower(n.Label)]
+	if !ok {
+		msg := fmt.Sprintf("[%s]: field not found", n.Label)
+		if s := suggestLabel(n.Label, c.labels); s != "" {
+			msg += fmt.Sprintf(", did you mean [%s]?", s)
+		}
+		c.errorf(n.Pos, "%s", msg)
+		return TypeAny
+	}
+
+	return t
+}
+
+// suggestLabel returns the label closest to the misspelled one, if any label
+// is close enough to be a likely typo, i.e., fewer edits than about a third
+// of its length.
+func suggestLabel(label string, labels map[string]Type) (suggestion string) {
+	best := len(label)/3 + 2
+	for l := range labels {
+		if d := editDistance(strings.ToLower(label), strings.ToLower(l)); d < best || d == best && l < suggestion {
+			best, suggestion = d, l
+		}
+	}
+	return
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(a int, b ...int) int {
+	for _, v := range b {
+		if v < a {
+			a = v
+		}
+	}
+	return a
+}
+
+func (c *checker) call(n *Call) Type {
+	args := make([]Type, len(n.Args))
+	for idx, arg := range n.Args {
+		args[idx] = c.typeOf(arg)
+	}
+
+	fn := LookupFunction(n.Name)
+	if fn == nil {
+		c.errorf(n.Pos, "%s: unknown function", n.Name)
+		return TypeAny
+	}
+
+	if len(args) < fn.Required {
+		c.errorf(n.Pos, "%s: expecting at least %d arguments, got %d", fn.Name, fn.Required, len(args))
+		return TypeAny
+	}
+	if !fn.Variadic && len(args) > len(fn.Params) {
+		c.errorf(n.Pos, "%s: expecting at most %d arguments, got %d", fn.Name, len(fn.Params), len(args))
+		return TypeAny
+	}
+
+	switch fn.Name {
+	case "If":
+		return c.ifCall(n, args)
+	case "Case":
+		return c.caseCall(n, args)
+	}
+
+	for idx, t := range args {
+		param := fn.Params[minInt(idx, len(fn.Params)-1)]
+		if !t.Compatible(param) {
+			c.errorf(n.Args[idx].Position(), "%s: argument %d is %s, expecting %s", fn.Name, idx+1, t, param)
+		}
+	}
+
+	if fn.ReturnsArg > 0 {
+		return args[fn.ReturnsArg-1]
+	}
+	return fn.Returns
+}
+
+// ifCall checks If(condition, result, [condition, result, ...], [else]).
+func (c *checker) ifCall(n *Call, args []Type) Type {
+	var results []int
+	for idx := 0; idx < len(args); idx += 2 {
+		if idx+1 == len(args) {
+			results = append(results, idx)
+			break
+		}
+		if !args[idx].Compatible(TypeBool) {
+			c.errorf(n.Args[idx].Position(), "If: condition is %s, expecting bool", args[idx])
+		}
+		results = append(results, idx+1)
+	}
+	return c.results("If", n, args, results)
+}
+
+// caseCall checks Case(value, match, result, [match, result, ...], [else]).
+func (c *checker) caseCall(n *Call, args []Type) Type {
+	var results []int
+	for idx := 1; idx < len(args); idx += 2 {
+		if idx+1 == len(args) {
+			results = append(results, idx)
+			break
+		}
+		if !args[idx].Compatible(args[0]) {
+			c.errorf(n.Args[idx].Position(), "Case: value is %s, expecting %s", args[idx], args[0])
+		}
+		results = append(results, idx+1)
+	}
+	return c.results("Case", n, args, results)
+}
+
+// results checks that the possible results of If or Case have the same type,
+// which is returned.
+func (c *checker) results(name string, n *Call, args []Type, results []int) Type {
+	t := TypeAny
+	for _, idx := range results {
+		if t == TypeAny {
+			t = args[idx]
+		} else if !args[idx].Compatible(t) {
+			c.errorf(n.Args[idx].Position(), "%s: result is %s, expecting %s", name, args[idx], t)
+		}
+	}
+	return t
+}
+
+func (c *checker) unary(n *Unary) Type {
+	t := c.typeOf(n.X)
+
+	if n.Op == "not" {
+		if !t.Compatible(TypeBool) {
+			c.errorf(n.Pos, "not: operand is %s, expecting bool", t)
+		}
+		return TypeBool
+	}
+
+	if !t.Compatible(TypeNumber) && !t.Compatible(TypeDuration) {
+		c.errorf(n.Pos, "%s: operand is %s, expecting number or duration", n.Op, t)
+		return TypeAny
+	}
+	return t
+}
+
+func (c *checker) binary(n *Binary) Type {
+	x, y := c.typeOf(n.X), c.typeOf(n.Y)
+
+	switch n.Op {
+	case "and", "or":
+		for _, t := range []Type{x, y} {
+			if !t.Compatible(TypeBool) {
+				c.errorf(n.Pos, "%s: operand is %s, expecting bool", n.Op, t)
+				break
+			}
+		}
+		return TypeBool
+	case "=", "==", "!=", "<>", "<", "<=", ">", ">=":
+		if !x.Compatible(y) {
+			c.errorf(n.Pos, "%s: can't compare %s with %s", n.Op, x, y)
+		}
+		return TypeBool
+	case "&":
+		return TypeText
+	}
+
+	t, ok := arithmetic(n.Op, x, y)
+	if !ok {
+		c.errorf(n.Pos, "%s: operator not valid for %s and %s", n.Op, x, y)
+	}
+	return t
+}
+
+// arithmetic returns the type of the result of an arithmetic operation, and
+// false if the operator isn't valid for the types, e.g., text - number.
+func arithmetic(op string, x, y Type) (Type, bool) {
+	if x == TypeBool || y == TypeBool {
+		return TypeAny, false
+	}
+	if x == TypeAny || y == TypeAny {
+		return TypeAny, true
+	}
+
+	type operands struct{ x, y Type }
+	switch o := (operands{x.family(), y.family()}); op {
+	case "+":
+		switch o {
+		case operands{TypeNumber, TypeNumber}, operands{TypeText, TypeText},
+			operands{TypeDuration, TypeDuration}:
+			return x, true
+		case operands{TypeDate, TypeDuration}, operands{TypeTimeOfDay, TypeDuration}:
+			return x, true
+		case operands{TypeDuration, TypeDate}, operands{TypeDuration, TypeTimeOfDay}:
+			return y, true
+		}
+	case "-":
+		switch o {
+		case operands{TypeNumber, TypeNumber}, operands{TypeDuration, TypeDuration},
+			operands{TypeDate, TypeDuration}, operands{TypeTimeOfDay, TypeDuration}:
+			return x, true
+		case operands{TypeDate, TypeDate}, operands{TypeTimeOfDay, TypeTimeOfDay}:
+			return TypeDuration, true
+		}
+	case "*":
+		switch o {
+		case operands{TypeNumber, TypeNumber}, operands{TypeDuration, TypeNumber}:
+			return x, true
+		case operands{TypeNumber, TypeDuration}:
+			return y, true
+		}
+	case "/":
+		switch o {
+		case operands{TypeNumber, TypeNumber}, operands{TypeDuration, TypeNumber}:
+			return x, true
+		case operands{TypeDuration, TypeDuration}:
+			return TypeNumber, true
+		}
+	case "^":
+		if o == (operands{TypeNumber, TypeNumber}) {
+			return TypeNumber, true
+		}
+	}
+
+	return TypeAny, false
+}
@@ -0,0 +1,69 @@
+package qbformula_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbformula"
+)
+
+func TestLint(t *testing.T) {
+	fields := map[string]qbformula.Type{
+		"Name":     qbformula.TypeText,
+		"Amount":   qbformula.TypeNumber,
+		"Due Date": qbformula.TypeDate,
+		"Done":     qbformula.TypeBool,
+	}
+
+	tests := []struct {
+		name     string
+		formula  string
+		expected []string
+		typ      qbformula.Type
+	}{
+		{"valid", `If([done], 0, [Amount] * 1.1)`, nil, qbformula.TypeNumber},
+		{"dates", `ToDays([Due Date] - Today()) > 3`, nil, qbformula.TypeBool},
+		{"field not found", `[Amont] + 1`, []string{"1:1: error: [Amont]: field not found, did you mean [Amount]?"}, qbformula.TypeAny},
+		{"unknown function", `Lenght([Name])`, []string{"1:1: error: Lenght: unknown function"}, qbformula.TypeAny},
+		{"argument type", `Left([Amount], 2)`, []string{"1:6: error: Left: argument 1 is number, expecting text"}, qbformula.TypeText},
+		{"too few arguments", `Mid([Name], 2)`, []string{"1:1: error: Mid: expecting at least 3 arguments, got 2"}, qbformula.TypeAny},
+		{"too many arguments", `Trim([Name], 2)`, []string{"1:1: error: Trim: expecting at most 1 arguments, got 2"}, qbformula.TypeAny},
+		{"arithmetic", `[Name] - [Amount]`, []string{"1:8: error: -: operator not valid for text and number"}, qbformula.TypeAny},
+		{"comparison", `[Name] = 1`, []string{"1:8: error: =: can't compare text with number"}, qbformula.TypeBool},
+		{"condition", `If([Name], 1, 2)`, []string{"1:4: error: If: condition is text, expecting bool"}, qbformula.TypeNumber},
+		{"results", `Case([Amount], 1, "one", 2)`, []string{"1:26: error: Case: result is number, expecting text"}, qbformula.TypeText},
+		{"variables", "var text x = [Amount];\nvar bool y = true;\n$x & $z", []string{
+			"1:14: error: $x: variable is text, got number",
+			"2:1: warning: $y: variable declared but not used",
+			"3:6: error: $z: variable not declared",
+		}, qbformula.TypeText},
+		{"syntax", `If([Done], 1`, []string{"1:3: error: unclosed (, expecting )"}, qbformula.TypeAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags, typ := qbformula.Lint(tt.formula, fields)
+
+			var got []string
+			for _, d := range diags {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got diagnostics %q, expected %q", got, tt.expected)
+			}
+			if typ != tt.typ {
+				t.Errorf("got type %s, expected %s", typ, tt.typ)
+			}
+		})
+	}
+}
+
+func TestLintWithoutFields(t *testing.T) {
+	diags, typ := qbformula.Lint(`[Anything] * 2`, nil)
+	if len(diags) != 0 {
+		t.Errorf("got diagnostics %v, expected none", diags)
+	}
+	if typ != qbformula.TypeAny {
+		t.Errorf("got type %s, expected any", typ)
+	}
+}
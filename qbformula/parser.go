@@ -0,0 +1,286 @@
+package qbformula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Binding powers of the binary operators. Operators with higher powers bind
+// more tightly, e.g., * binds more tightly than +.
+var binaryPowers = map[string]int{
+	"or":  1,
+	"and": 2,
+	"=":   4, "==": 4, "!=": 4, "<>": 4, "<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5, "&": 5,
+	"*": 6, "/": 6,
+	"^": 7,
+}
+
+// Binding powers of the unary operators. Not binds less tightly than the
+// comparisons so that not [A] = [B] negates the comparison.
+const (
+	notPower   = 3
+	unaryPower = 8
+)
+
+// parser builds a syntax tree from tokens.
+type parser struct {
+	tokens []*Token
+	idx    int
+}
+
+// Parse parses a formula. The returned error is a *SyntaxError.
+func Parse(formula string) (*Formula, error) {
+	tokens, err := Tokenize(formula)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	return p.formula()
+}
+
+func (p *parser) peek() *Token { return p.tokens[p.idx] }
+
+func (p *parser) next() *Token {
+	tok := p.tokens[p.idx]
+	if tok.Kind != TokenEOF {
+		p.idx++
+	}
+	return tok
+}
+
+// keyword returns whether the token is the keyword, which is case-insensitive.
+func keyword(tok *Token, kw string) bool {
+	return tok.Kind == TokenIdent && strings.EqualFold(tok.Value, kw)
+}
+
+func unexpected(tok *Token) error {
+	msg := "unexpected " + tok.Kind.String()
+	if tok.Kind != TokenEOF {
+		msg += " " + strconv.Quote(tok.Value)
+	}
+	return &SyntaxError{Pos: tok.Pos, Message: msg}
+}
+
+func (p *parser) formula() (*Formula, error) {
+	f := &Formula{}
+
+	for keyword(p.peek(), "var") {
+		v, err := p.varDecl()
+		if err != nil {
+			return nil, err
+		}
+		f.Vars = append(f.Vars, v)
+	}
+
+	if p.peek().Kind == TokenEOF {
+		return nil, &SyntaxError{Pos: p.peek().Pos, Message: "expression required"}
+	}
+
+	expr, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	f.Expr = expr
+
+	if tok := p.peek(); tok.Kind != TokenEOF {
+		if tok.Kind == TokenRParen {
+			return nil, &SyntaxError{Pos: tok.Pos, Message: "unexpected ) without matching ("}
+		}
+		return nil, unexpected(tok)
+	}
+
+	return f, nil
+}
+
+// varDecl parses a variable declaration, e.g., var text name = [Name];
+func (p *parser) varDecl() (*VarDecl, error) {
+	v := &VarDecl{Pos: p.next().Pos}
+
+	tok := p.next()
+	if tok.Kind != TokenIdent {
+		return nil, &SyntaxError{Pos: tok.Pos, Message: "variable type required after var"}
+	}
+	v.Type = Type(strings.ToLower(tok.Value))
+	if !v.Type.declarable() {
+		return nil, &SyntaxError{Pos: tok.Pos, Message: fmt.Sprintf("%s: variable type not valid", tok.Value)}
+	}
+
+	tok = p.next()
+	if tok.Kind != TokenIdent {
+		return nil, &SyntaxError{Pos: tok.Pos, Message: "variable name required after type"}
+	}
+	v.Name = tok.Value
+
+	if tok = p.next(); tok.Kind != TokenOperator || tok.Value != "=" {
+		return nil, &SyntaxError{Pos: tok.Pos, Message: "expecting = after variable name"}
+	}
+
+	value, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	v.Value = value
+
+	if tok = p.next(); tok.Kind != TokenSemicolon {
+		return nil, &SyntaxError{Pos: tok.Pos, Message: "expecting ; after variable declaration"}
+	}
+
+	return v, nil
+}
+
+// binaryOp returns the binary operator and its binding power if the token is
+// one, including the and and or keywords.
+func binaryOp(tok *Token) (string, int, bool) {
+	op := tok.Value
+	switch {
+	case tok.Kind == TokenOperator:
+	case keyword(tok, "and"), keyword(tok, "or"):
+		op = strings.ToLower(op)
+	default:
+		return "", 0, false
+	}
+	power, ok := binaryPowers[op]
+	return op, power, ok
+}
+
+// expr parses an expression whose operators bind more tightly than minPower.
+func (p *parser) expr(minPower int) (Node, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		op, power, ok := binaryOp(tok)
+		if !ok || power <= minPower {
+			return x, nil
+		}
+		p.next()
+
+		// Exponents are right-associative, i.e., 2^3^2 is 2^(3^2).
+		next := power
+		if op == "^" {
+			next--
+		}
+
+		y, err := p.expr(next)
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Pos: tok.Pos, Op: op, X: x, Y: y}
+	}
+}
+
+func (p *parser) unary() (Node, error) {
+	tok := p.peek()
+
+	switch {
+	case tok.Kind == TokenOperator && (tok.Value == "-" || tok.Value == "+"):
+		p.next()
+		x, err := p.expr(unaryPower)
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Pos: tok.Pos, Op: tok.Value, X: x}, nil
+	case keyword(tok, "not"):
+		p.next()
+		x, err := p.expr(notPower)
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Pos: tok.Pos, Op: "not", X: x}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (Node, error) {
+	tok := p.next()
+
+	switch tok.Kind {
+	case TokenNumber:
+		v, err := strconv.ParseFloat(tok.Value, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: tok.Pos, Message: fmt.Sprintf("%s: number not valid", tok.Value)}
+		}
+		return &NumberLit{Pos: tok.Pos, Value: v}, nil
+	case TokenString:
+		return &TextLit{Pos: tok.Pos, Value: tok.Value}, nil
+	case TokenField:
+		return &FieldRef{Pos: tok.Pos, Label: tok.Value}, nil
+	case TokenVariable:
+		return &VarRef{Pos: tok.Pos, Name: tok.Value}, nil
+	case TokenLParen:
+		x, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.closeParen(tok); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case TokenIdent:
+		switch strings.ToLower(tok.Value) {
+		case "true":
+			return &BoolLit{Pos: tok.Pos, Value: true}, nil
+		case "false":
+			return &BoolLit{Pos: tok.Pos, Value: false}, nil
+		case "null":
+			return &NullLit{Pos: tok.Pos}, nil
+		}
+		if p.peek().Kind != TokenLParen {
+			return nil, &SyntaxError{Pos: tok.Pos, Message: fmt.Sprintf("%s: expecting ( after function name, or [] around field label", tok.Value)}
+		}
+		return p.call(tok)
+	case TokenRParen:
+		return nil, &SyntaxError{Pos: tok.Pos, Message: "unexpected ) without matching ("}
+	}
+
+	return nil, unexpected(tok)
+}
+
+// call parses the arguments of a function call.
+func (p *parser) call(name *Token) (Node, error) {
+	open := p.next()
+	c := &Call{Pos: name.Pos, Name: name.Value}
+
+	if p.peek().Kind == TokenRParen {
+		p.next()
+		return c, nil
+	}
+
+	for {
+		arg, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		c.Args = append(c.Args, arg)
+
+		if p.peek().Kind != TokenComma {
+			break
+		}
+		p.next()
+	}
+
+	if err := p.closeParen(open); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// closeParen consumes the ) that closes the ( token.
+func (p *parser) closeParen(open *Token) error {
+	tok := p.peek()
+	if tok.Kind == TokenRParen {
+		p.next()
+		return nil
+	}
+	if tok.Kind == TokenEOF {
+		return &SyntaxError{Pos: open.Pos, Message: "unclosed (, expecting )"}
+	}
+	return &SyntaxError{Pos: tok.Pos, Message: fmt.Sprintf("expecting ) to close ( at %s, got %s %q", open.Pos, tok.Kind, tok.Value)}
+}
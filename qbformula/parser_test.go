@@ -0,0 +1,82 @@
+package qbformula_test
+
+import (
+	"testing"
+
+	"github.com/QuickBase/quickbase-cli/qbformula"
+)
+
+func TestParse(t *testing.T) {
+	f, err := qbformula.Parse(`
+// Days until the task is due.
+var date due = [Due Date];
+/* Weekends are
+   included. */
+If(not IsNull($due) and $due - Today() < Days(3), "Soon: " & ToText(-2^2), 'Later')`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(f.Vars) != 1 || f.Vars[0].Name != "due" || f.Vars[0].Type != qbformula.TypeDate {
+		t.Fatalf("got vars %v, expected $due date", f.Vars)
+	}
+
+	call, ok := f.Expr.(*qbformula.Call)
+	if !ok || call.Name != "If" || len(call.Args) != 3 {
+		t.Fatalf("got %#v, expected If with 3 arguments", f.Expr)
+	}
+	if pos := call.Position(); pos.Line != 6 || pos.Col != 1 {
+		t.Errorf("got position %s, expected 6:1", pos)
+	}
+
+	// not binds less tightly than comparisons, and more tightly than and.
+	and, ok := call.Args[0].(*qbformula.Binary)
+	if !ok || and.Op != "and" {
+		t.Fatalf("got %#v, expected and", call.Args[0])
+	}
+	if not, ok := and.X.(*qbformula.Unary); !ok || not.Op != "not" {
+		t.Errorf("got %#v, expected not", and.X)
+	}
+	if lt, ok := and.Y.(*qbformula.Binary); !ok || lt.Op != "<" {
+		t.Errorf("got %#v, expected <", and.Y)
+	}
+
+	var fields []string
+	f.Walk(func(n qbformula.Node) {
+		if ref, ok := n.(*qbformula.FieldRef); ok {
+			fields = append(fields, ref.Label)
+		}
+	})
+	if len(fields) != 1 || fields[0] != "Due Date" {
+		t.Errorf("got fields %v, expected [Due Date]", fields)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		formula  string
+		expected string
+	}{
+		{`Left([Name], 3`, `1:5: unclosed (, expecting )`},
+		{`Left([Name], 3))`, `1:16: unexpected ) without matching (`},
+		{`[Name] & "x`, `1:10: unclosed text, expecting "`},
+		{`[Name & "x"`, `1:1: unclosed [, expecting ]`},
+		{`Name] & "x"`, `1:5: unexpected ] without matching [`},
+		{`[Name] +`, `1:9: unexpected end of formula`},
+		{`Total + 1`, `1:1: Total: expecting ( after function name, or [] around field label`},
+		{`var number x = 1 $x`, `1:18: expecting ; after variable declaration`},
+		{`var money x = 1; $x`, `1:5: money: variable type not valid`},
+		{"1 +\n  # 2", `2:3: unexpected character '#'`},
+		{`/* note`, `1:1: unclosed comment`},
+		{``, `1:1: expression required`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.formula, func(t *testing.T) {
+			_, err := qbformula.Parse(tt.formula)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("got error %v, expected %q", err, tt.expected)
+			}
+		})
+	}
+}
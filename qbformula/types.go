@@ -0,0 +1,87 @@
+package qbformula
+
+import "github.com/QuickBase/quickbase-cli/qbclient"
+
+// Type is the data type of a value in a formula.
+type Type string
+
+// Type* constants contain the data types of formula values. TypeAny is used
+// when the type can't be determined, and it is compatible with every type.
+const (
+	TypeAny       Type = ""
+	TypeText      Type = "text"
+	TypeNumber    Type = "number"
+	TypeBool      Type = "bool"
+	TypeDate      Type = "date"
+	TypeDateTime  Type = "datetime"
+	TypeTimeOfDay Type = "timeofday"
+	TypeDuration  Type = "duration"
+	TypeUser      Type = "user"
+	TypeTextList  Type = "textlist"
+	TypeUserList  Type = "userlist"
+)
+
+func (t Type) String() string {
+	if t == TypeAny {
+		return "any"
+	}
+	return string(t)
+}
+
+// declarable returns whether variables can be declared with the type.
+func (t Type) declarable() bool {
+	switch t {
+	case TypeText, TypeNumber, TypeBool, TypeDate, TypeDateTime, TypeTimeOfDay,
+		TypeDuration, TypeUser, TypeTextList, TypeUserList:
+		return true
+	}
+	return false
+}
+
+// family groups types whose values Quickbase converts between implicitly,
+// i.e., dates and date/times.
+func (t Type) family() Type {
+	if t == TypeDateTime {
+		return TypeDate
+	}
+	return t
+}
+
+// Compatible returns whether a value of type t can be used where type u is
+// expected. Unknown types are compatible with every type so that only
+// obvious mismatches are reported.
+func (t Type) Compatible(u Type) bool {
+	return t == TypeAny || u == TypeAny || t.family() == u.family()
+}
+
+// FieldType returns the type of the values of a field with the passed
+// Quickbase field type, e.g., numeric. Formula fields have the field type of
+// their result, e.g., a formula numeric field is numeric.
+func FieldType(fieldType string) Type {
+	switch fieldType {
+	case qbclient.FieldText, qbclient.FieldTextMultiLine, qbclient.FieldTextMultipleChoice,
+		qbclient.FieldRichText, qbclient.FieldPhoneNumber, qbclient.FieldEmailAddress,
+		qbclient.FieldURL:
+		return TypeText
+	case qbclient.FieldMultiSelectText:
+		return TypeTextList
+	case qbclient.FieldRecordID, qbclient.FieldNumeric, qbclient.FieldNumericCurrency,
+		qbclient.FieldNumericPercent, qbclient.FieldNumericRating:
+		return TypeNumber
+	case qbclient.FieldDate:
+		return TypeDate
+	case qbclient.FieldDateTime:
+		return TypeDateTime
+	case qbclient.FieldTimeOfDay:
+		return TypeTimeOfDay
+	case qbclient.FieldDuration:
+		return TypeDuration
+	case qbclient.FieldCheckbox:
+		return TypeBool
+	case qbclient.FieldUser:
+		return TypeUser
+	case qbclient.FieldUserList:
+		return TypeUserList
+	}
+	return TypeAny
+}
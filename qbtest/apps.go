@@ -0,0 +1,230 @@
+package qbtest
+
+import (
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// createApp handles POST /v1/apps.
+func (s *Server) createApp(r *jsonRequest) (interface{}, error) {
+	var in qbclient.CreateAppInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+	if in.Name == "" {
+		return nil, errBadRequest("Bad Request", "App name is required.")
+	}
+
+	a := &app{Description: in.Description}
+	a.AppID = s.newID()
+	a.Name = in.Name
+	a.TimeZone = timeZone
+	a.DateFormat = dateFormat
+	a.Created = s.now()
+	a.Updated = a.Created
+	for _, v := range in.Variable {
+		a.setVariable(v.Name, v.Value)
+	}
+
+	s.apps = append(s.apps, a)
+	return a, nil
+}
+
+// getApp handles GET /v1/apps/{appId}.
+func (s *Server) getApp(r *jsonRequest) (interface{}, error) {
+	return s.app(r.params[0])
+}
+
+// updateApp handles POST /v1/apps/{appId}.
+func (s *Server) updateApp(r *jsonRequest) (interface{}, error) {
+	a, err := s.app(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.UpdateAppInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	if in.Name != "" {
+		a.Name = in.Name
+	}
+	if in.Description != "" {
+		a.Description = in.Description
+	}
+	for _, v := range in.Variable {
+		a.setVariable(v.Name, v.Value)
+	}
+	a.Updated = s.now()
+
+	return a, nil
+}
+
+// deleteApp handles DELETE /v1/apps/{appId}. The app's name must be passed
+// to confirm the deletion.
+func (s *Server) deleteApp(r *jsonRequest) (interface{}, error) {
+	a, err := s.app(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.DeleteAppInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+	if in.Name != a.Name {
+		return nil, errBadRequest("Bad Request", "The app name %q doesn't match the app being deleted.", in.Name)
+	}
+
+	for i := range s.apps {
+		if s.apps[i] == a {
+			s.apps = append(s.apps[:i], s.apps[i+1:]...)
+			break
+		}
+	}
+	for _, t := range a.tables {
+		delete(s.tables, t.TableID)
+	}
+
+	return &qbclient.DeleteAppOutput{ID: a.AppID}, nil
+}
+
+// listAppEvents handles GET /v1/apps/{appId}/events. Apps never have events.
+func (s *Server) listAppEvents(r *jsonRequest) (interface{}, error) {
+	if _, err := s.app(r.params[0]); err != nil {
+		return nil, err
+	}
+	return []*qbclient.ListAppEventsOutputEvent{}, nil
+}
+
+// createTable handles POST /v1/tables?appId={appId}.
+func (s *Server) createTable(r *jsonRequest) (interface{}, error) {
+	a, err := s.app(r.query("appId"))
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.CreateTableInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+	if in.Name == "" {
+		return nil, errBadRequest("Bad Request", "Table name is required.")
+	}
+
+	t := newTable(a.AppID, s.newID(), s.now())
+	t.Name = in.Name
+	t.Description = in.Description
+	t.iconName = in.IconName
+	t.SingleRecordName = in.SingularNoun
+	t.PluralRecordName = in.PluralNoun
+	t.Alias = "_DBID_" + toAlias(in.Name)
+
+	a.tables = append(a.tables, t)
+	s.tables[t.TableID] = t
+
+	return tableOutput(t), nil
+}
+
+// listTables handles GET /v1/tables?appId={appId}.
+func (s *Server) listTables(r *jsonRequest) (interface{}, error) {
+	a, err := s.app(r.query("appId"))
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]*qbclient.ListTablesOutputTable, len(a.tables))
+	for i, t := range a.tables {
+		tables[i] = &t.ListTablesOutputTable
+	}
+	return tables, nil
+}
+
+// getTable handles GET /v1/tables/{tableId}?appId={appId}.
+func (s *Server) getTable(r *jsonRequest) (interface{}, error) {
+	t, err := s.appTable(r.query("appId"), r.params[0])
+	if err != nil {
+		return nil, err
+	}
+	return &t.ListTablesOutputTable, nil
+}
+
+// updateTable handles POST /v1/tables/{tableId}?appId={appId}.
+func (s *Server) updateTable(r *jsonRequest) (interface{}, error) {
+	t, err := s.appTable(r.query("appId"), r.params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.UpdateTableInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	if in.Name != "" {
+		t.Name = in.Name
+	}
+	if in.Description != "" {
+		t.Description = in.Description
+	}
+	if in.IconName != "" {
+		t.iconName = in.IconName
+	}
+	if in.SingularNoun != "" {
+		t.SingleRecordName = in.SingularNoun
+	}
+	if in.PluralNoun != "" {
+		t.PluralRecordName = in.PluralNoun
+	}
+	t.Updated = s.now()
+
+	return tableOutput(t), nil
+}
+
+// deleteTable handles DELETE /v1/tables/{tableId}?appId={appId}.
+func (s *Server) deleteTable(r *jsonRequest) (interface{}, error) {
+	t, err := s.appTable(r.query("appId"), r.params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	a, _ := s.app(t.appID)
+	for i := range a.tables {
+		if a.tables[i] == t {
+			a.tables = append(a.tables[:i], a.tables[i+1:]...)
+			break
+		}
+	}
+	delete(s.tables, t.TableID)
+
+	return &qbclient.DeleteTableOutput{TableID: t.TableID}, nil
+}
+
+// tableOutput returns the response to creating or updating a table.
+func tableOutput(t *table) *qbclient.CreateTableOutput {
+	out := &qbclient.CreateTableOutput{
+		TableID:      t.TableID,
+		Name:         t.Name,
+		IconName:     t.iconName,
+		SingularNoun: t.SingleRecordName,
+		PluralNoun:   t.PluralRecordName,
+	}
+	out.Description = t.Description
+	return out
+}
+
+// toAlias converts a table name to the form used in its alias, e.g.,
+// "Open Tasks" to "OPEN_TASKS".
+func toAlias(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z':
+			b[i] = c - 'a' + 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,41 @@
+package qbtest
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// apiError is an error returned by the JSON API. It is converted to an
+// XML API error for XML requests.
+type apiError struct {
+	StatusCode  int    `json:"-"`
+	Message     string `json:"message"`
+	Description string `json:"description"`
+}
+
+func (e *apiError) Error() string { return e.Message + ": " + e.Description }
+
+// errBadRequest returns a 400 Bad Request error.
+func errBadRequest(message, format string, a ...interface{}) *apiError {
+	return &apiError{StatusCode: http.StatusBadRequest, Message: message, Description: fmt.Sprintf(format, a...)}
+}
+
+// errNotFound returns a 404 Not Found error.
+func errNotFound(message, format string, a ...interface{}) *apiError {
+	return &apiError{StatusCode: http.StatusNotFound, Message: message, Description: fmt.Sprintf(format, a...)}
+}
+
+// xmlErrorCode returns the XML API error code for err.
+// See https://help.quickbase.com/api-guide/errorcodes.html
+func xmlErrorCode(err *apiError) int {
+	switch err.Message {
+	case "App not found", "Table not found":
+		return 32
+	case "Record not found":
+		return 30
+	case "Field not found":
+		return 31
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,343 @@
+package qbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// listFields handles GET /v1/fields?tableId={tableId}.
+func (s *Server) listFields(r *jsonRequest) (interface{}, error) {
+	t, err := s.table(r.query("tableId"))
+	if err != nil {
+		return nil, err
+	}
+	return t.fields, nil
+}
+
+// createField handles POST /v1/fields?tableId={tableId}.
+func (s *Server) createField(r *jsonRequest) (interface{}, error) {
+	t, err := s.table(r.query("tableId"))
+	if err != nil {
+		return nil, err
+	}
+
+	var in field
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+	if in.Label == "" || in.Type == "" {
+		return nil, errBadRequest("Bad Request", "Field label and type are required.")
+	}
+	if t.fieldByLabel(in.Label) != nil {
+		return nil, errBadRequest("Bad Request", "Field label %q is already in use.", in.Label)
+	}
+
+	f := t.addField(in.Label, in.Type, in.Properties)
+	f.Field = in.Field
+	t.Updated = s.now()
+
+	return f, nil
+}
+
+// getField handles GET /v1/fields/{fieldId}?tableId={tableId}.
+func (s *Server) getField(r *jsonRequest) (interface{}, error) {
+	_, f, err := s.requestField(r)
+	return f, err
+}
+
+// updateField handles POST /v1/fields/{fieldId}?tableId={tableId}. Only the
+// properties in the request body are updated.
+func (s *Server) updateField(r *jsonRequest) (interface{}, error) {
+	t, f, err := s.requestField(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the request onto a copy of the field, so that only the
+	// properties in the request are changed.
+	updated := *f
+	if f.Properties != nil {
+		props := *f.Properties
+		updated.Properties = &props
+	}
+	if len(r.body) > 0 {
+		if err := json.Unmarshal(r.body, &updated); err != nil {
+			return nil, errBadRequest("Bad Request", "Request body is invalid: %s", err)
+		}
+	}
+	updated.FieldID = f.FieldID
+
+	if other := t.fieldByLabel(updated.Label); other != nil && other != f {
+		return nil, errBadRequest("Bad Request", "Field label %q is already in use.", updated.Label)
+	}
+
+	*f = updated
+	t.Updated = s.now()
+	return f, nil
+}
+
+// deleteFields handles DELETE /v1/fields?tableId={tableId}. Built-in fields
+// can't be deleted.
+func (s *Server) deleteFields(r *jsonRequest) (interface{}, error) {
+	t, err := s.table(r.query("tableId"))
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.DeleteFieldsInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	out := &qbclient.DeleteFieldsOutput{DeletedFieldIDs: []int{}, Errors: []string{}}
+	for _, fid := range in.FieldIDs {
+		switch {
+		case fid <= fidLastBuiltIn:
+			out.Errors = append(out.Errors, fmt.Sprintf("Field: %d is a built in field and cannot be deleted", fid))
+		case t.field(fid) == nil:
+			out.Errors = append(out.Errors, fmt.Sprintf("Field: %d was not found", fid))
+		default:
+			t.removeField(fid)
+			out.DeletedFieldIDs = append(out.DeletedFieldIDs, fid)
+		}
+	}
+	t.Updated = s.now()
+
+	return out, nil
+}
+
+// requestField returns the table and field a request is for.
+func (s *Server) requestField(r *jsonRequest) (*table, *field, error) {
+	t, err := s.table(r.query("tableId"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fid, err := r.intParam(0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f := t.field(fid)
+	if f == nil {
+		return nil, nil, errNotFound("Field not found", "Field %d was not found in table %s.", fid, t.TableID)
+	}
+
+	return t, f, nil
+}
+
+// listRelationships handles GET /v1/tables/{tableId}/relationships.
+func (s *Server) listRelationships(r *jsonRequest) (interface{}, error) {
+	t, err := s.table(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(t.relationships)
+	return &qbclient.ListRelationshipsOutput{
+		Relationships: t.relationships,
+		Metadata: &qbclient.ListRelationshipsOutputMetadata{
+			NumberOfRelationships: n,
+			TotalRelationships:    n,
+		},
+	}, nil
+}
+
+// createRelationship handles POST /v1/tables/{tableId}/relationship. The
+// relationship's ID is the ID of the foreign key field that is created in the
+// child table.
+func (s *Server) createRelationship(r *jsonRequest) (interface{}, error) {
+	child, err := s.table(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.CreateRelationshipInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	parent, err := s.table(in.ParentTableID)
+	if err != nil {
+		return nil, err
+	}
+
+	label := "Related " + parent.noun()
+	if in.ForeignKeyField != nil && in.ForeignKeyField.Label != "" {
+		label = in.ForeignKeyField.Label
+	}
+	if child.fieldByLabel(label) != nil {
+		return nil, errBadRequest("Bad Request", "Field label %q is already in use.", label)
+	}
+
+	fk := child.addField(label, qbclient.FieldNumeric, &qbclient.FieldProperties{ForeignKey: true, ParentTable: parent.TableID})
+	rel := &qbclient.Relationship{
+		RelationshipID:  fk.FieldID,
+		ChildTableID:    child.TableID,
+		ParentTableID:   parent.TableID,
+		IsCrossApp:      child.appID != parent.appID,
+		ForeignKeyField: fk.relationshipField(),
+	}
+
+	if err := addRelationshipFields(rel, child, parent, in.LookupFieldIDs, in.SummaryFields); err != nil {
+		child.removeField(fk.FieldID)
+		return nil, err
+	}
+
+	child.relationships = append(child.relationships, rel)
+	return &qbclient.CreateRelationshipOutput{Relationship: *rel}, nil
+}
+
+// updateRelationship handles POST /v1/tables/{tableId}/relationship/{id},
+// which adds lookup and summary fields.
+func (s *Server) updateRelationship(r *jsonRequest) (interface{}, error) {
+	child, rel, err := s.requestRelationship(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.UpdateRelationshipInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	parent, err := s.table(rel.ParentTableID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := addRelationshipFields(rel, child, parent, in.LookupFieldIDs, in.SummaryFields); err != nil {
+		return nil, err
+	}
+
+	return &qbclient.UpdateRelationshipOutput{Relationship: *rel}, nil
+}
+
+// deleteRelationship handles DELETE /v1/tables/{tableId}/relationship/{id}.
+// The lookup and summary fields are deleted, but the foreign key field isn't.
+func (s *Server) deleteRelationship(r *jsonRequest) (interface{}, error) {
+	child, rel, err := s.requestRelationship(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range rel.LookupFields {
+		child.removeField(f.FieldID)
+	}
+	if parent, err := s.table(rel.ParentTableID); err == nil {
+		for _, f := range rel.SummaryFields {
+			parent.removeField(f.FieldID)
+		}
+	}
+
+	for i := range child.relationships {
+		if child.relationships[i] == rel {
+			child.relationships = append(child.relationships[:i], child.relationships[i+1:]...)
+			break
+		}
+	}
+
+	return &qbclient.DeleteRelationshipOutput{RelationshipID: rel.RelationshipID}, nil
+}
+
+// requestRelationship returns the child table and relationship a request is
+// for.
+func (s *Server) requestRelationship(r *jsonRequest) (*table, *qbclient.Relationship, error) {
+	t, err := s.table(r.params[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id, err := r.intParam(1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rel := t.relationship(id)
+	if rel == nil {
+		return nil, nil, errNotFound("Relationship not found", "Relationship %d was not found in table %s.", id, t.TableID)
+	}
+
+	return t, rel, nil
+}
+
+// addRelationshipFields adds lookup fields to the child table and summary
+// fields to the parent table. Nothing is added if any field is invalid.
+func addRelationshipFields(rel *qbclient.Relationship, child, parent *table, lookupFieldIDs []int, summaryFields []*qbclient.RelationshipSummaryField) error {
+	for _, fid := range lookupFieldIDs {
+		if parent.field(fid) == nil {
+			return errBadRequest("Bad Request", "Lookup field %d was not found in table %s.", fid, parent.TableID)
+		}
+	}
+	for _, sf := range summaryFields {
+		if sf.AccumulationType == "" {
+			return errBadRequest("Bad Request", "Summary field accumulation type is required.")
+		}
+		if !strings.EqualFold(sf.AccumulationType, "COUNT") && child.field(sf.SummaryFieldID) == nil {
+			return errBadRequest("Bad Request", "Summary field %d was not found in table %s.", sf.SummaryFieldID, child.TableID)
+		}
+	}
+
+	for _, fid := range lookupFieldIDs {
+		pf := parent.field(fid)
+		props := &qbclient.FieldProperties{
+			LookupReferenceFieldID: rel.RelationshipID,
+			LookupTargetFieldID:    fid,
+		}
+		f := child.addField(uniqueLabel(child, parent.Name+" - "+pf.Label), pf.Type, props)
+		rel.LookupFields = append(rel.LookupFields, f.relationshipField())
+	}
+
+	for _, sf := range summaryFields {
+		label, ftype := "# of "+child.pluralNoun(), qbclient.FieldNumeric
+		if cf := child.field(sf.SummaryFieldID); cf != nil {
+			label = strings.Title(strings.ToLower(sf.AccumulationType)) + " " + cf.Label
+			ftype = cf.Type
+		}
+		if sf.Label != "" {
+			label = sf.Label
+		}
+
+		props := &qbclient.FieldProperties{
+			SummaryReferenceFieldID: rel.RelationshipID,
+			SummaryTargetFieldID:    sf.SummaryFieldID,
+		}
+		f := parent.addField(uniqueLabel(parent, label), ftype, props)
+		rel.SummaryFields = append(rel.SummaryFields, f.relationshipField())
+	}
+
+	return nil
+}
+
+// uniqueLabel appends a number to label if it's already in use.
+func uniqueLabel(t *table, label string) string {
+	unique := label
+	for n := 2; t.fieldByLabel(unique) != nil; n++ {
+		unique = fmt.Sprintf("%s %d", label, n)
+	}
+	return unique
+}
+
+// relationshipField returns the field as it's modeled in relationships.
+func (f *field) relationshipField() *qbclient.RelationshipField {
+	return &qbclient.RelationshipField{FieldID: f.FieldID, Label: f.Label, Type: f.Type}
+}
+
+// noun returns the name of a single record in the table.
+func (t *table) noun() string {
+	if t.SingleRecordName != "" {
+		return t.SingleRecordName
+	}
+	return t.Name
+}
+
+// pluralNoun returns the name of multiple records in the table.
+func (t *table) pluralNoun() string {
+	if t.PluralRecordName != "" {
+		return t.PluralRecordName
+	}
+	return t.Name
+}
@@ -0,0 +1,148 @@
+package qbtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// jsonHandler handles a JSON API request, returning the response body.
+type jsonHandler func(s *Server, r *jsonRequest) (interface{}, error)
+
+// jsonRoutes maps JSON API endpoints to their handlers. Path segments that
+// are "*" match any value and are passed to the handler as parameters.
+var jsonRoutes = []struct {
+	method  string
+	path    string
+	handler jsonHandler
+}{
+	{http.MethodPost, "apps", (*Server).createApp},
+	{http.MethodGet, "apps/*", (*Server).getApp},
+	{http.MethodPost, "apps/*", (*Server).updateApp},
+	{http.MethodDelete, "apps/*", (*Server).deleteApp},
+	{http.MethodGet, "apps/*/events", (*Server).listAppEvents},
+
+	{http.MethodPost, "tables", (*Server).createTable},
+	{http.MethodGet, "tables", (*Server).listTables},
+	{http.MethodGet, "tables/*", (*Server).getTable},
+	{http.MethodPost, "tables/*", (*Server).updateTable},
+	{http.MethodDelete, "tables/*", (*Server).deleteTable},
+
+	{http.MethodGet, "fields", (*Server).listFields},
+	{http.MethodPost, "fields", (*Server).createField},
+	{http.MethodDelete, "fields", (*Server).deleteFields},
+	{http.MethodGet, "fields/*", (*Server).getField},
+	{http.MethodPost, "fields/*", (*Server).updateField},
+
+	{http.MethodGet, "tables/*/relationships", (*Server).listRelationships},
+	{http.MethodPost, "tables/*/relationship", (*Server).createRelationship},
+	{http.MethodPost, "tables/*/relationship/*", (*Server).updateRelationship},
+	{http.MethodDelete, "tables/*/relationship/*", (*Server).deleteRelationship},
+
+	{http.MethodPost, "records", (*Server).insertRecords},
+	{http.MethodDelete, "records", (*Server).deleteRecords},
+	{http.MethodPost, "records/query", (*Server).queryRecords},
+
+	{http.MethodGet, "reports", (*Server).listReports},
+	{http.MethodGet, "reports/*", (*Server).getReport},
+	{http.MethodPost, "reports/*/run", (*Server).runReport},
+
+	{http.MethodPost, "formula/run", (*Server).runFormula},
+
+	{http.MethodDelete, "files/*/*/*/*", (*Server).deleteFile},
+}
+
+// jsonRequest is a JSON API request.
+type jsonRequest struct {
+	*http.Request
+	params []string
+	body   []byte
+}
+
+// decode unmarshals the request body into v. Empty bodies are ignored.
+func (r *jsonRequest) decode(v interface{}) error {
+	if len(r.body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.body, v); err != nil {
+		return errBadRequest("Bad Request", "Request body is invalid: %s", err)
+	}
+	return nil
+}
+
+// query returns a query string parameter.
+func (r *jsonRequest) query(key string) string {
+	return r.URL.Query().Get(key)
+}
+
+// intParam returns the nth path parameter as an integer.
+func (r *jsonRequest) intParam(n int) (int, error) {
+	i, err := strconv.Atoi(r.params[n])
+	if err != nil {
+		err = errBadRequest("Bad Request", "%q is not a valid ID.", r.params[n])
+	}
+	return i, err
+}
+
+// serveJSON routes a request to the JSON API handler and writes the response.
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	resp, err := s.routeJSON(r)
+	if err != nil {
+		aerr, ok := err.(*apiError)
+		if !ok {
+			aerr = &apiError{StatusCode: http.StatusInternalServerError, Message: "Internal Server Error", Description: err.Error()}
+		}
+		w.WriteHeader(aerr.StatusCode)
+		resp = aerr
+	} else if m, ok := resp.(multiStatus); ok && m.multiStatus() {
+		w.WriteHeader(http.StatusMultiStatus)
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) routeJSON(r *http.Request) (interface{}, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, errBadRequest("Bad Request", "Error reading request body: %s", err)
+	}
+
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+	for _, route := range jsonRoutes {
+		if route.method != r.Method {
+			continue
+		}
+		if params, ok := matchPath(route.path, path); ok {
+			return route.handler(s, &jsonRequest{Request: r, params: params, body: body})
+		}
+	}
+
+	return nil, errNotFound("Not Found", "%s %s is not implemented.", r.Method, r.URL.Path)
+}
+
+// matchPath matches path segments against a route's path, returning the
+// segments that matched "*".
+func matchPath(route string, path []string) (params []string, ok bool) {
+	segments := strings.Split(route, "/")
+	if len(segments) != len(path) {
+		return nil, false
+	}
+	for i, segment := range segments {
+		if segment == "*" {
+			params = append(params, path[i])
+		} else if segment != path[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// multiStatus is implemented by responses that are sent with a
+// 207 Multi-Status code when some of the request failed.
+type multiStatus interface {
+	multiStatus() bool
+}
@@ -0,0 +1,300 @@
+package qbtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qbformula"
+)
+
+// app models an app and its variables and pages. The tables are stored in
+// Server.tables.
+type app struct {
+	qbclient.App
+	Description string `json:"description,omitempty"`
+
+	tables []*table
+	pages  []*page
+}
+
+// variable returns the variable with the passed name, or nil if it doesn't
+// exist.
+func (a *app) variable(name string) *qbclient.Variable {
+	for _, v := range a.Variables {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// setVariable creates or updates a variable.
+func (a *app) setVariable(name, value string) {
+	if v := a.variable(name); v != nil {
+		v.Value = value
+	} else {
+		a.Variables = append(a.Variables, &qbclient.Variable{Name: name, Value: value})
+	}
+}
+
+// page returns the page whose ID or name is the passed value, or nil if it
+// doesn't exist.
+func (a *app) page(idOrName string) *page {
+	for _, p := range a.pages {
+		if p.name == idOrName || strconv.Itoa(p.id) == idOrName {
+			return p
+		}
+	}
+	return nil
+}
+
+// page models a code page.
+type page struct {
+	id   int
+	name string
+	body string
+}
+
+// table models a table and its fields, records, reports, and the
+// relationships it is the child table of.
+type table struct {
+	qbclient.ListTablesOutputTable
+
+	appID         string
+	iconName      string
+	fields        []*field
+	records       []record
+	reports       []*qbclient.ReportWithDescripton
+	relationships []*qbclient.Relationship
+	files         map[string][]byte
+}
+
+// newTable returns a new table with the built-in fields every table has.
+func newTable(appID, tableID string, now *qbclient.Timestamp) *table {
+	t := &table{
+		appID: appID,
+		files: make(map[string][]byte),
+	}
+
+	t.TableID = tableID
+	t.Created = now
+	t.Updated = now
+	t.NextRecordID = 1
+	t.NextFieldID = 1
+	t.KeyFieldID = fidRecordID
+	t.DefaultSortFieldID = fidDateModified
+	t.DefaultSortOrder = "DESC"
+	t.TimeZone = timeZone
+	t.DateFormat = dateFormat
+
+	t.addField("Date Created", qbclient.FieldDateTime, nil)
+	t.addField("Date Modified", qbclient.FieldDateTime, nil)
+	t.addField("Record ID#", qbclient.FieldRecordID, nil)
+	t.addField("Record Owner", qbclient.FieldUser, nil)
+	t.addField("Last Modified By", qbclient.FieldUser, nil)
+
+	return t
+}
+
+// The IDs of the built-in fields that are set by the server.
+const (
+	fidDateCreated  = 1
+	fidDateModified = 2
+	fidRecordID     = 3
+	fidLastBuiltIn  = 5
+)
+
+// The time zone and date format of apps and tables.
+const (
+	timeZone   = "(UTC) Coordinated Universal Time"
+	dateFormat = "MM-DD-YYYY"
+)
+
+// addField adds a field to the table and returns it.
+func (t *table) addField(label, ftype string, props *qbclient.FieldProperties) *field {
+	f := &field{FieldID: t.NextFieldID, Properties: props}
+	f.Label = label
+	f.Type = ftype
+	f.Searchable = true
+	f.AddToNewReports = true
+
+	t.NextFieldID++
+	t.fields = append(t.fields, f)
+	return f
+}
+
+// field returns the field with the passed ID, or nil if it doesn't exist.
+func (t *table) field(fid int) *field {
+	for _, f := range t.fields {
+		if f.FieldID == fid {
+			return f
+		}
+	}
+	return nil
+}
+
+// fieldByLabel returns the field with the passed label, or nil if it doesn't
+// exist. Labels are case insensitive.
+func (t *table) fieldByLabel(label string) *field {
+	for _, f := range t.fields {
+		if strings.EqualFold(f.Label, label) {
+			return f
+		}
+	}
+	return nil
+}
+
+// removeField removes a field and its values.
+func (t *table) removeField(fid int) {
+	for i, f := range t.fields {
+		if f.FieldID == fid {
+			t.fields = append(t.fields[:i], t.fields[i+1:]...)
+			break
+		}
+	}
+	for _, r := range t.records {
+		delete(r, fid)
+	}
+}
+
+// record returns the record with the passed ID, or nil if it doesn't exist.
+func (t *table) record(rid int) record {
+	for _, r := range t.records {
+		if r.id() == rid {
+			return r
+		}
+	}
+	return nil
+}
+
+// newRecord adds a new record to the table and returns it.
+func (t *table) newRecord(now *qbclient.Timestamp) record {
+	r := record{
+		fidDateCreated:  qbclient.NewDateTimeValue(now.Time),
+		fidDateModified: qbclient.NewDateTimeValue(now.Time),
+		fidRecordID:     qbclient.NewRecordIDValue(float64(t.NextRecordID)),
+	}
+	t.NextRecordID++
+	t.records = append(t.records, r)
+	return r
+}
+
+// values returns the record's values, including the values of fields with a
+// formula, which are evaluated in the order the fields were created.
+func (t *table) values(r record, now time.Time) record {
+	vals := make(record, len(r))
+	fields := make(map[string]*qbformula.Field, len(t.fields))
+	for _, f := range t.fields {
+		if !f.hasFormula() {
+			vals[f.FieldID] = r[f.FieldID]
+			fields[f.Label] = &qbformula.Field{Type: f.Type, Value: r[f.FieldID]}
+		}
+	}
+
+	env := &qbformula.Env{Now: now}
+	for _, f := range t.fields {
+		if f.hasFormula() {
+			v := f.eval(fields, env)
+			vals[f.FieldID] = v
+			fields[f.Label] = &qbformula.Field{Type: f.Type, Value: v}
+		}
+	}
+
+	return vals
+}
+
+// relationship returns the relationship with the passed ID, or nil if it
+// doesn't exist.
+func (t *table) relationship(id int) *qbclient.Relationship {
+	for _, r := range t.relationships {
+		if r.RelationshipID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// report returns the report with the passed ID, or nil if it doesn't exist.
+func (t *table) report(id string) *qbclient.ReportWithDescripton {
+	for _, r := range t.reports {
+		if r.ReportID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// field models a field. It is marshaled like fields are returned by the
+// JSON API.
+type field struct {
+	qbclient.Field
+	FieldID    int                       `json:"id"`
+	Properties *qbclient.FieldProperties `json:"properties,omitempty"`
+}
+
+// hasFormula returns whether the field's value is calculated by a formula.
+func (f *field) hasFormula() bool {
+	return f.Properties != nil && f.Properties.Formula != ""
+}
+
+// writable returns whether records can set the field's value.
+func (f *field) writable() bool {
+	if f.FieldID <= fidLastBuiltIn || f.hasFormula() {
+		return false
+	}
+	p := f.Properties
+	return p == nil || p.LookupTargetFieldID == 0 && p.SummaryTargetFieldID == 0
+}
+
+// eval evaluates the field's formula, returning nil if the formula can't be
+// evaluated offline or the result is null.
+func (f *field) eval(record map[string]*qbformula.Field, env *qbformula.Env) *qbclient.Value {
+	v, err := qbformula.Eval(f.Properties.Formula, record, env)
+	if err != nil || v == nil {
+		return nil
+	}
+	if v.QuickBaseType != f.Type {
+		v, _ = qbclient.NewValueFromString(v.String(), f.Type)
+	}
+	return v
+}
+
+// record models a record's values keyed by field ID. A missing or nil value
+// is null.
+type record map[int]*qbclient.Value
+
+// id returns the record's ID.
+func (r record) id() int {
+	if v := r[fidRecordID]; v != nil {
+		return int(v.Float64)
+	}
+	return 0
+}
+
+// set sets a field's value, returning whether the value changed.
+func (r record) set(fid int, v *qbclient.Value) bool {
+	if equalValues(r[fid], v) {
+		return false
+	}
+	if v == nil {
+		delete(r, fid)
+	} else {
+		r[fid] = v
+	}
+	return true
+}
+
+// equalValues returns whether two values are equal by comparing their JSON
+// encodings.
+func equalValues(a, b *qbclient.Value) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ab, aerr := json.Marshal(a)
+	bb, berr := json.Marshal(b)
+	return aerr == nil && berr == nil && bytes.Equal(ab, bb)
+}
@@ -0,0 +1,374 @@
+package qbtest
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// query is a parsed where clause in the Quickbase query language.
+// See https://developer.quickbase.com/queryLanguage
+type query interface {
+	match(vals record) bool
+}
+
+// queryAll matches every record. It is the query for an empty where clause.
+type queryAll struct{}
+
+func (queryAll) match(record) bool { return true }
+
+// queryAnd matches records that match all of its queries.
+type queryAnd []query
+
+func (q queryAnd) match(vals record) bool {
+	for _, sub := range q {
+		if !sub.match(vals) {
+			return false
+		}
+	}
+	return true
+}
+
+// queryOr matches records that match any of its queries.
+type queryOr []query
+
+func (q queryOr) match(vals record) bool {
+	for _, sub := range q {
+		if sub.match(vals) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryClause is a {fid.operator.'value'} clause.
+type queryClause struct {
+	field    *field
+	operator string
+	value    string
+}
+
+// queryOperators are the supported comparison operators.
+var queryOperators = map[string]bool{
+	"EX": true, "XEX": true, "CT": true, "XCT": true, "SW": true, "XSW": true,
+	"HAS": true, "XHAS": true, "LT": true, "LTE": true, "GT": true, "GTE": true,
+	"BF": true, "OBF": true, "AF": true, "OAF": true,
+}
+
+func (c *queryClause) match(vals record) bool {
+	v := vals[c.field.FieldID]
+	s := strings.ToLower(valueString(v))
+	want := strings.ToLower(c.value)
+
+	switch c.operator {
+	case "EX":
+		return c.equal(v)
+	case "XEX":
+		return !c.equal(v)
+	case "CT":
+		return strings.Contains(s, want)
+	case "XCT":
+		return !strings.Contains(s, want)
+	case "SW":
+		return strings.HasPrefix(s, want)
+	case "XSW":
+		return !strings.HasPrefix(s, want)
+	case "HAS":
+		return c.has(v)
+	case "XHAS":
+		return !c.has(v)
+	}
+
+	cmp, ok := c.compare(v)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "LT", "BF":
+		return cmp < 0
+	case "LTE", "OBF":
+		return cmp <= 0
+	case "GT", "AF":
+		return cmp > 0
+	default: // GTE, OAF
+		return cmp >= 0
+	}
+}
+
+// equal returns whether v equals the clause's value. Null values equal an
+// empty string.
+func (c *queryClause) equal(v *qbclient.Value) bool {
+	if v == nil {
+		return c.value == ""
+	}
+	cmp, ok := c.compare(v)
+	return ok && cmp == 0
+}
+
+// has returns whether a multi-select text or user list value includes the
+// clause's value.
+func (c *queryClause) has(v *qbclient.Value) bool {
+	if v == nil {
+		return false
+	}
+	for _, s := range v.StrSlice {
+		if strings.EqualFold(s, c.value) {
+			return true
+		}
+	}
+	for _, u := range v.UserSlice {
+		if strings.EqualFold(u.ID, c.value) || strings.EqualFold(u.Email, c.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares v to the clause's value, which is converted to the field's
+// type. The result is false if either value is null or can't be converted.
+func (c *queryClause) compare(v *qbclient.Value) (int, bool) {
+	if v == nil {
+		return 0, false
+	}
+	want, err := qbclient.NewValueFromString(c.value, c.field.Type)
+	if err != nil || want == nil {
+		return 0, false
+	}
+	return compareValues(v, want), true
+}
+
+// parseQuery parses a where clause, returning a query that matches every
+// record if it's empty.
+func parseQuery(t *table, where string) (query, error) {
+	if strings.TrimSpace(where) == "" {
+		return queryAll{}, nil
+	}
+
+	p := &queryParser{t: t, s: where}
+	q, err := p.parseOr()
+	if err == nil && p.skipSpace() < len(p.s) {
+		err = p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	return q, err
+}
+
+// queryParser is a recursive descent parser for the Quickbase query language.
+type queryParser struct {
+	t   *table
+	s   string
+	pos int
+}
+
+func (p *queryParser) errorf(format string, a ...interface{}) error {
+	return errBadRequest("Invalid query", "Error parsing the query at position %d: "+format, append([]interface{}{p.pos + 1}, a...)...)
+}
+
+// skipSpace advances past whitespace and returns the new position.
+func (p *queryParser) skipSpace() int {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+	return p.pos
+}
+
+// keyword consumes the keyword, e.g., AND, if it's next.
+func (p *queryParser) keyword(kw string) bool {
+	p.skipSpace()
+	end := p.pos + len(kw)
+	if end <= len(p.s) && strings.EqualFold(p.s[p.pos:end], kw) {
+		p.pos = end
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (query, error) {
+	q, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	or := queryOr{q}
+	for p.keyword("OR") {
+		if q, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		or = append(or, q)
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *queryParser) parseAnd() (query, error) {
+	q, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	and := queryAnd{q}
+	for p.keyword("AND") {
+		if q, err = p.parseTerm(); err != nil {
+			return nil, err
+		}
+		and = append(and, q)
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *queryParser) parseTerm() (query, error) {
+	switch {
+	case p.keyword("("):
+		q, err := p.parseOr()
+		if err == nil && !p.keyword(")") {
+			err = p.errorf("expecting )")
+		}
+		return q, err
+	case p.keyword("{"):
+		return p.parseClause()
+	default:
+		return nil, p.errorf("expecting { or (")
+	}
+}
+
+// parseClause parses the rest of a clause after the opening brace.
+func (p *queryParser) parseClause() (query, error) {
+	ref, err := p.parseValue(".")
+	if err != nil {
+		return nil, err
+	}
+	fid, err := strconv.Atoi(ref)
+	if err != nil {
+		return nil, p.errorf("%q is not a valid field ID", ref)
+	}
+	f := p.t.field(fid)
+	if f == nil {
+		return nil, p.errorf("field %d was not found", fid)
+	}
+
+	end := strings.IndexByte(p.s[p.pos:], '.')
+	if end < 0 {
+		return nil, p.errorf("expecting an operator")
+	}
+	op := strings.ToUpper(p.s[p.pos : p.pos+end])
+	if !queryOperators[op] {
+		return nil, p.errorf("%q is not a supported operator", op)
+	}
+	p.pos += end + 1
+
+	value, err := p.parseValue("}")
+	if err != nil {
+		return nil, err
+	}
+
+	return &queryClause{field: f, operator: op, value: value}, nil
+}
+
+// parseValue parses a quoted or unquoted value and consumes the delimiter
+// after it. Double quoted values may contain Go escape sequences.
+func (p *queryParser) parseValue(delim string) (string, error) {
+	var value string
+	rest := p.s[p.pos:]
+
+	switch {
+	case strings.HasPrefix(rest, `"`):
+		n := 1
+		for n < len(rest) && rest[n] != '"' {
+			if rest[n] == '\\' {
+				n++
+			}
+			n++
+		}
+		s, err := strconv.Unquote(rest[:min(n+1, len(rest))])
+		if err != nil {
+			return "", p.errorf("unterminated string")
+		}
+		value, p.pos = s, p.pos+n+1
+	case strings.HasPrefix(rest, "'"):
+		n := strings.IndexByte(rest[1:], '\'')
+		if n < 0 {
+			return "", p.errorf("unterminated string")
+		}
+		value, p.pos = rest[1:n+1], p.pos+n+2
+	default:
+		n := strings.Index(rest, delim)
+		if n < 0 {
+			return "", p.errorf("expecting %s", delim)
+		}
+		value, p.pos = rest[:n], p.pos+n
+	}
+
+	if !strings.HasPrefix(p.s[p.pos:], delim) {
+		return "", p.errorf("expecting %s", delim)
+	}
+	p.pos += len(delim)
+	return value, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// compareValues compares two values of the same type, returning -1, 0, or 1.
+// Null values sort first, and text is compared case insensitively.
+func compareValues(a, b *qbclient.Value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch a.QuickBaseType {
+	case qbclient.FieldRecordID, qbclient.FieldNumeric, qbclient.FieldNumericCurrency, qbclient.FieldNumericPercent, qbclient.FieldNumericRating:
+		return compareFloats(a.Float64, b.Float64)
+	case qbclient.FieldDate, qbclient.FieldDateTime, qbclient.FieldTimeOfDay:
+		return compareFloats(float64(a.Time.UnixNano()), float64(b.Time.UnixNano()))
+	case qbclient.FieldDuration:
+		return compareFloats(float64(a.Duration), float64(b.Duration))
+	case qbclient.FieldCheckbox:
+		return compareFloats(boolFloat(a.Bool), boolFloat(b.Bool))
+	default:
+		return strings.Compare(strings.ToLower(valueString(a)), strings.ToLower(valueString(b)))
+	}
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// valueString returns v as a string, which is empty if v is null or can't be
+// converted.
+func valueString(v *qbclient.Value) string {
+	switch {
+	case v == nil,
+		v.QuickBaseType == qbclient.FieldUser && v.User == nil,
+		v.QuickBaseType == qbclient.FieldFileAttachment && v.File == nil,
+		v.QuickBaseType == qbclient.FieldURL && v.URL == nil:
+		return ""
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,405 @@
+package qbtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qbformula"
+)
+
+// insertRecordsInput is the request body of POST /v1/records. Values are
+// decoded once the field types are known.
+type insertRecordsInput struct {
+	To           string `json:"to"`
+	MergeFieldID int    `json:"mergeFieldId"`
+	Data         []map[int]struct {
+		Value json.RawMessage `json:"value"`
+	} `json:"data"`
+}
+
+// insertRecordsOutput is the response to POST /v1/records, which is sent
+// with a 207 Multi-Status code if any record couldn't be inserted.
+type insertRecordsOutput struct {
+	qbclient.InsertRecordsOutput
+}
+
+func (o *insertRecordsOutput) multiStatus() bool { return len(o.Metadata.LineErrors) > 0 }
+
+// insertRecords handles POST /v1/records. Records are updated instead of
+// created if the value of the merge field, which defaults to the key field,
+// matches an existing record.
+func (s *Server) insertRecords(r *jsonRequest) (interface{}, error) {
+	var in insertRecordsInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	t, err := s.table(in.To)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeFieldID := in.MergeFieldID
+	if mergeFieldID == 0 {
+		mergeFieldID = t.KeyFieldID
+	} else if t.field(mergeFieldID) == nil {
+		return nil, errBadRequest("Bad Request", "Merge field %d was not found.", mergeFieldID)
+	}
+
+	meta := &qbclient.InsertRecordsOutputMetadata{
+		CreatedRecordIDs:   []int{},
+		UnchangedRecordIDs: []int{},
+		UpdatedRecordIDs:   []int{},
+		LineErrors:         make(map[string][]string),
+	}
+
+	now := s.now()
+	for i, data := range in.Data {
+		line := strconv.Itoa(i + 1)
+
+		// Decode the values, which are nil if they are null.
+		vals := make(record, len(data))
+		for fid, d := range data {
+			f := t.field(fid)
+			switch {
+			case f == nil:
+				meta.LineErrors[line] = append(meta.LineErrors[line], fmt.Sprintf("Field %d was not found.", fid))
+			case fid == mergeFieldID:
+				vals[fid], err = decodeValue(d.Value, f.Type)
+			case !f.writable():
+				meta.LineErrors[line] = append(meta.LineErrors[line], fmt.Sprintf("Field %d can't be written to.", fid))
+			default:
+				vals[fid], err = decodeValue(d.Value, f.Type)
+			}
+			if err != nil {
+				meta.LineErrors[line] = append(meta.LineErrors[line], fmt.Sprintf("Field %d: %s", fid, err))
+				err = nil
+			}
+		}
+		if len(meta.LineErrors[line]) > 0 {
+			continue
+		}
+
+		// Find the record to update, if any.
+		var rec record
+		if v, ok := vals[mergeFieldID]; ok && v != nil {
+			for _, existing := range t.records {
+				if equalValues(existing[mergeFieldID], v) {
+					rec = existing
+					break
+				}
+			}
+			if rec == nil && mergeFieldID == fidRecordID {
+				meta.LineErrors[line] = []string{fmt.Sprintf("Record %s was not found.", v)}
+				continue
+			}
+		}
+
+		created := rec == nil
+		if created {
+			rec = t.newRecord(now)
+		}
+
+		changed := false
+		for fid, v := range vals {
+			if fid != fidRecordID && rec.set(fid, v) {
+				changed = true
+			}
+		}
+
+		switch {
+		case created:
+			meta.CreatedRecordIDs = append(meta.CreatedRecordIDs, rec.id())
+		case changed:
+			rec[fidDateModified] = qbclient.NewDateTimeValue(now.Time)
+			meta.UpdatedRecordIDs = append(meta.UpdatedRecordIDs, rec.id())
+		default:
+			meta.UnchangedRecordIDs = append(meta.UnchangedRecordIDs, rec.id())
+		}
+		meta.TotalNumberOfRecordsProcessed++
+	}
+
+	if len(meta.LineErrors) == 0 {
+		meta.LineErrors = nil
+	}
+	return &insertRecordsOutput{qbclient.InsertRecordsOutput{Metadata: meta}}, nil
+}
+
+// deleteRecords handles DELETE /v1/records.
+func (s *Server) deleteRecords(r *jsonRequest) (interface{}, error) {
+	var in qbclient.DeleteRecordsInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	t, err := s.table(in.From)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := parseQuery(t, in.Where)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.Now()
+	kept := t.records[:0]
+	for _, rec := range t.records {
+		if !q.match(t.values(rec, now)) {
+			kept = append(kept, rec)
+		}
+	}
+
+	out := &qbclient.DeleteRecordsOutput{NumberDeleted: len(t.records) - len(kept)}
+	t.records = kept
+	return out, nil
+}
+
+// queryRecords handles POST /v1/records/query.
+func (s *Server) queryRecords(r *jsonRequest) (interface{}, error) {
+	var in qbclient.QueryRecordsInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	t, err := s.table(in.From)
+	if err != nil {
+		return nil, err
+	}
+
+	var skip, top int
+	if in.Options != nil {
+		skip, top = in.Options.Skip, in.Options.Top
+	}
+
+	records, err := s.query(t, in.Select, in.Where, in.SortBy, skip, top)
+	if err != nil {
+		return nil, err
+	}
+	return &qbclient.QueryRecordsOutput{Records: *records}, nil
+}
+
+// query returns the records that match the where clause in the Quickbase
+// query language, sorted, and paged by skip and top.
+func (s *Server) query(t *table, fids []int, where string, sortBy []*qbclient.QueryRecordsInputSortBy, skip, top int) (*qbclient.Records, error) {
+	fields := make([]*qbclient.RecordsField, len(fids))
+	for i, fid := range fids {
+		f := t.field(fid)
+		if f == nil {
+			return nil, errBadRequest("Bad Request", "Field %d was not found in table %s.", fid, t.TableID)
+		}
+		fields[i] = &qbclient.RecordsField{FieldID: f.FieldID, Label: f.Label, Type: f.Type}
+	}
+	for _, sb := range sortBy {
+		if t.field(sb.FieldID) == nil {
+			return nil, errBadRequest("Bad Request", "Sort field %d was not found in table %s.", sb.FieldID, t.TableID)
+		}
+	}
+
+	q, err := parseQuery(t, where)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.Now()
+	var matched []record
+	for _, rec := range t.records {
+		if vals := t.values(rec, now); q.match(vals) {
+			matched = append(matched, vals)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		for _, sb := range sortBy {
+			c := compareValues(matched[i][sb.FieldID], matched[j][sb.FieldID])
+			if sb.Order == "DESC" {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	total := len(matched)
+	if skip > len(matched) {
+		skip = len(matched)
+	}
+	matched = matched[skip:]
+	if top > 0 && top < len(matched) {
+		matched = matched[:top]
+	}
+
+	data := make([]map[int]*qbclient.RecordsData, len(matched))
+	for i, vals := range matched {
+		data[i] = make(map[int]*qbclient.RecordsData, len(fids))
+		for _, fid := range fids {
+			data[i][fid] = &qbclient.RecordsData{Value: vals[fid]}
+		}
+	}
+
+	return &qbclient.Records{
+		Data:   data,
+		Fields: fields,
+		Metadata: &qbclient.RecordsMetadata{
+			TotalRecords: total,
+			NumRecords:   len(matched),
+			NumFields:    len(fids),
+			Skip:         skip,
+			Top:          top,
+		},
+	}, nil
+}
+
+// listReports handles GET /v1/reports?tableId={tableId}.
+func (s *Server) listReports(r *jsonRequest) (interface{}, error) {
+	t, err := s.table(r.query("tableId"))
+	if err != nil {
+		return nil, err
+	}
+	return &qbclient.ListReportsOutput{Reports: t.reports}, nil
+}
+
+// getReport handles GET /v1/reports/{reportId}?tableId={tableId}.
+func (s *Server) getReport(r *jsonRequest) (interface{}, error) {
+	_, report, err := s.requestReport(r)
+	if err != nil {
+		return nil, err
+	}
+	return &qbclient.GetReportOutput{Report: report.Report}, nil
+}
+
+// runReport handles POST /v1/reports/{reportId}/run?tableId={tableId}. The
+// report's fields, filter, and sort order are used to query the records, and
+// all fields are returned if the report doesn't have any.
+func (s *Server) runReport(r *jsonRequest) (interface{}, error) {
+	t, report, err := s.requestReport(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fids := report.Query.Fields
+	if len(fids) == 0 {
+		for _, f := range t.fields {
+			fids = append(fids, f.FieldID)
+		}
+	}
+
+	sortBy := make([]*qbclient.QueryRecordsInputSortBy, len(report.Query.SortBy))
+	for i, sb := range report.Query.SortBy {
+		sortBy[i] = &qbclient.QueryRecordsInputSortBy{FieldID: sb.FieldID, Order: sb.Order}
+	}
+
+	skip, _ := strconv.Atoi(r.query("skip"))
+	top, _ := strconv.Atoi(r.query("top"))
+
+	records, err := s.query(t, fids, report.Query.Filter, sortBy, skip, top)
+	if err != nil {
+		return nil, err
+	}
+	return &qbclient.RunReportOutput{Records: *records}, nil
+}
+
+// requestReport returns the table and report a request is for.
+func (s *Server) requestReport(r *jsonRequest) (*table, *qbclient.ReportWithDescripton, error) {
+	t, err := s.table(r.query("tableId"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := t.report(r.params[0])
+	if report == nil {
+		return nil, nil, errNotFound("Report not found", "Report %s was not found in table %s.", r.params[0], t.TableID)
+	}
+
+	return t, report, nil
+}
+
+// runFormula handles POST /v1/formula/run. The formula is evaluated by
+// qbformula, so formulas that can't be evaluated offline are bad requests.
+func (s *Server) runFormula(r *jsonRequest) (interface{}, error) {
+	var in qbclient.RunFormulaInput
+	if err := r.decode(&in); err != nil {
+		return nil, err
+	}
+
+	t, err := s.table(in.From)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := t.record(in.RecordID)
+	if rec == nil {
+		return nil, errNotFound("Record not found", "Record %d was not found in table %s.", in.RecordID, t.TableID)
+	}
+
+	now := s.Now()
+	vals := t.values(rec, now)
+	fields := make(map[string]*qbformula.Field, len(t.fields))
+	for _, f := range t.fields {
+		fields[f.Label] = &qbformula.Field{Type: f.Type, Value: vals[f.FieldID]}
+	}
+
+	v, err := qbformula.Eval(in.Formula, fields, &qbformula.Env{Now: now})
+	if err != nil {
+		return nil, errBadRequest("Formula syntax error", "%s", err)
+	}
+
+	out := &qbclient.RunFormulaOutput{}
+	if v != nil {
+		out.Result = v.String()
+	}
+	return out, nil
+}
+
+// deleteFile handles DELETE /v1/files/{tableId}/{recordId}/{fieldId}/{version}.
+func (s *Server) deleteFile(r *jsonRequest) (interface{}, error) {
+	t, err := s.table(r.params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var ids [3]int
+	for i := range ids {
+		if ids[i], err = r.intParam(i + 1); err != nil {
+			return nil, err
+		}
+	}
+	rid, fid, version := ids[0], ids[1], ids[2]
+
+	rec := t.record(rid)
+	if rec == nil {
+		return nil, errNotFound("Record not found", "Record %d was not found in table %s.", rid, t.TableID)
+	}
+
+	if v := rec[fid]; v != nil && v.File != nil {
+		for i, fv := range v.File.Version {
+			if fv.Version == version {
+				v.File.Version = append(v.File.Version[:i], v.File.Version[i+1:]...)
+				delete(t.files, fileKey(rid, fid, version))
+				return &qbclient.DeleteFileOutput{Version: fv.Version, FileName: fv.FileName, Uploaded: fv.Uploaded}, nil
+			}
+		}
+	}
+
+	return nil, errNotFound("File not found", "Version %d of the file in field %d of record %d was not found.", version, fid, rid)
+}
+
+// fileKey returns the key the contents of a file version are stored under.
+func fileKey(rid, fid, version int) string {
+	return fmt.Sprintf("%d/%d/%d", rid, fid, version)
+}
+
+// decodeValue decodes a JSON encoded value of a field of the ftype type,
+// returning nil if it is null.
+func decodeValue(data json.RawMessage, ftype string) (*qbclient.Value, error) {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	return qbclient.NewValueFromJSON(data, ftype)
+}
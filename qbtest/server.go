@@ -0,0 +1,169 @@
+// Package qbtest provides an in-memory fake Quickbase server for testing
+// integrations offline, without a realm or user token.
+package qbtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/spf13/viper"
+)
+
+// Server is an in-memory fake Quickbase server. It implements the JSON API
+// endpoints and the XML API actions consumed by qbclient, storing apps,
+// tables, fields, relationships, records, reports, variables, pages, and
+// files in memory until the server is closed.
+//
+// Fields with a formula are evaluated by qbformula when records are read, so
+// formulas that can't be evaluated offline are null. Lookup and summary fields
+// are created by relationships, but they are always null.
+type Server struct {
+
+	// URL is the base URL of the JSON API, which is what Client.URL is set to.
+	URL string
+
+	// RealmHostname is the host the XML API is served on, which is what
+	// Client.ReamlHostname is set to.
+	RealmHostname string
+
+	// Now returns the current time, which defaults to time.Now. It sets the
+	// date created and modified fields and is the time formulas are
+	// evaluated at.
+	Now func() time.Time
+
+	ts *httptest.Server
+	mu sync.Mutex
+
+	ids    int
+	apps   []*app
+	tables map[string]*table
+}
+
+// NewServer starts and returns a new Server. The server uses TLS, because
+// the XML API is always consumed over HTTPS, so clients must use
+// Server.HTTPClient, which trusts the server's certificate. The caller
+// should call Close when finished to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		Now:    time.Now,
+		tables: make(map[string]*table),
+	}
+
+	s.ts = httptest.NewTLSServer(s)
+	s.URL = s.ts.URL + "/v1"
+	s.RealmHostname = s.ts.Listener.Addr().String()
+
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+// HTTPClient returns an *http.Client that trusts the server's certificate.
+func (s *Server) HTTPClient() *http.Client {
+	return s.ts.Client()
+}
+
+// Configure points c at the server. It replaces c.HTTPClient, so requests
+// are no longer retried or rate limited.
+func (s *Server) Configure(c *qbclient.Client) {
+	c.URL = s.URL
+	c.ReamlHostname = s.RealmHostname
+	c.HTTPClient = s.HTTPClient()
+}
+
+// Client returns a new *qbclient.Client that is configured to make requests
+// to the server.
+func (s *Server) Client() *qbclient.Client {
+	c := qbclient.New(qbclient.NewConfig(viper.New()))
+	s.Configure(c)
+	return c
+}
+
+// AddReport adds a report to a table and returns its ID. The JSON API can't
+// create reports, so they are added directly. The report's ID is set if
+// it's empty, and the query's table ID is always set.
+func (s *Server) AddReport(tableID string, report *qbclient.Report) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.table(tableID)
+	if err != nil {
+		return "", err
+	}
+
+	if report.ReportID == "" {
+		report.ReportID = fmt.Sprint(len(t.reports) + 1)
+	}
+	if report.Query == nil {
+		report.Query = &qbclient.ReportQuery{}
+	}
+	report.Query.TableID = tableID
+
+	t.reports = append(t.reports, &qbclient.ReportWithDescripton{Report: *report})
+	return report.ReportID, nil
+}
+
+// ServeHTTP implements http.Handler by routing requests to the JSON API or
+// the XML API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/"):
+		s.serveJSON(w, r)
+	case strings.HasPrefix(r.URL.Path, "/db/"):
+		s.serveXML(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newID returns a new app or table ID.
+func (s *Server) newID() string {
+	s.ids++
+	return fmt.Sprintf("bq%07d", s.ids)
+}
+
+// now returns the current time as a *qbclient.Timestamp.
+func (s *Server) now() *qbclient.Timestamp {
+	return &qbclient.Timestamp{Time: s.Now().UTC()}
+}
+
+// app returns the app with the passed ID.
+func (s *Server) app(appID string) (*app, error) {
+	for _, a := range s.apps {
+		if a.AppID == appID {
+			return a, nil
+		}
+	}
+	return nil, errNotFound("App not found", "App %s was not found.", appID)
+}
+
+// table returns the table with the passed ID.
+func (s *Server) table(tableID string) (*table, error) {
+	if t, ok := s.tables[tableID]; ok {
+		return t, nil
+	}
+	return nil, errNotFound("Table not found", "Table %s was not found.", tableID)
+}
+
+// appTable returns the table with the passed ID, which must be in the app.
+func (s *Server) appTable(appID, tableID string) (*table, error) {
+	if _, err := s.app(appID); err != nil {
+		return nil, err
+	}
+	t, err := s.table(tableID)
+	if err == nil && t.appID != appID {
+		err = errNotFound("Table not found", "Table %s was not found in app %s.", tableID, appID)
+	}
+	return t, err
+}
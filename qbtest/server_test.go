@@ -0,0 +1,355 @@
+package qbtest_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+	"github.com/QuickBase/quickbase-cli/qberrors"
+	"github.com/QuickBase/quickbase-cli/qbtest"
+)
+
+// newTable starts a server and creates an app with a table that has a text
+// and a numeric field.
+func newTable(t *testing.T) (*qbtest.Server, *qbclient.Client, string, string) {
+	s := qbtest.NewServer()
+	t.Cleanup(s.Close)
+	qb := s.Client()
+
+	app, err := qb.CreateApp(&qbclient.CreateAppInput{Name: "Projects"})
+	if err != nil {
+		t.Fatalf("unexpected error creating app: %s", err)
+	}
+
+	table, err := qb.CreateTable(&qbclient.CreateTableInput{AppID: app.AppID, Name: "Tasks", SingularNoun: "Task"})
+	if err != nil {
+		t.Fatalf("unexpected error creating table: %s", err)
+	}
+
+	for _, f := range []*qbclient.CreateFieldInput{
+		{TableID: table.TableID, Field: qbclient.Field{Label: "Name", Type: qbclient.FieldText}},
+		{TableID: table.TableID, Field: qbclient.Field{Label: "Hours", Type: qbclient.FieldNumeric}},
+	} {
+		if _, err := qb.CreateField(f); err != nil {
+			t.Fatalf("unexpected error creating field: %s", err)
+		}
+	}
+
+	return s, qb, app.AppID, table.TableID
+}
+
+// insert inserts records with Name and Hours values.
+func insert(t *testing.T, qb *qbclient.Client, tableID string, names []string, hours []float64) *qbclient.InsertRecordsOutput {
+	records := make([]*qbclient.Record, len(names))
+	for i := range names {
+		records[i] = &qbclient.Record{}
+		records[i].SetValue(6, qbclient.NewTextValue(names[i]))
+		records[i].SetValue(7, qbclient.NewNumericValue(hours[i]))
+	}
+
+	input := &qbclient.InsertRecordsInput{To: tableID}
+	input.SetRecords(records)
+
+	out, err := qb.InsertRecords(input)
+	if err != nil {
+		t.Fatalf("unexpected error inserting records: %s", err)
+	}
+	return out
+}
+
+func TestServerSchema(t *testing.T) {
+	_, qb, appID, tableID := newTable(t)
+
+	app, err := qb.GetApp(&qbclient.GetAppInput{AppID: appID})
+	if err != nil {
+		t.Fatalf("unexpected error getting app: %s", err)
+	}
+	if app.Name != "Projects" {
+		t.Errorf("got app name %q, expected %q", app.Name, "Projects")
+	}
+
+	tables, err := qb.ListTables(&qbclient.ListTablesInput{AppID: appID})
+	if err != nil {
+		t.Fatalf("unexpected error listing tables: %s", err)
+	}
+	if len(tables.Tables) != 1 || tables.Tables[0].TableID != tableID || tables.Tables[0].NextFieldID != 8 {
+		t.Errorf("got tables %+v, expected the Tasks table with next field ID 8", tables.Tables)
+	}
+
+	_, err = qb.UpdateField(&qbclient.UpdateFieldInput{
+		TableID:    tableID,
+		FieldID:    7,
+		Field:      qbclient.Field{Label: "Hours Spent"},
+		Properties: &qbclient.UpdateFieldInputProperties{FieldProperties: qbclient.FieldProperties{Comments: "Billable"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error updating field: %s", err)
+	}
+
+	field, err := qb.GetField(&qbclient.GetFieldInput{TableID: tableID, FieldID: 7})
+	if err != nil {
+		t.Fatalf("unexpected error getting field: %s", err)
+	}
+	if field.Label != "Hours Spent" || field.Type != qbclient.FieldNumeric || field.Properties.Comments != "Billable" {
+		t.Errorf("got field %+v %+v, expected the label and comments to be updated", field.Field, field.Properties)
+	}
+
+	deleted, err := qb.DeleteFields(&qbclient.DeleteFieldsInput{TableID: tableID, FieldIDs: []int{3, 7}})
+	if err != nil {
+		t.Fatalf("unexpected error deleting fields: %s", err)
+	}
+	if len(deleted.DeletedFieldIDs) != 1 || len(deleted.Errors) != 1 {
+		t.Errorf("got %+v, expected only field 7 to be deleted", deleted)
+	}
+
+	fields, err := qb.ListFields(&qbclient.ListFieldsInput{TableID: tableID})
+	if err != nil {
+		t.Fatalf("unexpected error listing fields: %s", err)
+	}
+	if len(fields.Fields) != 6 {
+		t.Errorf("got %v fields, expected 6", len(fields.Fields))
+	}
+
+	_, err = qb.GetTable(&qbclient.GetTableInput{AppID: appID, TableID: "bq9999999"})
+	if code := qberrors.StatusCode(err); code != http.StatusNotFound {
+		t.Errorf("got status %v, expected %v", code, http.StatusNotFound)
+	}
+}
+
+func TestServerRecords(t *testing.T) {
+	_, qb, _, tableID := newTable(t)
+
+	out := insert(t, qb, tableID, []string{"Design", "Build", "Test"}, []float64{3, 8, 5})
+	if have, want := out.Metadata.CreatedRecordIDs, []int{1, 2, 3}; len(have) != len(want) || have[2] != want[2] {
+		t.Errorf("got created record IDs %v, expected %v", have, want)
+	}
+
+	// Update a record by merging on the Name field.
+	input := &qbclient.InsertRecordsInput{To: tableID, MergeFieldID: 6}
+	rec := &qbclient.Record{}
+	rec.SetValue(6, qbclient.NewTextValue("Build"))
+	rec.SetValue(7, qbclient.NewNumericValue(13))
+	input.SetRecords([]*qbclient.Record{rec})
+	if out, err := qb.InsertRecords(input); err != nil {
+		t.Fatalf("unexpected error updating records: %s", err)
+	} else if len(out.Metadata.UpdatedRecordIDs) != 1 || out.Metadata.UpdatedRecordIDs[0] != 2 {
+		t.Errorf("got updated record IDs %v, expected [2]", out.Metadata.UpdatedRecordIDs)
+	}
+
+	query, err := qb.QueryRecords(&qbclient.QueryRecordsInput{
+		From:   tableID,
+		Select: []int{3, 6, 7},
+		Where:  "{7.GT.'4'} OR {6.EX.'design'}",
+		SortBy: []*qbclient.QueryRecordsInputSortBy{{FieldID: 7, Order: "DESC"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error querying records: %s", err)
+	}
+
+	var names []string
+	for _, data := range query.Data {
+		names = append(names, data[6].Value.Str)
+	}
+	if len(names) != 3 || names[0] != "Build" || names[1] != "Test" || names[2] != "Design" {
+		t.Errorf("got %v, expected [Build Test Design]", names)
+	}
+	if query.Data[0][7].Value.Float64 != 13 {
+		t.Errorf("got %v hours, expected 13", query.Data[0][7].Value.Float64)
+	}
+
+	deleted, err := qb.DeleteRecords(&qbclient.DeleteRecordsInput{From: tableID, Where: `{"6".CT."i"}`})
+	if err != nil {
+		t.Fatalf("unexpected error deleting records: %s", err)
+	}
+	if deleted.NumberDeleted != 2 {
+		t.Errorf("got %v deleted, expected 2", deleted.NumberDeleted)
+	}
+
+	_, err = qb.QueryRecords(&qbclient.QueryRecordsInput{From: tableID, Select: []int{3}, Where: "{6.EX.'Build'"})
+	if code := qberrors.StatusCode(err); code != http.StatusBadRequest {
+		t.Errorf("got status %v, expected %v", code, http.StatusBadRequest)
+	}
+}
+
+func TestServerFormulasAndReports(t *testing.T) {
+	s, qb, _, tableID := newTable(t)
+	s.Now = func() time.Time { return time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	_, err := qb.CreateField(&qbclient.CreateFieldInput{
+		TableID:    tableID,
+		Field:      qbclient.Field{Label: "Days", Type: qbclient.FieldNumeric},
+		Properties: &qbclient.CreateFieldInputProperties{FieldProperties: qbclient.FieldProperties{Formula: "[Hours] / 8"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating formula field: %s", err)
+	}
+
+	insert(t, qb, tableID, []string{"Design", "Build"}, []float64{4, 16})
+
+	run, err := qb.RunFormula(&qbclient.RunFormulaInput{From: tableID, RecordID: 2, Formula: `[Name] & ": " & [Days] & " days"`})
+	if err != nil {
+		t.Fatalf("unexpected error running formula: %s", err)
+	}
+	if run.Result != "Build: 2 days" {
+		t.Errorf("got %q, expected %q", run.Result, "Build: 2 days")
+	}
+
+	_, err = qb.RunFormula(&qbclient.RunFormulaInput{From: tableID, RecordID: 2, Formula: "[Nmae]"})
+	if code := qberrors.StatusCode(err); code != http.StatusBadRequest {
+		t.Errorf("got status %v, expected %v", code, http.StatusBadRequest)
+	}
+
+	reportID, err := s.AddReport(tableID, &qbclient.Report{
+		Name:  "Long tasks",
+		Type:  "table",
+		Query: &qbclient.ReportQuery{Fields: []int{6, 8}, Filter: "{8.GTE.'1'}"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error adding report: %s", err)
+	}
+
+	reports, err := qb.ListReports(&qbclient.ListReportsInput{TableID: tableID})
+	if err != nil {
+		t.Fatalf("unexpected error listing reports: %s", err)
+	}
+	if len(reports.Reports) != 1 || reports.Reports[0].Name != "Long tasks" {
+		t.Errorf("got reports %+v, expected the Long tasks report", reports.Reports)
+	}
+
+	report, err := qb.RunReport(&qbclient.RunReportInput{TableID: tableID, ReportID: reportID})
+	if err != nil {
+		t.Fatalf("unexpected error running report: %s", err)
+	}
+	if len(report.Data) != 1 || report.Data[0][6].Value.Str != "Build" || report.Data[0][8].Value.Float64 != 2 {
+		t.Errorf("got %+v, expected only Build with 2 days", report.Data)
+	}
+	if created := report.Fields; len(created) != 2 || created[1].Label != "Days" {
+		t.Errorf("got fields %+v, expected Name and Days", created)
+	}
+}
+
+func TestServerRelationships(t *testing.T) {
+	_, qb, appID, childID := newTable(t)
+
+	parent, err := qb.CreateTable(&qbclient.CreateTableInput{AppID: appID, Name: "Projects", SingularNoun: "Project"})
+	if err != nil {
+		t.Fatalf("unexpected error creating table: %s", err)
+	}
+
+	rel, err := qb.CreateRelationship(&qbclient.CreateRelationshipInput{
+		ChildTableID:   childID,
+		ParentTableID:  parent.TableID,
+		LookupFieldIDs: []int{3},
+		SummaryFields:  []*qbclient.RelationshipSummaryField{{SummaryFieldID: 7, AccumulationType: "SUM"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating relationship: %s", err)
+	}
+	if rel.RelationshipID != 8 || rel.ForeignKeyField.Label != "Related Project" {
+		t.Errorf("got relationship %v with foreign key %+v, expected 8 and Related Project", rel.RelationshipID, rel.ForeignKeyField)
+	}
+	if len(rel.LookupFields) != 1 || rel.LookupFields[0].Label != "Projects - Record ID#" {
+		t.Errorf("got lookup fields %+v, expected Projects - Record ID#", rel.LookupFields)
+	}
+	if len(rel.SummaryFields) != 1 || rel.SummaryFields[0].Label != "Sum Hours" {
+		t.Errorf("got summary fields %+v, expected Sum Hours", rel.SummaryFields)
+	}
+
+	summary, err := qb.GetField(&qbclient.GetFieldInput{TableID: parent.TableID, FieldID: rel.SummaryFields[0].FieldID})
+	if err != nil {
+		t.Fatalf("unexpected error getting summary field: %s", err)
+	}
+	if summary.Properties.SummaryReferenceFieldID != 8 || summary.Properties.SummaryTargetFieldID != 7 {
+		t.Errorf("got properties %+v, expected the summary to reference fields 8 and 7", summary.Properties)
+	}
+
+	if _, err := qb.DeleteRelationship(&qbclient.DeleteRelationshipInput{ChildTableID: childID, RelationshipID: 8}); err != nil {
+		t.Fatalf("unexpected error deleting relationship: %s", err)
+	}
+
+	list, err := qb.ListRelationships(&qbclient.ListRelationshipsInput{ChildTableID: childID})
+	if err != nil {
+		t.Fatalf("unexpected error listing relationships: %s", err)
+	}
+	if len(list.Relationships) != 0 {
+		t.Errorf("got %v relationships, expected 0", len(list.Relationships))
+	}
+}
+
+func TestServerXML(t *testing.T) {
+	_, qb, appID, tableID := newTable(t)
+
+	if _, err := qb.SetVariable(&qbclient.SetVariableInput{AppID: appID, Name: "env", Value: "prod"}); err != nil {
+		t.Fatalf("unexpected error setting variable: %s", err)
+	}
+	v, err := qb.GetVariable(&qbclient.GetVariableInput{AppID: appID, Name: "env"})
+	if err != nil {
+		t.Fatalf("unexpected error getting variable: %s", err)
+	}
+	if v.Value != "prod" {
+		t.Errorf("got %q, expected %q", v.Value, "prod")
+	}
+
+	_, err = qb.GetVariable(&qbclient.GetVariableInput{AppID: appID, Name: "missing"})
+	if code := qberrors.StatusCode(err); code != http.StatusNotFound {
+		t.Errorf("got status %v, expected %v", code, http.StatusNotFound)
+	}
+
+	page, err := qb.CreatePage(&qbclient.CreatePageInput{
+		AppID: appID,
+		Name:  "index.html",
+		Type:  1,
+		Body:  &qbclient.CreatePageInputBody{Data: "<h1>v1</h1>"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating page: %s", err)
+	}
+	_, err = qb.UpdatePage(&qbclient.UpdatePageInput{
+		AppID:  appID,
+		PageID: page.PageID,
+		Body:   &qbclient.UpdatePageInputBody{Data: "<h1>v2</h1>"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error updating page: %s", err)
+	}
+	got, err := qb.GetPage(&qbclient.GetPageInput{AppID: appID, PageID: "index.html"})
+	if err != nil {
+		t.Fatalf("unexpected error getting page: %s", err)
+	}
+	if got.Body != "<h1>v2</h1>" {
+		t.Errorf("got %q, expected %q", got.Body, "<h1>v2</h1>")
+	}
+
+	apps, err := qb.ListApps(&qbclient.ListAppsInput{WithEmbeddedTables: true})
+	if err != nil {
+		t.Fatalf("unexpected error listing apps: %s", err)
+	}
+	if len(apps.Databases) != 2 || apps.Databases[0].ID != appID || apps.Databases[1].ID != tableID {
+		t.Errorf("got %+v, expected the app and its table", apps.Databases)
+	}
+
+	if _, err := qb.CreateField(&qbclient.CreateFieldInput{TableID: tableID, Field: qbclient.Field{Label: "Spec", Type: qbclient.FieldFileAttachment}}); err != nil {
+		t.Fatalf("unexpected error creating field: %s", err)
+	}
+	insert(t, qb, tableID, []string{"Design"}, []float64{3})
+
+	for i := 0; i < 2; i++ {
+		_, err := qb.CreateFile(&qbclient.CreateFileInput{
+			TableID:  tableID,
+			RecordID: 1,
+			Fields:   []*qbclient.CreateFileInputField{{FieldID: 8, Name: "spec.txt", FileData: "draft"}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error uploading file: %s", err)
+		}
+	}
+
+	deleted, err := qb.DeleteFile(&qbclient.DeleteFileInput{TableID: tableID, RecordID: 1, FieldID: 8, Version: 2})
+	if err != nil {
+		t.Fatalf("unexpected error deleting file: %s", err)
+	}
+	if deleted.FileName != "spec.txt" || deleted.Version != 2 {
+		t.Errorf("got %+v, expected version 2 of spec.txt", deleted)
+	}
+}
@@ -0,0 +1,289 @@
+package qbtest
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/QuickBase/quickbase-cli/qbclient"
+)
+
+// xmlOutput is implemented by XML API responses.
+type xmlOutput interface {
+	params() *qbclient.XMLResponseParameters
+}
+
+// The XML API outputs wrap qbclient's to implement xmlOutput.
+type (
+	grantedDBsOutput       struct{ qbclient.ListAppsOutput }
+	getDBVarOutput         struct{ qbclient.GetVariableOutput }
+	setDBVarOutput         struct{ qbclient.SetVariableOutput }
+	getDBPageOutput        struct{ qbclient.GetPageOutput }
+	addReplaceDBPageOutput struct{ qbclient.CreatePageOutput }
+	uploadFileOutput       struct{ qbclient.CreateFileOutput }
+	xmlErrorOutput         struct{ qbclient.XMLResponseParameters }
+)
+
+func (o *grantedDBsOutput) params() *qbclient.XMLResponseParameters { return &o.XMLResponseParameters }
+func (o *getDBVarOutput) params() *qbclient.XMLResponseParameters   { return &o.XMLResponseParameters }
+func (o *setDBVarOutput) params() *qbclient.XMLResponseParameters   { return &o.XMLResponseParameters }
+func (o *getDBPageOutput) params() *qbclient.XMLResponseParameters  { return &o.XMLResponseParameters }
+func (o *addReplaceDBPageOutput) params() *qbclient.XMLResponseParameters {
+	return &o.XMLResponseParameters
+}
+func (o *uploadFileOutput) params() *qbclient.XMLResponseParameters { return &o.XMLResponseParameters }
+func (o *xmlErrorOutput) params() *qbclient.XMLResponseParameters   { return &o.XMLResponseParameters }
+
+// xmlHandler handles an XML API action, returning the response.
+type xmlHandler func(s *Server, dbid string, body []byte) (xmlOutput, error)
+
+// xmlActions maps XML API actions to their handlers.
+var xmlActions = map[string]xmlHandler{
+	"API_GrantedDBs":       (*Server).grantedDBs,
+	"API_GetDBvar":         (*Server).getDBVar,
+	"API_SetDBvar":         (*Server).setDBVar,
+	"API_GetDBPage":        (*Server).getDBPage,
+	"API_AddReplaceDBPage": (*Server).addReplaceDBPage,
+	"API_UploadFile":       (*Server).uploadFile,
+}
+
+// serveXML routes a request to the XML API action in the QUICKBASE-ACTION
+// header and writes the response. Like the XML API, errors are reported in the
+// errcode element of responses with a 200 OK status code.
+func (s *Server) serveXML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/xml")
+
+	action := r.Header.Get("QUICKBASE-ACTION")
+	dbid := strings.TrimPrefix(r.URL.Path, "/db/")
+
+	var out xmlOutput
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		err = errBadRequest("Bad Request", "Error reading request body: %s", err)
+	} else if handler, ok := xmlActions[action]; ok {
+		out, err = handler(s, dbid, body)
+	} else {
+		err = errBadRequest("Invalid input", "Unknown action %q.", action)
+	}
+
+	if err != nil {
+		aerr, ok := err.(*apiError)
+		if !ok {
+			aerr = &apiError{Message: "Unknown error", Description: err.Error()}
+		}
+		out = &xmlErrorOutput{qbclient.XMLResponseParameters{
+			ErrorCode:   xmlErrorCode(aerr),
+			ErrorText:   aerr.Message,
+			ErrorDetail: aerr.Description,
+		}}
+		if aerr.StatusCode >= 500 || !ok {
+			out.params().ErrorCode = 1
+		}
+	} else {
+		out.params().ErrorText = "No error"
+	}
+	out.params().Action = action
+
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(out)
+}
+
+// decodeXML unmarshals an XML API request body into v.
+func decodeXML(body []byte, v interface{}) error {
+	if err := xml.Unmarshal(body, v); err != nil {
+		return errBadRequest("Invalid input", "Request body is invalid: %s", err)
+	}
+	return nil
+}
+
+// grantedDBs handles API_GrantedDBs, which lists apps, and their tables if
+// withembeddedtables is set.
+func (s *Server) grantedDBs(dbid string, body []byte) (xmlOutput, error) {
+	var in qbclient.ListAppsInput
+	if err := decodeXML(body, &in); err != nil {
+		return nil, err
+	}
+
+	out := &grantedDBsOutput{}
+	out.Databases = []*qbclient.ListAppsOutputDatabases{}
+	for _, a := range s.apps {
+		out.Databases = append(out.Databases, &qbclient.ListAppsOutputDatabases{ID: a.AppID, Name: a.Name})
+		if in.WithEmbeddedTables {
+			for _, t := range a.tables {
+				db := &qbclient.ListAppsOutputDatabases{ID: t.TableID, Name: a.Name + ": " + t.Name}
+				out.Databases = append(out.Databases, db)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// getDBVar handles API_GetDBvar.
+func (s *Server) getDBVar(dbid string, body []byte) (xmlOutput, error) {
+	a, err := s.app(dbid)
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.GetVariableInput
+	if err := decodeXML(body, &in); err != nil {
+		return nil, err
+	}
+
+	v := a.variable(in.Name)
+	if v == nil {
+		return nil, errBadRequest("Invalid input", "Variable %q not found.", in.Name)
+	}
+
+	out := &getDBVarOutput{}
+	out.Value = v.Value
+	return out, nil
+}
+
+// setDBVar handles API_SetDBvar, which creates or updates a variable.
+func (s *Server) setDBVar(dbid string, body []byte) (xmlOutput, error) {
+	a, err := s.app(dbid)
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.SetVariableInput
+	if err := decodeXML(body, &in); err != nil {
+		return nil, err
+	}
+	if in.Name == "" {
+		return nil, errBadRequest("Invalid input", "Variable name is required.")
+	}
+
+	a.setVariable(in.Name, in.Value)
+	return &setDBVarOutput{}, nil
+}
+
+// getDBPage handles API_GetDBPage. Pages are referenced by ID or name.
+func (s *Server) getDBPage(dbid string, body []byte) (xmlOutput, error) {
+	a, err := s.app(dbid)
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.GetPageInput
+	if err := decodeXML(body, &in); err != nil {
+		return nil, err
+	}
+
+	p := a.page(in.PageID)
+	if p == nil {
+		return nil, errBadRequest("Invalid input", "Page %q not found.", in.PageID)
+	}
+
+	out := &getDBPageOutput{}
+	out.Body = p.body
+	return out, nil
+}
+
+// addReplaceDBPageInput models the request body of API_AddReplaceDBPage,
+// which is sent by both CreatePageInput and UpdatePageInput.
+type addReplaceDBPageInput struct {
+	PageID int    `xml:"pageid"`
+	Name   string `xml:"pagename"`
+	Body   string `xml:"pagebody"`
+}
+
+// addReplaceDBPage handles API_AddReplaceDBPage. The page with the passed ID
+// is replaced, otherwise the page with the passed name is replaced or
+// created.
+func (s *Server) addReplaceDBPage(dbid string, body []byte) (xmlOutput, error) {
+	a, err := s.app(dbid)
+	if err != nil {
+		return nil, err
+	}
+
+	var in addReplaceDBPageInput
+	if err := decodeXML(body, &in); err != nil {
+		return nil, err
+	}
+
+	var p *page
+	switch {
+	case in.PageID != 0:
+		if p = a.page(fmt.Sprint(in.PageID)); p == nil {
+			return nil, errBadRequest("Invalid input", "Page %d not found.", in.PageID)
+		}
+		if in.Name != "" {
+			p.name = in.Name
+		}
+	case in.Name != "":
+		if p = a.page(in.Name); p == nil {
+			p = &page{id: len(a.pages) + 1, name: in.Name}
+			a.pages = append(a.pages, p)
+		}
+	default:
+		return nil, errBadRequest("Invalid input", "Page ID or name is required.")
+	}
+	p.body = in.Body
+
+	out := &addReplaceDBPageOutput{}
+	out.PageID = p.id
+	return out, nil
+}
+
+// uploadFile handles API_UploadFile, which adds a new version of the file
+// in each passed file attachment field.
+func (s *Server) uploadFile(dbid string, body []byte) (xmlOutput, error) {
+	t, err := s.table(dbid)
+	if err != nil {
+		return nil, err
+	}
+
+	var in qbclient.CreateFileInput
+	if err := decodeXML(body, &in); err != nil {
+		return nil, err
+	}
+
+	rec := t.record(in.RecordID)
+	if rec == nil {
+		return nil, errNotFound("Record not found", "Record %d was not found in table %s.", in.RecordID, t.TableID)
+	}
+
+	for _, f := range in.Fields {
+		if fd := t.field(f.FieldID); fd == nil || fd.Type != qbclient.FieldFileAttachment {
+			return nil, errNotFound("Field not found", "File attachment field %d was not found in table %s.", f.FieldID, t.TableID)
+		}
+		if _, err := base64.StdEncoding.DecodeString(f.FileData); err != nil {
+			return nil, errBadRequest("Invalid input", "File data for field %d isn't base64 encoded.", f.FieldID)
+		}
+	}
+
+	now := s.now()
+	out := &uploadFileOutput{}
+	for _, f := range in.Fields {
+		v := rec[f.FieldID]
+		if v == nil || v.File == nil {
+			v = qbclient.NewFileAttachmentValue(&qbclient.File{})
+			rec[f.FieldID] = v
+		}
+
+		version := 1
+		if n := len(v.File.Version); n > 0 {
+			version = v.File.Version[n-1].Version + 1
+		}
+		v.File.URL = fmt.Sprintf("%s/files/%s/%d/%d/%d", s.URL, t.TableID, in.RecordID, f.FieldID, version)
+		v.File.Version = append(v.File.Version, &qbclient.FileVersion{
+			FileName: f.Name,
+			Uploaded: now,
+			Version:  version,
+		})
+
+		data, _ := base64.StdEncoding.DecodeString(f.FileData)
+		t.files[fileKey(in.RecordID, f.FieldID, version)] = data
+
+		out.Fields = append(out.Fields, &qbclient.CreateFileOutputField{FileID: f.FieldID, URL: v.File.URL})
+	}
+	rec[fidDateModified] = qbclient.NewDateTimeValue(now.Time)
+
+	return out, nil
+}